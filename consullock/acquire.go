@@ -0,0 +1,198 @@
+package consullock
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+// Acquire creates a session and obtains the lock of key with a KV
+// acquire, retrying a contended key with the backoff of
+// opts.RetryStrategy, like the pg adapter. An expired lock whose session
+// is still alive is taken over in a transaction, setting TookOver.
+//
+// ConfirmIfOwned takes over the lease of a lock held by opts.OwnerID
+// with a new nonce, keeping its metadata.
+func (a *ConsulLockAdapter) Acquire(ctx context.Context, key string, opts core.LockOptions) (*core.LockToken, error) {
+	if err := a.begin(); err != nil {
+		return nil, err
+	}
+	defer a.end()
+
+	storageKey, err := a.Cfg.storageKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.ValidateWithMaxTTL(a.Cfg.maxTTL()); err != nil {
+		return nil, err
+	}
+	metadata, err := core.EncodeMetadata(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	started := time.Now()
+
+	sessionCtx, cancel := context.WithTimeout(ctx, opts.RequestTimeout)
+	session, err := a.createSession(sessionCtx, key)
+	err = timedOut(ctx, sessionCtx, err)
+	cancel()
+	if err != nil {
+		return nil, &core.LockError{
+			Op:       core.OpAcquire,
+			Key:      key,
+			Attempts: 1,
+			Elapsed:  time.Since(started),
+			Err:      fmt.Errorf("failed to create session: %w", err),
+		}
+	}
+
+	// The session is the lease of the token, destroyed unless acquired
+	acquired := false
+	defer func() {
+		if !acquired {
+			a.destroySession(ctx, session)
+		}
+	}()
+
+	// tryAcquire runs a single attempt, returning a nil token on contention
+	tryAcquire := func(ctx context.Context, _ int) (*core.LockToken, func() *core.ContentionError, error) {
+		reqCtx, cancel := context.WithTimeout(ctx, opts.RequestTimeout)
+		defer cancel()
+
+		start := time.Now()
+		value := &lockValue{
+			Nonce:      a.Cfg.newID(),
+			OwnerID:    opts.OwnerID,
+			ValidUntil: start.Add(opts.TTL).UnixMilli(),
+			Metadata:   metadata,
+		}
+		token := &core.LockToken{
+			Key:                 key,
+			LeaseID:             session,
+			ValidUntil:          time.UnixMilli(value.ValidUntil),
+			ServerNonce:         value.Nonce,
+			OwnerID:             opts.OwnerID,
+			TTL:                 opts.TTL,
+			RefreshSafetyMargin: opts.RefreshSafetyMargin,
+		}
+
+		pair := &consulapi.KVPair{Key: storageKey, Value: value.encode(), Session: session}
+		ok, _, err := a.client.KV().Acquire(pair, (&consulapi.WriteOptions{}).WithContext(reqCtx))
+		a.observe(start, err)
+		if err != nil {
+			return nil, nil, timedOut(ctx, reqCtx, err)
+		}
+		if ok {
+			return token, nil, nil
+		}
+
+		// Held by another session, or free but within its lock-delay
+		current, currentValue, err := a.read(reqCtx, storageKey)
+		if err != nil {
+			return nil, nil, timedOut(ctx, reqCtx, err)
+		}
+		if current == nil || current.Session == "" || currentValue == nil || !currentValue.expired(time.Now()) {
+			return a.contended(ctx, key, storageKey, opts)
+		}
+
+		// The index check fails if the holder refreshed or released meanwhile
+		ok, err = a.txn(reqCtx,
+			&consulapi.KVTxnOp{Verb: consulapi.KVCheckIndex, Key: storageKey, Index: current.ModifyIndex},
+			&consulapi.KVTxnOp{Verb: consulapi.KVDelete, Key: storageKey},
+			&consulapi.KVTxnOp{Verb: consulapi.KVLock, Key: storageKey, Value: value.encode(), Session: session},
+		)
+		if err != nil {
+			return nil, nil, timedOut(ctx, reqCtx, err)
+		}
+		if !ok {
+			return a.contended(ctx, key, storageKey, opts)
+		}
+		// The session of the previous holder no longer holds any key
+		a.destroySession(ctx, current.Session)
+		token.TookOver = true
+		token.PreviousLeaseID = current.Session
+		return token, nil, nil
+	}
+
+	token, err := core.AcquireRetry{Key: key, Options: opts, Hooks: a.Cfg.Hooks}.Run(ctx, tryAcquire)
+	// The session of the holder, renewed by confirmOwned, is kept
+	acquired = token != nil && token.LeaseID == session
+	return token, err
+}
+
+// contended ends an attempt finding the key held: ConfirmIfOwned renews
+// a lock held by opts.OwnerID, others wait for the retries
+func (a *ConsulLockAdapter) contended(
+	ctx context.Context,
+	key, storageKey string,
+	opts core.LockOptions,
+) (*core.LockToken, func() *core.ContentionError, error) {
+	if opts.ConfirmIfOwned {
+		token, err := a.confirmOwned(ctx, key, storageKey, opts)
+		if err != nil || token != nil {
+			return token, nil, err
+		}
+	}
+	return nil, func() *core.ContentionError { return a.holder(ctx, storageKey) }, nil
+}
+
+// confirmOwned renews the lock of the key if it is held by opts.OwnerID,
+// returning a nil token otherwise
+func (a *ConsulLockAdapter) confirmOwned(ctx context.Context, key, storageKey string, opts core.LockOptions) (*core.LockToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, opts.RequestTimeout)
+	defer cancel()
+
+	pair, current, err := a.read(ctx, storageKey)
+	if err != nil || pair == nil || pair.Session == "" || current == nil || current.OwnerID != opts.OwnerID {
+		return nil, err
+	}
+
+	renewed, err := a.renewSession(ctx, pair.Session)
+	if err != nil || !renewed {
+		return nil, err
+	}
+
+	start := time.Now()
+	value := &lockValue{
+		Nonce:      a.Cfg.newID(),
+		OwnerID:    opts.OwnerID,
+		ValidUntil: start.Add(opts.TTL).UnixMilli(),
+		Metadata:   current.Metadata,
+	}
+	ok, err := a.txn(ctx, lockOps(pair, pair.Session, value)...)
+	if err != nil || !ok {
+		// Released or taken over meanwhile
+		return nil, err
+	}
+
+	return &core.LockToken{
+		Key:                 key,
+		LeaseID:             pair.Session,
+		ValidUntil:          time.UnixMilli(value.ValidUntil),
+		ServerNonce:         value.Nonce,
+		OwnerID:             opts.OwnerID,
+		TTL:                 opts.TTL,
+		RefreshSafetyMargin: opts.RefreshSafetyMargin,
+	}, nil
+}
+
+// holder describes the current holder of the storage key,
+// leaving the fields it cannot read empty
+func (a *ConsulLockAdapter) holder(ctx context.Context, storageKey string) *core.ContentionError {
+	pair, current, err := a.read(ctx, storageKey)
+	if err != nil || pair == nil || current == nil {
+		return &core.ContentionError{}
+	}
+	holder := &core.ContentionError{
+		HolderID:       current.OwnerID,
+		HolderMetadata: core.DecodeMetadata(current.Metadata),
+	}
+	if !current.expired(time.Now()) {
+		holder.HeldUntil = time.UnixMilli(current.ValidUntil)
+	}
+	return holder
+}
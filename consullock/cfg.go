@@ -0,0 +1,310 @@
+package consullock
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+// HealthCheck defaults
+const (
+	DefaultLatencyThreshold = 100 * time.Millisecond
+
+	// Fraction of failed recent operations
+	DefaultErrorRateThreshold = 0.05
+)
+
+// Prefix of the lock keys by default
+const DefaultKVPrefix = "lockbox/"
+
+// Lock-delay of the sessions by default, the one of Consul itself
+const DefaultLockDelay = 15 * time.Second
+
+// Bounds of Consul
+const (
+	// MaxLockDelay is the longest lock-delay of a session
+	MaxLockDelay = 60 * time.Second
+
+	// The TTL of a session must be [minSessionTTL, maxSessionTTL]
+	minSessionTTL = 10 * time.Second
+	maxSessionTTL = 24 * time.Hour
+)
+
+// ErrInvalidConfig is returned by Validate for an invalid configuration
+var ErrInvalidConfig = errors.New("invalid consul locker config")
+
+type ConsulLockerConfig struct {
+	// KVPrefix is prepended to the key of every lock, so the locks
+	// don't collide with the other keys of the KV store.
+	// Defaults to DefaultKVPrefix.
+	KVPrefix string
+
+	// Namespace transparently prefixed to every key, after KVPrefix, so
+	// adapters sharing the same KV store with different namespaces never
+	// collide. Segments are separated by core.KeySeparator.
+	Namespace string
+
+	Hooks core.Hooks
+
+	// MaxAllowedTTL raises (or lowers) the TTL ceiling of the adapter,
+	// up to 24h. The sessions outlive it, see ConsulLockAdapter.
+	// Defaults to core.MaxLockTTL.
+	MaxAllowedTTL time.Duration
+
+	// DefaultRequestTimeout bounds the requests of Release, Refresh and
+	// IsHeld, which have no LockOptions carrying a RequestTimeout. They
+	// fail with core.ErrOperationTimeout once it fires.
+	// Defaults to core.DefaultRequestTimeout.
+	DefaultRequestTimeout time.Duration
+
+	// RefreshSafetyMargin is the fraction of the new TTL during which an
+	// expired lock can still be refreshed, as long as nobody took it
+	// over. Must be [0, core.MaxRefreshMargin]; overridden per lock by
+	// core.LockOptions.RefreshSafetyMargin.
+	// Defaults to core.MaxClockDriftMargin when nil.
+	RefreshSafetyMargin *float64
+
+	// LockDelay is the lock-delay of the sessions: once Consul invalidates
+	// the session of a holder, e.g. its node failed, the key can't be
+	// acquired again before LockDelay, leaving the holder time to notice.
+	// Released locks are not delayed. Must be [0, MaxLockDelay]; Consul
+	// has no way to disable it, a millisecond is the shortest delay.
+	// Defaults to DefaultLockDelay.
+	LockDelay time.Duration
+
+	// HealthCheck reports StatusYellow when the probe takes longer than
+	// LatencyThreshold or the fraction (0.0-1.0) of the recent operations
+	// whose request failed exceeds ErrorRateThreshold
+	LatencyThreshold   time.Duration
+	ErrorRateThreshold float64
+
+	// IDGenerator produces the ServerNonce of the tokens; the LeaseID is
+	// the ID of the Consul session. Defaults to core.UUIDGenerator.
+	IDGenerator core.IDGenerator
+}
+
+// NewConsulLockerConfig creates a new instance of ConsulLockerConfig
+// with default values
+func NewConsulLockerConfig() *ConsulLockerConfig {
+	c := &ConsulLockerConfig{}
+	return c.WithDefaults()
+}
+
+func (c *ConsulLockerConfig) Validate() error {
+	msgs := []string{}
+	if strings.HasPrefix(c.KVPrefix, "/") {
+		msgs = append(msgs, "KVPrefix must not start with '/'")
+	}
+	if c.Namespace != "" {
+		if err := core.ValidateNamespace(c.Namespace); err != nil {
+			msgs = append(msgs, "Namespace must be [a-zA-Z0-9_-] segments separated by ':'")
+		}
+	}
+	if c.MaxAllowedTTL != 0 && (c.MaxAllowedTTL < core.MinLockTTL || c.MaxAllowedTTL > maxSessionTTL) {
+		msgs = append(msgs, fmt.Sprintf("MaxAllowedTTL must be [%v, %v]", core.MinLockTTL, maxSessionTTL))
+	}
+	if c.DefaultRequestTimeout < 0 {
+		msgs = append(msgs, "DefaultRequestTimeout must be ≥ 0")
+	}
+	if m := c.RefreshSafetyMargin; m != nil && (*m < 0 || *m > core.MaxRefreshMargin) {
+		msgs = append(msgs, fmt.Sprintf("RefreshSafetyMargin must be [0, %v]", core.MaxRefreshMargin))
+	}
+	if c.LockDelay < 0 || c.LockDelay > MaxLockDelay {
+		msgs = append(msgs, fmt.Sprintf("LockDelay must be [0, %v]", MaxLockDelay))
+	}
+	if c.LatencyThreshold < 0 {
+		msgs = append(msgs, "LatencyThreshold must be ≥ 0")
+	}
+	if c.ErrorRateThreshold < 0 || c.ErrorRateThreshold > 1 {
+		msgs = append(msgs, "ErrorRateThreshold must be [0.0, 1.0]")
+	}
+
+	if len(msgs) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidConfig, strings.Join(msgs, ", "))
+	}
+	return nil
+}
+
+// WithDefaults sets default values for the zero-valued fields.
+//
+// Returns the same instance
+// Defaults:
+//
+// - KVPrefix: DefaultKVPrefix
+//
+// - MaxAllowedTTL: core.MaxLockTTL
+//
+// - DefaultRequestTimeout: core.DefaultRequestTimeout
+//
+// - RefreshSafetyMargin: core.MaxClockDriftMargin
+//
+// - LockDelay: DefaultLockDelay
+//
+// - LatencyThreshold: 100ms
+//
+// - ErrorRateThreshold: 0.05
+//
+// - IDGenerator: core.UUIDGenerator
+func (c *ConsulLockerConfig) WithDefaults() *ConsulLockerConfig {
+	if c.KVPrefix == "" {
+		c.KVPrefix = DefaultKVPrefix
+	}
+	if c.MaxAllowedTTL == 0 {
+		c.MaxAllowedTTL = core.MaxLockTTL
+	}
+	if c.DefaultRequestTimeout == 0 {
+		c.DefaultRequestTimeout = core.DefaultRequestTimeout
+	}
+	if c.RefreshSafetyMargin == nil {
+		margin := core.MaxClockDriftMargin
+		c.RefreshSafetyMargin = &margin
+	}
+	if c.LockDelay == 0 {
+		c.LockDelay = DefaultLockDelay
+	}
+	if c.LatencyThreshold == 0 {
+		c.LatencyThreshold = DefaultLatencyThreshold
+	}
+	if c.ErrorRateThreshold == 0 {
+		c.ErrorRateThreshold = DefaultErrorRateThreshold
+	}
+	if c.IDGenerator == nil {
+		c.IDGenerator = core.UUIDGenerator{}
+	}
+	return c
+}
+
+// maxTTL returns the TTL ceiling of the adapter
+func (c *ConsulLockerConfig) maxTTL() time.Duration {
+	if c.MaxAllowedTTL == 0 {
+		return core.MaxLockTTL
+	}
+	return c.MaxAllowedTTL
+}
+
+// sessionTTL returns the TTL of the sessions, outliving the longest lock
+// and its refresh safety margin within the bounds of Consul
+func (c *ConsulLockerConfig) sessionTTL() time.Duration {
+	ttl := time.Duration(float64(c.maxTTL()) * (1 + core.MaxRefreshMargin))
+	return min(max(ttl, minSessionTTL), maxSessionTTL)
+}
+
+// requestTimeout returns the bound of the requests without LockOptions
+func (c *ConsulLockerConfig) requestTimeout() time.Duration {
+	if c.DefaultRequestTimeout == 0 {
+		return core.DefaultRequestTimeout
+	}
+	return c.DefaultRequestTimeout
+}
+
+// storageKey returns the KV key of the lock of key, prefixed by
+// KVPrefix and the namespace
+func (c *ConsulLockerConfig) storageKey(key string) (string, error) {
+	namespaced, err := core.NamespaceKey(c.Namespace, key)
+	if err != nil {
+		return "", err
+	}
+	return c.KVPrefix + namespaced, nil
+}
+
+// newID returns a ServerNonce from the IDGenerator, falling back to a
+// UUID for configurations built without WithDefaults
+func (c *ConsulLockerConfig) newID() string {
+	if c.IDGenerator == nil {
+		return core.UUIDGenerator{}.NewID()
+	}
+	return c.IDGenerator.NewID()
+}
+
+// SetKVPrefix sets the KVPrefix field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (c *ConsulLockerConfig) SetKVPrefix(v string) *ConsulLockerConfig {
+	c.KVPrefix = v
+	return c
+}
+
+// SetNamespace sets the Namespace field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (c *ConsulLockerConfig) SetNamespace(v string) *ConsulLockerConfig {
+	c.Namespace = v
+	return c
+}
+
+// SetHooks sets the Hooks field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (c *ConsulLockerConfig) SetHooks(v core.Hooks) *ConsulLockerConfig {
+	c.Hooks = v
+	return c
+}
+
+// SetMaxAllowedTTL sets the MaxAllowedTTL field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (c *ConsulLockerConfig) SetMaxAllowedTTL(v time.Duration) *ConsulLockerConfig {
+	c.MaxAllowedTTL = v
+	return c
+}
+
+// SetDefaultRequestTimeout sets the DefaultRequestTimeout field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (c *ConsulLockerConfig) SetDefaultRequestTimeout(v time.Duration) *ConsulLockerConfig {
+	c.DefaultRequestTimeout = v
+	return c
+}
+
+// SetRefreshSafetyMargin sets the RefreshSafetyMargin field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (c *ConsulLockerConfig) SetRefreshSafetyMargin(v float64) *ConsulLockerConfig {
+	c.RefreshSafetyMargin = &v
+	return c
+}
+
+// SetLockDelay sets the LockDelay field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (c *ConsulLockerConfig) SetLockDelay(v time.Duration) *ConsulLockerConfig {
+	c.LockDelay = v
+	return c
+}
+
+// SetLatencyThreshold sets the LatencyThreshold field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (c *ConsulLockerConfig) SetLatencyThreshold(v time.Duration) *ConsulLockerConfig {
+	c.LatencyThreshold = v
+	return c
+}
+
+// SetErrorRateThreshold sets the ErrorRateThreshold field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (c *ConsulLockerConfig) SetErrorRateThreshold(v float64) *ConsulLockerConfig {
+	c.ErrorRateThreshold = v
+	return c
+}
+
+// SetIDGenerator sets the IDGenerator field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (c *ConsulLockerConfig) SetIDGenerator(v core.IDGenerator) *ConsulLockerConfig {
+	c.IDGenerator = v
+	return c
+}
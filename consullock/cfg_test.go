@@ -0,0 +1,68 @@
+package consullock_test
+
+import (
+	"testing"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/oliveiracleidson/go-lockbox/consullock"
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewConsulLockerConfig_WithDefaults(t *testing.T) {
+	config := consullock.NewConsulLockerConfig()
+
+	assert.Equal(t, consullock.DefaultKVPrefix, config.KVPrefix)
+	assert.Equal(t, core.MaxLockTTL, config.MaxAllowedTTL)
+	assert.Equal(t, core.DefaultRequestTimeout, config.DefaultRequestTimeout)
+	assert.Equal(t, core.MaxClockDriftMargin, *config.RefreshSafetyMargin)
+	assert.Equal(t, consullock.DefaultLockDelay, config.LockDelay)
+	assert.Equal(t, consullock.DefaultLatencyThreshold, config.LatencyThreshold)
+	assert.Equal(t, consullock.DefaultErrorRateThreshold, config.ErrorRateThreshold)
+	assert.Equal(t, core.UUIDGenerator{}, config.IDGenerator)
+	assert.NoError(t, config.Validate())
+}
+
+func TestConsulLockerConfig_WithDefaults_RefreshSafetyMargin(t *testing.T) {
+	assert.Equal(t, core.MaxClockDriftMargin, *(&consullock.ConsulLockerConfig{}).WithDefaults().RefreshSafetyMargin)
+
+	// An explicit 0 refuses any late refresh, it is not defaulted
+	zero := 0.0
+	assert.Zero(t, *(&consullock.ConsulLockerConfig{RefreshSafetyMargin: &zero}).WithDefaults().RefreshSafetyMargin)
+}
+
+func TestConsulLockerConfig_Validate(t *testing.T) {
+	config := consullock.NewConsulLockerConfig().
+		SetKVPrefix("/lockbox/").
+		SetNamespace("team a").
+		SetMaxAllowedTTL(48 * time.Hour).
+		SetDefaultRequestTimeout(-time.Second).
+		SetRefreshSafetyMargin(0.9).
+		SetLockDelay(2 * time.Minute).
+		SetLatencyThreshold(-time.Second).
+		SetErrorRateThreshold(-0.1)
+
+	err := config.Validate()
+	require.ErrorIs(t, err, consullock.ErrInvalidConfig)
+	assert.Contains(t, err.Error(), "KVPrefix must not start with '/'")
+	assert.Contains(t, err.Error(), "Namespace must be")
+	assert.Contains(t, err.Error(), "MaxAllowedTTL must be")
+	assert.Contains(t, err.Error(), "DefaultRequestTimeout must be ≥ 0")
+	assert.Contains(t, err.Error(), "RefreshSafetyMargin must be")
+	assert.Contains(t, err.Error(), "LockDelay must be")
+	assert.Contains(t, err.Error(), "LatencyThreshold must be ≥ 0")
+	assert.Contains(t, err.Error(), "ErrorRateThreshold must be [0.0, 1.0]")
+}
+
+func TestNewConsulLockAdapter_InvalidConfig(t *testing.T) {
+	client, err := consulapi.NewClient(&consulapi.Config{Address: "127.0.0.1:1"})
+	require.NoError(t, err)
+
+	_, err = consullock.NewConsulLockAdapter(client, consullock.NewConsulLockerConfig().SetLockDelay(-time.Second))
+	require.ErrorIs(t, err, consullock.ErrInvalidConfig)
+
+	_, err = consullock.NewConsulLockAdapter(nil, consullock.NewConsulLockerConfig())
+	require.Error(t, err)
+}
@@ -0,0 +1,251 @@
+// Package consullock implements core.LockAdapter on the sessions and KV
+// store of Consul.
+//
+// It is a module of its own, so the Consul client is only pulled by the
+// services importing it:
+//
+//	client, err := consulapi.NewClient(consulapi.DefaultConfig())
+//	adapter, err := consullock.NewConsulLockAdapter(client, consullock.NewConsulLockerConfig())
+//
+// Every acquisition creates a session, whose ID is the LeaseID of the
+// token, and acquires the KV key of the lock with it. The value of the
+// key holds the ServerNonce, OwnerID and metadata of the holder, and
+// the expiry of the lock in Unix milliseconds. Refresh renews the session
+// and rewrites the value; Release deletes the key and destroys the
+// session.
+//
+// Consul sessions live for at least 10s and expire lazily, up to twice
+// their TTL, so the TTL of a lock is enforced by its expiry instead: an
+// expired lock is taken over by the next Acquire, setting TookOver, and
+// can be refreshed during the safety margin meanwhile. The sessions last
+// longer than any lock of the adapter, see MaxAllowedTTL, and only expire
+// on their own when the holder vanished without releasing.
+//
+// The expiry is computed from the clock of the host acquiring or
+// refreshing the lock, as Consul exposes none; hosts sharing locks must
+// keep their clocks in sync within the refresh safety margin. Once
+// Consul invalidates a session, e.g. its node failed, the key stays
+// locked for the LockDelay of the config.
+package consullock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+type ConsulLockAdapter struct {
+	client *consulapi.Client
+
+	// Read only once the adapter is created
+	Cfg *ConsulLockerConfig
+
+	// Reported by HealthCheck
+	startedAt time.Time
+	latencies *core.LatencyWindow
+
+	// Operations in flight, awaited by Close
+	closeMu  sync.RWMutex
+	closed   atomic.Bool
+	inFlight sync.WaitGroup
+}
+
+// NewConsulLockAdapter creates an adapter storing its locks through the
+// agent of client
+func NewConsulLockAdapter(client *consulapi.Client, cfg *ConsulLockerConfig) (*ConsulLockAdapter, error) {
+	if client == nil {
+		return nil, errors.New("consul client is required")
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &ConsulLockAdapter{
+		client:    client,
+		Cfg:       cfg,
+		startedAt: time.Now(),
+		latencies: core.NewLatencyWindow(core.DefaultLatencyWindowSize),
+	}, nil
+}
+
+// Close stops accepting operations and waits for the ones in flight.
+// The sessions of the locks still held are left to expire.
+//
+// Operations started after Close return core.ErrAdapterClosed. If ctx
+// expires first, the ctx error is returned. Closing twice is a no-op.
+func (a *ConsulLockAdapter) Close(ctx context.Context) error {
+	a.closeMu.Lock()
+	alreadyClosed := a.closed.Swap(true)
+	a.closeMu.Unlock()
+	if alreadyClosed {
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		a.inFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("operations still in flight: %w", ctx.Err())
+	}
+}
+
+// begin registers an operation in flight, failing once the adapter is
+// closed. Every successful begin must be paired with an end.
+func (a *ConsulLockAdapter) begin() error {
+	a.closeMu.RLock()
+	defer a.closeMu.RUnlock()
+	if a.closed.Load() {
+		return core.ErrAdapterClosed
+	}
+	a.inFlight.Add(1)
+	return nil
+}
+
+// end marks an operation started by begin as finished
+func (a *ConsulLockAdapter) end() {
+	a.inFlight.Done()
+}
+
+// withRequestTimeout bounds a request without LockOptions by the
+// DefaultRequestTimeout of the config
+func (a *ConsulLockAdapter) withRequestTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, a.Cfg.requestTimeout())
+}
+
+// timedOut wraps with core.ErrOperationTimeout the error of a request
+// cut by the timeout of reqCtx. The error of a ctx done beforehand is
+// returned as is.
+func timedOut(ctx, reqCtx context.Context, err error) error {
+	if err == nil || ctx.Err() != nil || !errors.Is(reqCtx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", core.ErrOperationTimeout, err)
+}
+
+// observe records the latency of an operation started at start, and
+// whether its request failed. A ctx cancelled by the caller is not a
+// failure.
+func (a *ConsulLockAdapter) observe(start time.Time, err error) {
+	if err == nil || errors.Is(err, context.Canceled) {
+		a.latencies.Record(time.Since(start))
+		return
+	}
+	a.latencies.RecordFailure(time.Since(start))
+}
+
+// lockValue is the value of the KV key of a lock
+type lockValue struct {
+	Nonce      string          `json:"nonce"`
+	OwnerID    string          `json:"owner_id,omitempty"`
+	ValidUntil int64           `json:"valid_until"` // Unix milliseconds
+	Metadata   json.RawMessage `json:"metadata,omitempty"`
+}
+
+// expired reports whether the lock expired at now
+func (v *lockValue) expired(now time.Time) bool {
+	return v.ValidUntil <= now.UnixMilli()
+}
+
+// encode returns the JSON of the value
+func (v *lockValue) encode() []byte {
+	encoded, _ := json.Marshal(v)
+	return encoded
+}
+
+// read returns the KV pair of the storage key and its decoded value,
+// with a consistent read; a nil pair when there is no lock. The value is
+// nil when the key was not written by an adapter.
+func (a *ConsulLockAdapter) read(ctx context.Context, storageKey string) (*consulapi.KVPair, *lockValue, error) {
+	q := &consulapi.QueryOptions{RequireConsistent: true}
+
+	start := time.Now()
+	pair, _, err := a.client.KV().Get(storageKey, q.WithContext(ctx))
+	a.observe(start, err)
+	if err != nil || pair == nil {
+		return nil, nil, err
+	}
+
+	var value lockValue
+	if err := json.Unmarshal(pair.Value, &value); err != nil || value.Nonce == "" {
+		return pair, nil, nil
+	}
+	return pair, &value, nil
+}
+
+// owns reports whether the pair holds the lease and nonce of the token
+func owns(pair *consulapi.KVPair, value *lockValue, token *core.LockToken) bool {
+	return pair.Session == token.LeaseID && value != nil && value.Nonce == token.ServerNonce
+}
+
+// txn runs the operations atomically, reporting false when one of them
+// failed and rolled the others back, e.g. a check of the index
+func (a *ConsulLockAdapter) txn(ctx context.Context, ops ...*consulapi.KVTxnOp) (bool, error) {
+	txnOps := make(consulapi.TxnOps, 0, len(ops))
+	for _, op := range ops {
+		txnOps = append(txnOps, &consulapi.TxnOp{KV: op})
+	}
+
+	start := time.Now()
+	ok, _, _, err := a.client.Txn().Txn(txnOps, (&consulapi.QueryOptions{}).WithContext(ctx))
+	a.observe(start, err)
+	return ok, err
+}
+
+// lockOps returns the operations writing value to the pair locked by
+// session, if it was not modified since it was read
+func lockOps(pair *consulapi.KVPair, session string, value *lockValue) []*consulapi.KVTxnOp {
+	return []*consulapi.KVTxnOp{
+		{Verb: consulapi.KVCheckIndex, Key: pair.Key, Index: pair.ModifyIndex},
+		{Verb: consulapi.KVLock, Key: pair.Key, Value: value.encode(), Session: session},
+	}
+}
+
+// createSession creates the session of a lock of key, invalidated by
+// Consul after the TTL of the sessions of the config or the failure of
+// the node of the agent, deleting the keys it holds
+func (a *ConsulLockAdapter) createSession(ctx context.Context, key string) (string, error) {
+	entry := &consulapi.SessionEntry{
+		Name:      "lockbox: " + key,
+		TTL:       a.Cfg.sessionTTL().String(),
+		Behavior:  consulapi.SessionBehaviorDelete,
+		LockDelay: a.Cfg.LockDelay,
+	}
+
+	start := time.Now()
+	id, _, err := a.client.Session().Create(entry, (&consulapi.WriteOptions{}).WithContext(ctx))
+	a.observe(start, err)
+	return id, err
+}
+
+// renewSession resets the TTL of the session, reporting false when
+// Consul already invalidated it
+func (a *ConsulLockAdapter) renewSession(ctx context.Context, id string) (bool, error) {
+	start := time.Now()
+	entry, _, err := a.client.Session().Renew(id, (&consulapi.WriteOptions{}).WithContext(ctx))
+	a.observe(start, err)
+	return entry != nil, err
+}
+
+// destroySession destroys the session once its lock is released or was
+// never acquired, even if ctx is already done. A session failing to be
+// destroyed expires after its TTL.
+func (a *ConsulLockAdapter) destroySession(ctx context.Context, id string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), a.Cfg.requestTimeout())
+	defer cancel()
+
+	start := time.Now()
+	_, err := a.client.Session().Destroy(id, (&consulapi.WriteOptions{}).WithContext(ctx))
+	a.observe(start, err)
+}
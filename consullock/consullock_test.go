@@ -0,0 +1,67 @@
+package consullock_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/oliveiracleidson/go-lockbox/consullock"
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsulLockAdapter_Unreachable(t *testing.T) {
+	// Nothing listens on port 1
+	client, err := consulapi.NewClient(&consulapi.Config{Address: "127.0.0.1:1"})
+	require.NoError(t, err)
+	adapter, err := consullock.NewConsulLockAdapter(client, consullock.NewConsulLockerConfig())
+	require.NoError(t, err)
+	defer adapter.Close(context.Background())
+
+	t.Run("given an unreachable agent, when health check, then status is Red", func(t *testing.T) {
+		report := adapter.HealthCheck(context.Background())
+		require.Equal(t, core.StatusRed, report.Status)
+		require.Equal(t, "consul", report.Backend)
+		require.ErrorContains(t, report.Error, "health probe failed")
+	})
+
+	t.Run("given an unreachable agent, when acquire, then fails without retrying", func(t *testing.T) {
+		_, err := adapter.Acquire(context.Background(), "key", core.LockOptions{TTL: time.Second})
+		lockErr, ok := core.AsLockError(err)
+		require.True(t, ok)
+		require.Equal(t, 1, lockErr.Attempts)
+		require.NotErrorIs(t, err, core.ErrLockContention)
+	})
+}
+
+func TestConsulLockAdapter_Closed(t *testing.T) {
+	client, err := consulapi.NewClient(&consulapi.Config{Address: "127.0.0.1:1"})
+	require.NoError(t, err)
+	closed, err := consullock.NewConsulLockAdapter(client, consullock.NewConsulLockerConfig())
+	require.NoError(t, err)
+	require.NoError(t, closed.Close(context.Background()))
+	require.NoError(t, closed.Close(context.Background()), "closing twice is a no-op")
+
+	ctx := context.Background()
+	token := &core.LockToken{Key: "key", LeaseID: "session", ServerNonce: "nonce"}
+
+	t.Run("given a closed adapter, when operate, then returns ErrAdapterClosed", func(t *testing.T) {
+		_, err := closed.Acquire(ctx, "key", core.LockOptions{TTL: time.Second})
+		require.ErrorIs(t, err, core.ErrAdapterClosed)
+
+		_, err = closed.Refresh(ctx, token, time.Second)
+		require.ErrorIs(t, err, core.ErrAdapterClosed)
+
+		require.ErrorIs(t, closed.Release(ctx, token), core.ErrAdapterClosed)
+
+		_, _, err = closed.IsHeld(ctx, token)
+		require.ErrorIs(t, err, core.ErrAdapterClosed)
+	})
+
+	t.Run("given a closed adapter, when health check, then status is Red", func(t *testing.T) {
+		report := closed.HealthCheck(ctx)
+		require.Equal(t, core.StatusRed, report.Status)
+		require.ErrorIs(t, report.Error, core.ErrAdapterClosed)
+	})
+}
@@ -0,0 +1,195 @@
+package consullock_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/oliveiracleidson/go-lockbox/consullock"
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/oliveiracleidson/go-lockbox/core/locktest"
+	"github.com/stretchr/testify/require"
+)
+
+// newClient returns a client of the agent of CONSUL_HTTP_ADDR, skipping
+// the test without one
+func newClient(t *testing.T) *consulapi.Client {
+	if os.Getenv("CONSUL_HTTP_ADDR") == "" {
+		t.Skip("CONSUL_HTTP_ADDR is required for the integration tests")
+	}
+	client, err := consulapi.NewClient(consulapi.DefaultConfig())
+	require.NoError(t, err)
+	return client
+}
+
+// newAdapter returns an adapter on the agent of CONSUL_HTTP_ADDR
+func newAdapter(t *testing.T, client *consulapi.Client, cfg *consullock.ConsulLockerConfig) *consullock.ConsulLockAdapter {
+	adapter, err := consullock.NewConsulLockAdapter(client, cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { adapter.Close(context.Background()) })
+	return adapter
+}
+
+func TestConsulLockAdapter_Contract(t *testing.T) {
+	client := newClient(t)
+	adapter := newAdapter(t, client, consullock.NewConsulLockerConfig().SetNamespace("contract"))
+	locktest.Run(t, adapter, "contract-consul")
+}
+
+func TestConsulLockAdapter_Integration(t *testing.T) {
+	client := newClient(t)
+	adapter := newAdapter(t, client, consullock.NewConsulLockerConfig().SetNamespace("integration"))
+	ctx := context.Background()
+	opts := core.LockOptions{
+		TTL:            10 * time.Second,
+		RetryStrategy:  core.NoRetry(),
+		RequestTimeout: 5 * time.Second,
+		OwnerID:        "worker-1",
+		Metadata:       map[string]string{"job": "reindex"},
+	}
+
+	t.Run("given a held key, when acquire with retries, then succeeds once released", func(t *testing.T) {
+		token, err := adapter.Acquire(ctx, "retried", opts)
+		require.NoError(t, err)
+		go func() {
+			time.Sleep(150 * time.Millisecond)
+			adapter.Release(ctx, token)
+		}()
+
+		retried := opts
+		retried.RetryStrategy = core.RetryStrategy{
+			MaxRetries:    10,
+			BaseDelay:     50 * time.Millisecond,
+			MaxDelay:      100 * time.Millisecond,
+			BackoffFactor: 2,
+		}
+		token, err = adapter.Acquire(ctx, "retried", retried)
+		require.NoError(t, err)
+		require.NoError(t, adapter.Release(ctx, token))
+	})
+
+	t.Run("given a held key, when acquire, then the contention error describes the holder", func(t *testing.T) {
+		token, err := adapter.Acquire(ctx, "described", opts)
+		require.NoError(t, err)
+		defer adapter.Release(ctx, token)
+
+		other := opts
+		other.OwnerID = "worker-2"
+		_, err = adapter.Acquire(ctx, "described", other)
+		var contentionErr *core.ContentionError
+		require.ErrorAs(t, err, &contentionErr)
+		require.Equal(t, "worker-1", contentionErr.HolderID)
+		require.Equal(t, map[string]string{"job": "reindex"}, contentionErr.HolderMetadata)
+		require.WithinDuration(t, token.ValidUntil, contentionErr.HeldUntil, time.Second)
+	})
+
+	t.Run("given a key held by the same owner, when acquire with ConfirmIfOwned, then takes over its session", func(t *testing.T) {
+		token, err := adapter.Acquire(ctx, "confirmed", opts)
+		require.NoError(t, err)
+
+		confirm := opts
+		confirm.ConfirmIfOwned = true
+		confirmed, err := adapter.Acquire(ctx, "confirmed", confirm)
+		require.NoError(t, err)
+		require.Equal(t, token.LeaseID, confirmed.LeaseID)
+		require.NotEqual(t, token.ServerNonce, confirmed.ServerNonce)
+
+		require.ErrorIs(t, adapter.Release(ctx, token), core.ErrLockOwnershipMismatch)
+		require.NoError(t, adapter.Release(ctx, confirmed))
+	})
+
+	t.Run("given an expired lock, when acquire, then takes it over from the previous session", func(t *testing.T) {
+		short := opts
+		short.TTL = 50 * time.Millisecond
+		expired, err := adapter.Acquire(ctx, "taken-over", short)
+		require.NoError(t, err)
+		time.Sleep(150 * time.Millisecond)
+
+		token, err := adapter.Acquire(ctx, "taken-over", opts)
+		require.NoError(t, err)
+		require.True(t, token.TookOver)
+		require.Equal(t, expired.LeaseID, token.PreviousLeaseID)
+		require.NotEqual(t, expired.LeaseID, token.LeaseID)
+
+		_, err = adapter.Refresh(ctx, expired, time.Second)
+		require.ErrorIs(t, err, core.ErrLockOwnershipMismatch)
+		require.NoError(t, adapter.Release(ctx, token))
+	})
+
+	t.Run("given a lock expired within the safety margin, when refresh, then extends it", func(t *testing.T) {
+		short := opts
+		short.TTL = 50 * time.Millisecond
+		token, err := adapter.Acquire(ctx, "margin", short)
+		require.NoError(t, err)
+		time.Sleep(100 * time.Millisecond)
+
+		// 15% of 1s is 150ms of margin
+		refreshed, err := adapter.Refresh(ctx, token, time.Second)
+		require.NoError(t, err)
+		require.NoError(t, adapter.Release(ctx, refreshed))
+	})
+
+	t.Run("given a released lock, when release, then destroys its session", func(t *testing.T) {
+		token, err := adapter.Acquire(ctx, "destroyed", opts)
+		require.NoError(t, err)
+		require.NoError(t, adapter.Release(ctx, token))
+
+		session, _, err := client.Session().Info(token.LeaseID, nil)
+		require.NoError(t, err)
+		require.Nil(t, session)
+	})
+
+	t.Run("given an invalidated session, when refresh, then fails with lock not found", func(t *testing.T) {
+		// The key is free again for the next run
+		undelayed := newAdapter(t, client, consullock.NewConsulLockerConfig().
+			SetNamespace("integration").
+			SetLockDelay(time.Millisecond))
+
+		token, err := undelayed.Acquire(ctx, "invalidated", opts)
+		require.NoError(t, err)
+		_, err = client.Session().Destroy(token.LeaseID, nil)
+		require.NoError(t, err)
+
+		held, _, err := undelayed.IsHeld(ctx, token)
+		require.NoError(t, err)
+		require.False(t, held)
+
+		_, err = undelayed.Refresh(ctx, token, time.Second)
+		require.ErrorIs(t, err, core.ErrLockNotFound)
+	})
+
+	t.Run("given an invalidated session, when acquire, then waits for the lock-delay", func(t *testing.T) {
+		delayed := newAdapter(t, client, consullock.NewConsulLockerConfig().
+			SetNamespace("integration").
+			SetLockDelay(500*time.Millisecond))
+
+		token, err := delayed.Acquire(ctx, "delayed", opts)
+		require.NoError(t, err)
+		_, err = client.Session().Destroy(token.LeaseID, nil)
+		require.NoError(t, err)
+
+		_, err = delayed.Acquire(ctx, "delayed", opts)
+		require.ErrorIs(t, err, core.ErrLockContention)
+
+		time.Sleep(600 * time.Millisecond)
+		token, err = delayed.Acquire(ctx, "delayed", opts)
+		require.NoError(t, err)
+		require.NoError(t, delayed.Release(ctx, token))
+	})
+
+	t.Run("given a reachable agent, when health check, then status is Green", func(t *testing.T) {
+		report := adapter.HealthCheck(ctx)
+		require.Equal(t, core.StatusGreen, report.Status, report.Error)
+		require.NotEmpty(t, report.Details["leader"])
+		require.NotEmpty(t, report.Details["datacenter"])
+	})
+
+	t.Run("given a consul DSN, when open, then returns a consul adapter", func(t *testing.T) {
+		opened, err := core.Open(ctx, "consul://"+os.Getenv("CONSUL_HTTP_ADDR"))
+		require.NoError(t, err)
+		defer opened.Close(ctx)
+		require.Equal(t, "consul", opened.HealthCheck(ctx).Backend)
+	})
+}
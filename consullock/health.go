@@ -0,0 +1,81 @@
+package consullock
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+// HealthCheck monitors service health.
+// Latency is the average latency and Throughput the operations per second
+// of the recent lock operations; the latency of the probe itself, reading
+// the leader of the cluster and the agent self endpoint, is reported in
+// Details["probe_latency"], along with the leader and the datacenter and
+// node of the agent.
+//
+// The status is Red when the probe fails, the cluster has no leader or
+// the adapter is closed, and Yellow when the probe takes longer than
+// LatencyThreshold or the fraction of the recent operations that failed
+// exceeds ErrorRateThreshold.
+func (a *ConsulLockAdapter) HealthCheck(ctx context.Context) core.HealthReport {
+	if err := a.begin(); err != nil {
+		return core.HealthReport{Status: core.StatusRed, Error: err, Backend: "consul"}
+	}
+	defer a.end()
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	leader, err := a.client.Status().LeaderWithQueryOptions((&consulapi.QueryOptions{}).WithContext(ctx))
+	var self struct {
+		Config struct {
+			Datacenter string
+			NodeName   string
+		}
+	}
+	if err == nil {
+		_, err = a.client.Raw().Query("/v1/agent/self", &self, (&consulapi.QueryOptions{}).WithContext(ctx))
+	}
+	latency := time.Since(start)
+
+	errorRate := a.latencies.ErrorRate()
+
+	status, reportErr := core.StatusGreen, error(nil)
+	switch {
+	case err != nil:
+		status, reportErr = core.StatusRed, fmt.Errorf("health probe failed: %w", err)
+	case leader == "":
+		status, reportErr = core.StatusRed, fmt.Errorf("health probe failed: no cluster leader")
+	case latency > a.Cfg.LatencyThreshold:
+		status, reportErr = core.StatusYellow, fmt.Errorf("high latency: %v > %v", latency, a.Cfg.LatencyThreshold)
+	case errorRate > a.Cfg.ErrorRateThreshold:
+		status, reportErr = core.StatusYellow, fmt.Errorf("high error rate: %.2f > %.2f", errorRate, a.Cfg.ErrorRateThreshold)
+	}
+
+	percentiles := a.latencies.Percentiles(50, 95, 99)
+
+	return core.HealthReport{
+		Status:     status,
+		Latency:    a.latencies.Average(),
+		Throughput: a.latencies.Throughput(time.Now(), core.DefaultThroughputWindow),
+		ErrorRate:  errorRate,
+		Error:      reportErr,
+		LatencyP50: percentiles[0],
+		LatencyP95: percentiles[1],
+		LatencyP99: percentiles[2],
+		Operations: a.latencies.Total(),
+		Uptime:     time.Since(a.startedAt),
+		Backend:    "consul",
+		Details: map[string]string{
+			"probe_latency": latency.String(),
+			"kv_prefix":     a.Cfg.KVPrefix,
+			"leader":        leader,
+			"datacenter":    self.Config.Datacenter,
+			"node":          self.Config.NodeName,
+		},
+	}
+}
@@ -0,0 +1,40 @@
+package consullock
+
+import (
+	"context"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+// IsHeld reports whether the token still owns its lock and the remaining
+// TTL: the Session of the KV entry must be the LeaseID of the token and
+// its value the nonce of the token, not expired yet. The read fails with
+// core.ErrOperationTimeout after DefaultRequestTimeout.
+func (a *ConsulLockAdapter) IsHeld(ctx context.Context, token *core.LockToken) (bool, time.Duration, error) {
+	if err := a.begin(); err != nil {
+		return false, 0, err
+	}
+	defer a.end()
+
+	storageKey, err := a.Cfg.storageKey(token.Key)
+	if err != nil {
+		return false, 0, err
+	}
+
+	reqCtx, cancel := a.withRequestTimeout(ctx)
+	defer cancel()
+
+	pair, current, err := a.read(reqCtx, storageKey)
+	if err != nil {
+		return false, 0, timedOut(ctx, reqCtx, err)
+	}
+	if pair == nil || !owns(pair, current, token) {
+		return false, 0, nil
+	}
+	remaining := time.Until(time.UnixMilli(current.ValidUntil))
+	if remaining <= 0 {
+		return false, 0, nil
+	}
+	return true, remaining, nil
+}
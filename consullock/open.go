@@ -0,0 +1,41 @@
+package consullock
+
+import (
+	"context"
+	"net/url"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+func init() {
+	core.Register("consul", open)
+}
+
+// open creates a ConsulLockAdapter with its own client from a DSN for
+// core.Open: consul://host:8500, with the optional query parameters
+// token, datacenter and tls=true for HTTPS. The other settings are the
+// ones of consulapi.DefaultConfig, read from the CONSUL_HTTP_*
+// environment variables.
+func open(ctx context.Context, dsn *url.URL, openCfg core.OpenConfig) (core.LockAdapter, error) {
+	cfg := consulapi.DefaultConfig()
+	if dsn.Host != "" {
+		cfg.Address = dsn.Host
+	}
+	query := dsn.Query()
+	if token := query.Get("token"); token != "" {
+		cfg.Token = token
+	}
+	if datacenter := query.Get("datacenter"); datacenter != "" {
+		cfg.Datacenter = datacenter
+	}
+	if query.Get("tls") == "true" {
+		cfg.Scheme = "https"
+	}
+
+	client, err := consulapi.NewClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewConsulLockAdapter(client, NewConsulLockerConfig().SetHooks(openCfg.Hooks))
+}
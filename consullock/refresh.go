@@ -0,0 +1,104 @@
+package consullock
+
+import (
+	"context"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+// Refresh renews the session of the token, extends the lock and returns
+// a new token. An expired lock can still be refreshed during the safety
+// margin (RefreshSafetyMargin of newTTL), as long as nobody took it over.
+//
+// Errors wrap:
+//
+// - core.ErrInvalidTTL: newTTL is out of range
+//
+// - core.ErrRefreshTooLate: the lock expired beyond the safety margin
+//
+// - core.ErrLockOwnershipMismatch: the key is held with another session or nonce
+//
+// - core.ErrLockNotFound: there is no lock for the key, or its session was invalidated
+//
+// - core.ErrOperationTimeout: the requests outlasted DefaultRequestTimeout
+func (a *ConsulLockAdapter) Refresh(ctx context.Context, token *core.LockToken, newTTL time.Duration) (*core.LockToken, error) {
+	if err := a.begin(); err != nil {
+		return nil, err
+	}
+	defer a.end()
+
+	fail := func(err error) (*core.LockToken, error) {
+		a.Cfg.Hooks.RefreshFailed(ctx, token, err)
+		return nil, &core.LockError{Op: core.OpRefresh, Key: token.Key, Attempts: 1, Err: err}
+	}
+
+	if err := core.ValidateTTL(newTTL, a.Cfg.maxTTL()); err != nil {
+		return fail(err)
+	}
+	storageKey, err := a.Cfg.storageKey(token.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	reqCtx, cancel := a.withRequestTimeout(ctx)
+	defer cancel()
+
+	pair, current, err := a.read(reqCtx, storageKey)
+	switch {
+	case err != nil:
+		return fail(timedOut(ctx, reqCtx, err))
+	case pair == nil:
+		return fail(core.ErrLockNotFound)
+	case !owns(pair, current, token):
+		return fail(core.ErrLockOwnershipMismatch)
+	}
+
+	now := time.Now()
+	margin := time.Duration(float64(newTTL) * a.refreshSafetyMargin(token))
+	if current.expired(now.Add(-margin)) {
+		return fail(core.ErrRefreshTooLate)
+	}
+
+	renewed, err := a.renewSession(reqCtx, token.LeaseID)
+	if err != nil {
+		return fail(timedOut(ctx, reqCtx, err))
+	}
+	if !renewed {
+		return fail(core.ErrLockNotFound)
+	}
+
+	value := &lockValue{
+		Nonce:      a.Cfg.newID(),
+		OwnerID:    current.OwnerID,
+		ValidUntil: now.Add(newTTL).UnixMilli(),
+		Metadata:   current.Metadata,
+	}
+	ok, err := a.txn(reqCtx, lockOps(pair, token.LeaseID, value)...)
+	if err != nil {
+		return fail(timedOut(ctx, reqCtx, err))
+	}
+	if !ok {
+		// Released, refreshed or taken over since it was read
+		return fail(core.ErrLockOwnershipMismatch)
+	}
+
+	refreshed := *token
+	refreshed.ValidUntil = time.UnixMilli(value.ValidUntil)
+	refreshed.ServerNonce = value.Nonce
+	refreshed.TTL = newTTL
+	return &refreshed, nil
+}
+
+// refreshSafetyMargin returns the margin of the token, or the one of the
+// config when the acquisition didn't override it, falling back to
+// core.MaxClockDriftMargin for configurations built without WithDefaults
+func (a *ConsulLockAdapter) refreshSafetyMargin(token *core.LockToken) float64 {
+	if token.RefreshSafetyMargin != nil {
+		return *token.RefreshSafetyMargin
+	}
+	if a.Cfg.RefreshSafetyMargin != nil {
+		return *a.Cfg.RefreshSafetyMargin
+	}
+	return core.MaxClockDriftMargin
+}
@@ -0,0 +1,62 @@
+package consullock
+
+import (
+	"context"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+// Release releases the lock of the token, deleting its key so no
+// lock-delay applies, then destroys its session.
+//
+// Errors wrap:
+//
+// - core.ErrLockNotFound: there is no lock for the key
+//
+// - core.ErrLockOwnershipMismatch: the key is held with another session or nonce
+//
+// - core.ErrOperationTimeout: the requests outlasted DefaultRequestTimeout
+func (a *ConsulLockAdapter) Release(ctx context.Context, token *core.LockToken) error {
+	if err := a.begin(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	storageKey, err := a.Cfg.storageKey(token.Key)
+	if err != nil {
+		return err
+	}
+
+	reqCtx, cancel := a.withRequestTimeout(ctx)
+	defer cancel()
+
+	pair, current, err := a.read(reqCtx, storageKey)
+	if err == nil {
+		switch {
+		case pair == nil:
+			err = core.ErrLockNotFound
+		case !owns(pair, current, token):
+			err = core.ErrLockOwnershipMismatch
+		}
+	}
+	if err == nil {
+		// The index check fails if the lock changed since it was read
+		var ok bool
+		ok, err = a.txn(reqCtx,
+			&consulapi.KVTxnOp{Verb: consulapi.KVCheckIndex, Key: storageKey, Index: pair.ModifyIndex},
+			&consulapi.KVTxnOp{Verb: consulapi.KVDelete, Key: storageKey},
+		)
+		if err == nil && !ok {
+			err = core.ErrLockOwnershipMismatch
+		}
+	}
+	if err != nil {
+		err = timedOut(ctx, reqCtx, err)
+		return &core.LockError{Op: core.OpRelease, Key: token.Key, Attempts: 1, Err: err}
+	}
+
+	a.destroySession(ctx, token.LeaseID)
+	a.Cfg.Hooks.Released(ctx, token)
+	return nil
+}
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// BoundLock is a lock tied to the lifetime of a context, see AcquireBound
+type BoundLock struct {
+	adapter LockAdapter
+	cancel  context.CancelFunc
+	done    chan struct{}
+	err     error // Set before done is closed
+
+	mu    sync.Mutex
+	token *LockToken
+}
+
+// AcquireBound acquires the key and releases it in the background as soon
+// as ctx is done or Release is called, whichever comes first, pairing the
+// lock with the lifetime of a request:
+//
+//	lock, err := core.AcquireBound(r.Context(), adapter, "orders-123", opts)
+//	if err != nil {
+//		return err
+//	}
+//	defer lock.Release()
+//
+// The release runs with its own opts.RequestTimeout, ctx being already
+// done by then. Its outcome is reported by Done and Err.
+func AcquireBound(ctx context.Context, adapter LockAdapter, key string, opts LockOptions) (*BoundLock, error) {
+	token, err := adapter.Acquire(ctx, key, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := opts.RequestTimeout
+	if timeout <= 0 {
+		timeout = DefaultRequestTimeout
+	}
+
+	boundCtx, cancel := context.WithCancel(ctx)
+	b := &BoundLock{
+		adapter: adapter,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		token:   token,
+	}
+
+	go func() {
+		<-boundCtx.Done()
+
+		releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+		defer cancel()
+
+		b.mu.Lock()
+		b.err = adapter.Release(releaseCtx, b.token)
+		b.mu.Unlock()
+		close(b.done)
+	}()
+
+	return b, nil
+}
+
+// Token returns the current token of the lock
+func (b *BoundLock) Token() *LockToken {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.token
+}
+
+// Refresh extends the lock, keeping the token released later up to date.
+// It fails with ErrLockNotFound once the lock is being released.
+func (b *BoundLock) Refresh(ctx context.Context, newTTL time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	select {
+	case <-b.done:
+		return ErrLockNotFound
+	default:
+	}
+
+	refreshed, err := b.adapter.Refresh(ctx, b.token, newTTL)
+	if err != nil {
+		return err
+	}
+	b.token = refreshed
+	return nil
+}
+
+// Release releases the lock in the background without waiting for it,
+// like a context.CancelFunc. Calling it again, or after ctx is done, is
+// a no-op.
+func (b *BoundLock) Release() {
+	b.cancel()
+}
+
+// Done is closed once the lock has been released, or the release failed
+func (b *BoundLock) Done() <-chan struct{} {
+	return b.done
+}
+
+// Err returns the error of the release once Done is closed, nil before
+func (b *BoundLock) Err() error {
+	select {
+	case <-b.done:
+		return b.err
+	default:
+		return nil
+	}
+}
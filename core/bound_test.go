@@ -0,0 +1,110 @@
+package core_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/stretchr/testify/require"
+)
+
+// releaseCounter records the releases of its locks
+type releaseCounter struct {
+	core.LockAdapter
+
+	mu       sync.Mutex
+	released []*core.LockToken
+	err      error
+}
+
+func (r *releaseCounter) Acquire(ctx context.Context, key string, opts core.LockOptions) (*core.LockToken, error) {
+	return &core.LockToken{Key: key, LeaseID: "lease", ServerNonce: "nonce-0"}, nil
+}
+
+func (r *releaseCounter) Refresh(ctx context.Context, token *core.LockToken, newTTL time.Duration) (*core.LockToken, error) {
+	refreshed := *token
+	refreshed.ServerNonce = "nonce-1"
+	return &refreshed, nil
+}
+
+func (r *releaseCounter) Release(ctx context.Context, token *core.LockToken) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.released = append(r.released, token)
+	return r.err
+}
+
+func (r *releaseCounter) releases() []*core.LockToken {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.released
+}
+
+func TestAcquireBound(t *testing.T) {
+	t.Run("given a bound lock, when ctx is cancelled, then the lock is released once", func(t *testing.T) {
+		adapter := &releaseCounter{}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		lock, err := core.AcquireBound(ctx, adapter, "key", core.LockOptions{})
+		require.NoError(t, err)
+		require.Equal(t, "key", lock.Token().Key)
+
+		cancel()
+		<-lock.Done()
+		require.NoError(t, lock.Err())
+
+		lock.Release()
+		require.Len(t, adapter.releases(), 1)
+	})
+
+	t.Run("given a bound lock, when released twice, then the second release is a no-op", func(t *testing.T) {
+		adapter := &releaseCounter{}
+
+		lock, err := core.AcquireBound(context.Background(), adapter, "key", core.LockOptions{})
+		require.NoError(t, err)
+		require.NoError(t, lock.Err())
+
+		lock.Release()
+		lock.Release()
+		<-lock.Done()
+		require.Len(t, adapter.releases(), 1)
+	})
+
+	t.Run("given a refreshed bound lock, when released, then the refreshed token is released", func(t *testing.T) {
+		adapter := &releaseCounter{}
+
+		lock, err := core.AcquireBound(context.Background(), adapter, "key", core.LockOptions{})
+		require.NoError(t, err)
+		require.NoError(t, lock.Refresh(context.Background(), time.Second))
+
+		lock.Release()
+		<-lock.Done()
+		require.Equal(t, "nonce-1", adapter.releases()[0].ServerNonce)
+
+		err = lock.Refresh(context.Background(), time.Second)
+		require.ErrorIs(t, err, core.ErrLockNotFound)
+	})
+
+	t.Run("given a failing release, when released, then Err reports it", func(t *testing.T) {
+		failure := errors.New("backend down")
+		adapter := &releaseCounter{err: failure}
+
+		lock, err := core.AcquireBound(context.Background(), adapter, "key", core.LockOptions{})
+		require.NoError(t, err)
+
+		lock.Release()
+		<-lock.Done()
+		require.ErrorIs(t, lock.Err(), failure)
+	})
+
+	t.Run("given a failing acquisition, when acquire bound, then returns the error", func(t *testing.T) {
+		_, err := core.AcquireBound(context.Background(), &stubAdapter{}, "key", core.LockOptions{})
+		require.ErrorIs(t, err, core.ErrLockContention)
+	})
+}
@@ -0,0 +1,27 @@
+package core
+
+import (
+	"context"
+	"time"
+)
+
+// ContentionInfo describes how contended a key currently is
+type ContentionInfo struct {
+	Key       string        // Inspected resource key
+	Held      bool          // Whether a valid lock exists on the key
+	Remaining time.Duration // Remaining TTL of the current holder (0 if not held)
+
+	// Number of callers currently retrying to acquire the key.
+	//
+	// Backends without a shared waiting queue can only count the waiters
+	// of the local adapter instance, so this is a lower bound of the real
+	// contention across processes.
+	Waiters int
+}
+
+// ContentionInspector is implemented by adapters able to report the
+// contention of a key, allowing a caller to decide whether to wait.
+type ContentionInspector interface {
+	// ContentionInfo returns the contention state of a key
+	ContentionInfo(ctx context.Context, key string) (*ContentionInfo, error)
+}
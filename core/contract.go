@@ -18,10 +18,15 @@ package core
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
+	"math/rand/v2"
 	"regexp"
+	"strings"
 	"time"
 )
 
@@ -34,7 +39,7 @@ var (
 	ErrLockOwnershipMismatch = errors.New("lock ownership mismatch")
 
 	// Specified TTL is out of allowed range
-	ErrInvalidTTL = errors.New("invalid TTL duration (1ms-10m)")
+	ErrInvalidTTL = errors.New("invalid TTL duration (1ms-10m unless raised by the adapter)")
 
 	// Operation attempted on a closed adapter
 	ErrAdapterClosed = errors.New("lock adapter closed")
@@ -45,6 +50,9 @@ var (
 	// Invalid key format
 	ErrInvalidKeyFormat = errors.New("invalid key format (max 256 chars, [a-zA-Z0-9_-])")
 
+	// Invalid namespace format
+	ErrInvalidNamespace = errors.New("invalid namespace format ([a-zA-Z0-9_-] segments separated by ':')")
+
 	// Renewal beyond the safe margin
 	ErrRefreshTooLate = errors.New("lock refresh beyond safety margin")
 
@@ -53,6 +61,24 @@ var (
 
 	// Lock not found
 	ErrLockNotFound = errors.New("lock not found")
+
+	// Invalid owner ID format
+	ErrInvalidOwnerID = errors.New("invalid owner ID format (max 256 chars, [a-zA-Z0-9_-])")
+
+	// Encoded metadata larger than MaxMetadataSize
+	ErrMetadataTooLarge = errors.New("lock metadata too large (max 8KB encoded)")
+
+	// MetadataJSON that is not a JSON object
+	ErrInvalidMetadata = errors.New("invalid lock metadata (must be a JSON object)")
+
+	// Operation refused because the backend was last reported unhealthy
+	ErrBackendUnhealthy = errors.New("lock backend unhealthy")
+
+	// Auto-renewal stopped after keeping the lock for its MaxHoldDuration
+	ErrMaxHoldExceeded = errors.New("lock held beyond its max hold duration")
+
+	// Lock still held but within the clock drift margin of expiring
+	ErrLockExpiring = errors.New("lock about to expire (within clock drift margin)")
 )
 
 // Configuration constants
@@ -63,8 +89,10 @@ const (
 	DefaultMaxRetries     = 5                    // Default retry attempts
 	DefaultJitterFactor   = 0.3                  // Default jitter factor
 	MaxClockDriftMargin   = 0.15                 // Maximum clock drift margin
+	MaxRefreshMargin      = 0.5                  // Maximum refresh safety margin
 	MaxKeyLength          = 256                  // Maximum key length
 	DefaultRequestTimeout = 3 * time.Second      // Default timeout
+	MaxMetadataSize       = 8 * 1024             // Maximum encoded metadata size in bytes
 )
 
 // LockOptions defines parameters for lock acquisition
@@ -73,19 +101,107 @@ type LockOptions struct {
 	RetryStrategy  RetryStrategy     // Retry strategy
 	Metadata       map[string]string // Custom metadata
 	RequestTimeout time.Duration     // Per-operation timeout
+	OwnerID        string            // Owner identity (defaults to DefaultOwnerID())
+
+	// MetadataJSON is a JSON object stored verbatim as the metadata, so
+	// its values can be numbers, nested objects or timestamps. When set,
+	// Metadata is ignored.
+	MetadataJSON json.RawMessage
+
+	// ConfirmIfOwned makes Acquire refresh and return the current lock when
+	// the key is already held by OwnerID, instead of contending against it.
+	// The returned token has the lease of the current lock and a new nonce,
+	// invalidating tokens previously issued for it.
+	//
+	// Meant for leader election with a stable OwnerID, so a node never
+	// fights its own lock after a transient reconnect.
+	ConfirmIfOwned bool
+
+	// RefreshSafetyMargin overrides, for the refreshes of this lock, the
+	// fraction of the new TTL during which the adapter still refreshes
+	// it once expired, e.g. 0.02 on hosts with a tight NTP discipline.
+	// Must be [0, MaxRefreshMargin]; nil keeps the margin of the adapter.
+	// Carried by the token, see LockToken.RefreshSafetyMargin.
+	RefreshSafetyMargin *float64
+
+	// BackoffFunc overrides CalculateBackoff for the delays between the
+	// attempts of Acquire, e.g. for decorrelated jitter or a fixed
+	// schedule. It gets the RetryStrategy and the number of the attempt
+	// that just failed, starting at 0; nil uses CalculateBackoff.
+	BackoffFunc func(strategy RetryStrategy, attempt int) time.Duration
 }
 
-// Validate checks LockOptions parameters
-func (o *LockOptions) Validate() error {
-	if o.TTL < MinLockTTL || o.TTL > MaxLockTTL {
-		return fmt.Errorf("%w: %v", ErrInvalidTTL, o.TTL)
+// Backoff returns the delay before the attempt following attempt, from
+// BackoffFunc when set or else CalculateBackoff
+func (o *LockOptions) Backoff(attempt int) time.Duration {
+	if o.BackoffFunc != nil {
+		return o.BackoffFunc(o.RetryStrategy, attempt)
+	}
+	return CalculateBackoff(o.RetryStrategy, attempt)
+}
+
+// WithDefaults sets default values for the zero-valued fields.
+// Explicit values, even invalid ones, are kept for Validate to reject.
+//
+// Returns the same instance
+// Defaults:
+//
+// - TTL: DefaultLockTTL
+//
+// - RetryStrategy: DefaultRetryStrategy()
+//
+// - RequestTimeout: DefaultRequestTimeout
+//
+// - OwnerID: DefaultOwnerID()
+func (o *LockOptions) WithDefaults() *LockOptions {
+	if o.TTL == 0 {
+		o.TTL = DefaultLockTTL
+	}
+	if o.RetryStrategy == (RetryStrategy{}) {
+		o.RetryStrategy = DefaultRetryStrategy()
 	}
 	if o.RequestTimeout <= 0 {
 		o.RequestTimeout = DefaultRequestTimeout
 	}
+	if o.OwnerID == "" {
+		o.OwnerID = DefaultOwnerID()
+	}
+	return o
+}
+
+// Validate checks LockOptions parameters, setting the defaults
+// of the zero-valued fields first
+func (o *LockOptions) Validate() error {
+	return o.ValidateWithMaxTTL(MaxLockTTL)
+}
+
+// ValidateWithMaxTTL checks LockOptions parameters allowing TTLs up to
+// maxTTL instead of MaxLockTTL.
+//
+// Adapters use it to let operators deliberately raise the TTL ceiling.
+// The MinLockTTL floor is always enforced.
+func (o *LockOptions) ValidateWithMaxTTL(maxTTL time.Duration) error {
+	o.WithDefaults()
+	if err := ValidateTTL(o.TTL, maxTTL); err != nil {
+		return err
+	}
+	if err := ValidateOwnerID(o.OwnerID); err != nil {
+		return err
+	}
+	if m := o.RefreshSafetyMargin; m != nil && (*m < 0 || *m > MaxRefreshMargin) {
+		return fmt.Errorf("refresh safety margin must be [0, %v]", MaxRefreshMargin)
+	}
 	return o.RetryStrategy.Validate()
 }
 
+// ValidateTTL checks that ttl is within [MinLockTTL, maxTTL]
+func ValidateTTL(ttl, maxTTL time.Duration) error {
+	if ttl < MinLockTTL || ttl > maxTTL {
+		return fmt.Errorf("%w: %v (max %v)", ErrInvalidTTL, ttl, maxTTL)
+	}
+	return nil
+}
+
 // RetryStrategy defines a retry policy
 type RetryStrategy struct {
 	MaxRetries    int           // Maximum number of attempts
@@ -93,8 +209,24 @@ type RetryStrategy struct {
 	MaxDelay      time.Duration // Maximum delay
 	JitterFactor  float64       // Random variation (0.0-1.0)
 	BackoffFactor float64       // Exponential growth factor
+	MaxElapsed    time.Duration // Total retry budget, attempts and delays included (0 = unbounded)
+	JitterMode    JitterMode    // Randomization of the delays, JitterNone by default
 }
 
+// JitterMode selects how CalculateBackoff randomizes the exponential delay
+// d = min(MaxDelay, BaseDelay * BackoffFactor^attempt)
+type JitterMode int
+
+const (
+	// JitterNone waits exactly d
+	JitterNone JitterMode = iota
+	// JitterEqual waits d/2 plus a random duration in [0, d/2)
+	JitterEqual
+	// JitterFull waits a random duration in [0, d), the AWS "full jitter",
+	// which spreads contending acquirers the most
+	JitterFull
+)
+
 func (r *RetryStrategy) Validate() error {
 	if r.MaxRetries < 0 {
 		return errors.New("max retries must be ≥ 0")
@@ -105,15 +237,57 @@ func (r *RetryStrategy) Validate() error {
 	if r.BackoffFactor < 1 {
 		return errors.New("backoff factor must be ≥ 1")
 	}
+	if r.MaxElapsed < 0 {
+		return errors.New("max elapsed must be ≥ 0")
+	}
+	if r.JitterMode < JitterNone || r.JitterMode > JitterFull {
+		return errors.New("unknown jitter mode")
+	}
 	return nil
 }
 
+// Deadline returns the moment retries must stop for an acquisition
+// started at start: the sooner of start+MaxElapsed and the ctx deadline.
+//
+// Returns false if neither is set.
+func (r *RetryStrategy) Deadline(ctx context.Context, start time.Time) (time.Time, bool) {
+	deadline, ok := ctx.Deadline()
+	if r.MaxElapsed > 0 {
+		budget := start.Add(r.MaxElapsed)
+		if !ok || budget.Before(deadline) {
+			return budget, true
+		}
+	}
+	return deadline, ok
+}
+
 // LockToken represents a successfully acquired lock
 type LockToken struct {
-	Key         string    // Locked resource key
-	LeaseID     string    // Unique lock identifier
-	ValidUntil  time.Time // Absolute expiration
-	ServerNonce string    // Security nonce
+	Key         string        // Locked resource key
+	LeaseID     string        // Unique lock identifier
+	ValidUntil  time.Time     // Absolute expiration
+	ServerNonce string        // Security nonce
+	OwnerID     string        // Owner identity
+	TTL         time.Duration // Lease of the last acquisition or refresh
+
+	// Time source of Remaining, IsExpired and NeedsRefresh,
+	// defaults to time.Now
+	Clock func() time.Time
+
+	// Offset of the backend clock, which sets ValidUntil, to the local
+	// clock. Added to Clock so Remaining, IsExpired and NeedsRefresh
+	// compare ValidUntil against the backend time.
+	ClockOffset time.Duration
+
+	// TookOver is set when the acquisition overwrote the lock of a
+	// previous holder whose TTL lapsed, a sign it probably crashed
+	// mid-work. PreviousLeaseID is the lease of that holder, when known.
+	TookOver        bool
+	PreviousLeaseID string
+
+	// RefreshSafetyMargin of the LockOptions of the acquisition, kept
+	// for the refreshes; nil for the margin of the adapter
+	RefreshSafetyMargin *float64
 }
 
 // LockAdapter main interface for distributed locks
@@ -141,7 +315,8 @@ type LockAdapter interface {
 	//
 	// Security:
 	// - Checks clock drift margin
-	// - Updates ServerNonce
+	// - Updates ServerNonce: returns a new token, the previous one
+	//   stops working
 	Refresh(ctx context.Context, token *LockToken, newTTL time.Duration) (*LockToken, error)
 
 	// IsHeld checks lock validity and ownership
@@ -157,9 +332,37 @@ type LockAdapter interface {
 // HealthReport provides service health status
 type HealthReport struct {
 	Status     HealthStatus  // Overall state
-	Latency    time.Duration // Average latency
-	Throughput float64       // Operations per second
-	Error      error         // Last relevant error
+	Latency    time.Duration // Average latency of recent operations
+	Throughput float64       // Operations per second over DefaultThroughputWindow
+	ErrorRate  float64       // Fraction of recent operations that failed (0.0-1.0)
+	Error      error         // Why the status is not Green, nil when healthy
+
+	LastError   error     // Last failed health probe, kept after recovering
+	LastErrorAt time.Time // When LastError happened
+
+	LatencyP50 time.Duration     // Median latency of recent operations
+	LatencyP95 time.Duration     // 95th percentile latency of recent operations
+	LatencyP99 time.Duration     // 99th percentile latency of recent operations
+	Operations uint64            // Operations since the adapter was created
+	Uptime     time.Duration     // Time since the adapter was created
+	Backend    string            // Backend identification (e.g. "postgres")
+	Pool       *PoolStats        // Connection pool state (nil without a pool)
+	Details    map[string]string // Backend specific information
+}
+
+// PoolStats describes the connection pool of an adapter
+type PoolStats struct {
+	MaxConns      int32   // Maximum size of the pool
+	TotalConns    int32   // Open connections, acquired or idle
+	AcquiredConns int32   // Connections in use
+	IdleConns     int32   // Connections ready to be acquired
+	Usage         float64 // AcquiredConns / MaxConns (0.0-1.0)
+
+	// Connections opened since the pool was created (0 if unknown)
+	NewConns int64
+	// Acquisitions that found no idle connection and had to wait for
+	// one, the leading indicator of a saturated pool (0 if unknown)
+	EmptyAcquires int64
 }
 
 type HealthStatus int
@@ -170,26 +373,214 @@ const (
 	StatusRed
 )
 
+// KeySeparator separates the namespace segments of a key, e.g. "team-a:orders:123"
+const KeySeparator = ":"
+
+var (
+	validKeyRegex       = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,256}$`)
+	validNamespaceRegex = regexp.MustCompile(`^([a-zA-Z0-9_-]+:)*[a-zA-Z0-9_-]+$`)
+)
+
 func ValidateKey(key string) error {
-	validKeyRegex := regexp.MustCompile(`^[a-zA-Z0-9_-]{1,256}$`)
 	if !validKeyRegex.MatchString(key) {
 		return fmt.Errorf("%w: %s", ErrInvalidKeyFormat, key)
 	}
 	return nil
 }
 
-// Helper for calculating backoff time
+// ValidateNamespace checks the namespace format: [a-zA-Z0-9_-] segments
+// separated by KeySeparator, e.g. "team-a:orders".
+func ValidateNamespace(namespace string) error {
+	if !validNamespaceRegex.MatchString(namespace) {
+		return fmt.Errorf("%w: %s", ErrInvalidNamespace, namespace)
+	}
+	return nil
+}
+
+// NamespaceKey prefixes the key with the namespace, the only way to build
+// a key containing KeySeparator.
+//
+// The key itself is validated by ValidateKey, so keys of different
+// namespaces never collide, and the result must not exceed MaxKeyLength.
+// An empty namespace returns the key as is.
+func NamespaceKey(namespace, key string) (string, error) {
+	if err := ValidateKey(key); err != nil {
+		return "", err
+	}
+	if namespace == "" {
+		return key, nil
+	}
+	if err := ValidateNamespace(namespace); err != nil {
+		return "", err
+	}
+	namespaced := namespace + KeySeparator + key
+	if len(namespaced) > MaxKeyLength {
+		return "", fmt.Errorf("%w: %s", ErrInvalidKeyFormat, namespaced)
+	}
+	return namespaced, nil
+}
+
+// StripNamespace removes the namespace prefix from a namespaced key
+func StripNamespace(namespace, key string) string {
+	if namespace == "" {
+		return key
+	}
+	return strings.TrimPrefix(key, namespace+KeySeparator)
+}
+
+// HashedKeyPrefix starts the keys returned by HashKey
+const HashedKeyPrefix = "sha256_"
+
+// HashKey derives a valid key from any string, e.g. a URL or a key
+// longer than MaxKeyLength: HashedKeyPrefix followed by the hex SHA-256
+// of key.
+//
+// Two keys only share a hash through a SHA-256 collision, not a practical
+// concern. A raw key of the same form collides with the key it is the
+// hash of though, so keys should not start with HashedKeyPrefix.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return HashedKeyPrefix + hex.EncodeToString(sum[:])
+}
+
+// Helper for calculating backoff time, randomized by the JitterMode
 func CalculateBackoff(strategy RetryStrategy, attempt int) time.Duration {
 	delay := strategy.BaseDelay * time.Duration(math.Pow(
 		strategy.BackoffFactor,
 		float64(attempt),
 	))
 	if delay > strategy.MaxDelay {
-		return strategy.MaxDelay
+		delay = strategy.MaxDelay
+	}
+	if delay <= 0 {
+		return delay
+	}
+
+	switch strategy.JitterMode {
+	case JitterEqual:
+		half := delay / 2
+		return half + rand.N(delay-half)
+	case JitterFull:
+		return rand.N(delay)
 	}
 	return delay
 }
 
+// AcquireAttempt runs a single acquisition attempt for AcquireRetry.
+//
+// It returns the token once the lock is obtained. A contended key returns
+// a nil token and error, with holder describing the blocker; holder is
+// only called once the retries are exhausted, so an adapter reads the
+// holder at most once, and may be nil when it is unknown.
+type AcquireAttempt func(ctx context.Context, attempt int) (token *LockToken, holder func() *ContentionError, err error)
+
+// AcquireRetry runs the attempts of the Acquire of an adapter, which only
+// supplies the single attempt, so every backend retries alike.
+//
+// A contended key is retried with the backoff of Options (see
+// LockOptions.Backoff) until Options.RetryStrategy.MaxRetries or its
+// MaxElapsed budget is exhausted, failing with a *LockError wrapping the
+// *ContentionError of the holder. A ctx done during the backoff fails
+// right away with ErrOperationTimeout, and any other error of an attempt
+// fails with a *LockError wrapping it.
+type AcquireRetry struct {
+	Key     string
+	Options LockOptions
+	Hooks   Hooks
+
+	// Transient reports the errors of an attempt retried like a
+	// contention, without counting as one, e.g. a busy database.
+	// Nil retries none.
+	Transient func(err error) bool
+}
+
+// Run retries try until it obtains the lock or the budget is exhausted
+func (r AcquireRetry) Run(ctx context.Context, try AcquireAttempt) (*LockToken, error) {
+	started := time.Now()
+	deadline, hasDeadline := r.Options.RetryStrategy.Deadline(ctx, started)
+	attempts := 0
+
+	fail := func(err error) (*LockToken, error) {
+		return nil, &LockError{
+			Op:       OpAcquire,
+			Key:      r.Key,
+			Attempts: attempts,
+			Elapsed:  time.Since(started),
+			Err:      err,
+		}
+	}
+
+	// backoff waits before the next attempt, reporting false once the
+	// budget is exhausted
+	backoff := func(attempt int) bool {
+		delay := r.Options.Backoff(attempt)
+		if attempt == r.Options.RetryStrategy.MaxRetries {
+			return false
+		}
+		// The retry budget would be exhausted before the next attempt
+		if hasDeadline && time.Now().Add(delay).After(deadline) {
+			return false
+		}
+		SleepContext(ctx, delay)
+		return true
+	}
+
+	var holder func() *ContentionError
+	for attempt := 0; attempt <= r.Options.RetryStrategy.MaxRetries; attempt++ {
+		attempts++
+		token, blocker, err := try(ctx, attempt)
+		if err == nil && token != nil {
+			r.Hooks.Acquired(ctx, token)
+			return token, nil
+		}
+
+		if err == nil {
+			holder = blocker
+			r.Hooks.Contention(ctx, r.Key, attempt)
+		} else if r.Transient == nil || !r.Transient(err) || ctx.Err() != nil {
+			return fail(fmt.Errorf("failed to acquire lock: %w", err))
+		}
+
+		if !backoff(attempt) {
+			if err != nil {
+				return fail(fmt.Errorf("failed to acquire lock: %w", err))
+			}
+			break
+		}
+		// Cancellation aborts the backoff right away
+		if err := ctx.Err(); err != nil {
+			return fail(fmt.Errorf("%w: %w", ErrOperationTimeout, err))
+		}
+	}
+
+	contention := &ContentionError{}
+	if holder != nil {
+		if described := holder(); described != nil {
+			contention = described
+		}
+	}
+	return nil, &LockError{
+		Op:               OpAcquire,
+		Key:              r.Key,
+		Attempts:         attempts,
+		Elapsed:          time.Since(started),
+		LastHolderExpiry: contention.HeldUntil,
+		LastHolderID:     contention.HolderID,
+		Err:              contention,
+	}
+}
+
+// SleepContext waits for d or until ctx is done
+func SleepContext(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
 // Advanced Example:
 //
 //  // Configuration with exponential retry
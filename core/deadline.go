@@ -0,0 +1,56 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// AcquireUntil acquires the key until deadline instead of for a TTL:
+//
+//	token, err := core.AcquireUntil(ctx, adapter, "report-2024", endOfWindow, opts)
+//
+// The TTL is deadline minus the current time, taken right before the
+// request, and replaces opts.TTL. A deadline closer than MinLockTTL fails
+// with ErrInvalidTTL without reaching the backend; one beyond the TTL
+// ceiling of the adapter is rejected by the adapter with ErrInvalidTTL.
+//
+// The retries stop at deadline, a lock obtained afterwards being of no
+// use. A lock obtained after retrying still holds for the TTL taken
+// before the first attempt, so it can outlive deadline by the time
+// spent waiting; bound it with RetryStrategy.MaxElapsed when it matters.
+func AcquireUntil(ctx context.Context, adapter LockAdapter, key string, deadline time.Time, opts LockOptions) (*LockToken, error) {
+	ttl, err := ttlUntil(deadline, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	opts.TTL = ttl
+
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+	return adapter.Acquire(ctx, key, opts)
+}
+
+// RefreshUntil refreshes the lock of token until deadline, the TTL being
+// deadline minus the current time of the token's Clock, taken right before
+// the request. The bounds are the ones of AcquireUntil.
+func RefreshUntil(ctx context.Context, adapter LockAdapter, token *LockToken, deadline time.Time) (*LockToken, error) {
+	now := time.Now
+	if token.Clock != nil {
+		now = token.Clock
+	}
+	ttl, err := ttlUntil(deadline, now())
+	if err != nil {
+		return nil, err
+	}
+	return adapter.Refresh(ctx, token, ttl)
+}
+
+// ttlUntil converts deadline into a TTL from now, at least MinLockTTL
+func ttlUntil(deadline, now time.Time) (time.Duration, error) {
+	ttl := deadline.Sub(now)
+	if ttl < MinLockTTL {
+		return 0, fmt.Errorf("%w: deadline %v is %v away (min %v)", ErrInvalidTTL, deadline.Format(time.RFC3339Nano), ttl, MinLockTTL)
+	}
+	return ttl, nil
+}
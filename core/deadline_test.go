@@ -0,0 +1,84 @@
+package core_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/oliveiracleidson/go-lockbox/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquireUntil(t *testing.T) {
+	ctx := context.Background()
+	opts := core.LockOptions{RetryStrategy: core.NoRetry()}
+
+	t.Run("given a deadline, when acquire, then the lock expires at the deadline", func(t *testing.T) {
+		adapter := memory.NewMemoryLockAdapter()
+		deadline := time.Now().Add(time.Minute)
+
+		token, err := core.AcquireUntil(ctx, adapter, "key", deadline, opts)
+		require.NoError(t, err)
+		require.WithinDuration(t, deadline, token.ValidUntil, 50*time.Millisecond)
+	})
+
+	t.Run("given a deadline closer than the min TTL, when acquire, then fails with ErrInvalidTTL", func(t *testing.T) {
+		adapter := memory.NewMemoryLockAdapter()
+
+		_, err := core.AcquireUntil(ctx, adapter, "key", time.Now().Add(-time.Second), opts)
+		require.ErrorIs(t, err, core.ErrInvalidTTL)
+		_, err = core.AcquireUntil(ctx, adapter, "key", time.Now().Add(core.MinLockTTL/2), opts)
+		require.ErrorIs(t, err, core.ErrInvalidTTL)
+	})
+
+	t.Run("given a deadline beyond the max TTL, when acquire, then fails with ErrInvalidTTL", func(t *testing.T) {
+		adapter := memory.NewMemoryLockAdapter()
+
+		_, err := core.AcquireUntil(ctx, adapter, "key", time.Now().Add(core.MaxLockTTL+time.Minute), opts)
+		require.ErrorIs(t, err, core.ErrInvalidTTL)
+	})
+
+	t.Run("given a held key, when the deadline passes while retrying, then stops retrying", func(t *testing.T) {
+		adapter := memory.NewMemoryLockAdapter()
+		_, err := adapter.Acquire(ctx, "key", core.LockOptions{TTL: time.Minute})
+		require.NoError(t, err)
+
+		retried := core.LockOptions{RetryStrategy: core.RetryStrategy{
+			MaxRetries: 1000, BaseDelay: 5 * time.Millisecond, MaxDelay: 5 * time.Millisecond, BackoffFactor: 1,
+		}}
+		start := time.Now()
+		_, err = core.AcquireUntil(ctx, adapter, "key", start.Add(50*time.Millisecond), retried)
+		require.ErrorIs(t, err, core.ErrLockContention)
+		require.Less(t, time.Since(start), time.Second)
+	})
+}
+
+func TestRefreshUntil(t *testing.T) {
+	ctx := context.Background()
+	clock := memory.NewManualClock(time.Now())
+	adapter := memory.NewMemoryLockAdapter(memory.WithClock(clock.Now))
+
+	token, err := adapter.Acquire(ctx, "key", core.LockOptions{TTL: time.Minute})
+	require.NoError(t, err)
+
+	t.Run("given a deadline, when refresh, then the lock expires at the deadline", func(t *testing.T) {
+		deadline := clock.Now().Add(5 * time.Minute)
+
+		refreshed, err := core.RefreshUntil(ctx, adapter, token, deadline)
+		require.NoError(t, err)
+		require.Equal(t, deadline, refreshed.ValidUntil)
+		require.Equal(t, 5*time.Minute, refreshed.TTL)
+		token = refreshed
+	})
+
+	t.Run("given a deadline closer than the min TTL, when refresh, then fails with ErrInvalidTTL", func(t *testing.T) {
+		_, err := core.RefreshUntil(ctx, adapter, token, clock.Now())
+		require.ErrorIs(t, err, core.ErrInvalidTTL)
+	})
+
+	t.Run("given a deadline beyond the max TTL, when refresh, then fails with ErrInvalidTTL", func(t *testing.T) {
+		_, err := core.RefreshUntil(ctx, adapter, token, clock.Now().Add(core.MaxLockTTL+time.Second))
+		require.ErrorIs(t, err, core.ErrInvalidTTL)
+	})
+}
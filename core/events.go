@@ -0,0 +1,147 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LockEventType tells what happened to a lock
+type LockEventType int
+
+const (
+	EventAcquired      LockEventType = iota // The lock was acquired
+	EventReleased                           // The holder released the lock
+	EventRefreshed                          // The holder extended the lock
+	EventContentionHit                      // An acquisition attempt found the key held
+	EventForceReleased                      // The lock was removed without its token, e.g. once expired
+)
+
+func (t LockEventType) String() string {
+	switch t {
+	case EventAcquired:
+		return "acquired"
+	case EventReleased:
+		return "released"
+	case EventRefreshed:
+		return "refreshed"
+	case EventContentionHit:
+		return "contention_hit"
+	case EventForceReleased:
+		return "force_released"
+	}
+	return "unknown"
+}
+
+// LockEvent is a step of the lifecycle of a lock, for dashboards and
+// audit trails
+type LockEvent struct {
+	Type    LockEventType
+	Key     string
+	LeaseID string    // Empty for EventContentionHit
+	At      time.Time // When the adapter observed the event
+}
+
+// EventPolicy tells what an EventStream does with an event its buffer
+// has no room for
+type EventPolicy int
+
+const (
+	// EventDrop discards the event, counted by Dropped, so a slow
+	// consumer never stalls the lock operations
+	EventDrop EventPolicy = iota
+
+	// EventBlock makes the lock operation wait for the consumer, until
+	// its ctx is done, so no event is lost while the consumer keeps up
+	EventBlock
+)
+
+// EventStream delivers the lock events of an adapter to a consumer
+// through a buffered channel.
+//
+// A nil EventStream discards every event.
+type EventStream struct {
+	policy  EventPolicy
+	events  chan LockEvent
+	dropped atomic.Uint64
+
+	// Close stops the blocked emitters before closing events
+	mu     sync.RWMutex
+	closed bool
+	done   chan struct{}
+}
+
+// NewEventStream creates a stream buffering up to size events
+func NewEventStream(size int, policy EventPolicy) *EventStream {
+	return &EventStream{
+		policy: policy,
+		events: make(chan LockEvent, size),
+		done:   make(chan struct{}),
+	}
+}
+
+// Events returns the channel of the events, closed by Close; nil for a
+// nil stream
+func (s *EventStream) Events() <-chan LockEvent {
+	if s == nil {
+		return nil
+	}
+	return s.events
+}
+
+// Emit delivers the event according to the policy. Events emitted once
+// the stream is closed are discarded.
+func (s *EventStream) Emit(ctx context.Context, event LockEvent) {
+	if s == nil {
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+
+	if s.policy == EventBlock {
+		select {
+		case s.events <- event:
+		case <-s.done:
+		case <-ctx.Done():
+			s.dropped.Add(1)
+		}
+		return
+	}
+
+	select {
+	case s.events <- event:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// Dropped returns how many events were discarded for lack of room
+func (s *EventStream) Dropped() uint64 {
+	if s == nil {
+		return 0
+	}
+	return s.dropped.Load()
+}
+
+// Close closes the channel of the events once the blocked emitters gave
+// up. Closing twice is a no-op.
+func (s *EventStream) Close() {
+	if s == nil {
+		return
+	}
+	select {
+	case <-s.done:
+		return
+	default:
+		close(s.done)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	close(s.events)
+}
@@ -0,0 +1,71 @@
+package core_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventStream(t *testing.T) {
+	event := core.LockEvent{Type: core.EventAcquired, Key: "key", LeaseID: "lease", At: time.Now()}
+
+	t.Run("given the drop policy and a full buffer, when emit, then the event is dropped without blocking", func(t *testing.T) {
+		stream := core.NewEventStream(1, core.EventDrop)
+		stream.Emit(context.Background(), event)
+		stream.Emit(context.Background(), event)
+
+		require.EqualValues(t, 1, stream.Dropped())
+		require.Equal(t, event, <-stream.Events())
+	})
+
+	t.Run("given the block policy and a full buffer, when emit, then waits for the consumer or ctx", func(t *testing.T) {
+		stream := core.NewEventStream(1, core.EventBlock)
+		stream.Emit(context.Background(), event)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		stream.Emit(ctx, event)
+		require.EqualValues(t, 1, stream.Dropped())
+
+		emitted := make(chan struct{})
+		go func() {
+			stream.Emit(context.Background(), event)
+			close(emitted)
+		}()
+		<-stream.Events()
+		<-emitted
+		require.Len(t, stream.Events(), 1)
+	})
+
+	t.Run("given a blocked emitter, when close, then the emitter gives up and the channel is closed", func(t *testing.T) {
+		stream := core.NewEventStream(0, core.EventBlock)
+		emitted := make(chan struct{})
+		go func() {
+			stream.Emit(context.Background(), event)
+			close(emitted)
+		}()
+
+		stream.Close()
+		<-emitted
+		_, open := <-stream.Events()
+		require.False(t, open)
+
+		require.NotPanics(t, func() {
+			stream.Emit(context.Background(), event)
+			stream.Close()
+		})
+	})
+
+	t.Run("given a nil stream, when used, then events are discarded", func(t *testing.T) {
+		var stream *core.EventStream
+		require.NotPanics(t, func() {
+			stream.Emit(context.Background(), event)
+			stream.Close()
+		})
+		require.Nil(t, stream.Events())
+		require.Zero(t, stream.Dropped())
+	})
+}
@@ -0,0 +1,103 @@
+package core
+
+import (
+	"context"
+)
+
+// Hooks defines optional callbacks invoked by an adapter at relevant
+// points of the lock lifecycle.
+//
+// Hooks are passed to the adapter at construction time and run
+// synchronously on the goroutine performing the operation, so they
+// should be cheap. A panicking hook never breaks the lock operation:
+// the panic is recovered, reported to OnHookPanic (if set) and the
+// operation continues.
+type Hooks struct {
+	// Called after a lock is successfully acquired
+	OnAcquired func(ctx context.Context, token *LockToken)
+
+	// Called after a lock is successfully released
+	OnReleased func(ctx context.Context, token *LockToken)
+
+	// Called every time an acquisition attempt finds the key held by
+	// another owner. attempt starts at 0.
+	OnContention func(ctx context.Context, key string, attempt int)
+
+	// Called when a refresh fails for any reason
+	OnRefreshFailed func(ctx context.Context, token *LockToken, err error)
+
+	// Called after expired locks are deleted, with how many were removed
+	OnExpiredCleanup func(ctx context.Context, removed int64)
+
+	// Called when recording an operation in the audit log of the adapter
+	// fails; the operation itself succeeded
+	OnAuditFailed func(ctx context.Context, err error)
+
+	// Called when any of the hooks above panics
+	OnHookPanic func(hook string, recovered any)
+}
+
+// Acquired invokes OnAcquired if set
+func (h Hooks) Acquired(ctx context.Context, token *LockToken) {
+	if h.OnAcquired == nil {
+		return
+	}
+	defer h.recover("OnAcquired")
+	h.OnAcquired(ctx, token)
+}
+
+// Released invokes OnReleased if set
+func (h Hooks) Released(ctx context.Context, token *LockToken) {
+	if h.OnReleased == nil {
+		return
+	}
+	defer h.recover("OnReleased")
+	h.OnReleased(ctx, token)
+}
+
+// Contention invokes OnContention if set
+func (h Hooks) Contention(ctx context.Context, key string, attempt int) {
+	if h.OnContention == nil {
+		return
+	}
+	defer h.recover("OnContention")
+	h.OnContention(ctx, key, attempt)
+}
+
+// RefreshFailed invokes OnRefreshFailed if set
+func (h Hooks) RefreshFailed(ctx context.Context, token *LockToken, err error) {
+	if h.OnRefreshFailed == nil {
+		return
+	}
+	defer h.recover("OnRefreshFailed")
+	h.OnRefreshFailed(ctx, token, err)
+}
+
+// ExpiredCleanup invokes OnExpiredCleanup if set
+func (h Hooks) ExpiredCleanup(ctx context.Context, removed int64) {
+	if h.OnExpiredCleanup == nil {
+		return
+	}
+	defer h.recover("OnExpiredCleanup")
+	h.OnExpiredCleanup(ctx, removed)
+}
+
+// AuditFailed invokes OnAuditFailed if set
+func (h Hooks) AuditFailed(ctx context.Context, err error) {
+	if h.OnAuditFailed == nil {
+		return
+	}
+	defer h.recover("OnAuditFailed")
+	h.OnAuditFailed(ctx, err)
+}
+
+// recover must be deferred directly by the hook invokers
+func (h Hooks) recover(hook string) {
+	r := recover()
+	if r == nil || h.OnHookPanic == nil {
+		return
+	}
+	// A panicking OnHookPanic is swallowed as well
+	defer func() { _ = recover() }()
+	h.OnHookPanic(hook, r)
+}
@@ -0,0 +1,157 @@
+package core_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/stretchr/testify/require"
+)
+
+// stubAdapter simulates a backend where the first acquisition attempt
+// is contended and every refresh fails
+type stubAdapter struct {
+	core.LockAdapter
+	attempts int
+}
+
+func (s *stubAdapter) Acquire(ctx context.Context, key string, opts core.LockOptions) (*core.LockToken, error) {
+	s.attempts++
+	if s.attempts == 1 {
+		return nil, core.ErrLockContention
+	}
+	return &core.LockToken{Key: key, LeaseID: "lease", ValidUntil: time.Now().Add(opts.TTL)}, nil
+}
+
+func (s *stubAdapter) Refresh(ctx context.Context, token *core.LockToken, newTTL time.Duration) (*core.LockToken, error) {
+	return nil, core.ErrRefreshTooLate
+}
+
+func (s *stubAdapter) Release(ctx context.Context, token *core.LockToken) error {
+	return nil
+}
+
+// hookedAdapter is a decorator invoking hooks the way adapters are
+// expected to
+type hookedAdapter struct {
+	next  core.LockAdapter
+	hooks core.Hooks
+}
+
+func (h *hookedAdapter) Acquire(ctx context.Context, key string, opts core.LockOptions) (*core.LockToken, error) {
+	for attempt := 0; attempt <= opts.RetryStrategy.MaxRetries; attempt++ {
+		token, err := h.next.Acquire(ctx, key, opts)
+		if errors.Is(err, core.ErrLockContention) {
+			h.hooks.Contention(ctx, key, attempt)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		h.hooks.Acquired(ctx, token)
+		return token, nil
+	}
+	return nil, core.ErrLockAcquisitionFailed
+}
+
+func (h *hookedAdapter) Refresh(ctx context.Context, token *core.LockToken, newTTL time.Duration) (*core.LockToken, error) {
+	refreshed, err := h.next.Refresh(ctx, token, newTTL)
+	if err != nil {
+		h.hooks.RefreshFailed(ctx, token, err)
+		return nil, err
+	}
+	return refreshed, nil
+}
+
+func (h *hookedAdapter) Release(ctx context.Context, token *core.LockToken) error {
+	if err := h.next.Release(ctx, token); err != nil {
+		return err
+	}
+	h.hooks.Released(ctx, token)
+	return nil
+}
+
+func TestHooks_Lifecycle(t *testing.T) {
+	t.Run("given all hooks set, when acquire, refresh and release, then hooks fire in order", func(t *testing.T) {
+		var calls []string
+		hooks := core.Hooks{
+			OnContention: func(ctx context.Context, key string, attempt int) {
+				calls = append(calls, "contention")
+			},
+			OnAcquired: func(ctx context.Context, token *core.LockToken) {
+				calls = append(calls, "acquired")
+			},
+			OnRefreshFailed: func(ctx context.Context, token *core.LockToken, err error) {
+				require.ErrorIs(t, err, core.ErrRefreshTooLate)
+				calls = append(calls, "refresh_failed")
+			},
+			OnReleased: func(ctx context.Context, token *core.LockToken) {
+				calls = append(calls, "released")
+			},
+		}
+		a := &hookedAdapter{next: &stubAdapter{}, hooks: hooks}
+
+		token, err := a.Acquire(context.Background(), "key", core.LockOptions{
+			TTL:           time.Second,
+			RetryStrategy: core.RetryStrategy{MaxRetries: 1, BackoffFactor: 1},
+		})
+		require.NoError(t, err)
+
+		_, err = a.Refresh(context.Background(), token, time.Second)
+		require.ErrorIs(t, err, core.ErrRefreshTooLate)
+
+		err = a.Release(context.Background(), token)
+		require.NoError(t, err)
+
+		require.Equal(t, []string{"contention", "acquired", "refresh_failed", "released"}, calls)
+	})
+
+	t.Run("given a panicking hook, when invoked, then panic is recovered and recorded", func(t *testing.T) {
+		var recorded []string
+		hooks := core.Hooks{
+			OnAcquired: func(ctx context.Context, token *core.LockToken) {
+				panic("boom")
+			},
+			OnHookPanic: func(hook string, recovered any) {
+				recorded = append(recorded, hook)
+				panic("boom again")
+			},
+		}
+
+		require.NotPanics(t, func() {
+			hooks.Acquired(context.Background(), &core.LockToken{})
+		})
+		require.Equal(t, []string{"OnAcquired"}, recorded)
+	})
+
+	t.Run("given a panicking audit hook, when invoked, then panic is recovered and recorded", func(t *testing.T) {
+		var recorded []string
+		hooks := core.Hooks{
+			OnAuditFailed: func(ctx context.Context, err error) {
+				panic("boom")
+			},
+			OnHookPanic: func(hook string, recovered any) {
+				recorded = append(recorded, hook)
+			},
+		}
+
+		require.NotPanics(t, func() {
+			hooks.AuditFailed(context.Background(), errors.New("audit table missing"))
+		})
+		require.Equal(t, []string{"OnAuditFailed"}, recorded)
+	})
+
+	t.Run("given zero value hooks, when invoked, then nothing happens", func(t *testing.T) {
+		var hooks core.Hooks
+		require.NotPanics(t, func() {
+			hooks.Acquired(context.Background(), nil)
+			hooks.Released(context.Background(), nil)
+			hooks.Contention(context.Background(), "key", 0)
+			hooks.RefreshFailed(context.Background(), nil, nil)
+			hooks.ExpiredCleanup(context.Background(), 1)
+			hooks.AuditFailed(context.Background(), nil)
+		})
+	})
+}
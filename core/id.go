@@ -0,0 +1,30 @@
+package core
+
+import (
+	"github.com/google/uuid"
+)
+
+// IDGenerator produces the LeaseID and ServerNonce of the tokens.
+//
+// IDs must be unique across every adapter sharing a backend, and hard to
+// guess: the ServerNonce is the proof of ownership of a lock.
+type IDGenerator interface {
+	NewID() string
+}
+
+// IDGeneratorFunc adapts a function to IDGenerator
+type IDGeneratorFunc func() string
+
+// NewID calls f
+func (f IDGeneratorFunc) NewID() string {
+	return f()
+}
+
+// UUIDGenerator generates random (version 4) UUIDs, the default of the
+// adapters
+type UUIDGenerator struct{}
+
+// NewID returns a new random UUID
+func (UUIDGenerator) NewID() string {
+	return uuid.NewString()
+}
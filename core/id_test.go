@@ -0,0 +1,27 @@
+package core_test
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIDGenerator(t *testing.T) {
+	t.Run("given the UUID generator, when generating, then returns distinct v4 UUIDs", func(t *testing.T) {
+		var gen core.IDGenerator = core.UUIDGenerator{}
+
+		first, second := gen.NewID(), gen.NewID()
+		require.NotEqual(t, first, second)
+
+		parsed, err := uuid.Parse(first)
+		require.NoError(t, err)
+		require.Equal(t, uuid.Version(4), parsed.Version())
+	})
+
+	t.Run("given a function, when adapted, then NewID calls it", func(t *testing.T) {
+		var gen core.IDGenerator = core.IDGeneratorFunc(func() string { return "fixed" })
+		require.Equal(t, "fixed", gen.NewID())
+	})
+}
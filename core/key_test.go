@@ -0,0 +1,84 @@
+package core_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateKey(t *testing.T) {
+	valid := []string{"key", "key_1-2", strings.Repeat("a", 256)}
+	for _, key := range valid {
+		require.NoError(t, core.ValidateKey(key), key)
+	}
+
+	invalid := []string{"", "key with space", "team-a:orders", "key;", strings.Repeat("a", 257)}
+	for _, key := range invalid {
+		require.ErrorIs(t, core.ValidateKey(key), core.ErrInvalidKeyFormat, key)
+	}
+}
+
+func TestValidateNamespace(t *testing.T) {
+	valid := []string{"team-a", "team-a:orders", "team_a:orders:eu-1"}
+	for _, namespace := range valid {
+		require.NoError(t, core.ValidateNamespace(namespace), namespace)
+	}
+
+	invalid := []string{"", ":team", "team:", "team::orders", "team a"}
+	for _, namespace := range invalid {
+		require.ErrorIs(t, core.ValidateNamespace(namespace), core.ErrInvalidNamespace, namespace)
+	}
+}
+
+func TestNamespaceKey(t *testing.T) {
+	t.Run("given a namespace, when namespace key, then key is prefixed", func(t *testing.T) {
+		key, err := core.NamespaceKey("team-a:orders", "123")
+		require.NoError(t, err)
+		require.Equal(t, "team-a:orders:123", key)
+		require.Equal(t, "123", core.StripNamespace("team-a:orders", key))
+	})
+
+	t.Run("given an empty namespace, when namespace key, then key is unchanged", func(t *testing.T) {
+		key, err := core.NamespaceKey("", "123")
+		require.NoError(t, err)
+		require.Equal(t, "123", key)
+		require.Equal(t, "123", core.StripNamespace("", key))
+	})
+
+	t.Run("given a key with separator, when namespace key, then returns error", func(t *testing.T) {
+		_, err := core.NamespaceKey("team-a", "orders:123")
+		require.ErrorIs(t, err, core.ErrInvalidKeyFormat)
+	})
+
+	t.Run("given an invalid namespace, when namespace key, then returns error", func(t *testing.T) {
+		_, err := core.NamespaceKey("team-a:", "123")
+		require.ErrorIs(t, err, core.ErrInvalidNamespace)
+	})
+
+	t.Run("given an empty namespace and a key with separator, when namespace key, then returns error", func(t *testing.T) {
+		_, err := core.NamespaceKey("", "team-a:orders")
+		require.ErrorIs(t, err, core.ErrInvalidKeyFormat)
+	})
+
+	t.Run("given a namespaced key too long, when namespace key, then returns error", func(t *testing.T) {
+		_, err := core.NamespaceKey("team-a", strings.Repeat("a", 250))
+		require.ErrorIs(t, err, core.ErrInvalidKeyFormat)
+	})
+}
+
+func TestHashKey(t *testing.T) {
+	t.Run("given a key too long or with slashes, when hash key, then the hash is a valid key", func(t *testing.T) {
+		for _, key := range []string{strings.Repeat("a", 1000), "https://example.com/orders/123?x=1", ""} {
+			hashed := core.HashKey(key)
+			require.NoError(t, core.ValidateKey(hashed), key)
+			require.True(t, strings.HasPrefix(hashed, core.HashedKeyPrefix))
+			require.Equal(t, hashed, core.HashKey(key), "deterministic")
+		}
+	})
+
+	t.Run("given different keys, when hash key, then the hashes differ", func(t *testing.T) {
+		require.NotEqual(t, core.HashKey("https://example.com/a"), core.HashKey("https://example.com/b"))
+	})
+}
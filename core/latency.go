@@ -0,0 +1,199 @@
+package core
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultLatencyWindowSize is the number of recent operations
+// kept by NewLatencyWindow when size is not positive
+const DefaultLatencyWindowSize = 1024
+
+// DefaultThroughputWindow is the period over which adapters
+// compute HealthReport.Throughput
+const DefaultThroughputWindow = time.Minute
+
+type latencySample struct {
+	at      time.Time
+	latency time.Duration
+	failed  bool
+}
+
+// LatencyWindow is a ring buffer of the latencies of the most recent
+// operations, used by adapters to report averages, percentiles and
+// throughput in HealthReport.
+//
+// It is safe for concurrent use.
+type LatencyWindow struct {
+	mu        sync.Mutex
+	samples   []latencySample
+	next      int
+	full      bool
+	total     uint64
+	createdAt time.Time
+}
+
+// NewLatencyWindow creates a window keeping the last size latencies
+func NewLatencyWindow(size int) *LatencyWindow {
+	if size <= 0 {
+		size = DefaultLatencyWindowSize
+	}
+	return &LatencyWindow{
+		samples:   make([]latencySample, size),
+		createdAt: time.Now(),
+	}
+}
+
+// Record adds the latency of an operation finished now
+func (w *LatencyWindow) Record(latency time.Duration) {
+	w.RecordAt(time.Now(), latency)
+}
+
+// RecordAt adds the latency of an operation finished at the given time
+func (w *LatencyWindow) RecordAt(at time.Time, latency time.Duration) {
+	w.record(latencySample{at: at, latency: latency})
+}
+
+// RecordFailure adds the latency of an operation that failed now,
+// counted by ErrorRate
+func (w *LatencyWindow) RecordFailure(latency time.Duration) {
+	w.record(latencySample{at: time.Now(), latency: latency, failed: true})
+}
+
+func (w *LatencyWindow) record(sample latencySample) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.samples[w.next] = sample
+	w.next = (w.next + 1) % len(w.samples)
+	if w.next == 0 {
+		w.full = true
+	}
+	w.total++
+}
+
+// Total returns the number of operations recorded since creation
+func (w *LatencyWindow) Total() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.total
+}
+
+// snapshot returns a copy of the samples in the window
+func (w *LatencyWindow) snapshot() []latencySample {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n := w.next
+	if w.full {
+		n = len(w.samples)
+	}
+	samples := make([]latencySample, n)
+	copy(samples, w.samples[:n])
+	return samples
+}
+
+// Average returns the mean latency over the window,
+// zero while the window is empty
+func (w *LatencyWindow) Average() time.Duration {
+	samples := w.snapshot()
+	if len(samples) == 0 {
+		return 0
+	}
+
+	var sum time.Duration
+	for _, s := range samples {
+		sum += s.latency
+	}
+	return sum / time.Duration(len(samples))
+}
+
+// ErrorRate returns the fraction (0.0-1.0) of failed operations over the
+// window, zero while the window is empty
+func (w *LatencyWindow) ErrorRate() float64 {
+	samples := w.snapshot()
+	if len(samples) == 0 {
+		return 0
+	}
+
+	failed := 0
+	for _, s := range samples {
+		if s.failed {
+			failed++
+		}
+	}
+	return float64(failed) / float64(len(samples))
+}
+
+// Percentiles returns the latency of each percentile (0-100) over the
+// window, using the nearest-rank method. Zero values are returned while
+// the window is empty.
+func (w *LatencyWindow) Percentiles(percentiles ...float64) []time.Duration {
+	samples := w.snapshot()
+	n := len(samples)
+
+	result := make([]time.Duration, len(percentiles))
+	if n == 0 {
+		return result
+	}
+
+	sorted := make([]time.Duration, n)
+	for idx, s := range samples {
+		sorted[idx] = s.latency
+	}
+	sort.Slice(sorted, func(a, b int) bool { return sorted[a] < sorted[b] })
+
+	for idx, p := range percentiles {
+		rank := int(math.Ceil(p/100*float64(n))) - 1
+		if rank < 0 {
+			rank = 0
+		}
+		if rank >= n {
+			rank = n - 1
+		}
+		result[idx] = sorted[rank]
+	}
+	return result
+}
+
+// Throughput returns the operations per second recorded during the
+// period ending at now.
+//
+// The period is shortened to the age of the window when it is younger,
+// and to the age of the oldest sample when the ring buffer overflowed
+// within the period.
+func (w *LatencyWindow) Throughput(now time.Time, period time.Duration) float64 {
+	samples := w.snapshot()
+
+	w.mu.Lock()
+	full, createdAt := w.full, w.createdAt
+	w.mu.Unlock()
+
+	from := now.Add(-period)
+	if createdAt.After(from) {
+		from = createdAt
+	}
+
+	count := 0
+	oldest := now
+	for _, s := range samples {
+		if s.at.After(from) && !s.at.After(now) {
+			count++
+			if s.at.Before(oldest) {
+				oldest = s.at
+			}
+		}
+	}
+	// Older samples of the period were overwritten
+	if full && count == len(samples) {
+		from = oldest
+	}
+
+	span := now.Sub(from)
+	if count == 0 || span <= 0 {
+		return 0
+	}
+	return float64(count) / span.Seconds()
+}
@@ -0,0 +1,97 @@
+package core_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatencyWindow(t *testing.T) {
+	t.Run("given an empty window, when get percentiles, then returns zeros", func(t *testing.T) {
+		w := core.NewLatencyWindow(10)
+		require.Equal(t, []time.Duration{0, 0}, w.Percentiles(50, 99))
+		require.Zero(t, w.Total())
+	})
+
+	t.Run("given 100 samples, when get percentiles, then returns nearest rank", func(t *testing.T) {
+		w := core.NewLatencyWindow(100)
+		for n := 100; n >= 1; n-- {
+			w.Record(time.Duration(n) * time.Millisecond)
+		}
+
+		require.Equal(t,
+			[]time.Duration{50 * time.Millisecond, 95 * time.Millisecond, 99 * time.Millisecond},
+			w.Percentiles(50, 95, 99),
+		)
+		require.Equal(t, uint64(100), w.Total())
+	})
+
+	t.Run("given more samples than the size, when get percentiles, then only recent samples count", func(t *testing.T) {
+		w := core.NewLatencyWindow(2)
+		w.Record(time.Hour)
+		w.Record(time.Millisecond)
+		w.Record(time.Millisecond)
+
+		require.Equal(t, []time.Duration{time.Millisecond}, w.Percentiles(100))
+		require.Equal(t, uint64(3), w.Total())
+	})
+	t.Run("given synthetic timings, when get average, then returns the mean latency", func(t *testing.T) {
+		w := core.NewLatencyWindow(10)
+		w.Record(10 * time.Millisecond)
+		w.Record(20 * time.Millisecond)
+		w.Record(60 * time.Millisecond)
+
+		require.Equal(t, 30*time.Millisecond, w.Average())
+		require.Zero(t, core.NewLatencyWindow(10).Average())
+	})
+
+	t.Run("given 20 operations over 10 seconds, when get throughput, then returns 2 ops per second", func(t *testing.T) {
+		w := core.NewLatencyWindow(100)
+		start := time.Now()
+		for n := 1; n <= 20; n++ {
+			w.RecordAt(start.Add(time.Duration(n)*500*time.Millisecond), time.Millisecond)
+		}
+
+		require.InDelta(t, 2.0, w.Throughput(start.Add(10*time.Second), time.Minute), 0.01)
+	})
+
+	t.Run("given operations older than the period, when get throughput, then they are ignored", func(t *testing.T) {
+		w := core.NewLatencyWindow(100)
+		start := time.Now()
+		for n := 1; n <= 10; n++ {
+			w.RecordAt(start.Add(time.Duration(n)*time.Second), time.Millisecond)
+		}
+
+		// Only the samples of the last 5 seconds count
+		require.InDelta(t, 1.0, w.Throughput(start.Add(10*time.Second), 5*time.Second), 0.01)
+		require.Zero(t, w.Throughput(start.Add(time.Hour), time.Minute))
+	})
+
+	t.Run("given an overflowed buffer, when get throughput, then uses the age of the oldest sample", func(t *testing.T) {
+		w := core.NewLatencyWindow(10)
+		start := time.Now()
+		for n := 1; n <= 100; n++ {
+			w.RecordAt(start.Add(time.Duration(n)*100*time.Millisecond), time.Millisecond)
+		}
+
+		// The last 10 samples span 1 second
+		require.InDelta(t, 10.0, w.Throughput(start.Add(10*time.Second), time.Minute), 1.5)
+	})
+	t.Run("given failed operations, when get error rate, then returns their fraction of the window", func(t *testing.T) {
+		w := core.NewLatencyWindow(4)
+		require.Zero(t, w.ErrorRate())
+
+		w.Record(time.Millisecond)
+		w.RecordFailure(time.Millisecond)
+		require.Equal(t, 0.5, w.ErrorRate())
+
+		// The failure leaves the window
+		for range 4 {
+			w.Record(time.Millisecond)
+		}
+		require.Zero(t, w.ErrorRate())
+		require.Equal(t, uint64(6), w.Total())
+	})
+}
@@ -0,0 +1,113 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Operation names used in LockError
+const (
+	OpAcquire    = "acquire"
+	OpRelease    = "release"
+	OpRefresh    = "refresh"
+	OpTransfer   = "transfer"
+	OpAssertHeld = "assert_held"
+)
+
+// LockError carries the context of a failed lock operation.
+//
+// It wraps the underlying error (usually one of the package sentinels),
+// so errors.Is keeps working:
+//
+//	if errors.Is(err, ErrLockAcquisitionFailed) {
+//	    lockErr, _ := AsLockError(err)
+//	    log.Printf("key %s busy until %s", lockErr.Key, lockErr.LastHolderExpiry)
+//	}
+type LockError struct {
+	Op               string        // Operation (acquire, release, refresh, ...)
+	Key              string        // Resource key
+	Attempts         int           // Number of attempts made
+	Elapsed          time.Duration // Time spent across the attempts (zero if unknown)
+	LastHolderExpiry time.Time     // Expiration of the last observed holder (zero if unknown)
+	LastHolderID     string        // Owner ID of the last observed holder (empty if unknown)
+	Err              error         // Underlying error
+}
+
+func (e *LockError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Op)
+	if e.Key != "" {
+		fmt.Fprintf(&b, " %q", e.Key)
+	}
+	if e.Attempts > 0 {
+		fmt.Fprintf(&b, " after %d attempts", e.Attempts)
+	}
+	if e.Elapsed > 0 {
+		fmt.Fprintf(&b, " in %v", e.Elapsed.Round(time.Millisecond))
+	}
+	fmt.Fprintf(&b, ": %v", e.Err)
+
+	// ContentionError already describes the holder
+	var contentionErr *ContentionError
+	if !errors.As(e.Err, &contentionErr) {
+		b.WriteString(holderSuffix(e.LastHolderID, e.LastHolderExpiry))
+	}
+	return b.String()
+}
+
+func (e *LockError) Unwrap() error {
+	return e.Err
+}
+
+// AsLockError extracts a *LockError from the error chain
+func AsLockError(err error) (*LockError, bool) {
+	var lockErr *LockError
+	if errors.As(err, &lockErr) {
+		return lockErr, true
+	}
+	return nil, false
+}
+
+// ContentionError is returned when a lock cannot be acquired because
+// the key is held by another owner.
+//
+// It satisfies both errors.Is(err, ErrLockContention) and
+// errors.Is(err, ErrLockAcquisitionFailed), and describes the blocker
+// so callers can log or act on it:
+//
+//	var contentionErr *ContentionError
+//	if errors.As(err, &contentionErr) {
+//	    log.Printf("blocked by %s until %s", contentionErr.HolderID, contentionErr.HeldUntil)
+//	}
+type ContentionError struct {
+	HeldUntil      time.Time         // Expiration of the holder (zero if unknown)
+	HolderID       string            // Owner ID of the holder (empty if unknown)
+	HolderMetadata map[string]string // Metadata of the holder
+}
+
+func (e *ContentionError) Error() string {
+	return fmt.Sprintf("%v: %v%s",
+		ErrLockAcquisitionFailed,
+		ErrLockContention,
+		holderSuffix(e.HolderID, e.HeldUntil),
+	)
+}
+
+func (e *ContentionError) Unwrap() []error {
+	return []error{ErrLockContention, ErrLockAcquisitionFailed}
+}
+
+// holderSuffix describes the holder of a lock in error messages
+func holderSuffix(holderID string, heldUntil time.Time) string {
+	switch {
+	case holderID != "" && !heldUntil.IsZero():
+		return fmt.Sprintf(" (held by %s until %s)", holderID, heldUntil.Format(time.RFC3339Nano))
+	case holderID != "":
+		return fmt.Sprintf(" (held by %s)", holderID)
+	case !heldUntil.IsZero():
+		return fmt.Sprintf(" (holder expires at %s)", heldUntil.Format(time.RFC3339Nano))
+	}
+	return ""
+}
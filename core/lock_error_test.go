@@ -0,0 +1,87 @@
+package core_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockError(t *testing.T) {
+	t.Run("given a wrapped lock error, when inspected, then sentinel and fields are reachable", func(t *testing.T) {
+		expiry := time.Date(2025, 3, 13, 10, 0, 0, 0, time.UTC)
+		err := fmt.Errorf("worker: %w", &core.LockError{
+			Op:               core.OpAcquire,
+			Key:              "orders",
+			Attempts:         3,
+			LastHolderExpiry: expiry,
+			LastHolderID:     "orders-worker-3",
+			Err:              core.ErrLockAcquisitionFailed,
+		})
+
+		require.ErrorIs(t, err, core.ErrLockAcquisitionFailed)
+
+		lockErr, ok := core.AsLockError(err)
+		require.True(t, ok)
+		require.Equal(t, "orders", lockErr.Key)
+		require.Equal(t, 3, lockErr.Attempts)
+		require.Equal(t, expiry, lockErr.LastHolderExpiry)
+		require.Equal(t, "orders-worker-3", lockErr.LastHolderID)
+		require.Equal(t,
+			`worker: acquire "orders" after 3 attempts: lock acquisition failed (held by orders-worker-3 until 2025-03-13T10:00:00Z)`,
+			err.Error(),
+		)
+	})
+
+	t.Run("given the elapsed time, when formatted, then reports attempts and duration", func(t *testing.T) {
+		err := &core.LockError{
+			Op:       core.OpAcquire,
+			Key:      "orders",
+			Attempts: 5,
+			Elapsed:  4200*time.Millisecond + 300*time.Microsecond,
+			Err:      &core.ContentionError{},
+		}
+
+		require.ErrorIs(t, err, core.ErrLockContention)
+		require.Equal(t,
+			`acquire "orders" after 5 attempts in 4.2s: lock acquisition failed: lock contention limit exceeded`,
+			err.Error(),
+		)
+	})
+
+	t.Run("given a plain error, when extracted, then returns false", func(t *testing.T) {
+		lockErr, ok := core.AsLockError(errors.New("boom"))
+		require.False(t, ok)
+		require.Nil(t, lockErr)
+	})
+	t.Run("given a contention error, when inspected, then both sentinels and the holder are reachable", func(t *testing.T) {
+		heldUntil := time.Date(2025, 3, 13, 10, 0, 0, 0, time.UTC)
+		err := error(&core.LockError{
+			Op:               core.OpAcquire,
+			Key:              "orders",
+			Attempts:         2,
+			LastHolderExpiry: heldUntil,
+			LastHolderID:     "orders-worker-3",
+			Err: &core.ContentionError{
+				HeldUntil:      heldUntil,
+				HolderID:       "orders-worker-3",
+				HolderMetadata: map[string]string{"job": "billing"},
+			},
+		})
+
+		require.ErrorIs(t, err, core.ErrLockContention)
+		require.ErrorIs(t, err, core.ErrLockAcquisitionFailed)
+
+		var contentionErr *core.ContentionError
+		require.ErrorAs(t, err, &contentionErr)
+		require.Equal(t, heldUntil, contentionErr.HeldUntil)
+		require.Equal(t, map[string]string{"job": "billing"}, contentionErr.HolderMetadata)
+		require.Equal(t,
+			`acquire "orders" after 2 attempts: lock acquisition failed: lock contention limit exceeded (held by orders-worker-3 until 2025-03-13T10:00:00Z)`,
+			err.Error(),
+		)
+	})
+}
@@ -0,0 +1,126 @@
+// Package locktest provides a conformance suite for core.LockAdapter
+// implementations, so every backend honours the same contract.
+package locktest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/stretchr/testify/require"
+)
+
+// Run checks the adapter against the core.LockAdapter contract.
+//
+// Every key used starts with prefix, so suites of adapters sharing a
+// backend do not collide. The adapter is left open.
+func Run(t *testing.T, adapter core.LockAdapter, prefix string) {
+	ctx := context.Background()
+	opts := core.LockOptions{
+		TTL:            10 * time.Second,
+		RetryStrategy:  core.NoRetry(),
+		RequestTimeout: 5 * time.Second,
+	}
+
+	t.Run("given a free key, when acquire, then the token holds it until released", func(t *testing.T) {
+		token, err := adapter.Acquire(ctx, prefix+"-free", opts)
+		require.NoError(t, err)
+		require.Equal(t, prefix+"-free", token.Key)
+		require.NotEmpty(t, token.LeaseID)
+		require.NotEmpty(t, token.ServerNonce)
+
+		held, remaining, err := adapter.IsHeld(ctx, token)
+		require.NoError(t, err)
+		require.True(t, held)
+		require.Positive(t, remaining)
+		require.LessOrEqual(t, remaining, opts.TTL+time.Second)
+
+		require.NoError(t, adapter.Release(ctx, token))
+
+		held, _, err = adapter.IsHeld(ctx, token)
+		require.NoError(t, err)
+		require.False(t, held)
+	})
+
+	t.Run("given a held key, when acquire again, then fails with contention", func(t *testing.T) {
+		token, err := adapter.Acquire(ctx, prefix+"-held", opts)
+		require.NoError(t, err)
+		defer adapter.Release(ctx, token)
+
+		_, err = adapter.Acquire(ctx, prefix+"-held", opts)
+		require.ErrorIs(t, err, core.ErrLockContention)
+		require.ErrorIs(t, err, core.ErrLockAcquisitionFailed)
+
+		lockErr, ok := core.AsLockError(err)
+		require.True(t, ok)
+		require.Equal(t, core.OpAcquire, lockErr.Op)
+		require.Equal(t, 1, lockErr.Attempts)
+	})
+
+	t.Run("given a released key, when acquire, then succeeds", func(t *testing.T) {
+		token, err := adapter.Acquire(ctx, prefix+"-released", opts)
+		require.NoError(t, err)
+		require.NoError(t, adapter.Release(ctx, token))
+
+		token, err = adapter.Acquire(ctx, prefix+"-released", opts)
+		require.NoError(t, err)
+		require.NoError(t, adapter.Release(ctx, token))
+	})
+
+	t.Run("given a token with another nonce, when release, then fails with ownership mismatch", func(t *testing.T) {
+		token, err := adapter.Acquire(ctx, prefix+"-mismatch", opts)
+		require.NoError(t, err)
+		defer adapter.Release(ctx, token)
+
+		forged := *token
+		forged.ServerNonce = "forged"
+		require.ErrorIs(t, adapter.Release(ctx, &forged), core.ErrLockOwnershipMismatch)
+	})
+
+	t.Run("given a released token, when release again, then fails with lock not found", func(t *testing.T) {
+		token, err := adapter.Acquire(ctx, prefix+"-twice", opts)
+		require.NoError(t, err)
+		require.NoError(t, adapter.Release(ctx, token))
+
+		require.ErrorIs(t, adapter.Release(ctx, token), core.ErrLockNotFound)
+	})
+
+	t.Run("given a held lock, when refresh, then only the new token holds it", func(t *testing.T) {
+		token, err := adapter.Acquire(ctx, prefix+"-refresh", opts)
+		require.NoError(t, err)
+
+		refreshed, err := adapter.Refresh(ctx, token, time.Minute)
+		require.NoError(t, err)
+		require.NotEqual(t, token.ServerNonce, refreshed.ServerNonce)
+		require.True(t, refreshed.ValidUntil.After(token.ValidUntil))
+
+		held, _, err := adapter.IsHeld(ctx, refreshed)
+		require.NoError(t, err)
+		require.True(t, held)
+
+		held, _, err = adapter.IsHeld(ctx, token)
+		require.NoError(t, err)
+		require.False(t, held)
+
+		require.NoError(t, adapter.Release(ctx, refreshed))
+	})
+
+	t.Run("given a short TTL, when it elapses, then another acquirer gets the key", func(t *testing.T) {
+		short := opts
+		short.TTL = 100 * time.Millisecond
+		_, err := adapter.Acquire(ctx, prefix+"-expired", short)
+		require.NoError(t, err)
+
+		time.Sleep(300 * time.Millisecond)
+
+		token, err := adapter.Acquire(ctx, prefix+"-expired", opts)
+		require.NoError(t, err)
+		require.NoError(t, adapter.Release(ctx, token))
+	})
+
+	t.Run("given an invalid key, when acquire, then fails with invalid key format", func(t *testing.T) {
+		_, err := adapter.Acquire(ctx, prefix+" invalid", opts)
+		require.ErrorIs(t, err, core.ErrInvalidKeyFormat)
+	})
+}
@@ -0,0 +1,13 @@
+package locktest_test
+
+import (
+	"testing"
+
+	"github.com/oliveiracleidson/go-lockbox/core/locktest"
+	"github.com/oliveiracleidson/go-lockbox/memory"
+)
+
+// The in-memory adapter is the reference implementation of the contract
+func TestRun(t *testing.T) {
+	locktest.Run(t, memory.NewMemoryLockAdapter(), "mem")
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// EncodeMetadata encodes the metadata of opts as the JSON object stored by
+// the adapters, or nil when there is none. MetadataJSON takes precedence
+// over Metadata and fails with ErrInvalidMetadata unless it is a JSON
+// object; an encoding larger than MaxMetadataSize fails with
+// ErrMetadataTooLarge.
+func EncodeMetadata(opts LockOptions) ([]byte, error) {
+	var encoded []byte
+	switch {
+	case len(opts.MetadataJSON) > 0:
+		trimmed := bytes.TrimSpace(opts.MetadataJSON)
+		if len(trimmed) == 0 || trimmed[0] != '{' || !json.Valid(trimmed) {
+			return nil, ErrInvalidMetadata
+		}
+		encoded = trimmed
+	case len(opts.Metadata) > 0:
+		var err error
+		encoded, err = json.Marshal(opts.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal metadata: %w", err)
+		}
+	default:
+		return nil, nil
+	}
+
+	if len(encoded) > MaxMetadataSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrMetadataTooLarge, len(encoded))
+	}
+	return encoded, nil
+}
+
+// DecodeMetadata returns the string values of metadata stored by
+// EncodeMetadata, the others having no map[string]string
+// representation. Nil when raw is empty or not a JSON object.
+func DecodeMetadata(raw []byte) map[string]string {
+	if len(raw) == 0 {
+		return nil
+	}
+	var values map[string]any
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return nil
+	}
+	metadata := make(map[string]string, len(values))
+	for key, value := range values {
+		if s, ok := value.(string); ok {
+			metadata[key] = s
+		}
+	}
+	return metadata
+}
@@ -0,0 +1,45 @@
+package core_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeMetadata(t *testing.T) {
+	t.Run("given no metadata, when encode, then returns nil", func(t *testing.T) {
+		encoded, err := core.EncodeMetadata(core.LockOptions{})
+		require.NoError(t, err)
+		require.Nil(t, encoded)
+	})
+
+	t.Run("given metadata JSON, when encode, then it takes precedence and is kept verbatim", func(t *testing.T) {
+		encoded, err := core.EncodeMetadata(core.LockOptions{
+			Metadata:     map[string]string{"ignored": "yes"},
+			MetadataJSON: json.RawMessage(` {"attempt": 3, "job": "42", "nested": {"a": "b"}} `),
+		})
+		require.NoError(t, err)
+		require.Equal(t, `{"attempt": 3, "job": "42", "nested": {"a": "b"}}`, string(encoded))
+		require.Equal(t, map[string]string{"job": "42"}, core.DecodeMetadata(encoded))
+	})
+
+	t.Run("given invalid or oversized metadata, when encode, then returns the error", func(t *testing.T) {
+		_, err := core.EncodeMetadata(core.LockOptions{MetadataJSON: json.RawMessage(`[1, 2]`)})
+		require.ErrorIs(t, err, core.ErrInvalidMetadata)
+
+		_, err = core.EncodeMetadata(core.LockOptions{Metadata: map[string]string{"blob": strings.Repeat("x", core.MaxMetadataSize)}})
+		require.ErrorIs(t, err, core.ErrMetadataTooLarge)
+	})
+
+	t.Run("given metadata, when encode and decode, then the values round trip", func(t *testing.T) {
+		metadata := map[string]string{"job": "42", "region": "eu"}
+		encoded, err := core.EncodeMetadata(core.LockOptions{Metadata: metadata})
+		require.NoError(t, err)
+		require.Equal(t, metadata, core.DecodeMetadata(encoded))
+		require.Nil(t, core.DecodeMetadata(nil))
+		require.Nil(t, core.DecodeMetadata([]byte("null-ish")))
+	})
+}
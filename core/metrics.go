@@ -0,0 +1,17 @@
+package core
+
+// LockMetrics is a sink for the measurements sampled by an adapter,
+// implemented over the metrics backend of the service (Prometheus,
+// OpenTelemetry, StatsD...)
+type LockMetrics interface {
+	// Gauge records the current value of the named gauge
+	Gauge(name string, value float64)
+}
+
+// LockMetricsFunc is a function implementing LockMetrics
+type LockMetricsFunc func(name string, value float64)
+
+// Gauge calls f
+func (f LockMetricsFunc) Gauge(name string, value float64) {
+	f(name, value)
+}
@@ -0,0 +1,98 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+)
+
+// ErrUnsupportedBackend is returned by Open when no backend is registered
+// for the DSN scheme
+var ErrUnsupportedBackend = errors.New("unsupported lock backend")
+
+// OpenFunc creates a LockAdapter from a parsed DSN
+type OpenFunc func(ctx context.Context, dsn *url.URL, cfg OpenConfig) (LockAdapter, error)
+
+// OpenConfig holds the backend independent options of Open
+type OpenConfig struct {
+	Hooks Hooks // Lifecycle hooks passed to the adapter
+}
+
+// OpenOption configures Open
+type OpenOption func(*OpenConfig)
+
+// WithHooks sets the lifecycle hooks of the opened adapter
+func WithHooks(hooks Hooks) OpenOption {
+	return func(c *OpenConfig) {
+		c.Hooks = hooks
+	}
+}
+
+var (
+	backendsMu sync.RWMutex
+	backends   = map[string]OpenFunc{}
+)
+
+// Register makes a backend available to Open for the given DSN scheme.
+//
+// Backends register themselves in their package init, so a backend must be
+// imported (even if only for side effects) to be usable:
+//
+//	import _ "github.com/oliveiracleidson/go-lockbox/pg"
+//
+// Register panics if the scheme is already registered or fn is nil.
+func Register(scheme string, fn OpenFunc) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+
+	if fn == nil {
+		panic("core: Register open func is nil")
+	}
+	if _, dup := backends[scheme]; dup {
+		panic("core: Register called twice for scheme " + scheme)
+	}
+	backends[scheme] = fn
+}
+
+// Backends returns the sorted list of registered DSN schemes
+func Backends() []string {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+
+	schemes := make([]string, 0, len(backends))
+	for scheme := range backends {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// Open returns the LockAdapter of the backend selected by the DSN scheme
+// (postgres://, redis://, memory://, ...), so a service can switch
+// backends with just a connection string change.
+//
+// Query parameters are backend specific, see the documentation of each
+// backend.
+func Open(ctx context.Context, dsn string, opts ...OpenOption) (LockAdapter, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dsn: %w", err)
+	}
+
+	backendsMu.RLock()
+	fn, ok := backends[u.Scheme]
+	backendsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q (registered: %v)", ErrUnsupportedBackend, u.Scheme, Backends())
+	}
+
+	cfg := OpenConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return fn(ctx, u, cfg)
+}
@@ -0,0 +1,56 @@
+package core_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAdapter struct {
+	core.LockAdapter
+	dsn   *url.URL
+	hooks core.Hooks
+}
+
+func TestOpen(t *testing.T) {
+	core.Register("fake", func(ctx context.Context, dsn *url.URL, cfg core.OpenConfig) (core.LockAdapter, error) {
+		return &fakeAdapter{dsn: dsn, hooks: cfg.Hooks}, nil
+	})
+
+	t.Run("given a registered scheme, when open, then returns the backend adapter", func(t *testing.T) {
+		called := false
+		a, err := core.Open(
+			context.Background(),
+			"fake://host/db?opt=1",
+			core.WithHooks(core.Hooks{
+				OnAcquired: func(ctx context.Context, token *core.LockToken) { called = true },
+			}),
+		)
+		require.NoError(t, err)
+
+		fake, ok := a.(*fakeAdapter)
+		require.True(t, ok)
+		require.Equal(t, "host", fake.dsn.Host)
+		require.Equal(t, "1", fake.dsn.Query().Get("opt"))
+
+		fake.hooks.Acquired(context.Background(), nil)
+		require.True(t, called)
+	})
+
+	t.Run("given an unknown scheme, when open, then returns ErrUnsupportedBackend", func(t *testing.T) {
+		a, err := core.Open(context.Background(), "unknown://host")
+		require.ErrorIs(t, err, core.ErrUnsupportedBackend)
+		require.Nil(t, a)
+	})
+
+	t.Run("given a registered scheme, when register again, then panics", func(t *testing.T) {
+		require.Panics(t, func() {
+			core.Register("fake", func(ctx context.Context, dsn *url.URL, cfg core.OpenConfig) (core.LockAdapter, error) {
+				return nil, nil
+			})
+		})
+	})
+}
@@ -0,0 +1,120 @@
+package core_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockOptions_ValidateWithMaxTTL(t *testing.T) {
+	opts := core.LockOptions{
+		TTL:           30 * time.Minute,
+		RetryStrategy: core.RetryStrategy{BackoffFactor: 1},
+	}
+
+	require.ErrorIs(t, opts.Validate(), core.ErrInvalidTTL)
+	require.NoError(t, opts.ValidateWithMaxTTL(time.Hour))
+
+	opts.TTL = time.Microsecond
+	require.ErrorIs(t, opts.ValidateWithMaxTTL(time.Hour), core.ErrInvalidTTL)
+}
+
+func TestLockOptions_Validate_RefreshSafetyMargin(t *testing.T) {
+	for _, margin := range []float64{0, 0.02, core.MaxClockDriftMargin, core.MaxRefreshMargin} {
+		opts := core.LockOptions{RefreshSafetyMargin: &margin}
+		require.NoError(t, opts.Validate(), margin)
+	}
+
+	for _, margin := range []float64{-0.01, core.MaxRefreshMargin + 0.01} {
+		opts := core.LockOptions{RefreshSafetyMargin: &margin}
+		require.ErrorContains(t, opts.Validate(), "refresh safety margin must be [0, 0.5]", margin)
+	}
+}
+
+func TestRetryStrategy_Deadline(t *testing.T) {
+	start := time.Now()
+
+	t.Run("given no budget and no ctx deadline, then there is no deadline", func(t *testing.T) {
+		r := core.RetryStrategy{}
+		_, ok := r.Deadline(context.Background(), start)
+		require.False(t, ok)
+	})
+
+	t.Run("given a budget sooner than the ctx deadline, then the budget wins", func(t *testing.T) {
+		ctx, cancel := context.WithDeadline(context.Background(), start.Add(time.Hour))
+		defer cancel()
+
+		r := core.RetryStrategy{MaxElapsed: 2 * time.Second}
+		deadline, ok := r.Deadline(ctx, start)
+		require.True(t, ok)
+		require.Equal(t, start.Add(2*time.Second), deadline)
+	})
+
+	t.Run("given a ctx deadline sooner than the budget, then the ctx deadline wins", func(t *testing.T) {
+		ctx, cancel := context.WithDeadline(context.Background(), start.Add(time.Second))
+		defer cancel()
+
+		r := core.RetryStrategy{MaxElapsed: time.Hour}
+		deadline, ok := r.Deadline(ctx, start)
+		require.True(t, ok)
+		require.Equal(t, start.Add(time.Second), deadline)
+	})
+
+	t.Run("given a negative budget, when validate, then returns error", func(t *testing.T) {
+		r := core.RetryStrategy{BackoffFactor: 1, MaxElapsed: -time.Second}
+		require.Error(t, r.Validate())
+	})
+}
+
+func TestLockOptions_WithDefaults(t *testing.T) {
+	t.Run("given zero-valued options, when validate, then fills the defaults", func(t *testing.T) {
+		opts := core.LockOptions{}
+
+		require.NoError(t, opts.Validate())
+		require.Equal(t, core.DefaultLockTTL, opts.TTL)
+		require.Equal(t, core.DefaultRetryStrategy(), opts.RetryStrategy)
+		require.Equal(t, core.DefaultRequestTimeout, opts.RequestTimeout)
+		require.Equal(t, core.DefaultOwnerID(), opts.OwnerID)
+	})
+
+	t.Run("given only a TTL, when validate, then uses the default retry strategy", func(t *testing.T) {
+		opts := core.LockOptions{TTL: 10 * time.Second}
+
+		require.NoError(t, opts.Validate())
+		require.Equal(t, 10*time.Second, opts.TTL)
+		require.Equal(t, core.DefaultMaxRetries, opts.RetryStrategy.MaxRetries)
+		require.Equal(t, core.DefaultJitterFactor, opts.RetryStrategy.JitterFactor)
+		require.Equal(t, 2.0, opts.RetryStrategy.BackoffFactor)
+	})
+
+	t.Run("given explicit invalid values, when validate, then rejects them", func(t *testing.T) {
+		opts := core.LockOptions{TTL: -time.Second}
+		require.ErrorIs(t, opts.Validate(), core.ErrInvalidTTL)
+
+		opts = core.LockOptions{RetryStrategy: core.RetryStrategy{MaxRetries: 3}}
+		require.ErrorContains(t, opts.Validate(), "backoff factor")
+	})
+}
+
+func TestLockOptions_Backoff(t *testing.T) {
+	strategy := core.RetryStrategy{BaseDelay: time.Millisecond, MaxDelay: time.Second, BackoffFactor: 2}
+
+	t.Run("given no BackoffFunc, when backoff, then uses CalculateBackoff", func(t *testing.T) {
+		opts := core.LockOptions{RetryStrategy: strategy}
+		require.Equal(t, core.CalculateBackoff(strategy, 3), opts.Backoff(3))
+	})
+
+	t.Run("given a BackoffFunc, when backoff, then it overrides CalculateBackoff", func(t *testing.T) {
+		opts := core.LockOptions{
+			RetryStrategy: strategy,
+			BackoffFunc: func(got core.RetryStrategy, attempt int) time.Duration {
+				require.Equal(t, strategy, got)
+				return time.Duration(attempt) * time.Minute
+			},
+		}
+		require.Equal(t, 3*time.Minute, opts.Backoff(3))
+	})
+}
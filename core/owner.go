@@ -0,0 +1,70 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	validOwnerIDRegex   = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,256}$`)
+	invalidOwnerIDChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+	defaultOwnerID     string
+	defaultOwnerIDOnce sync.Once
+)
+
+// ValidateOwnerID checks an owner ID, which follows the key format
+func ValidateOwnerID(ownerID string) error {
+	if !validOwnerIDRegex.MatchString(ownerID) {
+		return fmt.Errorf("%w: %s", ErrInvalidOwnerID, ownerID)
+	}
+	return nil
+}
+
+// DefaultOwnerID returns the owner ID used when LockOptions.OwnerID is
+// empty: the hostname and the process ID, e.g. "orders-worker-3-4242".
+//
+// Characters of the hostname not allowed in owner IDs are replaced by "-".
+func DefaultOwnerID() string {
+	defaultOwnerIDOnce.Do(func() {
+		host, err := os.Hostname()
+		if err != nil || host == "" {
+			host = "unknown"
+		}
+		host = strings.Trim(invalidOwnerIDChars.ReplaceAllString(host, "-"), "-")
+
+		defaultOwnerID = fmt.Sprintf("%s-%d", host, os.Getpid())
+		if len(defaultOwnerID) > MaxKeyLength {
+			defaultOwnerID = defaultOwnerID[len(defaultOwnerID)-MaxKeyLength:]
+		}
+	})
+	return defaultOwnerID
+}
+
+// LockInfo describes a lock stored in the backend
+type LockInfo struct {
+	Key          string            // Locked resource key
+	OwnerID      string            // Owner identity
+	ValidUntil   time.Time         // Absolute expiration
+	Metadata     map[string]string // Custom metadata, the string values of MetadataJSON
+	MetadataJSON json.RawMessage   // Metadata as stored, nil without metadata
+
+	AcquiredAt   time.Time     // When the current holder acquired the key
+	TTL          time.Duration // TTL requested by the acquisition
+	RefreshCount int           // Refreshes since the acquisition
+}
+
+// LockInspector is implemented by adapters able to list the stored locks
+type LockInspector interface {
+	// GetLockInfo returns the active lock of a key or ErrLockNotFound
+	GetLockInfo(ctx context.Context, key string) (*LockInfo, error)
+
+	// ListLocks returns all active locks
+	ListLocks(ctx context.Context) ([]LockInfo, error)
+}
@@ -0,0 +1,30 @@
+package core_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOwnerID(t *testing.T) {
+	t.Run("when get default owner ID, then it is a valid owner ID", func(t *testing.T) {
+		require.NoError(t, core.ValidateOwnerID(core.DefaultOwnerID()))
+	})
+
+	t.Run("given options without owner ID, when validate, then default owner ID is set", func(t *testing.T) {
+		opts := core.LockOptions{TTL: time.Second, RetryStrategy: core.RetryStrategy{BackoffFactor: 1}}
+		require.NoError(t, opts.Validate())
+		require.Equal(t, core.DefaultOwnerID(), opts.OwnerID)
+	})
+
+	t.Run("given an invalid owner ID, when validate, then returns ErrInvalidOwnerID", func(t *testing.T) {
+		opts := core.LockOptions{
+			TTL:           time.Second,
+			RetryStrategy: core.RetryStrategy{BackoffFactor: 1},
+			OwnerID:       "orders worker",
+		}
+		require.ErrorIs(t, opts.Validate(), core.ErrInvalidOwnerID)
+	})
+}
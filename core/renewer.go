@@ -0,0 +1,160 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// RenewerOptions configures AutoRefresh
+type RenewerOptions struct {
+	TTL      time.Duration // Lease of every refresh, the token TTL when zero
+	Interval time.Duration // Delay between refreshes, a third of TTL when zero
+
+	// Cap of the time the renewer keeps the lock, counted from
+	// AutoRefresh (0 = unbounded). A hung worker can't hold the lock
+	// past it: the last refresh ends the lease at the cap, then the
+	// renewer stops with ErrMaxHoldExceeded and the lock expires.
+	MaxHoldDuration time.Duration
+}
+
+// Renewer refreshes a lock in the background, see AutoRefresh
+type Renewer struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	errs   chan error
+
+	mu    sync.Mutex
+	token *LockToken
+}
+
+// AutoRefresh refreshes the lock of token every opts.Interval until Stop
+// is called, ctx is done, a refresh fails or opts.MaxHoldDuration is
+// reached. It never releases the lock.
+//
+// The error stopping the renewer, if any, is sent on Errors.
+func AutoRefresh(ctx context.Context, adapter LockAdapter, token *LockToken, opts RenewerOptions) *Renewer {
+	if opts.TTL <= 0 {
+		opts.TTL = token.TTL
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = opts.TTL / 3
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	r := &Renewer{
+		cancel: cancel,
+		done:   make(chan struct{}),
+		errs:   make(chan error, 1),
+		token:  token,
+	}
+	go r.run(ctx, adapter, opts, time.Now())
+
+	return r
+}
+
+func (r *Renewer) run(ctx context.Context, adapter LockAdapter, opts RenewerOptions, start time.Time) {
+	defer close(r.done)
+	defer close(r.errs)
+
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		ttl := opts.TTL
+		if opts.MaxHoldDuration > 0 {
+			// Never extend the lease past the cap
+			left := opts.MaxHoldDuration - time.Since(start)
+			if left < time.Millisecond {
+				r.errs <- ErrMaxHoldExceeded
+				return
+			}
+			ttl = min(ttl, left)
+		}
+
+		refreshed, err := adapter.Refresh(ctx, r.Token(), ttl)
+		if err != nil {
+			if ctx.Err() == nil {
+				r.errs <- err
+			}
+			return
+		}
+
+		r.mu.Lock()
+		r.token = refreshed
+		r.mu.Unlock()
+	}
+}
+
+// Token returns the latest refreshed token
+func (r *Renewer) Token() *LockToken {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.token
+}
+
+// Errors receives the error stopping the renewer, and is closed once it
+// stopped
+func (r *Renewer) Errors() <-chan error {
+	return r.errs
+}
+
+// Stop stops refreshing and waits for the refresh in flight, leaving the
+// lock to be released or to expire
+func (r *Renewer) Stop() {
+	r.cancel()
+	<-r.done
+}
+
+// RenewUntil keeps the lock of token, refreshing it with ttl every third
+// of ttl, until ctx is done or a refresh fails for good. It is the
+// blocking counterpart of AutoRefresh, cancelling the work once done:
+//
+//	ctx, cancel := context.WithCancel(ctx)
+//	defer cancel() // Stops the work when the lock is lost
+//	go func() {
+//		defer cancel() // Releases the lock when the work is done
+//		work(ctx)
+//	}()
+//	return core.RenewUntil(ctx, adapter, token, ttl)
+//
+// Once ctx is done the lock is released, with its own
+// DefaultRequestTimeout, and the release error is returned. A refresh
+// timing out is retried at the next tick while the lease lasts; any
+// other failure means the lock is lost and is returned as is.
+func RenewUntil(ctx context.Context, adapter LockAdapter, token *LockToken, ttl time.Duration) error {
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), DefaultRequestTimeout)
+			defer cancel()
+			return adapter.Release(releaseCtx, token)
+		case <-ticker.C:
+		}
+
+		// The refresh in flight is not cancelled with ctx, so the token
+		// released afterwards is the latest one
+		refreshed, err := adapter.Refresh(context.WithoutCancel(ctx), token, ttl)
+		if err != nil {
+			if isTimeout(err) && !token.IsExpired() {
+				continue
+			}
+			return err
+		}
+		token = refreshed
+	}
+}
+
+func isTimeout(err error) bool {
+	return errors.Is(err, ErrOperationTimeout) || errors.Is(err, context.DeadlineExceeded)
+}
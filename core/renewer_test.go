@@ -0,0 +1,146 @@
+package core_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/stretchr/testify/require"
+)
+
+// refreshRecorder records the refreshes of its locks
+type refreshRecorder struct {
+	core.LockAdapter
+
+	mu        sync.Mutex
+	refreshed []*core.LockToken
+}
+
+func (r *refreshRecorder) Refresh(ctx context.Context, token *core.LockToken, newTTL time.Duration) (*core.LockToken, error) {
+	refreshed := *token
+	refreshed.TTL = newTTL
+	refreshed.ValidUntil = time.Now().Add(newTTL)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.refreshed = append(r.refreshed, &refreshed)
+	return &refreshed, nil
+}
+
+func (r *refreshRecorder) refreshes() []*core.LockToken {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.refreshed
+}
+
+func TestAutoRefresh(t *testing.T) {
+	token := &core.LockToken{Key: "key", LeaseID: "lease", TTL: 60 * time.Millisecond}
+
+	t.Run("given a max hold duration, when reached, then the renewer stops extending the lock", func(t *testing.T) {
+		adapter := &refreshRecorder{}
+		start := time.Now()
+		renewer := core.AutoRefresh(context.Background(), adapter, token, core.RenewerOptions{
+			Interval:        10 * time.Millisecond,
+			MaxHoldDuration: 100 * time.Millisecond,
+		})
+		defer renewer.Stop()
+
+		select {
+		case err := <-renewer.Errors():
+			require.ErrorIs(t, err, core.ErrMaxHoldExceeded)
+		case <-time.After(time.Second):
+			t.Fatal("the renewer never stopped")
+		}
+
+		refreshes := adapter.refreshes()
+		require.NotEmpty(t, refreshes)
+		// The leases shrink so the lock expires at the cap
+		last := refreshes[len(refreshes)-1]
+		require.Less(t, last.TTL, token.TTL)
+		require.WithinDuration(t, start.Add(100*time.Millisecond), last.ValidUntil, 20*time.Millisecond)
+		require.Equal(t, last, renewer.Token())
+
+		time.Sleep(30 * time.Millisecond)
+		require.Len(t, adapter.refreshes(), len(refreshes))
+	})
+
+	t.Run("given no max hold duration, when stopped, then the renewer exits without error", func(t *testing.T) {
+		adapter := &refreshRecorder{}
+		renewer := core.AutoRefresh(context.Background(), adapter, token, core.RenewerOptions{})
+
+		require.Eventually(t, func() bool {
+			return len(adapter.refreshes()) >= 2
+		}, time.Second, 5*time.Millisecond)
+
+		renewer.Stop()
+		err, open := <-renewer.Errors()
+		require.False(t, open)
+		require.NoError(t, err)
+	})
+}
+
+// failingRefresher fails the refreshes with errs, in order, then succeeds
+type failingRefresher struct {
+	refreshRecorder
+	errs     []error
+	released *core.LockToken
+}
+
+func (f *failingRefresher) Refresh(ctx context.Context, token *core.LockToken, newTTL time.Duration) (*core.LockToken, error) {
+	f.mu.Lock()
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		f.mu.Unlock()
+		return nil, err
+	}
+	f.mu.Unlock()
+	return f.refreshRecorder.Refresh(ctx, token, newTTL)
+}
+
+func (f *failingRefresher) Release(ctx context.Context, token *core.LockToken) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.released = token
+	return nil
+}
+
+func TestRenewUntil(t *testing.T) {
+	ttl := 30 * time.Millisecond
+	token := &core.LockToken{Key: "key", LeaseID: "lease", TTL: ttl, ValidUntil: time.Now().Add(time.Hour)}
+
+	t.Run("given the ctx is cancelled, when renewing, then the latest token is released", func(t *testing.T) {
+		adapter := &failingRefresher{}
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			require.Eventually(t, func() bool { return len(adapter.refreshes()) >= 2 }, time.Second, time.Millisecond)
+			cancel()
+		}()
+
+		require.NoError(t, core.RenewUntil(ctx, adapter, token, ttl))
+
+		refreshes := adapter.refreshes()
+		require.Equal(t, refreshes[len(refreshes)-1], adapter.released)
+	})
+
+	t.Run("given a timed out refresh, when renewing, then it is retried", func(t *testing.T) {
+		adapter := &failingRefresher{errs: []error{core.ErrOperationTimeout}}
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			require.Eventually(t, func() bool { return len(adapter.refreshes()) >= 1 }, time.Second, time.Millisecond)
+			cancel()
+		}()
+
+		require.NoError(t, core.RenewUntil(ctx, adapter, token, ttl))
+	})
+
+	t.Run("given a lost lock, when renewing, then the error is returned without releasing", func(t *testing.T) {
+		adapter := &failingRefresher{errs: []error{core.ErrLockOwnershipMismatch}}
+
+		err := core.RenewUntil(context.Background(), adapter, token, ttl)
+		require.ErrorIs(t, err, core.ErrLockOwnershipMismatch)
+		require.Nil(t, adapter.released)
+	})
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"errors"
+	"time"
+)
+
+// DefaultRetryStrategy retries DefaultMaxRetries times, doubling the delay
+// from 100ms up to 5s
+func DefaultRetryStrategy() RetryStrategy {
+	return RetryStrategy{
+		MaxRetries:    DefaultMaxRetries,
+		BaseDelay:     100 * time.Millisecond,
+		MaxDelay:      5 * time.Second,
+		JitterFactor:  DefaultJitterFactor,
+		BackoffFactor: 2,
+	}
+}
+
+// AggressiveRetryStrategy retries often with short delays, for locks held
+// briefly where waiting a few milliseconds is cheaper than failing
+func AggressiveRetryStrategy() RetryStrategy {
+	return RetryStrategy{
+		MaxRetries:    20,
+		BaseDelay:     10 * time.Millisecond,
+		MaxDelay:      500 * time.Millisecond,
+		JitterFactor:  0.2,
+		BackoffFactor: 1.5,
+	}
+}
+
+// NoRetry makes a single acquisition attempt
+func NoRetry() RetryStrategy {
+	return RetryStrategy{BackoffFactor: 1}
+}
+
+// ExponentialRetry builds a validated strategy retrying up to retries
+// times, with delays growing by factor from base up to max
+func ExponentialRetry(base, max time.Duration, factor, jitter float64, retries int) (RetryStrategy, error) {
+	r := RetryStrategy{
+		MaxRetries:    retries,
+		BaseDelay:     base,
+		MaxDelay:      max,
+		JitterFactor:  jitter,
+		BackoffFactor: factor,
+	}
+	if base <= 0 {
+		return RetryStrategy{}, errors.New("base delay must be > 0")
+	}
+	if max < base {
+		return RetryStrategy{}, errors.New("max delay must be ≥ base delay")
+	}
+	if err := r.Validate(); err != nil {
+		return RetryStrategy{}, err
+	}
+	return r, nil
+}
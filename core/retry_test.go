@@ -0,0 +1,221 @@
+package core_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryStrategy_Presets(t *testing.T) {
+	t.Run("given the presets, when validate, then they are valid", func(t *testing.T) {
+		for name, strategy := range map[string]core.RetryStrategy{
+			"default":    core.DefaultRetryStrategy(),
+			"aggressive": core.AggressiveRetryStrategy(),
+			"no retry":   core.NoRetry(),
+		} {
+			require.NoError(t, strategy.Validate(), name)
+		}
+	})
+
+	t.Run("given no retry, when acquire, then makes a single attempt", func(t *testing.T) {
+		require.Zero(t, core.NoRetry().MaxRetries)
+	})
+}
+
+func TestExponentialRetry(t *testing.T) {
+	t.Run("given valid parameters, when build, then returns the strategy", func(t *testing.T) {
+		strategy, err := core.ExponentialRetry(50*time.Millisecond, time.Second, 2, 0.1, 3)
+		require.NoError(t, err)
+		require.Equal(t, core.RetryStrategy{
+			MaxRetries:    3,
+			BaseDelay:     50 * time.Millisecond,
+			MaxDelay:      time.Second,
+			JitterFactor:  0.1,
+			BackoffFactor: 2,
+		}, strategy)
+	})
+
+	t.Run("given invalid parameters, when build, then returns an error", func(t *testing.T) {
+		for name, build := range map[string]func() (core.RetryStrategy, error){
+			"zero base": func() (core.RetryStrategy, error) { return core.ExponentialRetry(0, time.Second, 2, 0, 3) },
+			"max below base": func() (core.RetryStrategy, error) {
+				return core.ExponentialRetry(time.Second, time.Millisecond, 2, 0, 3)
+			},
+			"factor below 1": func() (core.RetryStrategy, error) {
+				return core.ExponentialRetry(time.Millisecond, time.Second, 0.5, 0, 3)
+			},
+			"jitter above 1": func() (core.RetryStrategy, error) {
+				return core.ExponentialRetry(time.Millisecond, time.Second, 2, 1.5, 3)
+			},
+			"negative retry": func() (core.RetryStrategy, error) {
+				return core.ExponentialRetry(time.Millisecond, time.Second, 2, 0, -1)
+			},
+		} {
+			strategy, err := build()
+			require.Error(t, err, name)
+			require.Equal(t, core.RetryStrategy{}, strategy, name)
+		}
+	})
+}
+
+func TestCalculateBackoff_JitterMode(t *testing.T) {
+	strategy := core.RetryStrategy{
+		BaseDelay:     100 * time.Millisecond,
+		MaxDelay:      time.Second,
+		BackoffFactor: 2,
+	}
+
+	t.Run("given no jitter, when calculate, then returns the capped exponential delay", func(t *testing.T) {
+		require.Equal(t, 100*time.Millisecond, core.CalculateBackoff(strategy, 0))
+		require.Equal(t, 400*time.Millisecond, core.CalculateBackoff(strategy, 2))
+		require.Equal(t, time.Second, core.CalculateBackoff(strategy, 10))
+	})
+
+	t.Run("given equal jitter, when calculate, then the delay is within its upper half", func(t *testing.T) {
+		equal := strategy
+		equal.JitterMode = core.JitterEqual
+		for range 1000 {
+			delay := core.CalculateBackoff(equal, 2)
+			require.GreaterOrEqual(t, delay, 200*time.Millisecond)
+			require.Less(t, delay, 400*time.Millisecond)
+
+			capped := core.CalculateBackoff(equal, 10)
+			require.GreaterOrEqual(t, capped, 500*time.Millisecond)
+			require.Less(t, capped, time.Second)
+		}
+	})
+
+	t.Run("given full jitter, when calculate, then the delay spreads over the whole range", func(t *testing.T) {
+		full := strategy
+		full.JitterMode = core.JitterFull
+		var low, high bool
+		for range 1000 {
+			delay := core.CalculateBackoff(full, 2)
+			require.GreaterOrEqual(t, delay, time.Duration(0))
+			require.Less(t, delay, 400*time.Millisecond)
+			low = low || delay < 100*time.Millisecond
+			high = high || delay >= 300*time.Millisecond
+		}
+		require.True(t, low && high)
+	})
+
+	t.Run("given an unknown mode, when validate, then returns an error", func(t *testing.T) {
+		unknown := strategy
+		unknown.JitterMode = core.JitterFull + 1
+		require.Error(t, unknown.Validate())
+	})
+}
+
+func TestAcquireRetry_Run(t *testing.T) {
+	opts := core.LockOptions{
+		TTL: time.Second,
+		RetryStrategy: core.RetryStrategy{
+			MaxRetries:    2,
+			BaseDelay:     time.Millisecond,
+			MaxDelay:      time.Millisecond,
+			BackoffFactor: 1,
+		},
+	}
+	contended := func(attempts *int, holder func() *core.ContentionError) core.AcquireAttempt {
+		return func(context.Context, int) (*core.LockToken, func() *core.ContentionError, error) {
+			*attempts++
+			return nil, holder, nil
+		}
+	}
+
+	t.Run("given a lock obtained on retry, when run, then returns its token and calls the hooks", func(t *testing.T) {
+		var contentions []int
+		var acquired *core.LockToken
+		retry := core.AcquireRetry{Key: "key", Options: opts, Hooks: core.Hooks{
+			OnAcquired:   func(_ context.Context, token *core.LockToken) { acquired = token },
+			OnContention: func(_ context.Context, _ string, attempt int) { contentions = append(contentions, attempt) },
+		}}
+
+		token, err := retry.Run(context.Background(), func(_ context.Context, attempt int) (*core.LockToken, func() *core.ContentionError, error) {
+			if attempt < 2 {
+				return nil, nil, nil
+			}
+			return &core.LockToken{Key: "key"}, nil, nil
+		})
+		require.NoError(t, err)
+		require.Same(t, acquired, token)
+		require.Equal(t, []int{0, 1}, contentions)
+	})
+
+	t.Run("given a key staying held, when run, then fails with the holder read once", func(t *testing.T) {
+		attempts, reads := 0, 0
+		holder := func() *core.ContentionError {
+			reads++
+			return &core.ContentionError{HolderID: "other"}
+		}
+
+		_, err := core.AcquireRetry{Key: "key", Options: opts}.Run(context.Background(), contended(&attempts, holder))
+		require.ErrorIs(t, err, core.ErrLockContention)
+		lockErr, ok := core.AsLockError(err)
+		require.True(t, ok)
+		require.Equal(t, core.OpAcquire, lockErr.Op)
+		require.Equal(t, 3, lockErr.Attempts)
+		require.Equal(t, "other", lockErr.LastHolderID)
+		require.Equal(t, 3, attempts)
+		require.Equal(t, 1, reads)
+	})
+
+	t.Run("given an unknown holder, when the retries are exhausted, then fails with an empty contention", func(t *testing.T) {
+		attempts := 0
+		_, err := core.AcquireRetry{Key: "key", Options: opts}.Run(context.Background(), contended(&attempts, nil))
+		var contention *core.ContentionError
+		require.ErrorAs(t, err, &contention)
+		require.Empty(t, contention.HolderID)
+	})
+
+	t.Run("given a max elapsed budget, when the next backoff exceeds it, then stops retrying", func(t *testing.T) {
+		budgeted := opts
+		budgeted.RetryStrategy.MaxRetries = 100
+		budgeted.RetryStrategy.MaxElapsed = 50 * time.Millisecond
+		budgeted.BackoffFunc = func(core.RetryStrategy, int) time.Duration { return 30 * time.Millisecond }
+
+		attempts := 0
+		_, err := core.AcquireRetry{Key: "key", Options: budgeted}.Run(context.Background(), contended(&attempts, nil))
+		require.ErrorIs(t, err, core.ErrLockContention)
+		require.Equal(t, 2, attempts)
+	})
+
+	t.Run("given a cancelled context during the backoff, when run, then fails with ErrOperationTimeout", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		slow := opts
+		slow.BackoffFunc = func(core.RetryStrategy, int) time.Duration { return time.Minute }
+
+		start := time.Now()
+		_, err := core.AcquireRetry{Key: "key", Options: slow}.Run(ctx, func(context.Context, int) (*core.LockToken, func() *core.ContentionError, error) {
+			cancel()
+			return nil, nil, nil
+		})
+		require.ErrorIs(t, err, core.ErrOperationTimeout)
+		require.ErrorIs(t, err, context.Canceled)
+		require.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("given a failing attempt, when run, then fails without retrying unless transient", func(t *testing.T) {
+		errBusy := errors.New("busy")
+		attempts := 0
+		failing := func(context.Context, int) (*core.LockToken, func() *core.ContentionError, error) {
+			attempts++
+			return nil, nil, errBusy
+		}
+
+		_, err := core.AcquireRetry{Key: "key", Options: opts}.Run(context.Background(), failing)
+		require.ErrorIs(t, err, errBusy)
+		require.NotErrorIs(t, err, core.ErrLockContention)
+		require.Equal(t, 1, attempts)
+
+		attempts = 0
+		transient := core.AcquireRetry{Key: "key", Options: opts, Transient: func(err error) bool { return errors.Is(err, errBusy) }}
+		_, err = transient.Run(context.Background(), failing)
+		require.ErrorIs(t, err, errBusy)
+		require.Equal(t, 3, attempts)
+	})
+}
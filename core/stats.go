@@ -0,0 +1,29 @@
+package core
+
+// Stats is a snapshot of the operation counters of an adapter instance,
+// counted since the adapter was created
+type Stats struct {
+	Acquires        uint64 // Acquire calls
+	Successes       uint64 // Acquire calls that obtained the lock
+	Contentions     uint64 // Acquisition attempts that found the key held
+	TransientErrors uint64 // Acquisition attempts failed with a transient error, then retried
+	Releases        uint64 // Locks released
+	Refreshes       uint64 // Locks refreshed
+	RefreshTooLate  uint64 // Refreshes rejected with ErrRefreshTooLate
+	EventsDropped   uint64 // Lock events discarded for a slow consumer
+
+	// Locks acquired and not yet released through this adapter.
+	// Locks left to expire are counted until the adapter is recreated.
+	Held int64
+
+	// Acquire calls currently retrying a held key, the contention depth
+	// behind ContentionInfo.Waiters across every key
+	Waiters int64
+}
+
+// StatsReporter is implemented by adapters keeping operation counters,
+// a zero dependency alternative to a metrics backend.
+type StatsReporter interface {
+	// Stats returns a snapshot of the counters
+	Stats() Stats
+}
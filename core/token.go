@@ -0,0 +1,37 @@
+package core
+
+import "time"
+
+// now reads the clock of the token, adjusted to the backend clock
+func (t *LockToken) now() time.Time {
+	if t.Clock == nil {
+		return time.Now().Add(t.ClockOffset)
+	}
+	return t.Clock().Add(t.ClockOffset)
+}
+
+// Remaining returns the time left until ValidUntil, or 0 once expired
+func (t *LockToken) Remaining() time.Duration {
+	remaining := t.ValidUntil.Sub(t.now())
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// IsExpired reports whether ValidUntil has passed
+func (t *LockToken) IsExpired() bool {
+	return !t.now().Before(t.ValidUntil)
+}
+
+// NeedsRefresh reports whether the token is within margin (a fraction of
+// its TTL) of expiring, so it should be refreshed now to survive the clock
+// drift between hosts. A margin ≤ 0 defaults to MaxClockDriftMargin.
+//
+// Tokens without a TTL need refresh only once expired.
+func (t *LockToken) NeedsRefresh(margin float64) bool {
+	if margin <= 0 {
+		margin = MaxClockDriftMargin
+	}
+	return t.Remaining() <= time.Duration(float64(t.TTL)*margin)
+}
@@ -0,0 +1,62 @@
+package core_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockToken_Expiry(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	t.Run("given a valid token, when get remaining, then returns the time until ValidUntil", func(t *testing.T) {
+		token := &core.LockToken{ValidUntil: now.Add(10 * time.Second), TTL: time.Minute, Clock: clock}
+
+		require.Equal(t, 10*time.Second, token.Remaining())
+		require.False(t, token.IsExpired())
+	})
+
+	t.Run("given an expired token, when get remaining, then returns 0", func(t *testing.T) {
+		token := &core.LockToken{ValidUntil: now.Add(-time.Second), TTL: time.Minute, Clock: clock}
+
+		require.Zero(t, token.Remaining())
+		require.True(t, token.IsExpired())
+		require.True(t, token.NeedsRefresh(0.1))
+	})
+
+	t.Run("given a token within the margin, when needs refresh, then returns true", func(t *testing.T) {
+		token := &core.LockToken{ValidUntil: now.Add(10 * time.Second), TTL: time.Minute, Clock: clock}
+
+		// 10s left of a 60s lease
+		require.True(t, token.NeedsRefresh(0.2))
+		require.False(t, token.NeedsRefresh(0.1))
+	})
+
+	t.Run("given no margin, when needs refresh, then uses MaxClockDriftMargin", func(t *testing.T) {
+		token := &core.LockToken{ValidUntil: now.Add(9 * time.Second), TTL: time.Minute, Clock: clock}
+		require.True(t, token.NeedsRefresh(0))
+
+		token.ValidUntil = now.Add(10 * time.Second)
+		require.False(t, token.NeedsRefresh(0))
+	})
+
+	t.Run("given no clock, when get remaining, then uses the system clock", func(t *testing.T) {
+		token := &core.LockToken{ValidUntil: time.Now().Add(time.Hour)}
+
+		require.Greater(t, token.Remaining(), 59*time.Minute)
+		require.False(t, token.IsExpired())
+		require.False(t, token.NeedsRefresh(0))
+	})
+
+	t.Run("given a clock offset, when get remaining, then compares ValidUntil with the offset clock", func(t *testing.T) {
+		// The backend clock is 4s ahead
+		token := &core.LockToken{ValidUntil: now.Add(10 * time.Second), TTL: time.Minute, Clock: clock, ClockOffset: 4 * time.Second}
+		require.Equal(t, 6*time.Second, token.Remaining())
+
+		token.ClockOffset = 11 * time.Second
+		require.True(t, token.IsExpired())
+	})
+}
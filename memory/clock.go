@@ -0,0 +1,42 @@
+package memory
+
+import (
+	"sync"
+	"time"
+)
+
+// ManualClock is a time source moved only by Advance and Set, for tests
+// expiring locks without waiting:
+//
+//	clock := memory.NewManualClock(time.Now())
+//	adapter := memory.NewMemoryLockAdapter(memory.WithClock(clock.Now))
+type ManualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewManualClock creates a clock stopped at now
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+// Now returns the current time of the clock
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Set moves the clock to now
+func (c *ManualClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
@@ -0,0 +1,364 @@
+// Package memory implements core.LockAdapter in process, for unit tests
+// and single process services.
+//
+// The locks live in a map guarded by a mutex and expire lazily: a lock
+// past its TTL stays in the map, and can be refreshed within the safety
+// margin, until released or taken over, as a row of the pg adapter. The
+// contention semantics, retries and errors are the ones of the pg
+// adapter, which makes it the reference implementation of the
+// core/locktest suite.
+//
+// The clock can be injected, so tests expire locks instantly:
+//
+//	clock := memory.NewManualClock(time.Now())
+//	adapter := memory.NewMemoryLockAdapter(memory.WithClock(clock.Now))
+//	token, _ := adapter.Acquire(ctx, "key", opts)
+//	clock.Advance(opts.TTL)
+//	held, _, _ := adapter.IsHeld(ctx, token) // false
+//
+// The backoff between the retries of Acquire waits in real time.
+package memory
+
+import (
+	"context"
+	"maps"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+func init() {
+	core.Register("memory", func(ctx context.Context, dsn *url.URL, cfg core.OpenConfig) (core.LockAdapter, error) {
+		return NewMemoryLockAdapter(WithHooks(cfg.Hooks)), nil
+	})
+}
+
+// Option configures NewMemoryLockAdapter
+type Option func(*MemoryLockAdapter)
+
+// WithClock sets the time source of the expirations, time.Now by default.
+// The tokens issued carry it as their Clock.
+func WithClock(now func() time.Time) Option {
+	return func(a *MemoryLockAdapter) {
+		a.now = now
+	}
+}
+
+// WithHooks sets the lifecycle hooks of the adapter
+func WithHooks(hooks core.Hooks) Option {
+	return func(a *MemoryLockAdapter) {
+		a.hooks = hooks
+	}
+}
+
+// WithIDGenerator sets the generator of the LeaseID and ServerNonce of
+// the tokens, core.UUIDGenerator by default
+func WithIDGenerator(ids core.IDGenerator) Option {
+	return func(a *MemoryLockAdapter) {
+		a.ids = ids
+	}
+}
+
+// WithRefreshSafetyMargin sets the fraction of the new TTL during which
+// an expired lock can still be refreshed, core.MaxClockDriftMargin by
+// default. Overridden per lock by core.LockOptions.RefreshSafetyMargin.
+func WithRefreshSafetyMargin(margin float64) Option {
+	return func(a *MemoryLockAdapter) {
+		a.refreshSafetyMargin = margin
+	}
+}
+
+// lock is a held lock, or an expired one not yet released or taken over
+type lock struct {
+	leaseID    string
+	nonce      string
+	ownerID    string
+	metadata   map[string]string
+	validUntil time.Time
+}
+
+type MemoryLockAdapter struct {
+	now                 func() time.Time
+	hooks               core.Hooks
+	ids                 core.IDGenerator
+	refreshSafetyMargin float64
+	startedAt           time.Time
+
+	mu         sync.Mutex
+	locks      map[string]*lock
+	closed     bool
+	operations uint64
+}
+
+// NewMemoryLockAdapter creates an empty adapter
+func NewMemoryLockAdapter(opts ...Option) *MemoryLockAdapter {
+	a := &MemoryLockAdapter{
+		now:                 time.Now,
+		ids:                 core.UUIDGenerator{},
+		refreshSafetyMargin: core.MaxClockDriftMargin,
+		locks:               map[string]*lock{},
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	a.startedAt = a.now()
+	return a
+}
+
+// begin locks the adapter for an operation, failing once it is closed.
+// Every successful begin must be paired with an unlock of mu.
+func (a *MemoryLockAdapter) begin() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return core.ErrAdapterClosed
+	}
+	a.operations++
+	return nil
+}
+
+// Acquire obtains the lock of key, retrying a contended key with the
+// backoff of opts.RetryStrategy. A lock past its TTL is taken over, with
+// TookOver and PreviousLeaseID set on the token.
+//
+// Errors wrap, as the ones of the pg adapter:
+//
+// - *core.ContentionError (core.ErrLockContention): the key stayed held
+// through the retries
+//
+// - core.ErrOperationTimeout: ctx was done during the backoff
+//
+// - the validation error of an invalid key, options or metadata
+func (a *MemoryLockAdapter) Acquire(ctx context.Context, key string, opts core.LockOptions) (*core.LockToken, error) {
+	if err := core.ValidateKey(key); err != nil {
+		return nil, err
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	encoded, err := core.EncodeMetadata(opts)
+	if err != nil {
+		return nil, err
+	}
+	// Kept as the backends read it back, the string values only
+	metadata := core.DecodeMetadata(encoded)
+
+	leaseID := a.ids.NewID()
+	tryAcquire := func(context.Context, int) (*core.LockToken, func() *core.ContentionError, error) {
+		token, err := a.tryAcquire(key, leaseID, metadata, opts)
+		if err != nil || token != nil {
+			return token, nil, err
+		}
+		return nil, func() *core.ContentionError { return a.holder(key) }, nil
+	}
+	return core.AcquireRetry{Key: key, Options: opts, Hooks: a.hooks}.Run(ctx, tryAcquire)
+}
+
+// tryAcquire runs a single attempt, returning a nil token on contention
+func (a *MemoryLockAdapter) tryAcquire(key, leaseID string, metadata map[string]string, opts core.LockOptions) (*core.LockToken, error) {
+	if err := a.begin(); err != nil {
+		return nil, err
+	}
+	defer a.mu.Unlock()
+
+	now := a.now()
+	current, held := a.locks[key]
+	if held && current.validUntil.After(now) {
+		if !opts.ConfirmIfOwned || current.ownerID != opts.OwnerID {
+			return nil, nil
+		}
+		// The lease goes on with a new nonce
+		current.nonce = a.ids.NewID()
+		current.validUntil = now.Add(opts.TTL)
+		if metadata != nil {
+			current.metadata = metadata
+		}
+		return a.token(key, current, opts), nil
+	}
+
+	acquired := &lock{
+		leaseID:    leaseID,
+		nonce:      a.ids.NewID(),
+		ownerID:    opts.OwnerID,
+		metadata:   metadata,
+		validUntil: now.Add(opts.TTL),
+	}
+	a.locks[key] = acquired
+
+	token := a.token(key, acquired, opts)
+	if held {
+		token.TookOver = true
+		token.PreviousLeaseID = current.leaseID
+	}
+	return token, nil
+}
+
+func (a *MemoryLockAdapter) token(key string, l *lock, opts core.LockOptions) *core.LockToken {
+	return &core.LockToken{
+		Key:                 key,
+		LeaseID:             l.leaseID,
+		ValidUntil:          l.validUntil,
+		ServerNonce:         l.nonce,
+		OwnerID:             l.ownerID,
+		TTL:                 opts.TTL,
+		Clock:               a.now,
+		RefreshSafetyMargin: opts.RefreshSafetyMargin,
+	}
+}
+
+// holder describes the current holder of the key
+func (a *MemoryLockAdapter) holder(key string) *core.ContentionError {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	current, ok := a.locks[key]
+	if !ok {
+		return &core.ContentionError{}
+	}
+	return &core.ContentionError{
+		HeldUntil:      current.validUntil,
+		HolderID:       current.ownerID,
+		HolderMetadata: maps.Clone(current.metadata),
+	}
+}
+
+// Release releases the lock of the token, even past its TTL as long as
+// nobody took it over.
+//
+// Errors wrap:
+//
+// - core.ErrLockNotFound: there is no lock for the key
+//
+// - core.ErrLockOwnershipMismatch: the key is held with another lease or nonce
+func (a *MemoryLockAdapter) Release(ctx context.Context, token *core.LockToken) error {
+	if err := a.begin(); err != nil {
+		return err
+	}
+	err := a.release(token)
+	a.mu.Unlock()
+
+	if err != nil {
+		return &core.LockError{Op: core.OpRelease, Key: token.Key, Attempts: 1, Err: err}
+	}
+	a.hooks.Released(ctx, token)
+	return nil
+}
+
+func (a *MemoryLockAdapter) release(token *core.LockToken) error {
+	current, ok := a.locks[token.Key]
+	if !ok {
+		return core.ErrLockNotFound
+	}
+	if current.leaseID != token.LeaseID || current.nonce != token.ServerNonce {
+		return core.ErrLockOwnershipMismatch
+	}
+	delete(a.locks, token.Key)
+	return nil
+}
+
+// Refresh extends the lock and returns a new token.
+//
+// Errors wrap:
+//
+// - core.ErrInvalidTTL: newTTL is out of range
+//
+// - core.ErrRefreshTooLate: the lock is still ours but expired beyond the safety margin
+//
+// - core.ErrLockOwnershipMismatch: the key is held with another lease or nonce
+//
+// - core.ErrLockNotFound: there is no lock for the key
+func (a *MemoryLockAdapter) Refresh(ctx context.Context, token *core.LockToken, newTTL time.Duration) (*core.LockToken, error) {
+	if err := a.begin(); err != nil {
+		return nil, err
+	}
+	refreshed, err := a.refresh(token, newTTL)
+	a.mu.Unlock()
+
+	if err != nil {
+		a.hooks.RefreshFailed(ctx, token, err)
+		return nil, &core.LockError{Op: core.OpRefresh, Key: token.Key, Attempts: 1, Err: err}
+	}
+	return refreshed, nil
+}
+
+func (a *MemoryLockAdapter) refresh(token *core.LockToken, newTTL time.Duration) (*core.LockToken, error) {
+	if err := core.ValidateTTL(newTTL, core.MaxLockTTL); err != nil {
+		return nil, err
+	}
+	current, ok := a.locks[token.Key]
+	if !ok {
+		return nil, core.ErrLockNotFound
+	}
+	if current.leaseID != token.LeaseID || current.nonce != token.ServerNonce {
+		return nil, core.ErrLockOwnershipMismatch
+	}
+
+	margin := a.refreshSafetyMargin
+	if token.RefreshSafetyMargin != nil {
+		margin = *token.RefreshSafetyMargin
+	}
+	now := a.now()
+	if !current.validUntil.After(now.Add(-time.Duration(float64(newTTL) * margin))) {
+		return nil, core.ErrRefreshTooLate
+	}
+
+	current.nonce = a.ids.NewID()
+	current.validUntil = now.Add(newTTL)
+
+	refreshed := *token
+	refreshed.ValidUntil = current.validUntil
+	refreshed.ServerNonce = current.nonce
+	refreshed.TTL = newTTL
+	refreshed.Clock = a.now
+	return &refreshed, nil
+}
+
+// IsHeld reports whether the token still owns its lock and the remaining TTL
+func (a *MemoryLockAdapter) IsHeld(ctx context.Context, token *core.LockToken) (bool, time.Duration, error) {
+	if err := a.begin(); err != nil {
+		return false, 0, err
+	}
+	defer a.mu.Unlock()
+
+	current, ok := a.locks[token.Key]
+	if !ok || current.leaseID != token.LeaseID || current.nonce != token.ServerNonce {
+		return false, 0, nil
+	}
+	remaining := current.validUntil.Sub(a.now())
+	if remaining <= 0 {
+		return false, 0, nil
+	}
+	return true, remaining, nil
+}
+
+// Close makes the operations fail with core.ErrAdapterClosed and drops
+// the locks. Closing twice is a no-op.
+func (a *MemoryLockAdapter) Close(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.closed = true
+	a.locks = map[string]*lock{}
+	return nil
+}
+
+// HealthCheck reports StatusGreen until the adapter is closed, with the
+// number of locks in Details["locks"]
+func (a *MemoryLockAdapter) HealthCheck(ctx context.Context) core.HealthReport {
+	if err := a.begin(); err != nil {
+		return core.HealthReport{Status: core.StatusRed, Error: err, Backend: "memory"}
+	}
+	defer a.mu.Unlock()
+
+	return core.HealthReport{
+		Status:     core.StatusGreen,
+		Operations: a.operations,
+		Uptime:     a.now().Sub(a.startedAt),
+		Backend:    "memory",
+		Details: map[string]string{
+			"locks": strconv.Itoa(len(a.locks)),
+		},
+	}
+}
@@ -0,0 +1,209 @@
+package memory_test
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/oliveiracleidson/go-lockbox/memory"
+	"github.com/stretchr/testify/require"
+)
+
+var opts = core.LockOptions{
+	TTL:            10 * time.Second,
+	RetryStrategy:  core.NoRetry(),
+	RequestTimeout: time.Second,
+	OwnerID:        "worker-1",
+	Metadata:       map[string]string{"job": "reindex"},
+}
+
+func TestMemoryLockAdapter_Expiry(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("given a manual clock, when it passes the TTL, then the lock is no longer held", func(t *testing.T) {
+		clock := memory.NewManualClock(time.Now())
+		adapter := memory.NewMemoryLockAdapter(memory.WithClock(clock.Now))
+
+		token, err := adapter.Acquire(ctx, "key", opts)
+		require.NoError(t, err)
+		require.False(t, token.IsExpired())
+
+		clock.Advance(opts.TTL - time.Second)
+		held, remaining, err := adapter.IsHeld(ctx, token)
+		require.NoError(t, err)
+		require.True(t, held)
+		require.Equal(t, time.Second, remaining)
+
+		clock.Advance(time.Second)
+		held, _, err = adapter.IsHeld(ctx, token)
+		require.NoError(t, err)
+		require.False(t, held)
+		require.True(t, token.IsExpired(), "the token follows the clock of the adapter")
+	})
+
+	t.Run("given an expired lock, when acquire, then takes it over", func(t *testing.T) {
+		clock := memory.NewManualClock(time.Now())
+		adapter := memory.NewMemoryLockAdapter(memory.WithClock(clock.Now))
+
+		previous, err := adapter.Acquire(ctx, "key", opts)
+		require.NoError(t, err)
+		clock.Advance(opts.TTL)
+
+		token, err := adapter.Acquire(ctx, "key", opts)
+		require.NoError(t, err)
+		require.True(t, token.TookOver)
+		require.Equal(t, previous.LeaseID, token.PreviousLeaseID)
+
+		require.ErrorIs(t, adapter.Release(ctx, previous), core.ErrLockOwnershipMismatch)
+	})
+
+	t.Run("given an expired lock, when refresh within the safety margin, then extends it", func(t *testing.T) {
+		clock := memory.NewManualClock(time.Now())
+		adapter := memory.NewMemoryLockAdapter(memory.WithClock(clock.Now))
+
+		token, err := adapter.Acquire(ctx, "key", opts)
+		require.NoError(t, err)
+		clock.Advance(opts.TTL + time.Second)
+
+		refreshed, err := adapter.Refresh(ctx, token, opts.TTL)
+		require.NoError(t, err)
+		require.Equal(t, clock.Now().Add(opts.TTL), refreshed.ValidUntil)
+
+		clock.Advance(2 * opts.TTL)
+		_, err = adapter.Refresh(ctx, refreshed, opts.TTL)
+		require.ErrorIs(t, err, core.ErrRefreshTooLate)
+	})
+}
+
+func TestMemoryLockAdapter_Contention(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("given a held key, when acquire, then the contention error describes the holder", func(t *testing.T) {
+		var contentions atomic.Int64
+		adapter := memory.NewMemoryLockAdapter(memory.WithHooks(core.Hooks{
+			OnContention: func(ctx context.Context, key string, attempt int) { contentions.Add(1) },
+		}))
+		token, err := adapter.Acquire(ctx, "key", opts)
+		require.NoError(t, err)
+
+		retried := opts
+		retried.OwnerID = "worker-2"
+		retried.RetryStrategy = core.RetryStrategy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, BackoffFactor: 1}
+		_, err = adapter.Acquire(ctx, "key", retried)
+
+		var contentionErr *core.ContentionError
+		require.ErrorAs(t, err, &contentionErr)
+		require.Equal(t, "worker-1", contentionErr.HolderID)
+		require.Equal(t, token.ValidUntil, contentionErr.HeldUntil)
+		require.Equal(t, map[string]string{"job": "reindex"}, contentionErr.HolderMetadata)
+
+		lockErr, ok := core.AsLockError(err)
+		require.True(t, ok)
+		require.Equal(t, 3, lockErr.Attempts)
+		require.EqualValues(t, 3, contentions.Load())
+	})
+
+	t.Run("given a held key, when acquire with retries, then succeeds once released", func(t *testing.T) {
+		adapter := memory.NewMemoryLockAdapter()
+		token, err := adapter.Acquire(ctx, "key", opts)
+		require.NoError(t, err)
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			adapter.Release(ctx, token)
+		}()
+
+		retried := opts
+		retried.RetryStrategy = core.RetryStrategy{MaxRetries: 20, BaseDelay: 10 * time.Millisecond, MaxDelay: 20 * time.Millisecond, BackoffFactor: 2}
+		_, err = adapter.Acquire(ctx, "key", retried)
+		require.NoError(t, err)
+	})
+
+	t.Run("given a BackoffFunc, when acquire retries, then waits the delays it returns", func(t *testing.T) {
+		adapter := memory.NewMemoryLockAdapter()
+		_, err := adapter.Acquire(ctx, "key", opts)
+		require.NoError(t, err)
+
+		var attempts []int
+		retried := opts
+		retried.RetryStrategy = core.RetryStrategy{MaxRetries: 3, BaseDelay: time.Hour, MaxDelay: time.Hour, BackoffFactor: 1}
+		retried.BackoffFunc = func(strategy core.RetryStrategy, attempt int) time.Duration {
+			attempts = append(attempts, attempt)
+			return 10 * time.Millisecond
+		}
+		start := time.Now()
+		_, err = adapter.Acquire(ctx, "key", retried)
+		require.ErrorIs(t, err, core.ErrLockContention)
+		require.Equal(t, []int{0, 1, 2, 3}, attempts)
+		require.Less(t, time.Since(start), time.Second, "the hour long delays of the strategy are overridden")
+	})
+
+	t.Run("given a cancelled ctx, when the backoff is interrupted, then fails with ErrOperationTimeout", func(t *testing.T) {
+		adapter := memory.NewMemoryLockAdapter()
+		_, err := adapter.Acquire(ctx, "key", opts)
+		require.NoError(t, err)
+
+		cancelled, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		retried := opts
+		retried.RetryStrategy = core.RetryStrategy{MaxRetries: 5, BaseDelay: time.Second, MaxDelay: time.Second, BackoffFactor: 1}
+		_, err = adapter.Acquire(cancelled, "key", retried)
+		require.ErrorIs(t, err, core.ErrLockContention)
+		require.NotErrorIs(t, err, core.ErrOperationTimeout, "the backoff would outlast the deadline, so it gives up first")
+	})
+
+	t.Run("given a key held by the same owner, when acquire with ConfirmIfOwned, then takes over its lease", func(t *testing.T) {
+		adapter := memory.NewMemoryLockAdapter()
+		token, err := adapter.Acquire(ctx, "key", opts)
+		require.NoError(t, err)
+
+		confirm := opts
+		confirm.ConfirmIfOwned = true
+		confirmed, err := adapter.Acquire(ctx, "key", confirm)
+		require.NoError(t, err)
+		require.Equal(t, token.LeaseID, confirmed.LeaseID)
+		require.NotEqual(t, token.ServerNonce, confirmed.ServerNonce)
+		require.ErrorIs(t, adapter.Release(ctx, token), core.ErrLockOwnershipMismatch)
+	})
+
+	t.Run("given metadata the backends reject, when acquire, then fails the same way", func(t *testing.T) {
+		adapter := memory.NewMemoryLockAdapter()
+		oversized := opts
+		oversized.Metadata = map[string]string{"blob": strings.Repeat("x", core.MaxMetadataSize)}
+		_, err := adapter.Acquire(ctx, "key", oversized)
+		require.ErrorIs(t, err, core.ErrMetadataTooLarge)
+
+		invalid := opts
+		invalid.MetadataJSON = []byte(`["job"]`)
+		_, err = adapter.Acquire(ctx, "key", invalid)
+		require.ErrorIs(t, err, core.ErrInvalidMetadata)
+	})
+}
+
+func TestMemoryLockAdapter_Close(t *testing.T) {
+	ctx := context.Background()
+	adapter := memory.NewMemoryLockAdapter()
+	token, err := adapter.Acquire(ctx, "key", opts)
+	require.NoError(t, err)
+	require.Equal(t, core.StatusGreen, adapter.HealthCheck(ctx).Status)
+
+	require.NoError(t, adapter.Close(ctx))
+	require.NoError(t, adapter.Close(ctx), "closing twice is a no-op")
+
+	_, err = adapter.Acquire(ctx, "key", opts)
+	require.ErrorIs(t, err, core.ErrAdapterClosed)
+	require.ErrorIs(t, adapter.Release(ctx, token), core.ErrAdapterClosed)
+	_, err = adapter.Refresh(ctx, token, time.Second)
+	require.ErrorIs(t, err, core.ErrAdapterClosed)
+	_, _, err = adapter.IsHeld(ctx, token)
+	require.ErrorIs(t, err, core.ErrAdapterClosed)
+	require.Equal(t, core.StatusRed, adapter.HealthCheck(ctx).Status)
+}
+
+func TestOpen_Memory(t *testing.T) {
+	adapter, err := core.Open(context.Background(), "memory://")
+	require.NoError(t, err)
+	require.Equal(t, "memory", adapter.HealthCheck(context.Background()).Backend)
+}
@@ -0,0 +1,163 @@
+// Package otel traces the operations of a core.LockAdapter with
+// OpenTelemetry.
+//
+// It is a module of its own, so the OpenTelemetry dependency is only
+// pulled by the services importing it:
+//
+//	cfg := pg.NewPostgresLockerConfig().SetHooks(otel.Hooks(core.Hooks{}))
+//	adapter, err := pg.NewPostgresLockAdapter(pool, cfg)
+//	...
+//	traced := otel.Wrap(adapter)
+//
+// Wrap starts a span around Acquire, Release and Refresh, as a child of
+// the span in the ctx passed in. Hooks records the contention seen by
+// the adapter as events of the Acquire span.
+package otel
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope of the tracer
+const ScopeName = "github.com/oliveiracleidson/go-lockbox/otel"
+
+// Attribute keys set on the spans
+const (
+	KeyAttr      = attribute.Key("lock.key")
+	AttemptsAttr = attribute.Key("lock.attempts")
+	OutcomeAttr  = attribute.Key("lock.outcome")
+	LeaseIDAttr  = attribute.Key("lock.lease_id")
+)
+
+// Outcomes of the operations, the value of OutcomeAttr
+const (
+	OutcomeSuccess   = "success"
+	OutcomeContended = "contended"
+	OutcomeError     = "error"
+)
+
+// ContentionEvent is the name of the span events recorded by Hooks
+const ContentionEvent = "lock.contention"
+
+// Option configures Wrap
+type Option func(*Adapter)
+
+// WithTracerProvider sets where the tracer comes from, the global
+// provider by default
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(a *Adapter) {
+		a.tracer = provider.Tracer(ScopeName)
+	}
+}
+
+// Adapter is a core.LockAdapter tracing the operations of another one.
+// The operations without a span are passed through.
+type Adapter struct {
+	core.LockAdapter
+	tracer trace.Tracer
+}
+
+// Wrap traces the operations of adapter
+func Wrap(adapter core.LockAdapter, opts ...Option) *Adapter {
+	a := &Adapter{
+		LockAdapter: adapter,
+		tracer:      otel.GetTracerProvider().Tracer(ScopeName),
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+func (a *Adapter) Acquire(ctx context.Context, key string, opts core.LockOptions) (*core.LockToken, error) {
+	ctx, span := a.tracer.Start(ctx, "lock.acquire", trace.WithAttributes(KeyAttr.String(key)))
+	defer span.End()
+
+	// Counted by the OnContention of Hooks
+	contended := new(atomic.Int64)
+	ctx = context.WithValue(ctx, contentionKey{}, contended)
+
+	token, err := a.LockAdapter.Acquire(ctx, key, opts)
+
+	attempts := int(contended.Load()) + 1
+	if lockErr, ok := core.AsLockError(err); ok && lockErr.Attempts > 0 {
+		attempts = lockErr.Attempts
+	}
+	span.SetAttributes(AttemptsAttr.Int(attempts))
+	end(span, token, err)
+	return token, err
+}
+
+func (a *Adapter) Release(ctx context.Context, token *core.LockToken) error {
+	ctx, span := a.tracer.Start(ctx, "lock.release", trace.WithAttributes(tokenAttrs(token)...))
+	defer span.End()
+
+	err := a.LockAdapter.Release(ctx, token)
+	end(span, nil, err)
+	return err
+}
+
+func (a *Adapter) Refresh(ctx context.Context, token *core.LockToken, newTTL time.Duration) (*core.LockToken, error) {
+	ctx, span := a.tracer.Start(ctx, "lock.refresh", trace.WithAttributes(tokenAttrs(token)...))
+	defer span.End()
+
+	refreshed, err := a.LockAdapter.Refresh(ctx, token, newTTL)
+	end(span, refreshed, err)
+	return refreshed, err
+}
+
+// Hooks returns next with an OnContention also recording the contention
+// as an event of the span in the ctx
+func Hooks(next core.Hooks) core.Hooks {
+	onContention := next.OnContention
+	next.OnContention = func(ctx context.Context, key string, attempt int) {
+		if contended, ok := ctx.Value(contentionKey{}).(*atomic.Int64); ok {
+			contended.Add(1)
+		}
+		trace.SpanFromContext(ctx).AddEvent(ContentionEvent, trace.WithAttributes(
+			KeyAttr.String(key),
+			attribute.Int("lock.attempt", attempt),
+		))
+		if onContention != nil {
+			onContention(ctx, key, attempt)
+		}
+	}
+	return next
+}
+
+type contentionKey struct{}
+
+func tokenAttrs(token *core.LockToken) []attribute.KeyValue {
+	if token == nil {
+		return nil
+	}
+	return []attribute.KeyValue{KeyAttr.String(token.Key), LeaseIDAttr.String(token.LeaseID)}
+}
+
+// end records the outcome of the operation on the span
+func end(span trace.Span, token *core.LockToken, err error) {
+	if err == nil {
+		if token != nil {
+			span.SetAttributes(LeaseIDAttr.String(token.LeaseID))
+		}
+		span.SetAttributes(OutcomeAttr.String(OutcomeSuccess))
+		return
+	}
+
+	outcome := OutcomeError
+	if errors.Is(err, core.ErrLockContention) || errors.Is(err, core.ErrLockAcquisitionFailed) {
+		outcome = OutcomeContended
+	}
+	span.SetAttributes(OutcomeAttr.String(outcome))
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,125 @@
+package otel_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	lockotel "github.com/oliveiracleidson/go-lockbox/otel"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// stubAdapter reports contention on the first attempts, the way an
+// adapter does through its hooks
+type stubAdapter struct {
+	core.LockAdapter
+	hooks     core.Hooks
+	contended int
+}
+
+func (s *stubAdapter) Acquire(ctx context.Context, key string, opts core.LockOptions) (*core.LockToken, error) {
+	for attempt := 0; attempt < s.contended; attempt++ {
+		s.hooks.Contention(ctx, key, attempt)
+	}
+	if s.contended > opts.RetryStrategy.MaxRetries {
+		return nil, &core.LockError{Op: core.OpAcquire, Key: key, Attempts: s.contended, Err: core.ErrLockAcquisitionFailed}
+	}
+	return &core.LockToken{Key: key, LeaseID: "lease", ValidUntil: time.Now().Add(opts.TTL)}, nil
+}
+
+func (s *stubAdapter) Refresh(ctx context.Context, token *core.LockToken, newTTL time.Duration) (*core.LockToken, error) {
+	return nil, core.ErrRefreshTooLate
+}
+
+func (s *stubAdapter) Release(ctx context.Context, token *core.LockToken) error {
+	return nil
+}
+
+func attr(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestWrap(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := provider.Tracer("test")
+
+	opts := core.LockOptions{TTL: time.Second, RetryStrategy: core.RetryStrategy{MaxRetries: 2, BackoffFactor: 1}}
+
+	t.Run("given a contended key, when acquire, then the span records the attempts and contention events", func(t *testing.T) {
+		stub := &stubAdapter{hooks: lockotel.Hooks(core.Hooks{}), contended: 2}
+		traced := lockotel.Wrap(stub, lockotel.WithTracerProvider(provider))
+
+		ctx, parent := tracer.Start(context.Background(), "parent")
+		token, err := traced.Acquire(ctx, "key", opts)
+		parent.End()
+		require.NoError(t, err)
+
+		spans := recorder.Ended()
+		span := spans[len(spans)-2]
+		require.Equal(t, "lock.acquire", span.Name())
+		require.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+		require.Equal(t, "key", attr(span, lockotel.KeyAttr).AsString())
+		require.Equal(t, int64(3), attr(span, lockotel.AttemptsAttr).AsInt64())
+		require.Equal(t, lockotel.OutcomeSuccess, attr(span, lockotel.OutcomeAttr).AsString())
+		require.Equal(t, token.LeaseID, attr(span, lockotel.LeaseIDAttr).AsString())
+		require.Len(t, span.Events(), 2)
+		require.Equal(t, lockotel.ContentionEvent, span.Events()[0].Name)
+	})
+
+	t.Run("given retries are exhausted, when acquire, then the span is an error with the contended outcome", func(t *testing.T) {
+		stub := &stubAdapter{hooks: lockotel.Hooks(core.Hooks{}), contended: 5}
+		traced := lockotel.Wrap(stub, lockotel.WithTracerProvider(provider))
+
+		_, err := traced.Acquire(context.Background(), "key", opts)
+		require.ErrorIs(t, err, core.ErrLockAcquisitionFailed)
+
+		spans := recorder.Ended()
+		span := spans[len(spans)-1]
+		require.Equal(t, codes.Error, span.Status().Code)
+		require.Equal(t, int64(5), attr(span, lockotel.AttemptsAttr).AsInt64())
+		require.Equal(t, lockotel.OutcomeContended, attr(span, lockotel.OutcomeAttr).AsString())
+	})
+
+	t.Run("given a failing refresh, when refresh, then the error is recorded on the span", func(t *testing.T) {
+		traced := lockotel.Wrap(&stubAdapter{}, lockotel.WithTracerProvider(provider))
+
+		_, err := traced.Refresh(context.Background(), &core.LockToken{Key: "key", LeaseID: "lease"}, time.Second)
+		require.ErrorIs(t, err, core.ErrRefreshTooLate)
+
+		spans := recorder.Ended()
+		span := spans[len(spans)-1]
+		require.Equal(t, "lock.refresh", span.Name())
+		require.Equal(t, lockotel.OutcomeError, attr(span, lockotel.OutcomeAttr).AsString())
+		require.Equal(t, "lease", attr(span, lockotel.LeaseIDAttr).AsString())
+		require.NotEmpty(t, span.Events()) // The recorded error
+	})
+
+	t.Run("given a held lock, when release, then the span succeeds", func(t *testing.T) {
+		traced := lockotel.Wrap(&stubAdapter{}, lockotel.WithTracerProvider(provider))
+
+		require.NoError(t, traced.Release(context.Background(), &core.LockToken{Key: "key", LeaseID: "lease"}))
+
+		spans := recorder.Ended()
+		span := spans[len(spans)-1]
+		require.Equal(t, "lock.release", span.Name())
+		require.Equal(t, codes.Unset, span.Status().Code)
+	})
+
+	t.Run("given a user hook, when contention, then it still runs", func(t *testing.T) {
+		var calls int
+		hooks := lockotel.Hooks(core.Hooks{OnContention: func(ctx context.Context, key string, attempt int) { calls++ }})
+		hooks.Contention(context.Background(), "key", 0)
+		require.Equal(t, 1, calls)
+	})
+}
@@ -1,64 +1,365 @@
 package pg
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/oliveiracleidson/go-lockbox/core"
 )
 
-// i.pool = pgxpool.Pool
+// i.db = pgxpool.Pool, pgx.Conn or database/sql, see backend
+
+var (
+	tryAcquireLockSQL = `
+	SELECT result_acquired, result_valid_until, result_lease_id, result_nonce,
+		result_took_over, COALESCE(result_previous_lease_id, '')
+	FROM %s($1, $2, $3, $4, $5, $6);`
+
+	tryAcquireLockFIFOSQL = `
+	SELECT result_acquired, result_valid_until, result_lease_id, result_nonce,
+		result_took_over, COALESCE(result_previous_lease_id, '')
+	FROM %s($1, $2, $3, $4, $5, $6, $7);`
+
+	// Takes over the lease of a valid lock held by the same owner. The
+	// lease goes on, so it counts as a refresh.
+	confirmOwnedSQL = `
+	UPDATE %s
+	SET
+		valid_until = NOW() + ($3::BIGINT * INTERVAL '1 millisecond'),
+		server_nonce = $4,
+		metadata = COALESCE($5, metadata),
+		refresh_count = refresh_count + 1,
+		updated_at = NOW()
+	WHERE
+		key = $1 AND
+		owner_id = $2 AND
+		valid_until > NOW()
+	RETURNING lease_id, valid_until, server_nonce;`
+
+	holderSQL = `
+	SELECT COALESCE(owner_id, ''), valid_until, metadata
+	FROM %s
+	WHERE key = $1;`
+
+	dequeueSQL = `
+	DELETE FROM %s
+	WHERE key = $1 AND lease_id = $2;`
+)
 
 func (i *PostgresLockAdapter) Acquire(ctx context.Context, key string, opts core.LockOptions) (*core.LockToken, error) {
-	if err := core.ValidateKey(key); err != nil {
+	if err := i.begin(); err != nil {
+		return nil, err
+	}
+	defer i.end()
+
+	storageKey, err := i.Cfg.storageKey(key)
+	if err != nil {
 		return nil, err
 	}
-	if err := opts.Validate(); err != nil {
+	if err := opts.ValidateWithMaxTTL(i.Cfg.maxTTL()); err != nil {
 		return nil, err
 	}
+	if err := i.failFast(); err != nil {
+		return nil, &core.LockError{Op: core.OpAcquire, Key: key, Err: err}
+	}
+	i.stats.acquires.Add(1)
 
-	leaseID := uuid.NewString()
-	nonce := uuid.NewString()
-	metadata, err := json.Marshal(opts.Metadata)
+	leaseID := i.Cfg.newID()
+	nonce := i.Cfg.newID()
+	metadata, err := encodeMetadata(opts, i.Cfg.originalKey(key, storageKey))
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal metadata: %w", err)
+		return nil, err
 	}
 
-	var lockToken *core.LockToken
-
-	for attempt := 0; attempt <= opts.RetryStrategy.MaxRetries; attempt++ {
+	// tryAcquire runs a single attempt, returning a nil token on contention.
+	// Each attempt cancels its own timeout as soon as it finishes.
+	tryAcquire := func(attempt int) (*core.LockToken, error) {
 		txCtx, cancel := context.WithTimeout(ctx, opts.RequestTimeout)
 		defer cancel()
 
-		row := i.pool.QueryRow(txCtx,
-			fmt.Sprintf(`SELECT * FROM "%s".try_acquire_lock($1, $2, $3, $4, $5)`, i.Cfg.LockSchema),
-			key, leaseID, opts.TTL.Milliseconds(), nonce, metadata,
-		)
+		var row pgx.Row
+		start := time.Now()
+		if i.Cfg.FIFO {
+			// Keep our place in the queue until the next attempt, whatever
+			// its jitter
+			longest := opts.RetryStrategy
+			longest.JitterMode = core.JitterNone
+			wait := core.CalculateBackoff(longest, attempt) + opts.RequestTimeout
+			if opts.BackoffFunc != nil {
+				wait = opts.BackoffFunc(opts.RetryStrategy, attempt) + opts.RequestTimeout
+			}
+			row = i.db.QueryRow(txCtx,
+				i.sql.tryAcquireLockFIFO,
+				storageKey, leaseID, opts.TTL.Milliseconds(), nonce, metadata, opts.OwnerID, wait.Milliseconds(),
+			)
+		} else {
+			row = i.db.QueryRow(txCtx,
+				i.sql.tryAcquireLock,
+				storageKey, leaseID, opts.TTL.Milliseconds(), nonce, metadata, opts.OwnerID,
+			)
+		}
 
 		var acquired bool
-		var validUntil time.Time
-		err := row.Scan(&acquired, &validUntil)
-		if err == nil && acquired {
-			lockToken = &core.LockToken{
-				Key:         key,
-				LeaseID:     leaseID,
-				ValidUntil:  validUntil,
-				ServerNonce: nonce,
+		var validUntil *time.Time
+		var acquiredLeaseID, acquiredNonce *string
+		var tookOver bool
+		var previousLeaseID string
+		err := row.Scan(&acquired, &validUntil, &acquiredLeaseID, &acquiredNonce, &tookOver, &previousLeaseID)
+		i.observe(start, err)
+		if err != nil || !acquired {
+			return nil, err
+		}
+
+		return &core.LockToken{
+			Key:                 key,
+			LeaseID:             *acquiredLeaseID,
+			ValidUntil:          *validUntil,
+			ServerNonce:         *acquiredNonce,
+			OwnerID:             opts.OwnerID,
+			TTL:                 opts.TTL,
+			ClockOffset:         i.ClockDrift(),
+			TookOver:            tookOver,
+			PreviousLeaseID:     previousLeaseID,
+			RefreshSafetyMargin: opts.RefreshSafetyMargin,
+		}, nil
+	}
+
+	started := time.Now()
+	deadline, hasDeadline := opts.RetryStrategy.Deadline(ctx, started)
+	attempts := 0
+
+	var wake <-chan struct{}
+	waiting := false
+
+	// backoff waits before the next attempt, reporting false once the
+	// budget is exhausted
+	backoff := func(attempt int) bool {
+		delay := opts.Backoff(attempt)
+		if attempt == opts.RetryStrategy.MaxRetries {
+			return false
+		}
+		// The retry budget would be exhausted before the next attempt
+		if hasDeadline && time.Now().Add(delay).After(deadline) {
+			return false
+		}
+		// Without notifications wake is nil, keeping the timed retries
+		waitRelease(ctx, wake, delay)
+		return true
+	}
+	canceled := func(err error) error {
+		return &core.LockError{
+			Op:       core.OpAcquire,
+			Key:      key,
+			Attempts: attempts,
+			Elapsed:  time.Since(started),
+			Err:      fmt.Errorf("%w: %w", core.ErrOperationTimeout, err),
+		}
+	}
+
+	for attempt := 0; attempt <= opts.RetryStrategy.MaxRetries; attempt++ {
+		attempts++
+		lockToken, err := tryAcquire(attempt)
+		var entry auditEntry
+		switch {
+		case err == nil && lockToken != nil:
+			i.stats.held.Add(1)
+			entry = acquiredEntry(storageKey, lockToken, metadata)
+		case err == nil && opts.ConfirmIfOwned:
+			lockToken, err = i.confirmOwned(ctx, key, storageKey, metadata, opts)
+			if lockToken != nil && i.Cfg.FIFO {
+				i.dequeue(ctx, storageKey, leaseID)
+			}
+			if lockToken != nil {
+				// The lease goes on
+				entry = auditEntry{storageKey, lockToken.LeaseID, lockToken.OwnerID, AuditRefreshed, nil}
 			}
+		}
+		if err == nil && lockToken != nil {
+			i.stats.successes.Add(1)
+			i.Cfg.Hooks.Acquired(ctx, lockToken)
+			i.emit(ctx, core.EventAcquired, key, lockToken.LeaseID)
+			i.audit(ctx, entry)
 			return lockToken, nil
 		}
 
 		// Se o erro for relacionado a contenção de lock, tentamos novamente com backoff
-		if err == nil && !acquired {
-			time.Sleep(core.CalculateBackoff(opts.RetryStrategy, attempt))
+		if err == nil {
+			if !waiting {
+				waiting = true
+				defer i.addWaiter(key)()
+				if i.Cfg.FIFO {
+					defer i.dequeue(ctx, storageKey, leaseID)
+				}
+				if i.Cfg.NotifyOnRelease {
+					var unsubscribe func()
+					wake, unsubscribe = i.releases.subscribe(i.releaseChannel(storageKey))
+					defer unsubscribe()
+				}
+			}
+			i.stats.contentions.Add(1)
+			i.Cfg.Hooks.Contention(ctx, key, attempt)
+			i.emit(ctx, core.EventContentionHit, key, "")
+
+			if !backoff(attempt) {
+				break
+			}
+			// Cancellation aborts the backoff right away
+			if err := ctx.Err(); err != nil {
+				return nil, canceled(err)
+			}
+			continue
+		}
+
+		// A transient failure is retried like a contention, without
+		// counting as one
+		if IsTransient(err) && ctx.Err() == nil && backoff(attempt) {
+			i.stats.transientErrors.Add(1)
+			if err := ctx.Err(); err != nil {
+				return nil, canceled(err)
+			}
 			continue
 		}
 
-		return nil, fmt.Errorf("failed to acquire lock: %w", err)
+		return nil, &core.LockError{
+			Op:       core.OpAcquire,
+			Key:      key,
+			Attempts: attempt + 1,
+			Elapsed:  time.Since(started),
+			Err:      fmt.Errorf("failed to acquire lock: %w", err),
+		}
+	}
+
+	holder := i.holder(ctx, i.db, storageKey)
+	return nil, &core.LockError{
+		Op:               core.OpAcquire,
+		Key:              key,
+		Attempts:         attempts,
+		Elapsed:          time.Since(started),
+		LastHolderExpiry: holder.HeldUntil,
+		LastHolderID:     holder.HolderID,
+		Err:              holder,
+	}
+}
+
+// confirmOwned refreshes the lock of the key if it is held by opts.OwnerID,
+// returning a nil token otherwise
+func (i *PostgresLockAdapter) confirmOwned(
+	ctx context.Context,
+	key, storageKey string,
+	metadata []byte,
+	opts core.LockOptions,
+) (*core.LockToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, opts.RequestTimeout)
+	defer cancel()
+
+	token := &core.LockToken{
+		Key:                 key,
+		OwnerID:             opts.OwnerID,
+		TTL:                 opts.TTL,
+		ClockOffset:         i.ClockDrift(),
+		RefreshSafetyMargin: opts.RefreshSafetyMargin,
+	}
+	start := time.Now()
+	err := i.db.QueryRow(ctx,
+		i.sql.confirmOwned,
+		storageKey, opts.OwnerID, opts.TTL.Milliseconds(), i.Cfg.newID(), metadata,
+	).Scan(&token.LeaseID, &token.ValidUntil, &token.ServerNonce)
+	i.observe(start, err)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// dequeue removes an acquirer that gave up from the FIFO queue.
+//
+// It runs even if ctx is done; if it fails, the waiter expires anyway
+// and stops blocking the queue.
+func (i *PostgresLockAdapter) dequeue(ctx context.Context, storageKey, leaseID string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), core.DefaultRequestTimeout)
+	defer cancel()
+
+	_, _ = i.db.Exec(ctx,
+		i.sql.dequeue,
+		storageKey, leaseID,
+	)
+}
+
+// holder describes the current holder of the storage key,
+// leaving the fields it cannot read empty
+func (i *PostgresLockAdapter) holder(ctx context.Context, q querier, storageKey string) *core.ContentionError {
+	holder := &core.ContentionError{}
+	var metadata []byte
+	err := q.QueryRow(ctx,
+		i.sql.holder,
+		storageKey,
+	).Scan(&holder.HolderID, &holder.HeldUntil, &metadata)
+	if err != nil {
+		return &core.ContentionError{}
+	}
+
+	holder.HolderMetadata = core.DecodeMetadata(metadata)
+
+	return holder
+}
+
+// encodeMetadata encodes the metadata of opts as JSON, or nil (SQL NULL)
+// when there is none. MetadataJSON takes precedence over Metadata.
+//
+// A non empty originalKey, of a key stored under its hash, is added as
+// the OriginalKeyMetadata entry.
+func encodeMetadata(opts core.LockOptions, originalKey string) ([]byte, error) {
+	var encoded []byte
+	var err error
+	switch {
+	case len(opts.MetadataJSON) > 0:
+		if !isJSONObject(opts.MetadataJSON) {
+			return nil, core.ErrInvalidMetadata
+		}
+		encoded = opts.MetadataJSON
+		if originalKey != "" {
+			var entries map[string]json.RawMessage
+			if err := json.Unmarshal(encoded, &entries); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+			}
+			entries[OriginalKeyMetadata], _ = json.Marshal(originalKey)
+			encoded, err = json.Marshal(entries)
+		}
+	case len(opts.Metadata) > 0 || originalKey != "":
+		metadata := opts.Metadata
+		if originalKey != "" {
+			metadata = maps.Clone(opts.Metadata)
+			if metadata == nil {
+				metadata = map[string]string{}
+			}
+			metadata[OriginalKeyMetadata] = originalKey
+		}
+		encoded, err = json.Marshal(metadata)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	if len(encoded) > core.MaxMetadataSize {
+		return nil, fmt.Errorf("%w: %d bytes", core.ErrMetadataTooLarge, len(encoded))
 	}
 
-	return nil, core.ErrLockAcquisitionFailed
+	return encoded, nil
+}
+
+func isJSONObject(raw json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(raw)
+	return len(trimmed) > 0 && trimmed[0] == '{' && json.Valid(trimmed)
 }
@@ -0,0 +1,261 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+var (
+	// Same upsert as the TryAcquireLock function, for every key at once.
+	// The outer SELECT sees the table as it was before the INSERT, so it
+	// tells the lease taken over and the holder of the contended keys.
+	acquireBatchSQL = `
+	WITH input AS (
+		SELECT *
+		FROM unnest($1::TEXT[], $2::TEXT[], $3::TEXT[], $5::TEXT[])
+			WITH ORDINALITY AS t(key, lease_id, server_nonce, metadata, idx)
+	),
+	inserted AS (
+		INSERT INTO %[1]s AS l (
+			key, lease_id, valid_until, server_nonce, metadata, owner_id,
+			created_at, updated_at, acquired_at, refresh_count, ttl_ms
+		)
+		SELECT
+			i.key,
+			i.lease_id,
+			NOW() + ($4::BIGINT * INTERVAL '1 millisecond') + (10 * INTERVAL '1 millisecond'),
+			i.server_nonce,
+			i.metadata::JSONB,
+			$6::TEXT,
+			NOW(), NOW(), NOW(), 0, $4::BIGINT
+		FROM input i
+		-- Rows are locked in key order, so concurrent batches can't deadlock
+		ORDER BY i.key
+		ON CONFLICT (key) DO UPDATE SET
+			lease_id = EXCLUDED.lease_id,
+			valid_until = EXCLUDED.valid_until,
+			server_nonce = EXCLUDED.server_nonce,
+			metadata = EXCLUDED.metadata,
+			owner_id = EXCLUDED.owner_id,
+			created_at = NOW(),
+			updated_at = NOW(),
+			acquired_at = NOW(),
+			refresh_count = 0,
+			ttl_ms = EXCLUDED.ttl_ms
+		WHERE l.valid_until <= NOW()
+		RETURNING l.key, l.valid_until, l.xmax::TEXT <> '0' AS took_over
+	)
+	SELECT
+		i.idx,
+		n.valid_until,
+		COALESCE(n.took_over, FALSE),
+		CASE WHEN n.took_over THEN COALESCE(h.lease_id, '') ELSE '' END,
+		COALESCE(h.owner_id, ''),
+		h.valid_until,
+		h.metadata
+	FROM input i
+	LEFT JOIN inserted n ON n.key = i.key
+	LEFT JOIN %[1]s h ON h.key = i.key
+	ORDER BY i.idx;`
+)
+
+// AcquireBatch acquires whichever of keys are free in a single round
+// trip, e.g. the partitions a sharded consumer grabs on every tick.
+//
+// It is best effort, not all-or-nothing: the keys held by others are not
+// retried and the acquired ones stay acquired. Every lock gets its own
+// lease with the TTL, OwnerID and Metadata of opts; RetryStrategy,
+// ConfirmIfOwned and the FIFO queue don't apply. A key repeated in keys
+// is acquired once.
+//
+// Every key is either in acquired, in the order of keys, or in failed
+// with a *core.LockError wrapping:
+//
+// - *core.ContentionError (core.ErrLockContention): the key is held
+//
+// - the validation error of an invalid key
+//
+// - the error of the statement, failing every key. When reading its
+// result fails, the locks it acquired are released again rather than
+// left held, unreported, until they expire.
+func (i *PostgresLockAdapter) AcquireBatch(ctx context.Context, keys []string, opts core.LockOptions) (acquired []*core.LockToken, failed map[string]error) {
+	acquired = []*core.LockToken{}
+	failed = map[string]error{}
+	if len(keys) == 0 {
+		return acquired, failed
+	}
+
+	fail := func(key string, err error) {
+		failed[key] = &core.LockError{Op: core.OpAcquire, Key: key, Attempts: 1, Err: err}
+	}
+	failAll := func(err error) ([]*core.LockToken, map[string]error) {
+		for _, key := range keys {
+			fail(key, err)
+		}
+		return []*core.LockToken{}, failed
+	}
+
+	if err := i.begin(); err != nil {
+		return failAll(err)
+	}
+	defer i.end()
+
+	if err := opts.ValidateWithMaxTTL(i.Cfg.maxTTL()); err != nil {
+		return failAll(err)
+	}
+	if err := i.failFast(); err != nil {
+		return failAll(err)
+	}
+	metadata, err := encodeMetadata(opts, "")
+	if err != nil {
+		return failAll(err)
+	}
+
+	var userKeys, storageKeys, leaseIDs, nonces []string
+	var metadatas []*string // NULL without metadata
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		storageKey, err := i.Cfg.storageKey(key)
+		if err != nil {
+			fail(key, err)
+			continue
+		}
+		// Only the keys stored under their hash have their own metadata
+		keyMetadata := metadata
+		if originalKey := i.Cfg.originalKey(key, storageKey); originalKey != "" {
+			keyMetadata, err = encodeMetadata(opts, originalKey)
+			if err != nil {
+				fail(key, err)
+				continue
+			}
+		}
+		if keyMetadata != nil {
+			encoded := string(keyMetadata)
+			metadatas = append(metadatas, &encoded)
+		} else {
+			metadatas = append(metadatas, nil)
+		}
+		userKeys = append(userKeys, key)
+		storageKeys = append(storageKeys, storageKey)
+		leaseIDs = append(leaseIDs, i.Cfg.newID())
+		nonces = append(nonces, i.Cfg.newID())
+	}
+	if len(storageKeys) == 0 {
+		return acquired, failed
+	}
+	i.stats.acquires.Add(uint64(len(storageKeys)))
+
+	queryCtx, cancel := context.WithTimeout(ctx, opts.RequestTimeout)
+	defer cancel()
+
+	start := time.Now()
+	rows, err := i.db.Query(queryCtx,
+		i.sql.acquireBatch,
+		storageKeys, leaseIDs, nonces, opts.TTL.Milliseconds(), metadatas, opts.OwnerID,
+	)
+	if err != nil {
+		i.observe(start, err)
+		return failAll(err)
+	}
+	defer rows.Close()
+	defer func() { i.observe(start, rows.Err()) }()
+
+	// The statement acquired its locks whether or not its rows are read
+	releaseAll := func(err error) ([]*core.LockToken, map[string]error) {
+		rows.Close()
+		i.releaseLeases(ctx, storageKeys, leaseIDs, nonces)
+		return failAll(err)
+	}
+
+	var contended []string
+	var entries []auditEntry
+	for rows.Next() {
+		var idx int
+		var validUntil, heldUntil *time.Time
+		var tookOver bool
+		var previousLeaseID, holderID string
+		var holderMetadata []byte
+		if err := rows.Scan(&idx, &validUntil, &tookOver, &previousLeaseID, &holderID, &heldUntil, &holderMetadata); err != nil {
+			return releaseAll(err)
+		}
+
+		// WITH ORDINALITY starts at 1
+		key := userKeys[idx-1]
+		if validUntil == nil {
+			holder := &core.ContentionError{HolderID: holderID}
+			if heldUntil != nil {
+				holder.HeldUntil = *heldUntil
+			}
+			holder.HolderMetadata = core.DecodeMetadata(holderMetadata)
+			failed[key] = &core.LockError{
+				Op:               core.OpAcquire,
+				Key:              key,
+				Attempts:         1,
+				LastHolderExpiry: holder.HeldUntil,
+				LastHolderID:     holder.HolderID,
+				Err:              holder,
+			}
+			contended = append(contended, key)
+			continue
+		}
+
+		token := &core.LockToken{
+			Key:                 key,
+			LeaseID:             leaseIDs[idx-1],
+			ValidUntil:          *validUntil,
+			ServerNonce:         nonces[idx-1],
+			OwnerID:             opts.OwnerID,
+			TTL:                 opts.TTL,
+			ClockOffset:         i.ClockDrift(),
+			TookOver:            tookOver,
+			PreviousLeaseID:     previousLeaseID,
+			RefreshSafetyMargin: opts.RefreshSafetyMargin,
+		}
+		acquired = append(acquired, token)
+
+		var tokenMetadata []byte
+		if m := metadatas[idx-1]; m != nil {
+			tokenMetadata = []byte(*m)
+		}
+		entries = append(entries, acquiredEntry(storageKeys[idx-1], token, tokenMetadata))
+	}
+	if err := rows.Err(); err != nil {
+		return releaseAll(err)
+	}
+
+	for _, token := range acquired {
+		i.stats.held.Add(1)
+		i.stats.successes.Add(1)
+		i.Cfg.Hooks.Acquired(ctx, token)
+		i.emit(ctx, core.EventAcquired, token.Key, token.LeaseID)
+	}
+	for _, key := range contended {
+		i.stats.contentions.Add(1)
+		i.Cfg.Hooks.Contention(ctx, key, 0)
+		i.emit(ctx, core.EventContentionHit, key, "")
+	}
+	i.audit(ctx, entries...)
+
+	return acquired, failed
+}
+
+// releaseLeases releases the locks acquired with the leases and nonces,
+// ignoring the keys acquired by nobody.
+//
+// It runs even if ctx is done; if it fails, the locks expire anyway.
+func (i *PostgresLockAdapter) releaseLeases(ctx context.Context, storageKeys, leaseIDs, nonces []string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), core.DefaultRequestTimeout)
+	defer cancel()
+
+	_, _ = i.db.Exec(ctx,
+		i.sql.releaseMany,
+		storageKeys, leaseIDs, nonces,
+	)
+}
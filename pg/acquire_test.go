@@ -0,0 +1,89 @@
+package pg_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/oliveiracleidson/go-lockbox/pg"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeMetadata(t *testing.T) {
+	t.Run("given both metadata fields, when encode, then MetadataJSON is stored verbatim", func(t *testing.T) {
+		raw := json.RawMessage(`{"attempt": 3, "job": {"id": "42"}}`)
+		encoded, err := pg.EncodeMetadata(core.LockOptions{
+			Metadata:     map[string]string{"ignored": "yes"},
+			MetadataJSON: raw,
+		}, "")
+		require.NoError(t, err)
+		require.Equal(t, []byte(raw), encoded)
+	})
+
+	t.Run("given a hashed key, when encode, then the original key is added to the metadata", func(t *testing.T) {
+		metadata := map[string]string{"job": "42"}
+		encoded, err := pg.EncodeMetadata(core.LockOptions{Metadata: metadata}, "https://example.com/a")
+		require.NoError(t, err)
+		require.JSONEq(t, `{"job": "42", "lockbox_original_key": "https://example.com/a"}`, string(encoded))
+		require.Len(t, metadata, 1, "the metadata of the caller is left untouched")
+
+		encoded, err = pg.EncodeMetadata(core.LockOptions{MetadataJSON: json.RawMessage(`{"n": 1}`)}, "https://example.com/a")
+		require.NoError(t, err)
+		require.JSONEq(t, `{"n": 1, "lockbox_original_key": "https://example.com/a"}`, string(encoded))
+
+		encoded, err = pg.EncodeMetadata(core.LockOptions{}, "https://example.com/a")
+		require.NoError(t, err)
+		require.JSONEq(t, `{"lockbox_original_key": "https://example.com/a"}`, string(encoded))
+	})
+
+	t.Run("given only the string map, when encode, then it is marshalled", func(t *testing.T) {
+		encoded, err := pg.EncodeMetadata(core.LockOptions{Metadata: map[string]string{"job": "42"}}, "")
+		require.NoError(t, err)
+		require.JSONEq(t, `{"job": "42"}`, string(encoded))
+	})
+
+	t.Run("given no metadata, when encode, then it is NULL without allocating", func(t *testing.T) {
+		for _, opts := range []core.LockOptions{{}, {Metadata: map[string]string{}}} {
+			encoded, err := pg.EncodeMetadata(opts, "")
+			require.NoError(t, err)
+			require.Nil(t, encoded)
+
+			allocs := testing.AllocsPerRun(100, func() { _, _ = pg.EncodeMetadata(opts, "") })
+			require.Zero(t, allocs)
+		}
+	})
+
+	t.Run("given JSON that is not an object, when encode, then ErrInvalidMetadata", func(t *testing.T) {
+		for _, raw := range []string{`[1, 2]`, `"job"`, `{"job":`, `null`} {
+			_, err := pg.EncodeMetadata(core.LockOptions{MetadataJSON: json.RawMessage(raw)}, "")
+			require.ErrorIs(t, err, core.ErrInvalidMetadata, raw)
+		}
+	})
+
+	t.Run("given a huge MetadataJSON, when encode, then ErrMetadataTooLarge", func(t *testing.T) {
+		raw := `{"blob": "` + strings.Repeat("x", core.MaxMetadataSize) + `"}`
+		_, err := pg.EncodeMetadata(core.LockOptions{MetadataJSON: json.RawMessage(raw)}, "")
+		require.ErrorIs(t, err, core.ErrMetadataTooLarge)
+	})
+}
+
+func BenchmarkEncodeMetadata(b *testing.B) {
+	for _, bc := range []struct {
+		name string
+		opts core.LockOptions
+	}{
+		{"nil", core.LockOptions{}},
+		{"map", core.LockOptions{Metadata: map[string]string{"job": "42"}}},
+		{"json", core.LockOptions{MetadataJSON: json.RawMessage(`{"job": 42}`)}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				if _, err := pg.EncodeMetadata(bc.opts, ""); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+// AcquireTx acquires the lock inside the caller transaction, tying the
+// lock row to the fate of the business work done in it.
+//
+// Unlike Acquire, the lock is transaction-scoped until the transaction
+// ends:
+//
+// - Rolling back the transaction removes the lock, no Release needed
+//
+// - Until the commit, other acquirers of the key block on the
+// uncommitted row instead of seeing it held, for up to their RequestTimeout
+//
+// - After the commit it is a regular TTL-scoped lock, counting from the
+// acquisition, to be released or refreshed with the returned token
+//
+// A single attempt is made, RetryStrategy is ignored: a key held by
+// another lease fails right away with a *core.LockError wrapping a
+// *core.ContentionError, leaving the transaction usable. FIFO and
+// NotifyOnRelease do not apply. Any other error aborts the transaction,
+// as every failed statement does in Postgres.
+func (i *PostgresLockAdapter) AcquireTx(ctx context.Context, tx pgx.Tx, key string, opts core.LockOptions) (*core.LockToken, error) {
+	if err := i.begin(); err != nil {
+		return nil, err
+	}
+	defer i.end()
+
+	storageKey, err := i.Cfg.storageKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.ValidateWithMaxTTL(i.Cfg.maxTTL()); err != nil {
+		return nil, err
+	}
+	i.stats.acquires.Add(1)
+
+	metadata, err := encodeMetadata(opts, i.Cfg.originalKey(key, storageKey))
+	if err != nil {
+		return nil, err
+	}
+
+	txCtx, cancel := context.WithTimeout(ctx, opts.RequestTimeout)
+	defer cancel()
+
+	var acquired bool
+	var validUntil *time.Time
+	var leaseID, nonce *string
+	var tookOver bool
+	var previousLeaseID string
+	start := time.Now()
+	q := i.onTx(tx)
+	err = q.QueryRow(txCtx,
+		i.sql.tryAcquireLock,
+		storageKey, i.Cfg.newID(), opts.TTL.Milliseconds(), i.Cfg.newID(), metadata, opts.OwnerID,
+	).Scan(&acquired, &validUntil, &leaseID, &nonce, &tookOver, &previousLeaseID)
+	i.observe(start, err)
+	if err != nil {
+		return nil, &core.LockError{
+			Op:       core.OpAcquire,
+			Key:      key,
+			Attempts: 1,
+			Err:      fmt.Errorf("failed to acquire lock: %w", err),
+		}
+	}
+
+	if !acquired {
+		i.stats.contentions.Add(1)
+		i.Cfg.Hooks.Contention(ctx, key, 0)
+		i.emit(ctx, core.EventContentionHit, key, "")
+
+		holder := i.holder(txCtx, q, storageKey)
+		return nil, &core.LockError{
+			Op:               core.OpAcquire,
+			Key:              key,
+			Attempts:         1,
+			LastHolderExpiry: holder.HeldUntil,
+			LastHolderID:     holder.HolderID,
+			Err:              holder,
+		}
+	}
+
+	token := &core.LockToken{
+		Key:                 key,
+		LeaseID:             *leaseID,
+		ValidUntil:          *validUntil,
+		ServerNonce:         *nonce,
+		OwnerID:             opts.OwnerID,
+		TTL:                 opts.TTL,
+		ClockOffset:         i.ClockDrift(),
+		TookOver:            tookOver,
+		PreviousLeaseID:     previousLeaseID,
+		RefreshSafetyMargin: opts.RefreshSafetyMargin,
+	}
+	i.stats.held.Add(1)
+	i.stats.successes.Add(1)
+	i.Cfg.Hooks.Acquired(ctx, token)
+	i.emit(ctx, core.EventAcquired, key, token.LeaseID)
+	i.auditTx(ctx, tx, acquiredEntry(storageKey, token, metadata))
+	return token, nil
+}
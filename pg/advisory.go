@@ -0,0 +1,42 @@
+package pg
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// NewAdvisoryLockAdapter creates an adapter backed only by Postgres
+// advisory locks, for databases where running DDL is not an option: it
+// needs no lock table and no migrations.
+//
+// Each key is locked with pg_try_advisory_lock on a 64-bit hash of the
+// lock table name and the key, held on a dedicated pool connection. The
+// TTL is emulated by a client-side watchdog unlocking the key when it
+// elapses, and Refresh extends it, rotating the ServerNonce like the
+// table based adapter does.
+//
+// The semantics differ from PostgresLockAdapter:
+//
+// - Nothing persists: a restart of the process or the database, or the
+// loss of the connection, releases the lock immediately
+//
+// - The TTL is only enforced by this process: a process that hangs while
+// its connection stays open keeps the lock past the TTL
+//
+// - Two different keys may hash to the same 64-bit value and contend
+// with each other, which is unlikely but not impossible
+//
+// - Every held lock pins a pool connection
+//
+// - Locks are invisible to GetLockInfo, ListLocks and the other table
+// based inspection methods
+//
+// Only the identifiers, Namespace, MaxAllowedTTL and Hooks of the
+// configuration apply.
+func NewAdvisoryLockAdapter(pool *pgxpool.Pool, cfg *PostgresLockerConfig) (*PostgresSessionLockAdapter, error) {
+	adapter, err := NewPostgresSessionLockAdapter(pool, cfg)
+	if err != nil {
+		return nil, err
+	}
+	adapter.expireByTTL = true
+	return adapter, nil
+}
@@ -0,0 +1,88 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+var assertHeldSQL = `
+	SELECT
+		lease_id = $2 AND server_nonce = $3 AS owned,
+		COALESCE(owner_id, ''),
+		EXTRACT(EPOCH FROM (valid_until - NOW())) AS remaining_ttl
+	FROM %s
+	WHERE key = $1 AND valid_until > NOW();`
+
+// AssertHeld checks in one statement that the token still owns its lock,
+// for middleware guarding a write with it:
+//
+//	if err := adapter.AssertHeld(r.Context(), token); err != nil {
+//		http.Error(w, err.Error(), http.StatusConflict)
+//		return
+//	}
+//
+// Returns nil while held, or a *core.LockError wrapping
+//   - core.ErrLockNotFound when the lock expired or was released,
+//   - core.ErrLockOwnershipMismatch when another lease holds the key,
+//   - core.ErrLockExpiring when the remaining TTL is within the refresh
+//     safety margin of the TTL of the token (RefreshSafetyMargin), too
+//     short to be trusted across hosts; refresh the lock before writing,
+//   - core.ErrOperationTimeout when the statement outlasts
+//     DefaultRequestTimeout.
+func (i *PostgresLockAdapter) AssertHeld(ctx context.Context, token *core.LockToken) error {
+	if err := i.begin(); err != nil {
+		return err
+	}
+	defer i.end()
+
+	storageKey, err := i.Cfg.storageKey(token.Key)
+	if err != nil {
+		return err
+	}
+
+	queryCtx, cancel := i.withRequestTimeout(ctx)
+	defer cancel()
+
+	var owned bool
+	var holderID string
+	var remainingTTL float64
+
+	start := time.Now()
+	err = i.db.QueryRow(queryCtx,
+		i.sql.assertHeld,
+		storageKey, token.LeaseID, token.ServerNonce,
+	).Scan(&owned, &holderID, &remainingTTL)
+	i.observe(start, err)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &core.LockError{Op: core.OpAssertHeld, Key: token.Key, Attempts: 1, Err: core.ErrLockNotFound}
+	}
+	if err != nil {
+		err = timedOut(ctx, queryCtx, err)
+		return &core.LockError{Op: core.OpAssertHeld, Key: token.Key, Attempts: 1, Err: err}
+	}
+
+	if !owned {
+		return &core.LockError{
+			Op:           core.OpAssertHeld,
+			Key:          token.Key,
+			Attempts:     1,
+			LastHolderID: holderID,
+			Err:          core.ErrLockOwnershipMismatch,
+		}
+	}
+	remaining := time.Duration(remainingTTL * float64(time.Second))
+	if remaining <= time.Duration(float64(token.TTL)*i.refreshSafetyMargin(token)) {
+		return &core.LockError{
+			Op:       core.OpAssertHeld,
+			Key:      token.Key,
+			Attempts: 1,
+			Err:      fmt.Errorf("%w: %v left", core.ErrLockExpiring, remaining.Round(time.Millisecond)),
+		}
+	}
+	return nil
+}
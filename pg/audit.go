@@ -0,0 +1,221 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+// AuditEvent is the step of the lock lifecycle recorded by a row of the
+// audit log, see AuditTableName
+type AuditEvent string
+
+const (
+	AuditAcquired      AuditEvent = "acquired"       // The key was free
+	AuditTakenOver     AuditEvent = "taken_over"     // The lock of a holder whose TTL lapsed was overwritten
+	AuditRefreshed     AuditEvent = "refreshed"      // The holder extended the lock
+	AuditTransferred   AuditEvent = "transferred"    // The holder handed the lock over, OwnerID is the new owner
+	AuditReleased      AuditEvent = "released"       // The holder released the lock
+	AuditForceReleased AuditEvent = "force_released" // Removed without its token, by ReleaseAllByOwner or CleanupExpired
+)
+
+// AuditRecord is a row of the audit log
+type AuditRecord struct {
+	ID       int64
+	Key      string
+	LeaseID  string
+	OwnerID  string
+	Event    AuditEvent
+	Metadata json.RawMessage // Metadata of the lock when the event happened, nil without
+	At       time.Time
+}
+
+var (
+	// Without metadata of its own, an entry takes the one of the lock
+	// row, when the lease still has it
+	auditSQL = `
+	INSERT INTO %[1]s (key, lease_id, owner_id, event, metadata)
+	SELECT a.key, a.lease_id, NULLIF(a.owner_id, ''), a.event, COALESCE(a.metadata::JSONB, l.metadata)
+	FROM unnest($1::TEXT[], $2::TEXT[], $3::TEXT[], $4::TEXT[], $5::TEXT[])
+		WITH ORDINALITY AS a(key, lease_id, owner_id, event, metadata, idx)
+	LEFT JOIN %[2]s l ON l.key = a.key AND l.lease_id = a.lease_id
+	ORDER BY a.idx;`
+
+	queryAuditSQL = `
+	SELECT id, lease_id, COALESCE(owner_id, ''), event, NULLIF(metadata, 'null'::JSONB), at
+	FROM %s
+	WHERE key = $1 AND at >= $2 AND at < $3
+	ORDER BY at, id;`
+
+	cleanupAuditSQL = `
+	DELETE FROM %[1]s
+	WHERE id IN (
+		SELECT id
+		FROM %[1]s
+		WHERE at < NOW() - ($1::BIGINT * INTERVAL '1 millisecond')
+		LIMIT $2
+	);`
+)
+
+// auditEntry is a row to append to the audit log
+type auditEntry struct {
+	storageKey string
+	leaseID    string
+	ownerID    string
+	event      AuditEvent
+	metadata   []byte // nil for the metadata of the lock row
+}
+
+// acquiredEntry is the audit entry of an acquisition
+func acquiredEntry(storageKey string, token *core.LockToken, metadata []byte) auditEntry {
+	event := AuditAcquired
+	if token.TookOver {
+		event = AuditTakenOver
+	}
+	return auditEntry{storageKey, token.LeaseID, token.OwnerID, event, metadata}
+}
+
+// audit appends the entries to the audit log, when enabled.
+//
+// It runs after the operation, even if ctx is done, for up to
+// DefaultRequestTimeout. A failure is reported to Hooks.OnAuditFailed
+// and never fails the operation.
+func (i *PostgresLockAdapter) audit(ctx context.Context, entries ...auditEntry) {
+	if i.Cfg.AuditTableName == "" || len(entries) == 0 {
+		return
+	}
+	auditCtx, cancel := i.withRequestTimeout(context.WithoutCancel(ctx))
+	defer cancel()
+
+	if err := i.insertAudit(auditCtx, i.db, entries); err != nil {
+		i.Cfg.Hooks.AuditFailed(ctx, err)
+	}
+}
+
+// auditTx appends the entries in the caller transaction, so they share
+// its fate. The insert runs in a savepoint: its failure leaves the
+// transaction usable.
+func (i *PostgresLockAdapter) auditTx(ctx context.Context, tx pgx.Tx, entries ...auditEntry) {
+	if i.Cfg.AuditTableName == "" || len(entries) == 0 {
+		return
+	}
+
+	err := func() error {
+		savepoint, err := tx.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		defer savepoint.Rollback(context.WithoutCancel(ctx))
+
+		if err := i.insertAudit(ctx, i.onTx(savepoint), entries); err != nil {
+			return err
+		}
+		return savepoint.Commit(ctx)
+	}()
+	if err != nil {
+		i.Cfg.Hooks.AuditFailed(ctx, err)
+	}
+}
+
+func (i *PostgresLockAdapter) insertAudit(ctx context.Context, q querier, entries []auditEntry) error {
+	keys := make([]string, len(entries))
+	leaseIDs := make([]string, len(entries))
+	ownerIDs := make([]string, len(entries))
+	events := make([]string, len(entries))
+	metadatas := make([]*string, len(entries)) // NULL for the metadata of the lock row
+	for idx, entry := range entries {
+		keys[idx] = entry.storageKey
+		leaseIDs[idx] = entry.leaseID
+		ownerIDs[idx] = entry.ownerID
+		events[idx] = string(entry.event)
+		if entry.metadata != nil {
+			encoded := string(entry.metadata)
+			metadatas[idx] = &encoded
+		}
+	}
+
+	_, err := q.Exec(ctx,
+		i.sql.audit,
+		keys, leaseIDs, ownerIDs, events, metadatas,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record the audit log: %w", err)
+	}
+	return nil
+}
+
+// QueryAudit returns the audit log of a key between from (inclusive) and
+// to (exclusive), oldest first, e.g. to tell who held the deploy lock
+// last Tuesday afternoon. Fails with ErrAuditDisabled without
+// AuditTableName.
+func (i *PostgresLockAdapter) QueryAudit(ctx context.Context, key string, from, to time.Time) ([]AuditRecord, error) {
+	if err := i.begin(); err != nil {
+		return nil, err
+	}
+	defer i.end()
+
+	if i.Cfg.AuditTableName == "" {
+		return nil, ErrAuditDisabled
+	}
+	storageKey, err := i.Cfg.storageKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := i.db.Query(ctx,
+		i.sql.queryAudit,
+		storageKey, from, to,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (AuditRecord, error) {
+		record := AuditRecord{Key: key}
+		var metadata []byte
+		err := row.Scan(&record.ID, &record.LeaseID, &record.OwnerID, &record.Event, &metadata, &record.At)
+		if metadata != nil {
+			record.Metadata = metadata
+		}
+		return record, err
+	})
+}
+
+// CleanupAudit deletes the rows of the audit log older than retention,
+// batchSize at a time, and returns how many were removed. Fails with
+// ErrAuditDisabled without AuditTableName.
+func (i *PostgresLockAdapter) CleanupAudit(ctx context.Context, retention time.Duration, batchSize int) (int64, error) {
+	if err := i.begin(); err != nil {
+		return 0, err
+	}
+	defer i.end()
+
+	if i.Cfg.AuditTableName == "" {
+		return 0, ErrAuditDisabled
+	}
+	if retention < 0 {
+		return 0, errors.New("retention must be ≥ 0")
+	}
+	if batchSize <= 0 {
+		return 0, errors.New("batchSize must be > 0")
+	}
+
+	var removed int64
+	for {
+		tag, err := i.db.Exec(ctx,
+			i.sql.cleanupAudit,
+			retention.Milliseconds(), batchSize,
+		)
+		if err != nil {
+			return removed, fmt.Errorf("failed to clean up the audit log: %w", err)
+		}
+		removed += tag.RowsAffected()
+		if tag.RowsAffected() < int64(batchSize) {
+			return removed, nil
+		}
+	}
+}
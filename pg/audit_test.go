@@ -0,0 +1,32 @@
+package pg_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/oliveiracleidson/go-lockbox/pg"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresLockAdapter_Audit_Disabled(t *testing.T) {
+	// Nothing reaches the database without the audit log
+	pool, err := pgxpool.New(context.Background(), "postgres://lockbox@127.0.0.1:1/lockbox?connect_timeout=1")
+	require.NoError(t, err)
+	defer pool.Close()
+
+	adapter, err := pg.NewPostgresLockAdapter(pool, pg.NewPostgresLockerConfig())
+	require.NoError(t, err)
+
+	t.Run("given no audit table, when query audit, then returns ErrAuditDisabled", func(t *testing.T) {
+		_, err := adapter.QueryAudit(context.Background(), "key", time.Now().Add(-time.Hour), time.Now())
+		require.ErrorIs(t, err, pg.ErrAuditDisabled)
+	})
+
+	t.Run("given no audit table, when cleanup audit, then returns ErrAuditDisabled", func(t *testing.T) {
+		removed, err := adapter.CleanupAudit(context.Background(), 24*time.Hour, 100)
+		require.ErrorIs(t, err, pg.ErrAuditDisabled)
+		require.Zero(t, removed)
+	})
+}
@@ -0,0 +1,214 @@
+package pg_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/oliveiracleidson/go-lockbox/pg"
+)
+
+// Benchmarks run after the playbook, which migrates the database:
+//
+//	DB_URL=... go test -bench . ./pg
+
+const benchmarkLocks = 200
+
+func acquireBenchmarkLocks(b *testing.B, prefix string) []*core.LockToken {
+	b.Helper()
+
+	tokens := make([]*core.LockToken, 0, benchmarkLocks)
+	for n := 0; n < benchmarkLocks; n++ {
+		token, err := adapter.Acquire(context.Background(), fmt.Sprintf("%s-%d", prefix, n), core.LockOptions{
+			TTL:           time.Minute,
+			RetryStrategy: core.RetryStrategy{BackoffFactor: 1},
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+		tokens = append(tokens, token)
+	}
+
+	b.Cleanup(func() {
+		for _, token := range tokens {
+			_ = adapter.Release(context.Background(), token)
+		}
+	})
+
+	return tokens
+}
+
+func BenchmarkRefresh_Sequential(b *testing.B) {
+	tokens := acquireBenchmarkLocks(b, "bench-refresh")
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for idx, token := range tokens {
+			refreshed, err := adapter.Refresh(context.Background(), token, time.Minute)
+			if err != nil {
+				b.Fatal(err)
+			}
+			tokens[idx] = refreshed
+		}
+	}
+}
+
+func BenchmarkRefreshBatch(b *testing.B) {
+	tokens := acquireBenchmarkLocks(b, "bench-refresh-batch")
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		refreshed, errs := adapter.RefreshBatch(context.Background(), tokens, time.Minute)
+		for idx, err := range errs {
+			if err != nil {
+				b.Fatal(err)
+			}
+			tokens[idx] = refreshed[idx]
+		}
+	}
+}
+
+// The partitions a sharded consumer grabs on every tick, see AcquireBatch
+const benchmarkPartitions = 100
+
+func benchmarkPartitionKeys(prefix string) []string {
+	keys := make([]string, benchmarkPartitions)
+	for n := range keys {
+		keys[n] = fmt.Sprintf("%s-%d", prefix, n)
+	}
+	return keys
+}
+
+func BenchmarkAcquire_Partitions(b *testing.B) {
+	keys := benchmarkPartitionKeys("bench-acquire-loop")
+	opts := core.LockOptions{TTL: time.Minute, RetryStrategy: core.NoRetry()}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		tokens := make([]*core.LockToken, 0, len(keys))
+		for _, key := range keys {
+			token, err := adapter.Acquire(context.Background(), key, opts)
+			if err != nil {
+				b.Fatal(err)
+			}
+			tokens = append(tokens, token)
+		}
+		adapter.ReleaseMany(context.Background(), tokens)
+	}
+}
+
+func BenchmarkAcquireBatch_Partitions(b *testing.B) {
+	keys := benchmarkPartitionKeys("bench-acquire-batch")
+	opts := core.LockOptions{TTL: time.Minute, RetryStrategy: core.NoRetry()}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		tokens, failed := adapter.AcquireBatch(context.Background(), keys, opts)
+		if len(failed) > 0 {
+			b.Fatal(failed)
+		}
+		adapter.ReleaseMany(context.Background(), tokens)
+	}
+}
+
+// BenchmarkAcquire_Handoff measures how long a contended Acquire takes to
+// get the lock once its holder releases it, with the waiter sleeping a
+// 100ms backoff between attempts. Without NotifyOnRelease the handoff
+// averages half the backoff, with it a round trip to the database.
+func BenchmarkAcquire_Handoff(b *testing.B) {
+	for _, notify := range []bool{false, true} {
+		b.Run(fmt.Sprintf("notify=%v", notify), func(b *testing.B) {
+			contended := make(chan struct{}, 1)
+			cfg := pg.NewPostgresLockerConfig().SetNotifyOnRelease(notify)
+			cfg.Hooks.OnContention = func(ctx context.Context, key string, attempt int) {
+				select {
+				case contended <- struct{}{}:
+				default:
+				}
+			}
+			a, err := pg.NewPostgresLockAdapter(pgxPool, cfg)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			opts := core.LockOptions{
+				TTL: time.Minute,
+				RetryStrategy: core.RetryStrategy{
+					MaxRetries:    100,
+					BaseDelay:     100 * time.Millisecond,
+					MaxDelay:      100 * time.Millisecond,
+					BackoffFactor: 1,
+				},
+			}
+			key := fmt.Sprintf("bench-handoff-%v", notify)
+
+			type result struct {
+				at    time.Time
+				token *core.LockToken
+				err   error
+			}
+
+			var handoff time.Duration
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				holder, err := a.Acquire(context.Background(), key, opts)
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				acquired := make(chan result, 1)
+				go func() {
+					token, err := a.Acquire(context.Background(), key, opts)
+					acquired <- result{at: time.Now(), token: token, err: err}
+				}()
+				<-contended
+
+				released := time.Now()
+				if err := a.Release(context.Background(), holder); err != nil {
+					b.Fatal(err)
+				}
+				waiter := <-acquired
+				if waiter.err != nil {
+					b.Fatal(waiter.err)
+				}
+				handoff += waiter.at.Sub(released)
+
+				if err := a.Release(context.Background(), waiter.token); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(handoff.Microseconds())/float64(b.N), "handoff-µs/op")
+		})
+	}
+}
+
+// BenchmarkAcquireRelease measures the lock rate and allocations of the
+// uncontended hot path, an Acquire, an IsHeld, a Refresh and a Release
+// per operation
+func BenchmarkAcquireRelease(b *testing.B) {
+	opts := core.LockOptions{
+		TTL:           time.Minute,
+		RetryStrategy: core.NoRetry(),
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		token, err := adapter.Acquire(context.Background(), "bench-acquire-release", opts)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, _, err := adapter.IsHeld(context.Background(), token); err != nil {
+			b.Fatal(err)
+		}
+		if token, err = adapter.Refresh(context.Background(), token, time.Minute); err != nil {
+			b.Fatal(err)
+		}
+		if err := adapter.Release(context.Background(), token); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "ops/s")
+}
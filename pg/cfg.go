@@ -1,16 +1,233 @@
 package pg
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"strings"
+	"regexp"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+// HealthCheck defaults
+const (
+	DefaultPoolHighWaterMark = 0.8
+	DefaultLatencyThreshold  = 500 * time.Millisecond
+
+	// Fraction of failed recent operations
+	DefaultErrorRateThreshold = 0.05
+
+	// Interval of the probes run by StartHealthMonitor
+	DefaultHealthCheckInterval = 10 * time.Second
+
+	// The margin kept by refreshes, for the default TTL
+	DefaultClockDriftThreshold = time.Duration(core.MaxClockDriftMargin * float64(core.DefaultLockTTL))
 )
 
+// Rows deleted per statement by the expired lock sweeper
+const DefaultSweepBatchSize = 1000
+
+// Metadata entry keeping the original key of a lock stored under its
+// hash, see HashLongKeys
+const OriginalKeyMetadata = "lockbox_original_key"
+
+// Postgres truncates identifiers longer than NAMEDATALEN-1 bytes
+const maxIdentifierLength = 63
+
+// The indexes of the audit table are named after it
+const maxAuditTableNameLength = maxIdentifierLength - len("_key_at_idx")
+
+var validIdentifierRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
 type PostgresLockerConfig struct {
 	MigrationSchema          string
 	MigrationTableName       string
 	LockSchema               string
 	LockTableName            string
 	CreateSchemasIfNotExists bool
+	Hooks                    core.Hooks
+
+	// Namespace transparently prefixed to every key, so adapters sharing
+	// the same lock table with different namespaces never collide.
+	// Segments are separated by core.KeySeparator, e.g. "team-a:orders".
+	Namespace string
+
+	// HashLongKeys makes the keys failing core.ValidateKey, e.g. longer
+	// than core.MaxKeyLength or derived from URLs, be stored under their
+	// core.HashKey instead of being rejected. The original key is kept in
+	// the OriginalKeyMetadata entry of the metadata, so tokens and
+	// GetLockInfo and ListLocks still report it. Valid keys are stored as
+	// is. See core.HashKey for the collisions.
+	HashLongKeys bool
+
+	// MaxAllowedTTL raises (or lowers) the TTL ceiling of the adapter.
+	// Defaults to core.MaxLockTTL.
+	MaxAllowedTTL time.Duration
+
+	// DefaultRequestTimeout bounds the statements of Release, Refresh,
+	// RefreshIfExpiring, TransferOwnership, IsHeld, IsKeyLocked, AssertHeld and
+	// ContentionInfo, which have no LockOptions carrying a
+	// RequestTimeout, so a stuck statement, e.g. while shutting down,
+	// can't hang the caller. They fail with core.ErrOperationTimeout once it fires.
+	// Defaults to core.DefaultRequestTimeout.
+	DefaultRequestTimeout time.Duration
+
+	// RefreshSafetyMargin is the fraction of the new TTL during which an
+	// expired lock can still be refreshed, as long as nobody took it
+	// over, absorbing the clock drift between the client and the server.
+	// Must be [0, core.MaxRefreshMargin]; 0 refuses any late refresh.
+	// Bound as a parameter of the refresh statement, it can be tuned per
+	// deployment and overridden per lock by
+	// core.LockOptions.RefreshSafetyMargin.
+	// Defaults to core.MaxClockDriftMargin when nil.
+	RefreshSafetyMargin *float64
+
+	// FIFO serves the acquirers of a key roughly in arrival order,
+	// preventing starvation under high contention.
+	//
+	// Waiters are queued in the waiters table: every acquisition costs an
+	// extra insert and delete, and every retry an extra update.
+	FIFO bool
+
+	// NotifyOnRelease makes Release and CleanupExpired issue a NOTIFY on a
+	// channel derived from the key, and a contended Acquire LISTEN on it to
+	// retry as soon as the lock is released instead of sleeping the full
+	// backoff. When no notification arrives, Acquire falls back to the
+	// timed retries. BenchmarkAcquire_Handoff measures the difference.
+	//
+	// The adapter LISTENs for all its waiters on a single dedicated pool
+	// connection, taken on the first contention and returned by Close. It
+	// does not work behind poolers in transaction mode, such as PgBouncer.
+	NotifyOnRelease bool
+
+	// CompatSimpleProtocol makes the lock operations run with the simple
+	// protocol, never preparing or caching statements, for poolers in
+	// transaction mode such as PgBouncer, where each statement may land
+	// on another server connection. The adapter sets no session state,
+	// so nothing else changes.
+	//
+	// The migrations take a session advisory lock: run them on a direct
+	// connection. NotifyOnRelease is not supported in this mode.
+	CompatSimpleProtocol bool
+
+	// StatementTimeout makes each statement with a deadline run in a
+	// transaction with SET LOCAL statement_timeout set to the time left
+	// before that deadline, so Postgres itself aborts a runaway statement
+	// when the cancellation of the ctx doesn't reach it, e.g. through a
+	// pooler. The deadline is RequestTimeout for Acquire and
+	// DefaultRequestTimeout for the operations listed there; the other
+	// statements, e.g. of ListLocks or the batch operations, are only
+	// bounded when the caller's ctx has a deadline, and otherwise run as
+	// is. A wrapped statement costs four round trips instead of one:
+	// BEGIN, set_config, the statement and COMMIT. The statements aborted
+	// by the server fail with core.ErrOperationTimeout.
+	StatementTimeout bool
+
+	// Unlogged makes the migrations create the lock and waiters tables
+	// UNLOGGED, skipping the write-ahead log: writes get roughly twice as
+	// fast, but a crash of Postgres empties the tables, losing every
+	// lock, and they are not replicated to standbys. Existing
+	// installations are converted with ConvertToUnlogged.
+	Unlogged bool
+
+	// MetadataIndex makes the migrations create a GIN index on the lock
+	// metadata, serving FindLocksByMetadata without scanning the table.
+	//
+	// The index slows down every acquisition carrying metadata and takes
+	// disk space proportional to it, small deployments are better off
+	// without it. Disabling it later does not drop an index already
+	// created, RollbackMigration does.
+	MetadataIndex bool
+
+	// AuditTableName enables the audit log: the lock operations append a
+	// row per acquisition, takeover, refresh, release and forced release
+	// to this table of LockSchema, created by its own migration, read by
+	// QueryAudit and trimmed by CleanupAudit. A failed audit insert never
+	// fails the operation, it is reported to Hooks.OnAuditFailed.
+	// Empty disables the audit log.
+	AuditTableName string
+
+	// MigrationLockTimeout bounds the wait for the migration lock, held
+	// by the instance migrating, e.g. a replica stuck mid-migration on
+	// first boot, after which the migrations fail with
+	// ErrMigrationLockTimeout. Zero waits as long as ctx allows.
+	MigrationLockTimeout time.Duration
+
+	// DisableRollbacks makes RollbackMigration fail with ErrRollbackDisabled,
+	// protecting production databases
+	DisableRollbacks bool
+
+	// ValidateOnStart makes NewPostgresLockAdapter run VerifySchema and
+	// fail when the schema doesn't match the adapter, instead of the first
+	// Acquire failing at runtime. Meant for schemas created by a DBA
+	// pipeline, with CreateSchemasIfNotExists disabled. The check takes
+	// up to core.DefaultRequestTimeout.
+	ValidateOnStart bool
+
+	// CheckSchemaVersionOnStart makes NewPostgresLockAdapter run
+	// CheckCompatibility and fail when the database is migrated to a
+	// version the library doesn't support. The check takes up to
+	// core.DefaultRequestTimeout.
+	CheckSchemaVersionOnStart bool
+
+	// HealthCheck reports StatusYellow when the fraction of acquired pool
+	// connections reaches PoolHighWaterMark (0.0-1.0) or the probe query
+	// takes longer than LatencyThreshold
+	PoolHighWaterMark float64
+	LatencyThreshold  time.Duration
+
+	// HealthCheck reports StatusYellow when the fraction (0.0-1.0) of the
+	// recent operations whose query failed exceeds ErrorRateThreshold
+	ErrorRateThreshold float64
+
+	// HealthCheckInterval is the interval between the probes of the
+	// loop started by StartHealthMonitor
+	HealthCheckInterval time.Duration
+
+	// HealthCheck reports StatusYellow when the clock of the client is
+	// further than ClockDriftThreshold from the clock of the server
+	ClockDriftThreshold time.Duration
+
+	// FailFastOnRed makes Acquire fail immediately with
+	// core.ErrBackendUnhealthy while the last report of the health
+	// monitor is StatusRed, instead of timing out against the database.
+	// It has no effect unless StartHealthMonitor is running.
+	FailFastOnRed bool
+
+	// SweepInterval enables a background sweeper, started by
+	// NewPostgresLockAdapter and stopped by Close, running CleanupExpired
+	// every SweepInterval to delete the locks expired for longer than
+	// SweepGracePeriod, SweepBatchSize rows per statement.
+	// Zero disables the sweeper.
+	SweepInterval    time.Duration
+	SweepGracePeriod time.Duration
+	SweepBatchSize   int
+
+	// PoolMetricsInterval enables a background sampler, started by
+	// NewPostgresLockAdapter and stopped by Close, recording the state of
+	// the connection pool into Metrics every PoolMetricsInterval, see
+	// MetricPoolAcquiredConns and the other gauges. An EmptyAcquireCount
+	// climbing is the leading indicator of the acquisition latency.
+	// Zero disables the sampler.
+	PoolMetricsInterval time.Duration
+	Metrics             core.LockMetrics
+
+	// IDGenerator produces the LeaseID and ServerNonce of the tokens.
+	// Defaults to core.UUIDGenerator.
+	IDGenerator core.IDGenerator
+
+	// EventBufferSize enables the lock events of Events, buffering up
+	// to EventBufferSize of them. Zero disables the events.
+	//
+	// EventPolicy tells what happens to an event when the buffer is full:
+	// core.EventDrop (default) discards it, counted in Stats, so a slow
+	// consumer never stalls the lock operations; core.EventBlock makes
+	// the operation wait for the consumer until its ctx is done.
+	EventBufferSize int
+	EventPolicy     core.EventPolicy
 }
 
 // NewPostgresLockerConfig creates a new instance of PostgresLockerConfig
@@ -24,32 +241,305 @@ func NewPostgresLockerConfig() *PostgresLockerConfig {
 	return r.WithDefaults()
 }
 
+// Validate checks the configuration, returning a *ConfigError with a
+// *FieldError per problem found
 func (p *PostgresLockerConfig) Validate() error {
-	msgs := []string{}
+	var errs []*FieldError
+	invalid := func(field, format string, args ...any) {
+		errs = append(errs, &FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
 	if p.MigrationSchema == "" {
-		msgs = append(msgs, "MigrationSchema is required")
+		invalid("MigrationSchema", "MigrationSchema is required")
 	}
 	if p.MigrationTableName == "" {
-		msgs = append(msgs, "MigrationTableName is required")
+		invalid("MigrationTableName", "MigrationTableName is required")
 	}
 	if p.LockSchema == "" {
-		msgs = append(msgs, "LockSchema is required")
+		invalid("LockSchema", "LockSchema is required")
 	}
 	if p.LockTableName == "" {
-		msgs = append(msgs, "LockTableName is required")
+		invalid("LockTableName", "LockTableName is required")
+	}
+
+	for _, f := range []struct{ name, value string }{
+		{"MigrationSchema", p.MigrationSchema},
+		{"MigrationTableName", p.MigrationTableName},
+		{"LockSchema", p.LockSchema},
+		{"LockTableName", p.LockTableName},
+	} {
+		if f.value != "" && !isValidIdentifier(f.value) {
+			invalid(f.name,
+				"%s must match %s and have at most %d chars",
+				f.name, validIdentifierRegex, maxIdentifierLength,
+			)
+		}
+	}
+
+	if p.AuditTableName != "" {
+		if !isValidIdentifier(p.AuditTableName) || len(p.AuditTableName) > maxAuditTableNameLength {
+			invalid("AuditTableName",
+				"AuditTableName must match %s and have at most %d chars",
+				validIdentifierRegex, maxAuditTableNameLength,
+			)
+		}
+		if p.AuditTableName == p.LockTableName || p.AuditTableName == p.derivedName("_waiters") {
+			invalid("AuditTableName", "AuditTableName must differ from the lock tables")
+		}
+	}
+
+	if p.Namespace != "" {
+		if err := core.ValidateNamespace(p.Namespace); err != nil {
+			invalid("Namespace", "Namespace must be [a-zA-Z0-9_-] segments separated by ':'")
+		}
+	}
+
+	if p.MaxAllowedTTL != 0 && p.MaxAllowedTTL < core.MinLockTTL {
+		invalid("MaxAllowedTTL", "MaxAllowedTTL must be ≥ %v", core.MinLockTTL)
+	}
+	if p.MigrationLockTimeout < 0 {
+		invalid("MigrationLockTimeout", "MigrationLockTimeout must be ≥ 0")
+	}
+	if p.DefaultRequestTimeout < 0 {
+		invalid("DefaultRequestTimeout", "DefaultRequestTimeout must be ≥ 0")
+	}
+	if m := p.RefreshSafetyMargin; m != nil && (*m < 0 || *m > core.MaxRefreshMargin) {
+		invalid("RefreshSafetyMargin", "RefreshSafetyMargin must be [0, %v]", core.MaxRefreshMargin)
+	}
+
+	if p.PoolHighWaterMark < 0 || p.PoolHighWaterMark > 1 {
+		invalid("PoolHighWaterMark", "PoolHighWaterMark must be [0.0, 1.0]")
+	}
+	if p.LatencyThreshold < 0 {
+		invalid("LatencyThreshold", "LatencyThreshold must be ≥ 0")
+	}
+	if p.ErrorRateThreshold < 0 || p.ErrorRateThreshold > 1 {
+		invalid("ErrorRateThreshold", "ErrorRateThreshold must be [0.0, 1.0]")
+	}
+	if p.HealthCheckInterval < 0 {
+		invalid("HealthCheckInterval", "HealthCheckInterval must be ≥ 0")
+	}
+	if p.ClockDriftThreshold < 0 {
+		invalid("ClockDriftThreshold", "ClockDriftThreshold must be ≥ 0")
+	}
+	if p.CompatSimpleProtocol && p.NotifyOnRelease {
+		invalid("NotifyOnRelease", "NotifyOnRelease is not supported with CompatSimpleProtocol")
+	}
+
+	if p.SweepInterval < 0 {
+		invalid("SweepInterval", "SweepInterval must be ≥ 0")
+	}
+	if p.SweepGracePeriod < 0 {
+		invalid("SweepGracePeriod", "SweepGracePeriod must be ≥ 0")
+	}
+	if p.EventBufferSize < 0 {
+		invalid("EventBufferSize", "EventBufferSize must be ≥ 0")
+	}
+	if p.EventPolicy != core.EventDrop && p.EventPolicy != core.EventBlock {
+		invalid("EventPolicy", "EventPolicy must be core.EventDrop or core.EventBlock")
+	}
+	if p.SweepInterval > 0 && p.SweepBatchSize <= 0 {
+		invalid("SweepBatchSize", "SweepBatchSize must be > 0")
+	}
+	if p.PoolMetricsInterval < 0 {
+		invalid("PoolMetricsInterval", "PoolMetricsInterval must be ≥ 0")
+	}
+	if p.PoolMetricsInterval > 0 && p.Metrics == nil {
+		invalid("Metrics", "Metrics is required with PoolMetricsInterval")
 	}
 
 	if p.LockTableName != "" && p.LockTableName == p.MigrationTableName {
-		msgs = append(msgs, "LockTableName and MigrationTableName must be different")
+		invalid("LockTableName", "LockTableName and MigrationTableName must be different")
 	}
 
-	if len(msgs) > 0 {
-		return fmt.Errorf("%w: %s", ErrInvalidConfig, strings.Join(msgs, ", "))
+	if len(errs) > 0 {
+		return &ConfigError{Errors: errs}
 	}
 
 	return nil
 }
 
+func isValidIdentifier(v string) bool {
+	return len(v) <= maxIdentifierLength && validIdentifierRegex.MatchString(v)
+}
+
+// maxTTL returns the TTL ceiling of the adapter
+func (p *PostgresLockerConfig) maxTTL() time.Duration {
+	if p.MaxAllowedTTL == 0 {
+		return core.MaxLockTTL
+	}
+	return p.MaxAllowedTTL
+}
+
+// requestTimeout returns the bound of the statements without LockOptions
+func (p *PostgresLockerConfig) requestTimeout() time.Duration {
+	if p.DefaultRequestTimeout == 0 {
+		return core.DefaultRequestTimeout
+	}
+	return p.DefaultRequestTimeout
+}
+
+// lockSchema returns the quoted lock schema, safe to interpolate in SQL
+func (p *PostgresLockerConfig) lockSchema() string {
+	return pgx.Identifier{p.LockSchema}.Sanitize()
+}
+
+// lockTable returns the quoted, schema qualified lock table,
+// safe to interpolate in SQL
+func (p *PostgresLockerConfig) lockTable() string {
+	return pgx.Identifier{p.LockSchema, p.LockTableName}.Sanitize()
+}
+
+// lockWaitersTable returns the quoted, schema qualified table
+// of the FIFO waiters
+func (p *PostgresLockerConfig) lockWaitersTable() string {
+	return pgx.Identifier{p.LockSchema, p.derivedName("_waiters")}.Sanitize()
+}
+
+// auditTableName returns the name of the audit table, defaulting to one
+// derived from the lock table so its migration renders even disabled
+func (p *PostgresLockerConfig) auditTableName() string {
+	if p.AuditTableName == "" {
+		return p.derivedName("_audit")
+	}
+	return p.AuditTableName
+}
+
+// auditTable returns the quoted, schema qualified audit table,
+// safe to interpolate in SQL
+func (p *PostgresLockerConfig) auditTable() string {
+	return pgx.Identifier{p.LockSchema, p.auditTableName()}.Sanitize()
+}
+
+// auditIndex returns the quoted name of an index of the audit table
+func (p *PostgresLockerConfig) auditIndex(name string) string {
+	return pgx.Identifier{derivedName(p.auditTableName(), "_"+name+"_idx")}.Sanitize()
+}
+
+// lockKeyCheck returns the quoted name of the key check constraint
+// of the lock table
+func (p *PostgresLockerConfig) lockKeyCheck() string {
+	return pgx.Identifier{p.derivedName("_key_check")}.Sanitize()
+}
+
+// tablePersistence returns the persistence keyword of the created
+// tables, followed by a space
+func (p *PostgresLockerConfig) tablePersistence() string {
+	if p.Unlogged {
+		return "UNLOGGED "
+	}
+	return ""
+}
+
+// lockIndex returns the quoted name of an index of the lock table,
+// unique per table so several lock tables can share a schema
+func (p *PostgresLockerConfig) lockIndex(name string) string {
+	return pgx.Identifier{p.lockIndexName(name)}.Sanitize()
+}
+
+// lockIndexName returns the unquoted name of an index of the lock table
+func (p *PostgresLockerConfig) lockIndexName(name string) string {
+	return p.derivedName("_" + name + "_idx")
+}
+
+// lockHealthView returns the quoted, schema qualified health view
+// of the lock table
+func (p *PostgresLockerConfig) lockHealthView() string {
+	return pgx.Identifier{p.LockSchema, p.lockHealthViewName()}.Sanitize()
+}
+
+// lockHealthViewName returns the unquoted name of the health view
+func (p *PostgresLockerConfig) lockHealthViewName() string {
+	return p.derivedName("_health")
+}
+
+// tryAcquireLock returns the quoted, schema qualified acquisition function
+// of the lock table
+func (p *PostgresLockerConfig) tryAcquireLock() string {
+	return pgx.Identifier{p.LockSchema, p.tryAcquireLockName()}.Sanitize()
+}
+
+// tryAcquireLockName returns the unquoted name of the acquisition function
+func (p *PostgresLockerConfig) tryAcquireLockName() string {
+	return p.derivedName("_try_acquire_lock")
+}
+
+// tryAcquireLockFIFO returns the quoted, schema qualified acquisition
+// function of the FIFO mode
+func (p *PostgresLockerConfig) tryAcquireLockFIFO() string {
+	return pgx.Identifier{p.LockSchema, p.derivedName("_try_acquire_lock_fifo")}.Sanitize()
+}
+
+// derivedName returns the name of an object of the lock table, see
+// derivedName
+func (p *PostgresLockerConfig) derivedName(suffix string) string {
+	return derivedName(p.LockTableName, suffix)
+}
+
+// derivedName returns the name of an object named after a table: the
+// table name followed by suffix. Past the Postgres identifier limit, the
+// table name is cut and followed by a hash of it, so the names of two
+// long tables never collide once truncated.
+func derivedName(table, suffix string) string {
+	name := table + suffix
+	if len(name) <= maxIdentifierLength {
+		return name
+	}
+	sum := sha256.Sum256([]byte(table))
+	hash := hex.EncodeToString(sum[:4])
+	return table[:maxIdentifierLength-len(suffix)-len(hash)-1] + "_" + hash + suffix
+}
+
+// storageKey returns the key as stored in the lock table,
+// prefixed by the namespace
+func (p *PostgresLockerConfig) storageKey(key string) (string, error) {
+	storageKey, err := core.NamespaceKey(p.Namespace, key)
+	if err != nil && p.HashLongKeys && errors.Is(err, core.ErrInvalidKeyFormat) {
+		return core.NamespaceKey(p.Namespace, core.HashKey(key))
+	}
+	return storageKey, err
+}
+
+// originalKey returns key when storageKey is its hash, "" otherwise
+func (p *PostgresLockerConfig) originalKey(key, storageKey string) string {
+	if p.userKey(storageKey) == key {
+		return ""
+	}
+	return key
+}
+
+// userKey returns the key as seen by the caller,
+// without the namespace prefix
+func (p *PostgresLockerConfig) userKey(storageKey string) string {
+	return core.StripNamespace(p.Namespace, storageKey)
+}
+
+// newID returns a LeaseID or ServerNonce from the IDGenerator,
+// falling back to a UUID for configurations built without WithDefaults
+func (p *PostgresLockerConfig) newID() string {
+	if p.IDGenerator == nil {
+		return core.UUIDGenerator{}.NewID()
+	}
+	return p.IDGenerator.NewID()
+}
+
+// migrationSchema returns the quoted migration schema, safe to interpolate in SQL
+func (p *PostgresLockerConfig) migrationSchema() string {
+	return pgx.Identifier{p.MigrationSchema}.Sanitize()
+}
+
+// migrationTable returns the quoted, schema qualified migration table,
+// safe to interpolate in SQL
+func (p *PostgresLockerConfig) migrationTable() string {
+	return pgx.Identifier{p.MigrationSchema, p.MigrationTableName}.Sanitize()
+}
+
+// migrationVersionIndex returns the quoted name of the unique index
+// of the migration versions
+func (p *PostgresLockerConfig) migrationVersionIndex() string {
+	return pgx.Identifier{p.MigrationTableName + "_version_key"}.Sanitize()
+}
+
 // WithDefaults sets default values for missing fields
 // if they are not provided.
 //
@@ -63,6 +553,26 @@ func (p *PostgresLockerConfig) Validate() error {
 // - LockSchema: public
 //
 // - LockTableName: locker_locks
+//
+// - MaxAllowedTTL: core.MaxLockTTL
+//
+// - DefaultRequestTimeout: core.DefaultRequestTimeout
+//
+// - RefreshSafetyMargin: core.MaxClockDriftMargin
+//
+// - PoolHighWaterMark: 0.8
+//
+// - LatencyThreshold: 500ms
+//
+// - ErrorRateThreshold: 0.05
+//
+// - HealthCheckInterval: 10s
+//
+// - ClockDriftThreshold: 2.25s
+//
+// - SweepBatchSize: 1000
+//
+// - IDGenerator: core.UUIDGenerator
 func (p *PostgresLockerConfig) WithDefaults() *PostgresLockerConfig {
 	if p.MigrationSchema == "" {
 		p.MigrationSchema = "public"
@@ -76,6 +586,37 @@ func (p *PostgresLockerConfig) WithDefaults() *PostgresLockerConfig {
 	if p.LockTableName == "" {
 		p.LockTableName = "locker_locks"
 	}
+	if p.MaxAllowedTTL == 0 {
+		p.MaxAllowedTTL = core.MaxLockTTL
+	}
+	if p.DefaultRequestTimeout == 0 {
+		p.DefaultRequestTimeout = core.DefaultRequestTimeout
+	}
+	if p.RefreshSafetyMargin == nil {
+		margin := core.MaxClockDriftMargin
+		p.RefreshSafetyMargin = &margin
+	}
+	if p.PoolHighWaterMark == 0 {
+		p.PoolHighWaterMark = DefaultPoolHighWaterMark
+	}
+	if p.LatencyThreshold == 0 {
+		p.LatencyThreshold = DefaultLatencyThreshold
+	}
+	if p.ErrorRateThreshold == 0 {
+		p.ErrorRateThreshold = DefaultErrorRateThreshold
+	}
+	if p.HealthCheckInterval == 0 {
+		p.HealthCheckInterval = DefaultHealthCheckInterval
+	}
+	if p.ClockDriftThreshold == 0 {
+		p.ClockDriftThreshold = DefaultClockDriftThreshold
+	}
+	if p.SweepBatchSize == 0 {
+		p.SweepBatchSize = DefaultSweepBatchSize
+	}
+	if p.IDGenerator == nil {
+		p.IDGenerator = core.UUIDGenerator{}
+	}
 
 	return p
 }
@@ -124,3 +665,282 @@ func (p *PostgresLockerConfig) SetCreateSchemasIfNotExists(v bool) *PostgresLock
 	p.CreateSchemasIfNotExists = v
 	return p
 }
+
+// SetHooks sets the Hooks field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (p *PostgresLockerConfig) SetHooks(v core.Hooks) *PostgresLockerConfig {
+	p.Hooks = v
+	return p
+}
+
+// SetNamespace sets the Namespace field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (p *PostgresLockerConfig) SetNamespace(v string) *PostgresLockerConfig {
+	p.Namespace = v
+	return p
+}
+
+// SetMaxAllowedTTL sets the MaxAllowedTTL field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (p *PostgresLockerConfig) SetMaxAllowedTTL(v time.Duration) *PostgresLockerConfig {
+	p.MaxAllowedTTL = v
+	return p
+}
+
+// SetRefreshSafetyMargin sets the RefreshSafetyMargin field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (p *PostgresLockerConfig) SetRefreshSafetyMargin(v float64) *PostgresLockerConfig {
+	p.RefreshSafetyMargin = &v
+	return p
+}
+
+// SetFIFO sets the FIFO field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (p *PostgresLockerConfig) SetFIFO(v bool) *PostgresLockerConfig {
+	p.FIFO = v
+	return p
+}
+
+// SetNotifyOnRelease sets the NotifyOnRelease field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (p *PostgresLockerConfig) SetNotifyOnRelease(v bool) *PostgresLockerConfig {
+	p.NotifyOnRelease = v
+	return p
+}
+
+// SetCompatSimpleProtocol sets the CompatSimpleProtocol field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (p *PostgresLockerConfig) SetCompatSimpleProtocol(v bool) *PostgresLockerConfig {
+	p.CompatSimpleProtocol = v
+	return p
+}
+
+// SetStatementTimeout sets the StatementTimeout field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (p *PostgresLockerConfig) SetStatementTimeout(v bool) *PostgresLockerConfig {
+	p.StatementTimeout = v
+	return p
+}
+
+// SetUnlogged sets the Unlogged field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (p *PostgresLockerConfig) SetUnlogged(v bool) *PostgresLockerConfig {
+	p.Unlogged = v
+	return p
+}
+
+// SetMetadataIndex sets the MetadataIndex field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (p *PostgresLockerConfig) SetMetadataIndex(v bool) *PostgresLockerConfig {
+	p.MetadataIndex = v
+	return p
+}
+
+// SetDisableRollbacks sets the DisableRollbacks field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (p *PostgresLockerConfig) SetDisableRollbacks(v bool) *PostgresLockerConfig {
+	p.DisableRollbacks = v
+	return p
+}
+
+// SetPoolHighWaterMark sets the PoolHighWaterMark field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (p *PostgresLockerConfig) SetPoolHighWaterMark(v float64) *PostgresLockerConfig {
+	p.PoolHighWaterMark = v
+	return p
+}
+
+// SetLatencyThreshold sets the LatencyThreshold field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (p *PostgresLockerConfig) SetLatencyThreshold(v time.Duration) *PostgresLockerConfig {
+	p.LatencyThreshold = v
+	return p
+}
+
+// SetErrorRateThreshold sets the ErrorRateThreshold field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (p *PostgresLockerConfig) SetErrorRateThreshold(v float64) *PostgresLockerConfig {
+	p.ErrorRateThreshold = v
+	return p
+}
+
+// SetHealthCheckInterval sets the HealthCheckInterval field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (p *PostgresLockerConfig) SetHealthCheckInterval(v time.Duration) *PostgresLockerConfig {
+	p.HealthCheckInterval = v
+	return p
+}
+
+// SetClockDriftThreshold sets the ClockDriftThreshold field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (p *PostgresLockerConfig) SetClockDriftThreshold(v time.Duration) *PostgresLockerConfig {
+	p.ClockDriftThreshold = v
+	return p
+}
+
+// SetFailFastOnRed sets the FailFastOnRed field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (p *PostgresLockerConfig) SetFailFastOnRed(v bool) *PostgresLockerConfig {
+	p.FailFastOnRed = v
+	return p
+}
+
+// SetSweepInterval sets the SweepInterval field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (p *PostgresLockerConfig) SetSweepInterval(v time.Duration) *PostgresLockerConfig {
+	p.SweepInterval = v
+	return p
+}
+
+// SetSweepGracePeriod sets the SweepGracePeriod field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (p *PostgresLockerConfig) SetSweepGracePeriod(v time.Duration) *PostgresLockerConfig {
+	p.SweepGracePeriod = v
+	return p
+}
+
+// SetSweepBatchSize sets the SweepBatchSize field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (p *PostgresLockerConfig) SetSweepBatchSize(v int) *PostgresLockerConfig {
+	p.SweepBatchSize = v
+	return p
+}
+
+// SetPoolMetricsInterval sets the PoolMetricsInterval field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (p *PostgresLockerConfig) SetPoolMetricsInterval(v time.Duration) *PostgresLockerConfig {
+	p.PoolMetricsInterval = v
+	return p
+}
+
+// SetMetrics sets the Metrics field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (p *PostgresLockerConfig) SetMetrics(v core.LockMetrics) *PostgresLockerConfig {
+	p.Metrics = v
+	return p
+}
+
+// SetIDGenerator sets the IDGenerator field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (p *PostgresLockerConfig) SetIDGenerator(v core.IDGenerator) *PostgresLockerConfig {
+	p.IDGenerator = v
+	return p
+}
+
+// SetEventBufferSize sets the EventBufferSize field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (p *PostgresLockerConfig) SetEventBufferSize(v int) *PostgresLockerConfig {
+	p.EventBufferSize = v
+	return p
+}
+
+// SetEventPolicy sets the EventPolicy field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (p *PostgresLockerConfig) SetEventPolicy(v core.EventPolicy) *PostgresLockerConfig {
+	p.EventPolicy = v
+	return p
+}
+
+// SetValidateOnStart sets the ValidateOnStart field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (p *PostgresLockerConfig) SetValidateOnStart(v bool) *PostgresLockerConfig {
+	p.ValidateOnStart = v
+	return p
+}
+
+// SetCheckSchemaVersionOnStart sets the CheckSchemaVersionOnStart field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (p *PostgresLockerConfig) SetCheckSchemaVersionOnStart(v bool) *PostgresLockerConfig {
+	p.CheckSchemaVersionOnStart = v
+	return p
+}
+
+// SetHashLongKeys sets the HashLongKeys field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (p *PostgresLockerConfig) SetHashLongKeys(v bool) *PostgresLockerConfig {
+	p.HashLongKeys = v
+	return p
+}
+
+// SetDefaultRequestTimeout sets the DefaultRequestTimeout field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (p *PostgresLockerConfig) SetDefaultRequestTimeout(v time.Duration) *PostgresLockerConfig {
+	p.DefaultRequestTimeout = v
+	return p
+}
+
+// SetMigrationLockTimeout sets the MigrationLockTimeout field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (p *PostgresLockerConfig) SetMigrationLockTimeout(v time.Duration) *PostgresLockerConfig {
+	p.MigrationLockTimeout = v
+	return p
+}
+
+// SetAuditTableName sets the AuditTableName field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (p *PostgresLockerConfig) SetAuditTableName(v string) *PostgresLockerConfig {
+	p.AuditTableName = v
+	return p
+}
@@ -1,8 +1,12 @@
 package pg_test
 
 import (
+	"context"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/oliveiracleidson/go-lockbox/core"
 	"github.com/oliveiracleidson/go-lockbox/pg"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -16,6 +20,14 @@ func TestNewPostgresLockerConfig_WithDefaults(t *testing.T) {
 	assert.Equal(t, "public", config.LockSchema)
 	assert.Equal(t, "locker_locks", config.LockTableName)
 	assert.Equal(t, true, config.CreateSchemasIfNotExists)
+	assert.Equal(t, core.MaxLockTTL, config.MaxAllowedTTL)
+	assert.Equal(t, pg.DefaultPoolHighWaterMark, config.PoolHighWaterMark)
+	assert.Equal(t, pg.DefaultLatencyThreshold, config.LatencyThreshold)
+	assert.Equal(t, pg.DefaultErrorRateThreshold, config.ErrorRateThreshold)
+	assert.Equal(t, pg.DefaultHealthCheckInterval, config.HealthCheckInterval)
+	assert.Equal(t, pg.DefaultClockDriftThreshold, config.ClockDriftThreshold)
+	assert.Equal(t, pg.DefaultSweepBatchSize, config.SweepBatchSize)
+	assert.Equal(t, core.UUIDGenerator{}, config.IDGenerator)
 }
 
 func TestPostgresLockerConfig_Validate(t *testing.T) {
@@ -29,12 +41,32 @@ func TestPostgresLockerConfig_Validate(t *testing.T) {
 		config := &pg.PostgresLockerConfig{} // Config vazia
 		err := config.Validate()
 		require.Error(t, err)
-		assert.ErrorAs(t, err, &pg.ErrInvalidConfig)
-		assert.Contains(t, err.Error(), "MigrationSchema is required")
-		assert.Contains(t, err.Error(), "MigrationTableName is required")
-		assert.Contains(t, err.Error(), "LockSchema is required")
-		assert.Contains(t, err.Error(), "LockTableName is required")
-		assert.NotContains(t, err.Error(), "LockTableName and MigrationTableName must be different")
+		assert.ErrorIs(t, err, pg.ErrInvalidConfig)
+
+		var configErr *pg.ConfigError
+		require.ErrorAs(t, err, &configErr)
+		require.Len(t, configErr.Errors, 4)
+		for _, field := range []string{"MigrationSchema", "MigrationTableName", "LockSchema", "LockTableName"} {
+			assert.ErrorIs(t, err, &pg.FieldError{Field: field, Message: field + " is required"})
+		}
+		assert.NotErrorIs(t, err, &pg.FieldError{Field: "LockTableName", Message: "LockTableName and MigrationTableName must be different"})
+	})
+
+	t.Run("given an invalid config, when validate, then the combined message lists every field", func(t *testing.T) {
+		config := pg.NewPostgresLockerConfig().SetDefaultRequestTimeout(-time.Second)
+		config.PoolHighWaterMark = 2
+
+		err := config.Validate()
+		assert.EqualError(t, err, "invalid configuration: DefaultRequestTimeout must be ≥ 0, PoolHighWaterMark must be [0.0, 1.0]")
+
+		var fieldErr *pg.FieldError
+		require.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, "DefaultRequestTimeout", fieldErr.Field)
+
+		var configErr *pg.ConfigError
+		require.ErrorAs(t, err, &configErr)
+		assert.Equal(t, "PoolHighWaterMark must be [0.0, 1.0]", configErr.Field("PoolHighWaterMark").Message)
+		assert.Nil(t, configErr.Field("LockTableName"))
 	})
 }
 
@@ -45,7 +77,7 @@ func TestPostgresLockerConfig_Validate_DifferentTableNames(t *testing.T) {
 
 	err := config.Validate()
 	require.Error(t, err)
-	assert.Contains(t, err.Error(), "LockTableName and MigrationTableName must be different")
+	assert.ErrorIs(t, err, &pg.FieldError{Field: "LockTableName", Message: "LockTableName and MigrationTableName must be different"})
 }
 
 func TestPostgresLockerConfig_Setters(t *testing.T) {
@@ -63,3 +95,207 @@ func TestPostgresLockerConfig_Setters(t *testing.T) {
 	assert.Equal(t, "custom_lock_table", config.LockTableName)
 	assert.Equal(t, false, config.CreateSchemasIfNotExists)
 }
+
+func TestPostgresLockerConfig_Validate_Identifiers(t *testing.T) {
+	t.Run("given an identifier with SQL, when validate, then return error", func(t *testing.T) {
+		config := pg.NewPostgresLockerConfig()
+		config.LockTableName = `locks"; DROP TABLE users; --`
+
+		err := config.Validate()
+		require.Error(t, err)
+		assert.ErrorIs(t, err, pg.ErrInvalidConfig)
+		assert.Contains(t, err.Error(), "LockTableName must match")
+	})
+
+	t.Run("given a schema escaping its quotes, when validate, then return error", func(t *testing.T) {
+		config := pg.NewPostgresLockerConfig()
+		config.LockSchema = `public"."pg_catalog`
+		config.MigrationSchema = `public"."pg_catalog`
+
+		err := config.Validate()
+		require.Error(t, err)
+		assert.ErrorIs(t, err, pg.ErrInvalidConfig)
+		assert.Contains(t, err.Error(), "LockSchema must match")
+		assert.Contains(t, err.Error(), "MigrationSchema must match")
+	})
+
+	t.Run("given invalid identifiers, when validate, then return error for each field", func(t *testing.T) {
+		config := pg.NewPostgresLockerConfig()
+		config.MigrationSchema = "1schema"
+		config.MigrationTableName = "migrations.table"
+		config.LockSchema = "lock schema"
+		config.LockTableName = strings.Repeat("a", 64)
+
+		err := config.Validate()
+		require.Error(t, err)
+		for _, field := range []string{"MigrationSchema", "MigrationTableName", "LockSchema", "LockTableName"} {
+			assert.ErrorIs(t, err, &pg.FieldError{Field: field})
+		}
+		assert.Contains(t, err.Error(), "MigrationSchema must match")
+		assert.NotErrorIs(t, err, &pg.FieldError{Field: "Namespace"})
+	})
+
+	t.Run("given valid identifiers, when validate, then pass", func(t *testing.T) {
+		config := pg.NewPostgresLockerConfig()
+		config.LockSchema = "_Locker_1"
+		config.LockTableName = strings.Repeat("a", 63)
+
+		assert.NoError(t, config.Validate())
+	})
+}
+
+func TestNewPostgresLockAdapter_InvalidConfig(t *testing.T) {
+	config := pg.NewPostgresLockerConfig()
+	config.LockSchema = `public"; DROP TABLE "users`
+
+	a, err := pg.NewPostgresLockAdapter(nil, config)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, pg.ErrInvalidConfig)
+	assert.Nil(t, a)
+}
+
+func TestPostgresLockerConfig_Validate_Namespace(t *testing.T) {
+	config := pg.NewPostgresLockerConfig().SetNamespace("team-a:orders")
+	assert.NoError(t, config.Validate())
+
+	config.SetNamespace("team a")
+	err := config.Validate()
+	require.Error(t, err)
+	assert.ErrorIs(t, err, &pg.FieldError{Field: "Namespace"})
+	assert.Contains(t, err.Error(), "Namespace must be")
+}
+
+func TestPostgresLockerConfig_Validate_MaxAllowedTTL(t *testing.T) {
+	config := pg.NewPostgresLockerConfig().SetMaxAllowedTTL(30 * time.Minute)
+	assert.NoError(t, config.Validate())
+
+	config.SetMaxAllowedTTL(time.Microsecond)
+	err := config.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "MaxAllowedTTL must be")
+}
+
+func TestPostgresLockerConfig_Validate_RefreshSafetyMargin(t *testing.T) {
+	assert.Equal(t, core.MaxClockDriftMargin, *pg.NewPostgresLockerConfig().RefreshSafetyMargin)
+	assert.Equal(t, core.MaxClockDriftMargin, *(&pg.PostgresLockerConfig{LockTableName: "locks"}).WithDefaults().RefreshSafetyMargin)
+
+	// An explicit 0 refuses any late refresh, it is not defaulted
+	zero := 0.0
+	assert.Zero(t, *(&pg.PostgresLockerConfig{RefreshSafetyMargin: &zero}).WithDefaults().RefreshSafetyMargin)
+
+	for _, margin := range []float64{0, 0.02, core.MaxClockDriftMargin, 0.3, core.MaxRefreshMargin} {
+		config := pg.NewPostgresLockerConfig().SetRefreshSafetyMargin(margin)
+		assert.NoError(t, config.Validate(), margin)
+	}
+
+	for _, margin := range []float64{-0.01, core.MaxRefreshMargin + 0.01} {
+		config := pg.NewPostgresLockerConfig().SetRefreshSafetyMargin(margin)
+		err := config.Validate()
+		require.Error(t, err, margin)
+		assert.Contains(t, err.Error(), "RefreshSafetyMargin must be [0, 0.5]")
+	}
+}
+
+func TestPostgresLockerConfig_Validate_CompatSimpleProtocol(t *testing.T) {
+	config := pg.NewPostgresLockerConfig().SetCompatSimpleProtocol(true)
+	assert.NoError(t, config.Validate())
+
+	config.SetNotifyOnRelease(true)
+	err := config.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "NotifyOnRelease is not supported with CompatSimpleProtocol")
+}
+
+func TestPostgresLockerConfig_Validate_Events(t *testing.T) {
+	config := pg.NewPostgresLockerConfig().SetEventBufferSize(-1).SetEventPolicy(core.EventBlock + 1)
+
+	err := config.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "EventBufferSize must be ≥ 0")
+	assert.Contains(t, err.Error(), "EventPolicy must be core.EventDrop or core.EventBlock")
+}
+
+func TestPostgresLockAdapter_RollbackMigration_Disabled(t *testing.T) {
+	a, err := pg.NewPostgresLockAdapter(nil, pg.NewPostgresLockerConfig().SetDisableRollbacks(true))
+	require.NoError(t, err)
+
+	err = a.RollbackMigration(context.Background(), "v0.0.1")
+	assert.ErrorIs(t, err, pg.ErrRollbackDisabled)
+}
+
+func TestPostgresLockerConfig_Validate_HealthThresholds(t *testing.T) {
+	config := pg.NewPostgresLockerConfig().
+		SetPoolHighWaterMark(1.5).
+		SetLatencyThreshold(-time.Second).
+		SetErrorRateThreshold(1.5).
+		SetHealthCheckInterval(-time.Second).
+		SetClockDriftThreshold(-time.Second)
+
+	err := config.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "PoolHighWaterMark must be [0.0, 1.0]")
+	assert.Contains(t, err.Error(), "LatencyThreshold must be ≥ 0")
+	assert.Contains(t, err.Error(), "ErrorRateThreshold must be [0.0, 1.0]")
+	assert.Contains(t, err.Error(), "HealthCheckInterval must be ≥ 0")
+	assert.Contains(t, err.Error(), "ClockDriftThreshold must be ≥ 0")
+}
+
+func TestPostgresLockerConfig_Validate_Sweeper(t *testing.T) {
+	t.Run("given negative sweep durations and no batch size, when validate, then return error for each field", func(t *testing.T) {
+		config := pg.NewPostgresLockerConfig().
+			SetSweepInterval(-time.Second).
+			SetSweepGracePeriod(-time.Second)
+
+		err := config.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "SweepInterval must be ≥ 0")
+		assert.Contains(t, err.Error(), "SweepGracePeriod must be ≥ 0")
+
+		config = pg.NewPostgresLockerConfig().
+			SetSweepInterval(time.Second).
+			SetSweepBatchSize(-1)
+		assert.ErrorContains(t, config.Validate(), "SweepBatchSize must be > 0")
+	})
+
+	t.Run("given invalid arguments, when cleanup expired, then return error", func(t *testing.T) {
+		a, err := pg.NewPostgresLockAdapter(nil, pg.NewPostgresLockerConfig())
+		require.NoError(t, err)
+
+		_, err = a.CleanupExpired(context.Background(), -time.Second, 10)
+		assert.ErrorContains(t, err, "olderThan must be ≥ 0")
+		_, err = a.CleanupExpired(context.Background(), time.Second, 0)
+		assert.ErrorContains(t, err, "batchSize must be > 0")
+	})
+}
+
+func TestPostgresLockerConfig_Validate_DefaultRequestTimeout(t *testing.T) {
+	config := pg.NewPostgresLockerConfig()
+	assert.Equal(t, core.DefaultRequestTimeout, config.DefaultRequestTimeout)
+
+	config.SetDefaultRequestTimeout(-time.Second)
+	err := config.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "DefaultRequestTimeout must be")
+}
+
+func TestPostgresLockerConfig_Validate_MigrationLockTimeout(t *testing.T) {
+	config := pg.NewPostgresLockerConfig().SetMigrationLockTimeout(time.Minute)
+	assert.NoError(t, config.Validate())
+
+	config.SetMigrationLockTimeout(-time.Second)
+	err := config.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "MigrationLockTimeout must be")
+}
+
+func TestPostgresLockerConfig_Validate_AuditTableName(t *testing.T) {
+	config := pg.NewPostgresLockerConfig().SetAuditTableName("lock_audit")
+	assert.NoError(t, config.Validate())
+
+	for _, name := range []string{"lock audit", strings.Repeat("a", 60), "locker_locks", "locker_locks_waiters"} {
+		config := pg.NewPostgresLockerConfig().SetAuditTableName(name)
+		err := config.Validate()
+		require.Error(t, err, name)
+		assert.Contains(t, err.Error(), "AuditTableName must", name)
+	}
+}
@@ -0,0 +1,117 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+var (
+	// SKIP LOCKED leaves alone the expired rows an acquirer is taking over
+	cleanupExpiredSQL = `
+	WITH expired AS (
+		SELECT key
+		FROM %[1]s
+		WHERE valid_until < NOW() - ($1::BIGINT * INTERVAL '1 millisecond')
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	)
+	DELETE FROM %[1]s AS l
+	USING expired e
+	WHERE l.key = e.key
+	RETURNING l.key, l.lease_id, COALESCE(l.owner_id, ''), l.metadata;`
+)
+
+// expiredLock is a lock deleted by CleanupExpired
+type expiredLock struct {
+	storageKey string
+	leaseID    string
+	ownerID    string
+	metadata   []byte
+}
+
+// CleanupExpired deletes the locks that expired more than olderThan ago
+// and returns how many were removed.
+//
+// Rows are deleted batchSize at a time, each batch in its own short
+// statement, until no expired row is left or ctx is done. Locks that are
+// still valid, however close to expiry, are never deleted. The removed
+// count is reported to Hooks.OnExpiredCleanup, and with NotifyOnRelease
+// the acquirers waiting for the removed keys are woken.
+func (i *PostgresLockAdapter) CleanupExpired(ctx context.Context, olderThan time.Duration, batchSize int) (int64, error) {
+	if err := i.begin(); err != nil {
+		return 0, err
+	}
+	defer i.end()
+
+	if olderThan < 0 {
+		return 0, errors.New("olderThan must be ≥ 0")
+	}
+	if batchSize <= 0 {
+		return 0, errors.New("batchSize must be > 0")
+	}
+
+	var removed int64
+	defer func() {
+		if removed > 0 {
+			i.Cfg.Hooks.ExpiredCleanup(ctx, removed)
+		}
+	}()
+
+	for {
+		rows, err := i.db.Query(ctx,
+			i.sql.cleanupExpired,
+			olderThan.Milliseconds(), batchSize,
+		)
+		if err != nil {
+			return removed, fmt.Errorf("failed to clean up expired locks: %w", err)
+		}
+		locks, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (expiredLock, error) {
+			var l expiredLock
+			err := row.Scan(&l.storageKey, &l.leaseID, &l.ownerID, &l.metadata)
+			return l, err
+		})
+		if err != nil {
+			return removed, fmt.Errorf("failed to clean up expired locks: %w", err)
+		}
+
+		removed += int64(len(locks))
+		entries := make([]auditEntry, 0, len(locks))
+		for _, l := range locks {
+			if i.Cfg.NotifyOnRelease {
+				i.notifyRelease(ctx, l.storageKey)
+			}
+			i.emit(ctx, core.EventForceReleased, i.Cfg.userKey(l.storageKey), l.leaseID)
+			entries = append(entries, auditEntry{l.storageKey, l.leaseID, l.ownerID, AuditForceReleased, l.metadata})
+		}
+		i.audit(ctx, entries...)
+		if len(locks) < batchSize {
+			return removed, nil
+		}
+	}
+}
+
+// startSweeper runs CleanupExpired every SweepInterval until Close
+func (i *PostgresLockAdapter) startSweeper() {
+	ctx, cancel := context.WithCancel(context.Background())
+	i.stopSweeper = cancel
+
+	go func() {
+		ticker := time.NewTicker(i.Cfg.SweepInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// Failures are retried on the next tick
+				_, _ = i.CleanupExpired(ctx, i.Cfg.SweepGracePeriod, i.Cfg.SweepBatchSize)
+			}
+		}
+	}()
+}
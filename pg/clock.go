@@ -0,0 +1,44 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ServerTime returns the current time of the database server, measuring
+// the clock drift reported by ClockDrift on the way
+func (i *PostgresLockAdapter) ServerTime(ctx context.Context) (time.Time, error) {
+	if err := i.begin(); err != nil {
+		return time.Time{}, err
+	}
+	defer i.end()
+
+	start := time.Now()
+	var serverTime time.Time
+	if err := i.db.QueryRow(ctx, "SELECT clock_timestamp()").Scan(&serverTime); err != nil {
+		return time.Time{}, fmt.Errorf("failed to read server time: %w", err)
+	}
+	i.recordClockDrift(start, time.Since(start), serverTime)
+
+	return serverTime, nil
+}
+
+// ClockDrift returns the offset of the server clock to the local clock,
+// positive when the server is ahead, as last measured by ServerTime or
+// HealthCheck; 0 until measured. StartHealthMonitor keeps it up to date.
+//
+// Tokens returned by the adapter carry it as ClockOffset, so Remaining,
+// IsExpired and NeedsRefresh compare ValidUntil, set by the server
+// clock, against the server time.
+func (i *PostgresLockAdapter) ClockDrift() time.Duration {
+	return time.Duration(i.clockDrift.Load())
+}
+
+// recordClockDrift stores the drift measured by a query started at start
+// that took rtt, assuming the server read its clock halfway through
+func (i *PostgresLockAdapter) recordClockDrift(start time.Time, rtt time.Duration, serverTime time.Time) time.Duration {
+	drift := serverTime.Sub(start.Add(rtt / 2))
+	i.clockDrift.Store(int64(drift))
+	return drift
+}
@@ -0,0 +1,186 @@
+package pg_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/oliveiracleidson/go-lockbox/pg"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresLockAdapter_Closed(t *testing.T) {
+	// The pool never connects, a closed adapter must not reach it
+	pool, err := pgxpool.New(context.Background(), "postgres://lockbox@127.0.0.1:1/lockbox?connect_timeout=1")
+	require.NoError(t, err)
+
+	closed, err := pg.NewPostgresLockAdapter(pool, pg.NewPostgresLockerConfig())
+	require.NoError(t, err)
+	require.NoError(t, closed.Close(context.Background()))
+
+	ctx := context.Background()
+	token := &core.LockToken{Key: "key", LeaseID: "lease", ServerNonce: "nonce"}
+
+	t.Run("given a closed adapter, when acquire, then returns ErrAdapterClosed", func(t *testing.T) {
+		_, err := closed.Acquire(ctx, "key", core.LockOptions{TTL: time.Second})
+		require.ErrorIs(t, err, core.ErrAdapterClosed)
+
+		_, err = closed.AcquireTx(ctx, nil, "key", core.LockOptions{TTL: time.Second})
+		require.ErrorIs(t, err, core.ErrAdapterClosed)
+
+		_, failed := closed.AcquireBatch(ctx, []string{"key"}, core.LockOptions{TTL: time.Second})
+		require.ErrorIs(t, failed["key"], core.ErrAdapterClosed)
+	})
+
+	t.Run("given a closed adapter, when refresh, then returns ErrAdapterClosed", func(t *testing.T) {
+		_, err := closed.Refresh(ctx, token, time.Second)
+		require.ErrorIs(t, err, core.ErrAdapterClosed)
+
+		_, errs := closed.RefreshBatch(ctx, []*core.LockToken{token}, time.Second)
+		require.ErrorIs(t, errs[0], core.ErrAdapterClosed)
+
+		_, refreshed, err := closed.RefreshIfExpiring(ctx, token, time.Second, time.Second)
+		require.ErrorIs(t, err, core.ErrAdapterClosed)
+		require.False(t, refreshed)
+	})
+
+	t.Run("given a closed adapter, when release, then returns ErrAdapterClosed", func(t *testing.T) {
+		require.ErrorIs(t, closed.Release(ctx, token), core.ErrAdapterClosed)
+
+		_, err := closed.ReleaseAllByOwner(ctx, "owner")
+		require.ErrorIs(t, err, core.ErrAdapterClosed)
+
+		errs := closed.ReleaseMany(ctx, []*core.LockToken{token})
+		require.ErrorIs(t, errs[0], core.ErrAdapterClosed)
+
+		released, err := closed.ReleaseIfHeld(ctx, token)
+		require.ErrorIs(t, err, core.ErrAdapterClosed)
+		require.False(t, released)
+	})
+
+	t.Run("given a closed adapter, when transfer ownership, then returns ErrAdapterClosed", func(t *testing.T) {
+		transferred, err := closed.TransferOwnership(ctx, token, "worker-2")
+		require.ErrorIs(t, err, core.ErrAdapterClosed)
+		require.Nil(t, transferred)
+	})
+
+	t.Run("given a closed adapter, when query or clean up the audit log, then returns ErrAdapterClosed", func(t *testing.T) {
+		_, err := closed.QueryAudit(ctx, "key", time.Now().Add(-time.Hour), time.Now())
+		require.ErrorIs(t, err, core.ErrAdapterClosed)
+
+		_, err = closed.CleanupAudit(ctx, time.Hour, 100)
+		require.ErrorIs(t, err, core.ErrAdapterClosed)
+	})
+
+	t.Run("given a closed adapter, when is held, then returns ErrAdapterClosed", func(t *testing.T) {
+		_, _, err := closed.IsHeld(ctx, token)
+		require.ErrorIs(t, err, core.ErrAdapterClosed)
+
+		_, _, err = closed.IsKeyLocked(ctx, "key")
+		require.ErrorIs(t, err, core.ErrAdapterClosed)
+
+		require.ErrorIs(t, closed.AssertHeld(ctx, token), core.ErrAdapterClosed)
+	})
+
+	t.Run("given a closed adapter, when inspect locks, then returns ErrAdapterClosed", func(t *testing.T) {
+		_, err := closed.ContentionInfo(ctx, "key")
+		require.ErrorIs(t, err, core.ErrAdapterClosed)
+
+		_, err = closed.GetLockInfo(ctx, "key")
+		require.ErrorIs(t, err, core.ErrAdapterClosed)
+
+		_, err = closed.ListLocks(ctx)
+		require.ErrorIs(t, err, core.ErrAdapterClosed)
+	})
+
+	t.Run("given a closed adapter, when migrate, then returns ErrAdapterClosed", func(t *testing.T) {
+		_, err := closed.GetSchemaStatus(ctx)
+		require.ErrorIs(t, err, core.ErrAdapterClosed)
+
+		_, err = closed.PlanMigrations(ctx)
+		require.ErrorIs(t, err, core.ErrAdapterClosed)
+
+		_, err = closed.IndexStatus(ctx)
+		require.ErrorIs(t, err, core.ErrAdapterClosed)
+
+		require.ErrorIs(t, closed.ConvertToUnlogged(ctx), core.ErrAdapterClosed)
+		require.ErrorIs(t, closed.ConvertToLogged(ctx), core.ErrAdapterClosed)
+
+		require.ErrorIs(t, closed.PrepareDbForMigrations(ctx), core.ErrAdapterClosed)
+		require.ErrorIs(t, closed.RunMigrations(ctx), core.ErrAdapterClosed)
+		require.ErrorIs(t, closed.RollbackMigration(ctx, "v0.0.1"), core.ErrAdapterClosed)
+	})
+
+	t.Run("given a closed adapter, when warm up, then returns ErrAdapterClosed", func(t *testing.T) {
+		require.ErrorIs(t, closed.Warmup(ctx, 1), core.ErrAdapterClosed)
+	})
+
+	t.Run("given a closed adapter, when server time, then returns ErrAdapterClosed", func(t *testing.T) {
+		_, err := closed.ServerTime(ctx)
+		require.ErrorIs(t, err, core.ErrAdapterClosed)
+		require.Zero(t, closed.ClockDrift())
+	})
+
+	t.Run("given a closed adapter, when health check, then reports red", func(t *testing.T) {
+		report := closed.HealthCheck(ctx)
+		require.Equal(t, core.StatusRed, report.Status)
+		require.ErrorIs(t, report.Error, core.ErrAdapterClosed)
+	})
+
+	t.Run("given a closed adapter, when close again, then returns nil", func(t *testing.T) {
+		require.NoError(t, closed.Close(ctx))
+	})
+}
+
+func TestPostgresSessionLockAdapter_Closed(t *testing.T) {
+	pool, err := pgxpool.New(context.Background(), "postgres://lockbox@127.0.0.1:1/lockbox?connect_timeout=1")
+	require.NoError(t, err)
+
+	closed, err := pg.NewPostgresSessionLockAdapter(pool, pg.NewPostgresLockerConfig())
+	require.NoError(t, err)
+	require.NoError(t, closed.Close(context.Background()))
+	require.NoError(t, closed.Close(context.Background()))
+
+	ctx := context.Background()
+	token := &core.LockToken{Key: "key", LeaseID: "lease", ServerNonce: "nonce"}
+
+	t.Run("given a closed session adapter, when any operation, then returns ErrAdapterClosed", func(t *testing.T) {
+		_, err := closed.Acquire(ctx, "key", core.LockOptions{TTL: time.Second})
+		require.ErrorIs(t, err, core.ErrAdapterClosed)
+
+		_, err = closed.Refresh(ctx, token, time.Second)
+		require.ErrorIs(t, err, core.ErrAdapterClosed)
+
+		require.ErrorIs(t, closed.Release(ctx, token), core.ErrAdapterClosed)
+
+		_, _, err = closed.IsHeld(ctx, token)
+		require.ErrorIs(t, err, core.ErrAdapterClosed)
+
+		report := closed.HealthCheck(ctx)
+		require.Equal(t, core.StatusRed, report.Status)
+		require.ErrorIs(t, report.Error, core.ErrAdapterClosed)
+	})
+}
+
+func TestPostgresLockAdapter_Close_Events(t *testing.T) {
+	pool, err := pgxpool.New(context.Background(), "postgres://lockbox@127.0.0.1:1/lockbox?connect_timeout=1")
+	require.NoError(t, err)
+
+	t.Run("given events enabled, when close, then the channel is closed", func(t *testing.T) {
+		a, err := pg.NewPostgresLockAdapter(pool, pg.NewPostgresLockerConfig().SetEventBufferSize(8))
+		require.NoError(t, err)
+		require.NotNil(t, a.Events())
+		require.NoError(t, a.Close(context.Background()))
+
+		_, open := <-a.Events()
+		require.False(t, open)
+	})
+
+	t.Run("given events disabled, when events, then returns nil", func(t *testing.T) {
+		a, err := pg.NewPostgresLockAdapter(pool, pg.NewPostgresLockerConfig())
+		require.NoError(t, err)
+		require.Nil(t, a.Events())
+	})
+}
@@ -0,0 +1,81 @@
+package pg
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// compatBackend runs the statements with the simple protocol, see
+// CompatSimpleProtocol
+type compatBackend struct {
+	backend
+}
+
+func (b compatBackend) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return b.backend.Exec(ctx, sql, simpleArgs(args)...)
+}
+
+func (b compatBackend) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return b.backend.Query(ctx, sql, simpleArgs(args)...)
+}
+
+func (b compatBackend) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return b.backend.QueryRow(ctx, sql, simpleArgs(args)...)
+}
+
+func (b compatBackend) Begin(ctx context.Context) (pgx.Tx, error) {
+	tx, err := b.backend.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return compatTx{tx}, nil
+}
+
+// compatTx runs the statements of a transaction with the simple protocol
+type compatTx struct {
+	pgx.Tx
+}
+
+func (t compatTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return t.Tx.Exec(ctx, sql, simpleArgs(args)...)
+}
+
+func (t compatTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return t.Tx.Query(ctx, sql, simpleArgs(args)...)
+}
+
+func (t compatTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return t.Tx.QueryRow(ctx, sql, simpleArgs(args)...)
+}
+
+// onTx returns the querier of a caller transaction, honoring
+// CompatSimpleProtocol
+func (i *PostgresLockAdapter) onTx(tx pgx.Tx) querier {
+	if i.Cfg.CompatSimpleProtocol {
+		return compatTx{tx}
+	}
+	return tx
+}
+
+// simpleArgs prepends the simple protocol mode to the arguments of a
+// statement.
+//
+// The []byte arguments, all JSON, are sent as text: the simple protocol
+// would send them as bytea.
+func simpleArgs(args []any) []any {
+	simple := make([]any, 0, len(args)+1)
+	simple = append(simple, pgx.QueryExecModeSimpleProtocol)
+	for _, arg := range args {
+		if b, ok := arg.([]byte); ok {
+			if b == nil {
+				arg = nil
+			} else {
+				arg = string(b)
+			}
+		}
+		simple = append(simple, arg)
+	}
+	return simple
+}
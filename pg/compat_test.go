@@ -0,0 +1,21 @@
+package pg_test
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/oliveiracleidson/go-lockbox/pg"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimpleArgs(t *testing.T) {
+	t.Run("given JSON and other arguments, when simple args, then the mode leads and JSON is sent as text", func(t *testing.T) {
+		var noMetadata []byte
+		args := pg.SimpleArgs([]any{"key", int64(30), []byte(`{"a":"b"}`), noMetadata})
+		require.Equal(t, []any{pgx.QueryExecModeSimpleProtocol, "key", int64(30), `{"a":"b"}`, nil}, args)
+	})
+
+	t.Run("given no arguments, when simple args, then only the mode is set", func(t *testing.T) {
+		require.Equal(t, []any{pgx.QueryExecModeSimpleProtocol}, pg.SimpleArgs(nil))
+	})
+}
@@ -0,0 +1,107 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+var (
+	contentionInfoSQL = `
+	SELECT
+		valid_until > NOW() AS is_locked,
+		GREATEST(EXTRACT(EPOCH FROM (valid_until - NOW())), 0) AS remaining_ttl,
+		0 AS waiters
+	FROM %s
+	WHERE key = $1;`
+
+	// The queue of the FIFO mode holds the waiters of every process
+	contentionInfoFIFOSQL = `
+	SELECT
+		COALESCE(l.valid_until > NOW(), FALSE) AS is_locked,
+		COALESCE(GREATEST(EXTRACT(EPOCH FROM (l.valid_until - NOW())), 0), 0) AS remaining_ttl,
+		(
+			SELECT COUNT(*)
+			FROM %[2]s w
+			WHERE w.key = $1 AND w.expires_at > NOW()
+		) AS waiters
+	FROM (SELECT 1) AS one
+	LEFT JOIN %[1]s l ON l.key = $1;`
+)
+
+// ContentionInfo returns whether the key is held, its remaining TTL and
+// how many callers are currently retrying to acquire it. The query fails
+// with core.ErrOperationTimeout after DefaultRequestTimeout.
+//
+// The lock table stores only the current holder, so waiters of other
+// processes are not visible: Waiters counts the local waiters only,
+// unless FIFO is set. The FIFO queue holds the waiters of every process,
+// counted instead.
+//
+// Stats().Waiters reports the local waiters across every key.
+func (i *PostgresLockAdapter) ContentionInfo(ctx context.Context, key string) (*core.ContentionInfo, error) {
+	if err := i.begin(); err != nil {
+		return nil, err
+	}
+	defer i.end()
+
+	storageKey, err := i.Cfg.storageKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	queryCtx, cancel := i.withRequestTimeout(ctx)
+	defer cancel()
+
+	info := &core.ContentionInfo{Key: key}
+	var remainingTTL float64
+	var queued int
+
+	start := time.Now()
+	err = i.db.QueryRow(queryCtx,
+		i.sql.contentionInfo,
+		storageKey,
+	).Scan(&info.Held, &remainingTTL, &queued)
+	i.observe(start, err)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, timedOut(ctx, queryCtx, err)
+	}
+
+	info.Waiters = i.waiters(key)
+	if i.Cfg.FIFO {
+		info.Waiters = queued
+	}
+	if info.Held {
+		info.Remaining = time.Duration(remainingTTL * float64(time.Second))
+	}
+
+	return info, nil
+}
+
+// addWaiter registers a local waiter for the key and returns
+// a function that unregisters it
+func (i *PostgresLockAdapter) addWaiter(key string) func() {
+	i.waitersMu.Lock()
+	i.waitersByKey[key]++
+	i.waitersMu.Unlock()
+	i.stats.waiters.Add(1)
+
+	return func() {
+		i.stats.waiters.Add(-1)
+		i.waitersMu.Lock()
+		defer i.waitersMu.Unlock()
+		i.waitersByKey[key]--
+		if i.waitersByKey[key] <= 0 {
+			delete(i.waitersByKey, key)
+		}
+	}
+}
+
+func (i *PostgresLockAdapter) waiters(key string) int {
+	i.waitersMu.Lock()
+	defer i.waitersMu.Unlock()
+	return i.waitersByKey[key]
+}
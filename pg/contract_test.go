@@ -0,0 +1,140 @@
+package pg_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/oliveiracleidson/go-lockbox/core/locktest"
+	"github.com/oliveiracleidson/go-lockbox/pg"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresLockAdapter_Contract(t *testing.T) {
+	// The shared adapter is only migrated by the playbook
+	table, err := pg.NewPostgresLockAdapter(pgxPool, contractConfig("locker_contract_table"))
+	require.NoError(t, err)
+	migrateContract(t, table, "locker_contract_table")
+
+	locktest.Run(t, table, "contract-table")
+}
+
+func TestAdvisoryLockAdapter_Contract(t *testing.T) {
+	// Close closes the pool, keep it apart
+	pool, err := pgxpool.New(context.Background(), os.Getenv("DB_URL"))
+	require.NoError(t, err)
+
+	advisory, err := pg.NewAdvisoryLockAdapter(pool, pg.NewPostgresLockerConfig())
+	require.NoError(t, err)
+	defer advisory.Close(context.Background())
+
+	locktest.Run(t, advisory, "contract-advisory")
+}
+
+func TestPostgresLockAdapterFromDB_Contract(t *testing.T) {
+	db, err := sql.Open("pgx", os.Getenv("DB_URL"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	fromDB, err := pg.NewPostgresLockAdapterFromDB(db, contractConfig("locker_contract_db"))
+	require.NoError(t, err)
+	migrateContract(t, fromDB, "locker_contract_db")
+
+	locktest.Run(t, fromDB, "contract-db")
+
+	// The handle belongs to the caller
+	require.NoError(t, fromDB.Close(context.Background()))
+	require.NoError(t, db.Ping())
+}
+
+func TestPostgresLockAdapterFromConn_Contract(t *testing.T) {
+	conn, err := pgx.Connect(context.Background(), os.Getenv("DB_URL"))
+	require.NoError(t, err)
+	defer conn.Close(context.Background())
+
+	fromConn, err := pg.NewPostgresLockAdapterFromConn(conn, contractConfig("locker_contract_conn"))
+	require.NoError(t, err)
+	migrateContract(t, fromConn, "locker_contract_conn")
+
+	locktest.Run(t, fromConn, "contract-conn")
+
+	// The connection belongs to the caller
+	require.NoError(t, fromConn.Close(context.Background()))
+	require.NoError(t, conn.Ping(context.Background()))
+}
+
+func TestPostgresLockAdapterCompat_Contract(t *testing.T) {
+	for name, mode := range map[string]pgx.QueryExecMode{
+		// PgBouncer in transaction mode requires the simple protocol
+		"simple protocol pool": pgx.QueryExecModeSimpleProtocol,
+		// The adapter doesn't prepare statements on its own either
+		"caching pool": pgx.QueryExecModeCacheStatement,
+	} {
+		t.Run(name, func(t *testing.T) {
+			poolCfg, err := pgxpool.ParseConfig(os.Getenv("DB_URL"))
+			require.NoError(t, err)
+			poolCfg.ConnConfig.DefaultQueryExecMode = mode
+			pool, err := pgxpool.NewWithConfig(context.Background(), poolCfg)
+			require.NoError(t, err)
+
+			// Migrations run on a direct connection
+			migrator, err := pg.NewPostgresLockAdapter(pgxPool, contractConfig("locker_contract_compat"))
+			require.NoError(t, err)
+			migrateContract(t, migrator, "locker_contract_compat")
+
+			compat, err := pg.NewPostgresLockAdapter(pool, contractConfig("locker_contract_compat").SetCompatSimpleProtocol(true))
+			require.NoError(t, err)
+			defer compat.Close(context.Background())
+
+			locktest.Run(t, compat, "contract-compat")
+
+			_, err = compat.Acquire(context.Background(), "contract-compat-metadata", core.LockOptions{
+				TTL:      time.Minute,
+				Metadata: map[string]string{"job": "42"},
+			})
+			require.NoError(t, err)
+			info, err := compat.GetLockInfo(context.Background(), "contract-compat-metadata")
+			require.NoError(t, err)
+			require.Equal(t, "42", info.Metadata["job"])
+
+			// No connection of the pool got a prepared statement
+			conns := pool.AcquireAllIdle(context.Background())
+			require.NotEmpty(t, conns)
+			for _, conn := range conns {
+				var prepared int
+				err := conn.QueryRow(context.Background(),
+					"SELECT COUNT(*) FROM pg_prepared_statements",
+					pgx.QueryExecModeSimpleProtocol,
+				).Scan(&prepared)
+				require.NoError(t, err)
+				require.Zero(t, prepared)
+				conn.Release()
+			}
+		})
+	}
+}
+
+func contractConfig(schema string) *pg.PostgresLockerConfig {
+	return pg.NewPostgresLockerConfig().
+		SetMigrationSchema(schema).
+		SetLockSchema(schema)
+}
+
+// migrateContract migrates the schema of the adapter, dropped once the
+// test is done
+func migrateContract(t *testing.T, a *pg.PostgresLockAdapter, schema string) {
+	t.Helper()
+	require.NoError(t, a.PrepareDbForMigrations(context.Background()))
+	require.NoError(t, a.RunMigrations(context.Background()))
+
+	t.Cleanup(func() {
+		_, err := pgxPool.Exec(context.Background(), `DROP SCHEMA "`+schema+`" CASCADE`)
+		require.NoError(t, err)
+	})
+}
@@ -2,8 +2,96 @@ package pg
 
 import (
 	"errors"
+	"strings"
 )
 
 var (
 	ErrInvalidConfig = errors.New("invalid configuration")
+
+	// Rollbacks are disabled by the configuration
+	ErrRollbackDisabled = errors.New("migration rollbacks are disabled")
+
+	// The migration is unknown or not applied
+	ErrMigrationNotFound = errors.New("migration not found")
+
+	// A more recent migration must be rolled back first
+	ErrRollbackOutOfOrder = errors.New("migration rollback out of order")
+
+	// The migration has no down script
+	ErrNoDownMigration = errors.New("migration has no down script")
+
+	// An applied migration differs from the embedded one
+	ErrChecksumMismatch = errors.New("migration checksum mismatch")
+
+	// Another instance held the migration lock beyond MigrationLockTimeout
+	ErrMigrationLockTimeout = errors.New("migration lock timeout")
+
+	// The audit log is not enabled, see AuditTableName
+	ErrAuditDisabled = errors.New("audit log disabled")
+
+	// The schema in the database doesn't match the adapter
+	ErrSchemaMismatch = errors.New("lock schema mismatch")
+
+	// The database is migrated to a version the library doesn't support
+	ErrSchemaIncompatible = errors.New("lock schema version incompatible")
 )
+
+// FieldError is a problem of a field of PostgresLockerConfig, one of
+// the errors of a ConfigError.
+//
+// errors.Is matches a target *FieldError of the same Field, and of the
+// same Message unless the target leaves it empty:
+//
+//	if errors.Is(err, &pg.FieldError{Field: "LockTableName"}) {
+//	    ...
+//	}
+type FieldError struct {
+	Field   string // Name of the field, e.g. "LockTableName"
+	Message string // What is wrong, naming the field
+}
+
+func (e *FieldError) Error() string {
+	return e.Message
+}
+
+func (e *FieldError) Is(target error) bool {
+	t, ok := target.(*FieldError)
+	return ok && t.Field == e.Field && (t.Message == "" || t.Message == e.Message)
+}
+
+// ConfigError is returned by PostgresLockerConfig.Validate with every
+// problem found, so each can be shown next to its field.
+//
+// It wraps ErrInvalidConfig and each *FieldError, so errors.Is and
+// errors.As see them individually. Error combines them into a single
+// message for the logs.
+type ConfigError struct {
+	Errors []*FieldError
+}
+
+func (e *ConfigError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Message
+	}
+	return ErrInvalidConfig.Error() + ": " + strings.Join(msgs, ", ")
+}
+
+func (e *ConfigError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors)+1)
+	errs = append(errs, ErrInvalidConfig)
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// Field returns the problem of the field, nil if it is valid
+func (e *ConfigError) Field(name string) *FieldError {
+	for _, err := range e.Errors {
+		if err.Field == name {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+// Events returns the lock events of the adapter, in the order the
+// operations observed them, see EventBufferSize. The channel is closed by
+// Close, and nil when the events are disabled.
+//
+// Locks removed by ReleaseAllByOwner or CleanupExpired, without their
+// token, are reported as core.EventForceReleased.
+func (i *PostgresLockAdapter) Events() <-chan core.LockEvent {
+	return i.events.Events()
+}
+
+// emit sends a lock event when the events are enabled
+func (i *PostgresLockAdapter) emit(ctx context.Context, typ core.LockEventType, key, leaseID string) {
+	if i.events == nil {
+		return
+	}
+	i.events.Emit(ctx, core.LockEvent{Type: typ, Key: key, LeaseID: leaseID, At: time.Now()})
+}
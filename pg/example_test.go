@@ -0,0 +1,48 @@
+package pg_test
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/oliveiracleidson/go-lockbox/pg"
+)
+
+// Releases every lock of the worker when it receives SIGTERM
+func ExamplePostgresLockAdapter_ReleaseAllByOwner() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	adapter, err := pg.NewPostgresLockAdapter(pgxPool, pg.NewPostgresLockerConfig())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ownerID := "orders-worker-3"
+	for _, key := range []string{"orders-1", "orders-2"} {
+		_, err := adapter.Acquire(ctx, key, core.LockOptions{
+			TTL:           time.Minute,
+			RetryStrategy: core.RetryStrategy{BackoffFactor: 2},
+			OwnerID:       ownerID,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	<-ctx.Done()
+
+	// The signal context is done, release with a fresh one
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	released, err := adapter.ReleaseAllByOwner(shutdownCtx, ownerID)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("released %d locks", released)
+}
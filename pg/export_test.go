@@ -0,0 +1,50 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+// Exposes unexported helpers to the pg_test package
+var (
+	SplitStatements = splitStatements
+	SimpleArgs      = simpleArgs
+	EncodeMetadata  = encodeMetadata
+
+	CompareSchemaVersions = compareSchemaVersions
+)
+
+// HealthSignals mirrors healthSignals
+type HealthSignals struct {
+	ProbeErr      error
+	AcquiredConns int32
+	MaxConns      int32
+	Latency       time.Duration
+	ClockDrift    time.Duration
+	ErrorRate     float64
+}
+
+func HealthStatus(cfg *PostgresLockerConfig, s HealthSignals) (core.HealthStatus, error) {
+	return cfg.healthStatus(healthSignals{
+		probeErr:      s.ProbeErr,
+		acquiredConns: s.AcquiredConns,
+		maxConns:      s.MaxConns,
+		latency:       s.Latency,
+		clockDrift:    s.ClockDrift,
+		errorRate:     s.ErrorRate,
+	})
+}
+
+// LatestMigration returns the version of the last embedded migration
+func LatestMigration() string {
+	return migrationsData[len(migrationsData)-1].Version
+}
+
+// Exec runs sql like the statements of the lock operations, e.g. through
+// the statement timeout of StatementTimeout
+func (i *PostgresLockAdapter) Exec(ctx context.Context, sql string) error {
+	_, err := i.db.Exec(ctx, sql)
+	return err
+}
@@ -0,0 +1,45 @@
+package pg
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+// healthSignals are the measurements HealthCheck bases its status on
+type healthSignals struct {
+	probeErr      error // Failure of the probe query
+	acquiredConns int32
+	maxConns      int32
+	latency       time.Duration // Of the probe query
+	clockDrift    time.Duration
+	errorRate     float64 // Of the recent operations
+}
+
+// poolUsage returns the fraction of acquired pool connections
+func (s healthSignals) poolUsage() float64 {
+	if s.maxConns == 0 {
+		return 0
+	}
+	return float64(s.acquiredConns) / float64(s.maxConns)
+}
+
+// healthStatus decides the status of the signals and why it is not Green:
+// Red when the probe failed, Yellow when any threshold of the
+// configuration is exceeded.
+func (p *PostgresLockerConfig) healthStatus(s healthSignals) (core.HealthStatus, error) {
+	switch {
+	case s.probeErr != nil:
+		return core.StatusRed, fmt.Errorf("health probe failed: %w", s.probeErr)
+	case s.poolUsage() >= p.PoolHighWaterMark:
+		return core.StatusYellow, fmt.Errorf("pool saturated: %d/%d connections acquired", s.acquiredConns, s.maxConns)
+	case s.latency > p.LatencyThreshold:
+		return core.StatusYellow, fmt.Errorf("high latency: %v > %v", s.latency, p.LatencyThreshold)
+	case s.clockDrift.Abs() > p.ClockDriftThreshold:
+		return core.StatusYellow, fmt.Errorf("clock drift: %v > %v", s.clockDrift, p.ClockDriftThreshold)
+	case s.errorRate > p.ErrorRateThreshold:
+		return core.StatusYellow, fmt.Errorf("high error rate: %.2f > %.2f", s.errorRate, p.ErrorRateThreshold)
+	}
+	return core.StatusGreen, nil
+}
@@ -0,0 +1,69 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+// StartHealthMonitor runs HealthCheck every HealthCheckInterval in a
+// background goroutine, caching the report returned by LastHealth.
+//
+// The first probe runs immediately. The loop stops when ctx is done or
+// the adapter is closed, dropping the cached report so a stale one never
+// fails acquisitions; it is meant to be started once.
+func (p *PostgresLockAdapter) StartHealthMonitor(ctx context.Context) {
+	p.probeHealth(ctx)
+
+	go func() {
+		ticker := time.NewTicker(p.Cfg.HealthCheckInterval)
+		defer ticker.Stop()
+		defer p.lastHealth.Store(nil)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if p.closed.Load() {
+					return
+				}
+				p.probeHealth(ctx)
+			}
+		}
+	}()
+}
+
+// LastHealth returns the latest report of the health monitor without
+// querying the database; false until StartHealthMonitor has probed once.
+func (p *PostgresLockAdapter) LastHealth() (core.HealthReport, bool) {
+	report := p.lastHealth.Load()
+	if report == nil {
+		return core.HealthReport{}, false
+	}
+	return *report, true
+}
+
+func (p *PostgresLockAdapter) probeHealth(ctx context.Context) {
+	report := p.HealthCheck(ctx)
+	// A probe aborted by the shutdown of the monitor says nothing
+	// about the database
+	if ctx.Err() != nil {
+		return
+	}
+	p.lastHealth.Store(&report)
+}
+
+// failFast refuses the operation while the monitor reports StatusRed
+func (p *PostgresLockAdapter) failFast() error {
+	if !p.Cfg.FailFastOnRed {
+		return nil
+	}
+	report, ok := p.LastHealth()
+	if !ok || report.Status != core.StatusRed {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", core.ErrBackendUnhealthy, report.Error)
+}
@@ -0,0 +1,132 @@
+package pg_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/oliveiracleidson/go-lockbox/pg"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresLockAdapter_HealthCheck_Unreachable(t *testing.T) {
+	// Nothing listens on port 1, every probe fails
+	pool, err := pgxpool.New(context.Background(), "postgres://lockbox@127.0.0.1:1/lockbox?connect_timeout=1")
+	require.NoError(t, err)
+	defer pool.Close()
+
+	unreachable, err := pg.NewPostgresLockAdapter(pool, pg.NewPostgresLockerConfig())
+	require.NoError(t, err)
+
+	t.Run("given an unreachable database, when health check, then reports red with the probe error", func(t *testing.T) {
+		before := time.Now()
+		report := unreachable.HealthCheck(context.Background())
+
+		require.Equal(t, core.StatusRed, report.Status)
+		require.ErrorContains(t, report.Error, "health probe failed")
+		require.Equal(t, report.Error, report.LastError)
+		require.False(t, report.LastErrorAt.Before(before))
+		require.NotNil(t, report.Pool)
+		require.Positive(t, report.Pool.MaxConns)
+		require.Zero(t, report.Pool.AcquiredConns)
+	})
+
+	t.Run("given a cancelled context, when health check, then the error wraps the context error", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		report := unreachable.HealthCheck(ctx)
+		require.Equal(t, core.StatusRed, report.Status)
+		require.ErrorIs(t, report.Error, context.Canceled)
+	})
+}
+
+func TestPostgresLockAdapter_StartHealthMonitor_Unreachable(t *testing.T) {
+	pool, err := pgxpool.New(context.Background(), "postgres://lockbox@127.0.0.1:1/lockbox?connect_timeout=1")
+	require.NoError(t, err)
+	defer pool.Close()
+
+	t.Run("given no monitor, when last health, then there is no report", func(t *testing.T) {
+		adapter, err := pg.NewPostgresLockAdapter(pool, pg.NewPostgresLockerConfig())
+		require.NoError(t, err)
+
+		_, ok := adapter.LastHealth()
+		require.False(t, ok)
+	})
+
+	t.Run("given fail fast on red, when the monitor reports red, then acquire fails without retrying", func(t *testing.T) {
+		adapter, err := pg.NewPostgresLockAdapter(pool, pg.NewPostgresLockerConfig().
+			SetHealthCheckInterval(time.Hour).
+			SetFailFastOnRed(true),
+		)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		adapter.StartHealthMonitor(ctx)
+
+		report, ok := adapter.LastHealth()
+		require.True(t, ok)
+		require.Equal(t, core.StatusRed, report.Status)
+
+		start := time.Now()
+		_, err = adapter.Acquire(context.Background(), "key", core.LockOptions{TTL: time.Second})
+		require.ErrorIs(t, err, core.ErrBackendUnhealthy)
+		require.Less(t, time.Since(start), 100*time.Millisecond)
+
+		cancel()
+		require.Eventually(t, func() bool {
+			_, ok := adapter.LastHealth()
+			return !ok
+		}, time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestPostgresLockerConfig_HealthStatus(t *testing.T) {
+	cfg := pg.NewPostgresLockerConfig()
+	healthy := pg.HealthSignals{
+		AcquiredConns: 2,
+		MaxConns:      10,
+		Latency:       10 * time.Millisecond,
+		ClockDrift:    time.Millisecond,
+		ErrorRate:     0.01,
+	}
+
+	t.Run("given signals within the thresholds, when decide, then reports green", func(t *testing.T) {
+		status, err := pg.HealthStatus(cfg, healthy)
+		require.Equal(t, core.StatusGreen, status)
+		require.NoError(t, err)
+	})
+
+	for _, tc := range []struct {
+		name   string
+		modify func(*pg.HealthSignals)
+		status core.HealthStatus
+		reason string
+	}{
+		{"a failed probe", func(s *pg.HealthSignals) { s.ProbeErr = context.DeadlineExceeded }, core.StatusRed, "health probe failed"},
+		{"the pool at its high water mark", func(s *pg.HealthSignals) { s.AcquiredConns = 8 }, core.StatusYellow, "pool saturated: 8/10"},
+		{"a slow probe", func(s *pg.HealthSignals) { s.Latency = time.Second }, core.StatusYellow, "high latency"},
+		{"a server clock behind", func(s *pg.HealthSignals) { s.ClockDrift = -3 * time.Second }, core.StatusYellow, "clock drift"},
+		{"failing operations", func(s *pg.HealthSignals) { s.ErrorRate = 0.2 }, core.StatusYellow, "high error rate: 0.20 > 0.05"},
+	} {
+		t.Run("given "+tc.name+", when decide, then reports the status and why", func(t *testing.T) {
+			signals := healthy
+			tc.modify(&signals)
+
+			status, err := pg.HealthStatus(cfg, signals)
+			require.Equal(t, tc.status, status)
+			require.ErrorContains(t, err, tc.reason)
+		})
+	}
+
+	t.Run("given the error rate at its threshold, when decide, then reports green", func(t *testing.T) {
+		signals := healthy
+		signals.ErrorRate = pg.DefaultErrorRateThreshold
+
+		status, _ := pg.HealthStatus(cfg, signals)
+		require.Equal(t, core.StatusGreen, status)
+	})
+}
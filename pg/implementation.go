@@ -2,16 +2,67 @@ package pg
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/oliveiracleidson/go-lockbox/core"
 )
 
 type PostgresLockAdapter struct {
-	pool *pgxpool.Pool
-	Cfg  *PostgresLockerConfig
+	// Connections the statements run on, see backend
+	db backend
+
+	// Read only once the adapter is created, the lock operations run
+	// SQL rendered from it by NewPostgresLockAdapter
+	Cfg *PostgresLockerConfig
+
+	// Local waiters per key, see ContentionInfo
+	waitersMu    sync.Mutex
+	waitersByKey map[string]int
+
+	// Reported by HealthCheck
+	startedAt time.Time
+	latencies *core.LatencyWindow
+
+	// Last failed HealthCheck probe
+	healthMu    sync.Mutex
+	lastErr     error
+	lastErrTime time.Time
+
+	// Latest report of the health monitor, see LastHealth
+	lastHealth atomic.Pointer[core.HealthReport]
+
+	// Latest measured offset of the server clock, see ClockDrift
+	clockDrift atomic.Int64
+
+	// Reported by Stats
+	stats stats
+
+	// Operations in flight, awaited by Close
+	closeMu  sync.RWMutex
+	closed   atomic.Bool
+	inFlight sync.WaitGroup
+
+	// Stops the expired lock sweeper, nil when disabled
+	stopSweeper context.CancelFunc
+
+	// Stops the pool metrics sampler, nil when disabled
+	stopPoolSampler context.CancelFunc
+
+	// Release notifications of the contended acquirers, see NotifyOnRelease
+	releases *releaseHub
+
+	// Lock events, nil when disabled, see Events
+	events *core.EventStream
+
+	// SQL rendered with the configured identifiers
+	sql queries
 }
 
 // NewPostgresLockAdapter cria uma nova instância do adapter PostgreSQL
@@ -19,51 +70,269 @@ func NewPostgresLockAdapter(
 	pool *pgxpool.Pool,
 	cfg *PostgresLockerConfig,
 ) (*PostgresLockAdapter, error) {
+	return newPostgresLockAdapter(poolBackend{pool}, cfg)
+}
+
+// NewPostgresLockAdapterFromConn creates an adapter running on a single
+// connection, for services that manage one instead of a pool.
+//
+// The operations take turns on the connection, so they queue up under
+// load. NotifyOnRelease is not supported, as it keeps a connection
+// listening. Close leaves the connection open to its owner.
+func NewPostgresLockAdapterFromConn(
+	conn *pgx.Conn,
+	cfg *PostgresLockerConfig,
+) (*PostgresLockAdapter, error) {
+	if cfg.NotifyOnRelease {
+		return nil, errors.New("NotifyOnRelease is not supported on a single connection")
+	}
+	return newPostgresLockAdapter(newConnBackend(conn), cfg)
+}
+
+// NewPostgresLockAdapterFromDB creates an adapter running on a
+// database/sql handle, e.g. shared with sqlx, which must be opened with
+// the pgx stdlib driver (sql.Open("pgx", ...) or stdlib.OpenDB).
+//
+// Every operation holds one connection of the handle. Close leaves the
+// handle open to its owner.
+func NewPostgresLockAdapterFromDB(
+	db *sql.DB,
+	cfg *PostgresLockerConfig,
+) (*PostgresLockAdapter, error) {
+	b, err := newDBBackend(db)
+	if err != nil {
+		return nil, err
+	}
+	return newPostgresLockAdapter(b, cfg)
+}
+
+func newPostgresLockAdapter(db backend, cfg *PostgresLockerConfig) (*PostgresLockAdapter, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.CompatSimpleProtocol {
+		db = compatBackend{db}
+	}
+	if cfg.StatementTimeout {
+		db = timeoutBackend{db}
+	}
+
 	r := &PostgresLockAdapter{
-		Cfg:  cfg,
-		pool: pool,
+		Cfg:          cfg,
+		db:           db,
+		waitersByKey: map[string]int{},
+		startedAt:    time.Now(),
+		latencies:    core.NewLatencyWindow(core.DefaultLatencyWindowSize),
+		releases:     newReleaseHub(db),
+		sql:          newQueries(cfg),
+	}
+	if cfg.ValidateOnStart || cfg.CheckSchemaVersionOnStart {
+		ctx, cancel := context.WithTimeout(context.Background(), core.DefaultRequestTimeout)
+		defer cancel()
+		if cfg.CheckSchemaVersionOnStart {
+			if err := r.CheckCompatibility(ctx); err != nil {
+				return nil, err
+			}
+		}
+		if cfg.ValidateOnStart {
+			if err := r.VerifySchema(ctx); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if cfg.EventBufferSize > 0 {
+		r.events = core.NewEventStream(cfg.EventBufferSize, cfg.EventPolicy)
+	}
+	if cfg.SweepInterval > 0 {
+		r.startSweeper()
+	}
+	if cfg.PoolMetricsInterval > 0 {
+		r.startPoolSampler()
 	}
 
 	return r, nil
 }
 
-// Close the pgxPool
+// Close stops accepting operations, waits for the ones in flight and
+// closes the pgxPool. A connection or database/sql handle given to the
+// other constructors is left open.
+//
+// Operations started after Close return core.ErrAdapterClosed. If ctx
+// expires first, the pool is closed anyway, aborting the operations still
+// in flight, and the ctx error is returned. Closing twice is a no-op.
 func (p *PostgresLockAdapter) Close(ctx context.Context) error {
-	p.pool.Close()
+	p.closeMu.Lock()
+	alreadyClosed := p.closed.Swap(true)
+	p.closeMu.Unlock()
+	if alreadyClosed {
+		return nil
+	}
+	if p.stopSweeper != nil {
+		p.stopSweeper()
+	}
+	if p.stopPoolSampler != nil {
+		p.stopPoolSampler()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		p.inFlight.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("operations still in flight: %w", ctx.Err())
+	}
+
+	p.releases.close()
+	p.events.Close()
+	p.db.close()
+	return err
+}
+
+// begin registers an operation in flight, failing once the adapter is
+// closed. Every successful begin must be paired with an end.
+func (p *PostgresLockAdapter) begin() error {
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+	if p.closed.Load() {
+		return core.ErrAdapterClosed
+	}
+	p.inFlight.Add(1)
 	return nil
 }
 
+// end marks an operation started by begin as finished
+func (p *PostgresLockAdapter) end() {
+	p.inFlight.Done()
+}
+
+// withRequestTimeout bounds a statement without LockOptions by the
+// DefaultRequestTimeout of the config
+func (p *PostgresLockAdapter) withRequestTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, p.Cfg.requestTimeout())
+}
+
+// timedOut wraps with core.ErrOperationTimeout the error of a statement
+// cut by the timeout of queryCtx. The error of a ctx done beforehand,
+// cancelled or past its own deadline, is returned as is.
+func timedOut(ctx, queryCtx context.Context, err error) error {
+	if err == nil || ctx.Err() != nil || !errors.Is(queryCtx.Err(), context.DeadlineExceeded) ||
+		errors.Is(err, core.ErrOperationTimeout) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", core.ErrOperationTimeout, err)
+}
+
 // HealthCheck monitors service health.
-// Throughput is the number of acquired connections and
-// latency is the time taken to execute the query.
+// Latency is the average latency and Throughput the operations per second
+// of the recent Acquire, Release, Refresh and IsHeld calls; the latency of
+// the probe query itself is reported in Details["probe_latency"] and the
+// state of the connection pool in Pool.
+//
+// The status is Red when the probe query fails or the adapter is closed,
+// and Yellow when the pool usage reaches PoolHighWaterMark, the latency
+// exceeds LatencyThreshold, the clock drift, measured by the probe,
+// exceeds ClockDriftThreshold or the fraction of the recent operations
+// that failed exceeds ErrorRateThreshold.
 func (p *PostgresLockAdapter) HealthCheck(ctx context.Context) core.HealthReport {
+	if err := p.begin(); err != nil {
+		return core.HealthReport{Status: core.StatusRed, Error: err, Backend: "postgres"}
+	}
+	defer p.end()
+
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
 	start := time.Now()
 	var result int
-	err := p.pool.QueryRow(ctx, "SELECT 1").Scan(&result)
+	var serverVersion string
+	var serverTime time.Time
+	err := p.db.QueryRow(ctx, "SELECT 1, current_setting('server_version'), clock_timestamp()").Scan(&result, &serverVersion, &serverTime)
 	latency := time.Since(start) // Mede apenas o tempo da query
 
-	status := core.StatusGreen
-	var errMsg string
+	drift := p.ClockDrift()
+	if err == nil {
+		drift = p.recordClockDrift(start, latency, serverTime)
+	}
 
-	if err != nil || result != 1 {
-		status = core.StatusRed
-		if err != nil {
-			errMsg = err.Error() // Registrar erro
-		} else {
-			errMsg = "unexpected query result"
-		}
+	if err == nil && result != 1 {
+		err = errors.New("unexpected query result")
 	}
 
-	poolStats := p.pool.Stat()
-	throughput := int(poolStats.AcquiredConns())
+	poolStats := p.db.stat()
+	signals := healthSignals{
+		probeErr:      err,
+		acquiredConns: poolStats.AcquiredConns,
+		maxConns:      poolStats.MaxConns,
+		latency:       latency,
+		clockDrift:    drift,
+		errorRate:     p.latencies.ErrorRate(),
+	}
+	status, reportErr := p.Cfg.healthStatus(signals)
+	if status == core.StatusRed {
+		p.recordHealthError(reportErr)
+	}
+
+	lastErr, lastErrTime := p.lastHealthError()
+
+	pool := poolStats
+	pool.Usage = signals.poolUsage()
+
+	percentiles := p.latencies.Percentiles(50, 95, 99)
 
 	return core.HealthReport{
-		Status:     status,
-		Latency:    latency,
-		Throughput: float64(throughput),
-		Error:      errors.New(errMsg),
+		Status:      status,
+		Latency:     p.latencies.Average(),
+		Throughput:  p.latencies.Throughput(time.Now(), core.DefaultThroughputWindow),
+		ErrorRate:   signals.errorRate,
+		Error:       reportErr,
+		LastError:   lastErr,
+		LastErrorAt: lastErrTime,
+		LatencyP50:  percentiles[0],
+		LatencyP95:  percentiles[1],
+		LatencyP99:  percentiles[2],
+		Operations:  p.latencies.Total(),
+		Uptime:      time.Since(p.startedAt),
+		Backend:     "postgres",
+		Pool:        &pool,
+		Details: map[string]string{
+			"server_version": serverVersion,
+			"probe_latency":  latency.String(),
+			"clock_drift":    drift.String(),
+			"lock_schema":    p.Cfg.LockSchema,
+			"lock_table":     p.Cfg.LockTableName,
+		},
+	}
+}
+
+// recordHealthError keeps a failed probe, so intermittent failures are
+// visible in the reports of the following probes
+func (p *PostgresLockAdapter) recordHealthError(err error) {
+	p.healthMu.Lock()
+	defer p.healthMu.Unlock()
+	p.lastErr = err
+	p.lastErrTime = time.Now()
+}
+
+func (p *PostgresLockAdapter) lastHealthError() (error, time.Time) {
+	p.healthMu.Lock()
+	defer p.healthMu.Unlock()
+	return p.lastErr, p.lastErrTime
+}
+
+// observe records the latency of an operation started at start, and
+// whether its query failed.
+//
+// Finding no row is an outcome, and a ctx cancelled by the caller says
+// nothing about the database: neither counts as a failure.
+func (p *PostgresLockAdapter) observe(start time.Time, err error) {
+	if err == nil || errors.Is(err, pgx.ErrNoRows) || errors.Is(err, context.Canceled) {
+		p.latencies.Record(time.Since(start))
+		return
 	}
+	p.latencies.RecordFailure(time.Since(start))
 }
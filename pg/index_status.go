@@ -0,0 +1,87 @@
+package pg
+
+import (
+	"context"
+)
+
+var (
+	// An index whose concurrent build failed is left invalid, ignored by
+	// the planner until dropped and created again
+	indexStatusSQL = `
+	SELECT c.relname, i.indisvalid
+	FROM pg_index i
+	JOIN pg_class c ON c.oid = i.indexrelid
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	WHERE n.nspname = $1 AND c.relname = ANY($2);`
+)
+
+// IndexStatus is the state of an index of the lock table created by the
+// migrations
+type IndexStatus struct {
+	Name   string // Unquoted index name
+	Exists bool
+	Valid  bool // False when a concurrent build failed midway
+}
+
+// lockIndexes are the name suffixes of the indexes created by the
+// migrations, in migration order
+var lockIndexes = []string{"expiration", "lease", "owner", "metadata", "expiry_key", "key_expiry"}
+
+// requiredIndex tells whether the index of the suffix exists once the
+// migrations enabled by the config are applied
+func (p *PostgresLockerConfig) requiredIndex(suffix string) bool {
+	switch suffix {
+	case "expiration":
+		return false
+	case "metadata":
+		return p.MetadataIndex
+	}
+	return true
+}
+
+// IndexStatus reports which indexes of the lock table exist, e.g. to
+// check that the concurrent index migrations completed. The metadata
+// index only exists with MetadataIndex, and the expiration index is
+// dropped by v0.0.6-indexes, superseded by the expiry and key index.
+func (i *PostgresLockAdapter) IndexStatus(ctx context.Context) ([]IndexStatus, error) {
+	if err := i.begin(); err != nil {
+		return nil, err
+	}
+	defer i.end()
+
+	return i.indexStatus(ctx)
+}
+
+// indexStatus returns the status of the lockIndexes, in the same order
+func (i *PostgresLockAdapter) indexStatus(ctx context.Context) ([]IndexStatus, error) {
+	names := make([]string, len(lockIndexes))
+	for idx, suffix := range lockIndexes {
+		names[idx] = i.Cfg.lockIndexName(suffix)
+	}
+
+	rows, err := i.db.Query(ctx, indexStatusSQL, i.Cfg.LockSchema, names)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	valid := map[string]bool{}
+	for rows.Next() {
+		var name string
+		var isValid bool
+		if err := rows.Scan(&name, &isValid); err != nil {
+			return nil, err
+		}
+		valid[name] = isValid
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]IndexStatus, len(names))
+	for idx, name := range names {
+		isValid, exists := valid[name]
+		statuses[idx] = IndexStatus{Name: name, Exists: exists, Valid: isValid}
+	}
+	return statuses, nil
+}
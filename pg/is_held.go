@@ -3,7 +3,6 @@ package pg
 import (
 	"context"
 	"errors"
-	"fmt"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -15,16 +14,75 @@ var (
 	SELECT 
     	valid_until > NOW() AS is_locked,
     	EXTRACT(EPOCH FROM (valid_until - NOW())) AS remaining_ttl
-	FROM "%s"."%s"
+	FROM %s
+	WHERE
+		key = $1
+		AND lease_id = $2
+		AND server_nonce = $3;`
+
+	isKeyLockedSQL = `
+	SELECT 
+    	valid_until > NOW() AS is_locked,
+    	EXTRACT(EPOCH FROM (valid_until - NOW())) AS remaining_ttl
+	FROM %s
 	WHERE key = $1;`
 )
 
+// IsHeld reports whether the token still owns its lock and the remaining TTL.
+//
+// A lock that expired and was acquired by someone else is not held.
+// The statement fails with core.ErrOperationTimeout after
+// DefaultRequestTimeout.
 func (i *PostgresLockAdapter) IsHeld(ctx context.Context, token *core.LockToken) (bool, time.Duration, error) {
-	row := i.pool.QueryRow(ctx,
-		fmt.Sprintf(isHeldLockSQL, i.Cfg.LockSchema, i.Cfg.LockTableName),
-		token.Key,
-	)
+	if err := i.begin(); err != nil {
+		return false, 0, err
+	}
+	defer i.end()
+
+	storageKey, err := i.Cfg.storageKey(token.Key)
+	if err != nil {
+		return false, 0, err
+	}
+
+	queryCtx, cancel := i.withRequestTimeout(ctx)
+	defer cancel()
+
+	start := time.Now()
+	held, remaining, err := i.scanHeld(i.db.QueryRow(queryCtx,
+		i.sql.isHeld,
+		storageKey, token.LeaseID, token.ServerNonce,
+	))
+	i.observe(start, err)
+	return held, remaining, timedOut(ctx, queryCtx, err)
+}
 
+// IsKeyLocked reports whether anyone holds a lock on the key
+// and the remaining TTL, regardless of the owner. The statement fails
+// with core.ErrOperationTimeout after DefaultRequestTimeout.
+func (i *PostgresLockAdapter) IsKeyLocked(ctx context.Context, key string) (bool, time.Duration, error) {
+	if err := i.begin(); err != nil {
+		return false, 0, err
+	}
+	defer i.end()
+
+	storageKey, err := i.Cfg.storageKey(key)
+	if err != nil {
+		return false, 0, err
+	}
+
+	queryCtx, cancel := i.withRequestTimeout(ctx)
+	defer cancel()
+
+	start := time.Now()
+	held, remaining, err := i.scanHeld(i.db.QueryRow(queryCtx,
+		i.sql.isKeyLocked,
+		storageKey,
+	))
+	i.observe(start, err)
+	return held, remaining, timedOut(ctx, queryCtx, err)
+}
+
+func (i *PostgresLockAdapter) scanHeld(row pgx.Row) (bool, time.Duration, error) {
 	var isLocked bool
 	var remainingTTL float64
 
@@ -36,5 +94,10 @@ func (i *PostgresLockAdapter) IsHeld(ctx context.Context, token *core.LockToken)
 		return false, 0, err
 	}
 
-	return isLocked, time.Duration(remainingTTL) * time.Second, nil
+	remaining := time.Duration(remainingTTL * float64(time.Second))
+	if !isLocked || remaining <= 0 {
+		return false, 0, nil
+	}
+
+	return true, remaining, nil
 }
@@ -0,0 +1,186 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+var (
+	getLockInfoSQL = `
+	SELECT key, COALESCE(owner_id, ''), valid_until, metadata, acquired_at, ttl_ms, refresh_count
+	FROM %s
+	WHERE key = $1 AND valid_until > NOW();`
+
+	listLocksSQL = `
+	SELECT key, COALESCE(owner_id, ''), valid_until, metadata, acquired_at, ttl_ms, refresh_count
+	FROM %s
+	WHERE valid_until > NOW() AND LEFT(key, LENGTH($1)) = $1
+	ORDER BY key;`
+
+	// Rows written with a JSON null read as no metadata
+	readMetadataSQL = `
+	SELECT NULLIF(metadata, 'null'::JSONB)
+	FROM %s
+	WHERE key = $1 AND valid_until > NOW();`
+
+	findLocksByMetadataSQL = `
+	SELECT key, COALESCE(owner_id, ''), valid_until, metadata, acquired_at, ttl_ms, refresh_count
+	FROM %s
+	WHERE valid_until > NOW() AND LEFT(key, LENGTH($1)) = $1
+	AND metadata @> jsonb_build_object($2::TEXT, $3::TEXT)
+	ORDER BY key;`
+)
+
+// GetLockInfo returns the active lock of a key or core.ErrLockNotFound
+func (i *PostgresLockAdapter) GetLockInfo(ctx context.Context, key string) (*core.LockInfo, error) {
+	if err := i.begin(); err != nil {
+		return nil, err
+	}
+	defer i.end()
+
+	storageKey, err := i.Cfg.storageKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	row := i.db.QueryRow(ctx,
+		i.sql.getLockInfo,
+		storageKey,
+	)
+
+	info, err := i.scanLockInfo(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, core.ErrLockNotFound
+		}
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// ReadMetadata returns the metadata of the active lock of a key as
+// stored, e.g. set with LockOptions.MetadataJSON, nil when the lock has
+// none, or core.ErrLockNotFound
+func (i *PostgresLockAdapter) ReadMetadata(ctx context.Context, key string) (json.RawMessage, error) {
+	if err := i.begin(); err != nil {
+		return nil, err
+	}
+	defer i.end()
+
+	storageKey, err := i.Cfg.storageKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata []byte
+	err = i.db.QueryRow(ctx,
+		i.sql.readMetadata,
+		storageKey,
+	).Scan(&metadata)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, core.ErrLockNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
+
+// ListLocks returns all active locks of the namespace ordered by key
+func (i *PostgresLockAdapter) ListLocks(ctx context.Context) ([]core.LockInfo, error) {
+	if err := i.begin(); err != nil {
+		return nil, err
+	}
+	defer i.end()
+
+	rows, err := i.db.Query(ctx,
+		i.sql.listLocks,
+		i.namespacePrefix(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.collectLockInfos(rows)
+}
+
+// FindLocksByMetadata returns the active locks of the namespace whose
+// metadata maps key to value, ordered by key, e.g. the locks of the
+// "owner" "batch-worker-7".
+//
+// Without MetadataIndex the lookup scans the lock table.
+func (i *PostgresLockAdapter) FindLocksByMetadata(ctx context.Context, key, value string) ([]core.LockInfo, error) {
+	if err := i.begin(); err != nil {
+		return nil, err
+	}
+	defer i.end()
+
+	rows, err := i.db.Query(ctx,
+		i.sql.findByMetadata,
+		i.namespacePrefix(), key, value,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.collectLockInfos(rows)
+}
+
+// namespacePrefix returns the prefix of the storage keys of the namespace
+func (i *PostgresLockAdapter) namespacePrefix() string {
+	if i.Cfg.Namespace == "" {
+		return ""
+	}
+	return i.Cfg.Namespace + core.KeySeparator
+}
+
+// collectLockInfos scans and closes the rows of a lock listing
+func (i *PostgresLockAdapter) collectLockInfos(rows pgx.Rows) ([]core.LockInfo, error) {
+	defer rows.Close()
+
+	locks := []core.LockInfo{}
+	for rows.Next() {
+		info, err := i.scanLockInfo(rows)
+		if err != nil {
+			return nil, err
+		}
+		locks = append(locks, *info)
+	}
+
+	return locks, rows.Err()
+}
+
+// scanLockInfo scans a lock row, stripping the namespace from the key
+func (i *PostgresLockAdapter) scanLockInfo(row pgx.Row) (*core.LockInfo, error) {
+	info := &core.LockInfo{}
+	var metadata []byte
+	var ttlMs int64
+
+	err := row.Scan(&info.Key, &info.OwnerID, &info.ValidUntil, &metadata, &info.AcquiredAt, &ttlMs, &info.RefreshCount)
+	if err != nil {
+		return nil, err
+	}
+
+	info.TTL = time.Duration(ttlMs) * time.Millisecond
+
+	info.Key = i.Cfg.userKey(info.Key)
+
+	if len(metadata) > 0 {
+		info.MetadataJSON = metadata
+		info.Metadata = core.DecodeMetadata(metadata)
+	}
+
+	// A key stored under its hash, see HashLongKeys
+	if original, ok := info.Metadata[OriginalKeyMetadata]; ok && info.Key == core.HashKey(original) {
+		info.Key = original
+	}
+
+	return info, nil
+}
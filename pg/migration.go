@@ -2,17 +2,27 @@ package pg
 
 import (
 	"context"
+	"crypto/sha256"
 	"embed"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/oliveiracleidson/go-lockbox/core"
 )
 
 type migrationData struct {
-	Version     string
-	FileName    string
-	Transaction bool
+	Version      string
+	FileName     string
+	Transaction  bool
+	DownFileName string // Optional, reverts the migration in a transaction
+
+	// Optional, applies the migration only when it returns true
+	Enabled func(cfg *PostgresLockerConfig) bool
 }
 
 // Migrations File
@@ -20,16 +30,55 @@ var (
 	//go:embed migrations/*.sql
 	migrationsEmbed embed.FS
 	migrationsData  = []migrationData{
-		{Version: "v0.0.1", FileName: "migrations/v0.0.1.sql", Transaction: true},
-		{Version: "v0.0.1-indexes", FileName: "migrations/v0.0.1-indexes.sql", Transaction: false},
+		{Version: "v0.0.1", FileName: "migrations/v0.0.1.sql", Transaction: true, DownFileName: "migrations/v0.0.1.down.sql"},
+		{Version: "v0.0.1-indexes", FileName: "migrations/v0.0.1-indexes.sql", Transaction: false, DownFileName: "migrations/v0.0.1-indexes.down.sql"},
+		{Version: "v0.0.1-names", FileName: "migrations/v0.0.1-names.sql", Transaction: true, DownFileName: "migrations/v0.0.1-names.down.sql"},
+		{Version: "v0.0.2", FileName: "migrations/v0.0.2.sql", Transaction: true, DownFileName: "migrations/v0.0.2.down.sql"},
+		{Version: "v0.0.2-indexes", FileName: "migrations/v0.0.2-indexes.sql", Transaction: false, DownFileName: "migrations/v0.0.2-indexes.down.sql"},
+		{Version: "v0.0.3", FileName: "migrations/v0.0.3.sql", Transaction: true, DownFileName: "migrations/v0.0.3.down.sql"},
+		{Version: "v0.0.4", FileName: "migrations/v0.0.4.sql", Transaction: true, DownFileName: "migrations/v0.0.4.down.sql"},
+		{Version: "v0.0.6", FileName: "migrations/v0.0.6.sql", Transaction: true, DownFileName: "migrations/v0.0.6.down.sql"},
+		{Version: "v0.0.6-metadata-index", FileName: "migrations/v0.0.6-metadata-index.sql", Transaction: false, DownFileName: "migrations/v0.0.6-metadata-index.down.sql", Enabled: func(cfg *PostgresLockerConfig) bool { return cfg.MetadataIndex }},
+		{Version: "v0.0.6-indexes", FileName: "migrations/v0.0.6-indexes.sql", Transaction: false, DownFileName: "migrations/v0.0.6-indexes.down.sql"},
+		{Version: "v0.0.7", FileName: "migrations/v0.0.7.sql", Transaction: true, DownFileName: "migrations/v0.0.7.down.sql"},
+		{Version: "v0.0.8", FileName: "migrations/v0.0.8.sql", Transaction: true, DownFileName: "migrations/v0.0.8.down.sql"},
+		{Version: "v0.0.8-audit", FileName: "migrations/v0.0.8-audit.sql", Transaction: true, DownFileName: "migrations/v0.0.8-audit.down.sql", Enabled: func(cfg *PostgresLockerConfig) bool { return cfg.AuditTableName != "" }},
 	}
 )
 
-type schemaStatus struct {
+// migrations returns the migrations enabled by the configuration.
+//
+// Rollbacks and checksums consider every migration instead, an opt-in
+// migration applied before being disabled is still known.
+func (i *PostgresLockAdapter) migrations() []migrationData {
+	enabled := make([]migrationData, 0, len(migrationsData))
+	for _, migration := range migrationsData {
+		if migration.Enabled == nil || migration.Enabled(i.Cfg) {
+			enabled = append(enabled, migration)
+		}
+	}
+	return enabled
+}
+
+// SchemaStatus describes what of the schema of the adapter exists in the
+// database, see GetSchemaStatus
+type SchemaStatus struct {
 	MigrationSchemaExists bool
 	MigrationTableExists  bool
 	LockSchemaExists      bool
 	LockTableExists       bool
+	LockTableUnlogged     bool // Only meaningful when LockTableExists
+
+	AppliedVersions      []string // Applied migrations, in migration order
+	PendingVersions      []string // Migrations enabled by the config and not applied yet
+	TryAcquireLockExists bool     // Whether the acquisition function exists
+	IndexesExist         bool     // Whether every index the config needs exists and is valid
+}
+
+// Ready tells whether the schema is fully migrated, e.g. for a readiness
+// probe failing fast until RunMigrations completed
+func (s *SchemaStatus) Ready() bool {
+	return s.LockTableExists && s.TryAcquireLockExists && s.IndexesExist && len(s.PendingVersions) == 0
 }
 
 // Queries
@@ -50,18 +99,36 @@ var (
 		AND table_name = $2
 	);
 	`
+	tableUnloggedQuery = `
+	SELECT relpersistence = 'u'
+	FROM pg_class
+	WHERE oid = to_regclass($1);`
+	functionExistsQuery = `
+	SELECT EXISTS (
+		SELECT 1
+		FROM pg_proc p
+		JOIN pg_namespace n ON n.oid = p.pronamespace
+		WHERE n.nspname = $1
+		AND p.proname = $2
+	);`
 )
 
-// Returns the status of existance of the migration and lock schemas and tables
-func (i *PostgresLockAdapter) GetSchemaStatus(ctx context.Context) (*schemaStatus, error) {
-	status := &schemaStatus{
+// Returns the status of existance of the migration and lock schemas and
+// tables, along with the applied migrations and the objects they create
+func (i *PostgresLockAdapter) GetSchemaStatus(ctx context.Context) (*SchemaStatus, error) {
+	if err := i.begin(); err != nil {
+		return nil, err
+	}
+	defer i.end()
+
+	status := &SchemaStatus{
 		MigrationSchemaExists: false,
 		MigrationTableExists:  false,
 		LockSchemaExists:      false,
 		LockTableExists:       false,
 	}
 
-	rows := i.pool.QueryRow(
+	rows := i.db.QueryRow(
 		ctx,
 		schemaExistsQuery,
 		i.Cfg.MigrationSchema,
@@ -76,7 +143,7 @@ func (i *PostgresLockAdapter) GetSchemaStatus(ctx context.Context) (*schemaStatu
 		status.LockSchemaExists = status.MigrationSchemaExists
 	}
 
-	rows = i.pool.QueryRow(
+	rows = i.db.QueryRow(
 		ctx,
 		tableExistsQuery,
 		i.Cfg.MigrationSchema,
@@ -90,7 +157,7 @@ func (i *PostgresLockAdapter) GetSchemaStatus(ctx context.Context) (*schemaStatu
 	}
 
 	if i.Cfg.LockSchema != i.Cfg.MigrationSchema {
-		rows = i.pool.QueryRow(
+		rows = i.db.QueryRow(
 			ctx,
 			schemaExistsQuery,
 			i.Cfg.LockSchema,
@@ -103,7 +170,7 @@ func (i *PostgresLockAdapter) GetSchemaStatus(ctx context.Context) (*schemaStatu
 		}
 	}
 
-	rows = i.pool.QueryRow(
+	rows = i.db.QueryRow(
 		ctx,
 		tableExistsQuery,
 		i.Cfg.LockSchema,
@@ -116,15 +183,75 @@ func (i *PostgresLockAdapter) GetSchemaStatus(ctx context.Context) (*schemaStatu
 		}
 	}
 
+	if status.LockTableExists {
+		err = i.db.QueryRow(
+			ctx,
+			tableUnloggedQuery,
+			i.Cfg.lockTable(),
+		).Scan(&status.LockTableUnlogged)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	applied, err := i.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status.AppliedVersions = []string{}
+	for _, migration := range migrationsData {
+		if applied[migration.Version] {
+			status.AppliedVersions = append(status.AppliedVersions, migration.Version)
+		}
+	}
+	status.PendingVersions = []string{}
+	for _, migration := range i.migrations() {
+		if !applied[migration.Version] {
+			status.PendingVersions = append(status.PendingVersions, migration.Version)
+		}
+	}
+
+	err = i.db.QueryRow(
+		ctx,
+		functionExistsQuery,
+		i.Cfg.LockSchema,
+		i.Cfg.tryAcquireLockName(),
+	).Scan(&status.TryAcquireLockExists)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes, err := i.indexStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status.IndexesExist = true
+	for idx, index := range indexes {
+		if i.Cfg.requiredIndex(lockIndexes[idx]) && !(index.Exists && index.Valid) {
+			status.IndexesExist = false
+		}
+	}
+
 	return status, nil
 }
 
 func (i *PostgresLockAdapter) PrepareDbForMigrations(ctx context.Context) error {
+	if err := i.begin(); err != nil {
+		return err
+	}
+	defer i.end()
+
 	if !i.Cfg.CreateSchemasIfNotExists {
 		return nil
 	}
 
-	err := i.createMigrationSchema(ctx)
+	unlock, err := i.lockMigrations(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	err = i.createMigrationSchema(ctx)
 	if err != nil {
 		return err
 	}
@@ -141,15 +268,139 @@ func (i *PostgresLockAdapter) PrepareDbForMigrations(ctx context.Context) error
 	return nil
 }
 
-func (i *PostgresLockAdapter) RunMigrations(ctx context.Context) error {
-	for _, migration := range migrationsData {
-		err := i.runMigration(ctx, migration)
+// MigrationPlanEntry describes an embedded migration
+// not yet applied to the database
+type MigrationPlanEntry struct {
+	Version     string
+	FileName    string
+	Transaction bool
+	SQL         string // Rendered with the configured schema and table
+}
+
+// PlanMigrations returns the ordered list of migrations that RunMigrations
+// would apply, without executing anything.
+//
+// If the migration table doesn't exist yet, every migration is pending.
+func (i *PostgresLockAdapter) PlanMigrations(ctx context.Context) ([]MigrationPlanEntry, error) {
+	if err := i.begin(); err != nil {
+		return nil, err
+	}
+	defer i.end()
+
+	applied, err := i.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []MigrationPlanEntry{}
+	for _, migration := range i.migrations() {
+		if applied[migration.Version] {
+			continue
+		}
+		sql, err := i.renderedMigration(migration)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		pending = append(pending, MigrationPlanEntry{
+			Version:     migration.Version,
+			FileName:    migration.FileName,
+			Transaction: migration.Transaction,
+			SQL:         sql,
+		})
 	}
 
-	return nil
+	return pending, nil
+}
+
+// appliedMigrations returns the set of versions in the migration table
+func (i *PostgresLockAdapter) appliedMigrations(ctx context.Context) (map[string]bool, error) {
+	applied := map[string]bool{}
+
+	var tableExists bool
+	err := i.db.QueryRow(
+		ctx,
+		tableExistsQuery,
+		i.Cfg.MigrationSchema,
+		i.Cfg.MigrationTableName,
+	).Scan(&tableExists)
+	if err != nil {
+		return nil, err
+	}
+	if !tableExists {
+		return applied, nil
+	}
+
+	rows, err := i.db.Query(ctx, "SELECT version FROM "+i.Cfg.migrationTable())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+
+	return applied, rows.Err()
+}
+
+// MigrationSummary lists the versions applied and skipped by ApplyMigrations
+type MigrationSummary struct {
+	Applied []string // Versions executed by this run
+	Skipped []string // Versions already recorded in the migration table
+}
+
+// RunMigrations applies the pending migrations, see ApplyMigrations
+func (i *PostgresLockAdapter) RunMigrations(ctx context.Context) error {
+	_, err := i.ApplyMigrations(ctx)
+	return err
+}
+
+// ApplyMigrations applies, in order, the migrations not yet recorded in
+// the migration table, so running it again is a no-op.
+//
+// The checksum of every applied migration is verified first: if an
+// embedded migration changed since it was applied, nothing runs and the
+// error wraps ErrChecksumMismatch. See RepairChecksums.
+func (i *PostgresLockAdapter) ApplyMigrations(ctx context.Context) (*MigrationSummary, error) {
+	if err := i.begin(); err != nil {
+		return nil, err
+	}
+	defer i.end()
+
+	unlock, err := i.lockMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if err := i.addChecksumColumn(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := i.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := i.verifyChecksums(ctx, applied); err != nil {
+		return nil, err
+	}
+
+	summary := &MigrationSummary{Applied: []string{}, Skipped: []string{}}
+	for _, migration := range i.migrations() {
+		if applied[migration.Version] {
+			summary.Skipped = append(summary.Skipped, migration.Version)
+			continue
+		}
+		if err := i.runMigration(ctx, migration); err != nil {
+			return summary, fmt.Errorf("migration %s: %w", migration.Version, err)
+		}
+		summary.Applied = append(summary.Applied, migration.Version)
+	}
+
+	return summary, nil
 }
 
 func (i *PostgresLockAdapter) runMigration(ctx context.Context, migration migrationData) error {
@@ -162,31 +413,27 @@ func (i *PostgresLockAdapter) runMigration(ctx context.Context, migration migrat
 		return err
 	}
 
-	sql := string(migrationData)
-	sql = strings.ReplaceAll(sql, "{{ LockSchema }}", i.Cfg.LockSchema)
-	sql = strings.ReplaceAll(sql, "{{ LockTable }}", i.Cfg.LockTableName)
+	sql := i.renderMigration(migrationData)
 
-	conn, err := i.pool.Acquire(ctx)
+	conn, release, err := i.db.acquire(ctx)
 	if err != nil {
 		return err
 	}
 
-	defer conn.Release()
+	defer release(false)
 
-	// split by ;
-	queries := strings.Split(sql, ";")
-	for _, query := range queries {
-		rows := conn.QueryRow(ctx, query)
-		err = rows.Scan()
-		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
-			return err
+	// Run the statements one by one, CREATE INDEX CONCURRENTLY
+	// cannot run in the implicit transaction of a multi-statement query
+	for _, statement := range splitStatements(sql) {
+		if _, err := conn.Exec(ctx, statement); err != nil {
+			return fmt.Errorf("%s: %w", migration.Version, err)
 		}
 	}
 
 	_, err = conn.Exec(
 		ctx,
-		"INSERT INTO "+i.Cfg.MigrationSchema+"."+i.Cfg.MigrationTableName+" (version) VALUES ($1)",
-		migration.Version,
+		"INSERT INTO "+i.Cfg.migrationTable()+" (version, checksum) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		migration.Version, migrationChecksum(sql),
 	)
 	if err != nil {
 		return err
@@ -195,8 +442,34 @@ func (i *PostgresLockAdapter) runMigration(ctx context.Context, migration migrat
 	return nil
 }
 
+// renderMigration replaces the template placeholders of a migration file
+// with the sanitized identifiers of the configuration
+func (i *PostgresLockAdapter) renderMigration(migrationData []byte) string {
+	sql := string(migrationData)
+	sql = strings.ReplaceAll(sql, "{{ Unlogged }}", i.Cfg.tablePersistence())
+	sql = strings.ReplaceAll(sql, "{{ LockSchema }}", i.Cfg.lockSchema())
+	sql = strings.ReplaceAll(sql, "{{ LockTable }}", i.Cfg.lockTable())
+	sql = strings.ReplaceAll(sql, "{{ LockKeyCheck }}", i.Cfg.lockKeyCheck())
+	sql = strings.ReplaceAll(sql, "{{ LockWaitersTable }}", i.Cfg.lockWaitersTable())
+	sql = strings.ReplaceAll(sql, "{{ LockHealthView }}", i.Cfg.lockHealthView())
+	sql = strings.ReplaceAll(sql, "{{ LockHealthViewName }}", pgx.Identifier{i.Cfg.lockHealthViewName()}.Sanitize())
+	sql = strings.ReplaceAll(sql, "{{ TryAcquireLockName }}", pgx.Identifier{i.Cfg.tryAcquireLockName()}.Sanitize())
+	sql = strings.ReplaceAll(sql, "{{ LockExpirationIndex }}", i.Cfg.lockIndex("expiration"))
+	sql = strings.ReplaceAll(sql, "{{ LockLeaseIndex }}", i.Cfg.lockIndex("lease"))
+	sql = strings.ReplaceAll(sql, "{{ LockOwnerIndex }}", i.Cfg.lockIndex("owner"))
+	sql = strings.ReplaceAll(sql, "{{ LockMetadataIndex }}", i.Cfg.lockIndex("metadata"))
+	sql = strings.ReplaceAll(sql, "{{ LockExpiryKeyIndex }}", i.Cfg.lockIndex("expiry_key"))
+	sql = strings.ReplaceAll(sql, "{{ LockKeyExpiryIndex }}", i.Cfg.lockIndex("key_expiry"))
+	sql = strings.ReplaceAll(sql, "{{ AuditTable }}", i.Cfg.auditTable())
+	sql = strings.ReplaceAll(sql, "{{ AuditKeyAtIndex }}", i.Cfg.auditIndex("key_at"))
+	sql = strings.ReplaceAll(sql, "{{ AuditAtIndex }}", i.Cfg.auditIndex("at"))
+	sql = strings.ReplaceAll(sql, "{{ TryAcquireLockFIFO }}", i.Cfg.tryAcquireLockFIFO())
+	sql = strings.ReplaceAll(sql, "{{ TryAcquireLock }}", i.Cfg.tryAcquireLock())
+	return sql
+}
+
 func (i *PostgresLockAdapter) runMigrationTransaction(ctx context.Context, migration migrationData) error {
-	tx, err := i.pool.Begin(ctx)
+	tx, err := i.db.Begin(ctx)
 	if err != nil {
 		return err
 	}
@@ -207,9 +480,7 @@ func (i *PostgresLockAdapter) runMigrationTransaction(ctx context.Context, migra
 		return err
 	}
 
-	sql := string(migrationData)
-	sql = strings.ReplaceAll(sql, "{{ LockSchema }}", i.Cfg.LockSchema)
-	sql = strings.ReplaceAll(sql, "{{ LockTable }}", i.Cfg.LockTableName)
+	sql := i.renderMigration(migrationData)
 	_, err = tx.Exec(ctx, sql)
 	if err != nil {
 		return err
@@ -217,7 +488,139 @@ func (i *PostgresLockAdapter) runMigrationTransaction(ctx context.Context, migra
 
 	_, err = tx.Exec(
 		ctx,
-		"INSERT INTO "+i.Cfg.MigrationSchema+"."+i.Cfg.MigrationTableName+" (version) VALUES ($1)",
+		"INSERT INTO "+i.Cfg.migrationTable()+" (version, checksum) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		migration.Version, migrationChecksum(sql),
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RollbackMigration reverts the given migration, running its down script
+// and removing its version from the migration table in a transaction.
+//
+// Only the most recently applied migration can be rolled back, so
+// migrations are reverted in the reverse order they were applied;
+// ForceRollbackMigration skips this check.
+// Returns ErrRollbackDisabled when PostgresLockerConfig.DisableRollbacks is set.
+func (i *PostgresLockAdapter) RollbackMigration(ctx context.Context, version string) error {
+	return i.rollbackMigration(ctx, version, false)
+}
+
+// ForceRollbackMigration is RollbackMigration without the ordering check,
+// the more recent migrations stay applied.
+//
+// It exists to recover from a failed upgrade by hand, the down script
+// may break the migrations applied after it.
+func (i *PostgresLockAdapter) ForceRollbackMigration(ctx context.Context, version string) error {
+	return i.rollbackMigration(ctx, version, true)
+}
+
+// RollbackAll reverts every applied migration, most recent first,
+// each in its own transaction.
+//
+// It stops at the first failure, leaving the older migrations applied.
+func (i *PostgresLockAdapter) RollbackAll(ctx context.Context) error {
+	if i.Cfg.DisableRollbacks {
+		return ErrRollbackDisabled
+	}
+	if err := i.begin(); err != nil {
+		return err
+	}
+	defer i.end()
+
+	unlock, err := i.lockMigrations(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	applied, err := i.appliedMigrations(ctx)
+	if err != nil {
+		return err
+	}
+
+	for idx := len(migrationsData) - 1; idx >= 0; idx-- {
+		migration := migrationsData[idx]
+		if !applied[migration.Version] {
+			continue
+		}
+		if err := i.runDownMigration(ctx, migration); err != nil {
+			return fmt.Errorf("failed to roll back %s: %w", migration.Version, err)
+		}
+	}
+
+	return nil
+}
+
+func (i *PostgresLockAdapter) rollbackMigration(ctx context.Context, version string, force bool) error {
+	if i.Cfg.DisableRollbacks {
+		return ErrRollbackDisabled
+	}
+	if err := i.begin(); err != nil {
+		return err
+	}
+	defer i.end()
+
+	unlock, err := i.lockMigrations(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	applied, err := i.appliedMigrations(ctx)
+	if err != nil {
+		return err
+	}
+
+	var last, target *migrationData
+	for idx := range migrationsData {
+		if !applied[migrationsData[idx].Version] {
+			continue
+		}
+		last = &migrationsData[idx]
+		if last.Version == version {
+			target = last
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("%w: %s is not applied", ErrMigrationNotFound, version)
+	}
+	if !force && last.Version != version {
+		return fmt.Errorf("%w: %s must be rolled back before %s", ErrRollbackOutOfOrder, last.Version, version)
+	}
+
+	return i.runDownMigration(ctx, *target)
+}
+
+// runDownMigration runs the down script and deletes the version
+// in a single transaction
+func (i *PostgresLockAdapter) runDownMigration(ctx context.Context, migration migrationData) error {
+	if migration.DownFileName == "" {
+		return fmt.Errorf("%w: %s", ErrNoDownMigration, migration.Version)
+	}
+
+	downData, err := migrationsEmbed.ReadFile(migration.DownFileName)
+	if err != nil {
+		return err
+	}
+
+	tx, err := i.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, i.renderMigration(downData))
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		ctx,
+		"DELETE FROM "+i.Cfg.migrationTable()+" WHERE version = $1",
 		migration.Version,
 	)
 	if err != nil {
@@ -228,29 +631,318 @@ func (i *PostgresLockAdapter) runMigrationTransaction(ctx context.Context, migra
 }
 
 func (i *PostgresLockAdapter) createMigrationSchema(ctx context.Context) error {
-	_, err := i.pool.Exec(
+	_, err := i.db.Exec(
 		ctx,
-		"CREATE SCHEMA IF NOT EXISTS "+i.Cfg.MigrationSchema,
+		"CREATE SCHEMA IF NOT EXISTS "+i.Cfg.migrationSchema(),
 	)
 	return err
 }
 
 func (i *PostgresLockAdapter) createLockSchema(ctx context.Context) error {
-	_, err := i.pool.Exec(
+	_, err := i.db.Exec(
 		ctx,
-		"CREATE SCHEMA IF NOT EXISTS "+i.Cfg.LockSchema,
+		"CREATE SCHEMA IF NOT EXISTS "+i.Cfg.lockSchema(),
 	)
 	return err
 }
 
+// createMigrationTableSQL creates the migration table of the configuration
+func (i *PostgresLockAdapter) createMigrationTableSQL() string {
+	return `CREATE TABLE IF NOT EXISTS ` + i.Cfg.migrationTable() + ` (
+	id SERIAL PRIMARY KEY,
+	version varchar(50) NOT NULL,
+	checksum TEXT,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);`
+}
+
+// migrationVersionIndexSQL keeps a version from being recorded twice
+func (i *PostgresLockAdapter) migrationVersionIndexSQL() string {
+	return `CREATE UNIQUE INDEX IF NOT EXISTS ` + i.Cfg.migrationVersionIndex() +
+		` ON ` + i.Cfg.migrationTable() + ` (version);`
+}
+
 func (i *PostgresLockAdapter) createMigrationTable(ctx context.Context) error {
-	_, err := i.pool.Exec(
+	_, err := i.db.Exec(ctx, i.createMigrationTableSQL())
+	if err != nil {
+		return err
+	}
+	if err := i.addChecksumColumn(ctx); err != nil {
+		return err
+	}
+
+	// Older releases recorded a version again on every run,
+	// the duplicates must go before the unique index is built
+	_, err = i.db.Exec(
 		ctx,
-		`CREATE TABLE IF NOT EXISTS `+i.Cfg.MigrationSchema+`.`+i.Cfg.MigrationTableName+` (
-			id SERIAL PRIMARY KEY,
-			version varchar(50) NOT NULL,
-			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
-		);`,
+		`DELETE FROM `+i.Cfg.migrationTable()+` a
+		USING `+i.Cfg.migrationTable()+` b
+		WHERE a.version = b.version AND a.id > b.id;`,
 	)
+	if err != nil {
+		return err
+	}
+
+	_, err = i.db.Exec(ctx, i.migrationVersionIndexSQL())
+	return err
+}
+
+// lockMigrations takes a session advisory lock keyed on the migration table,
+// so replicas starting together run PrepareDbForMigrations, ApplyMigrations
+// and RollbackMigration one at a time; the ones waiting then observe the
+// migrated state.
+//
+// The lock holds a connection while the migrations use others. With a
+// single connection, the lock is taken by its session, the one the
+// migrations run on.
+func (i *PostgresLockAdapter) lockMigrations(ctx context.Context) (func(), error) {
+	key := "lockbox:migrations:" + i.Cfg.migrationTable()
+
+	var conn querier = i.db
+	release := func(bool) {}
+	if i.db.stat().MaxConns != 1 {
+		var err error
+		if conn, release, err = i.db.acquire(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := i.waitMigrationLock(ctx, conn, key); err != nil {
+		release(false)
+		return nil, err
+	}
+
+	return func() {
+		// Unlock even if ctx is done, a connection that cannot unlock is
+		// closed, which releases the lock
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), core.DefaultRequestTimeout)
+		defer cancel()
+
+		_, err := conn.Exec(ctx, "SELECT pg_advisory_unlock(hashtext($1))", key)
+		release(err != nil)
+	}, nil
+}
+
+// Interval between two attempts to take the migration lock, see
+// MigrationLockTimeout
+const migrationLockPollInterval = 100 * time.Millisecond
+
+// waitMigrationLock takes the advisory lock of key on conn, giving up
+// with ErrMigrationLockTimeout after MigrationLockTimeout when set.
+//
+// A timed wait polls pg_try_advisory_lock instead of cancelling a
+// blocked pg_advisory_lock, which would cost the connection, possibly
+// the one of the caller.
+func (i *PostgresLockAdapter) waitMigrationLock(ctx context.Context, conn querier, key string) error {
+	timeout := i.Cfg.MigrationLockTimeout
+	if timeout <= 0 {
+		if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock(hashtext($1))", key); err != nil {
+			return fmt.Errorf("failed to lock migrations: %w", err)
+		}
+		return nil
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		var locked bool
+		err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock(hashtext($1))", key).Scan(&locked)
+		if err != nil {
+			return fmt.Errorf("failed to lock migrations: %w", err)
+		}
+		if locked {
+			return nil
+		}
+
+		left := time.Until(deadline)
+		if left <= 0 {
+			return fmt.Errorf("%w: still held after %v", ErrMigrationLockTimeout, timeout)
+		}
+		core.SleepContext(ctx, min(migrationLockPollInterval, left))
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("failed to lock migrations: %w", err)
+		}
+	}
+}
+
+// migrationChecksum returns the SHA-256 of a rendered migration.
+//
+// The persistence of the tables is left out, so toggling Unlogged on an
+// installation converted with ConvertToUnlogged doesn't fail the checksum
+// verification.
+func migrationChecksum(sql string) string {
+	sql = strings.ReplaceAll(sql, "CREATE UNLOGGED TABLE", "CREATE TABLE")
+	sum := sha256.Sum256([]byte(sql))
+	return hex.EncodeToString(sum[:])
+}
+
+// renderedMigration reads and renders the migration file
+func (i *PostgresLockAdapter) renderedMigration(migration migrationData) (string, error) {
+	data, err := migrationsEmbed.ReadFile(migration.FileName)
+	if err != nil {
+		return "", err
+	}
+	return i.renderMigration(data), nil
+}
+
+// addChecksumColumn upgrades migration tables created by older releases
+func (i *PostgresLockAdapter) addChecksumColumn(ctx context.Context) error {
+	_, err := i.db.Exec(
+		ctx,
+		"ALTER TABLE IF EXISTS "+i.Cfg.migrationTable()+" ADD COLUMN IF NOT EXISTS checksum TEXT",
+	)
+	return err
+}
+
+// unverifiedChecksum is recorded for the versions applied before
+// checksums existed: the SQL they were applied with is unknown, so they
+// are not stamped with the checksum of the embedded migration
+const unverifiedChecksum = "unverified"
+
+// verifyChecksums compares the stored checksums of the applied migrations
+// with the embedded ones. Versions applied before checksums existed are
+// recorded as unverified and skipped, until RepairChecksums accepts the
+// embedded migrations.
+func (i *PostgresLockAdapter) verifyChecksums(ctx context.Context, applied map[string]bool) error {
+	if len(applied) == 0 {
+		return nil
+	}
+
+	rows, err := i.db.Query(ctx, "SELECT version, COALESCE(checksum, '') FROM "+i.Cfg.migrationTable())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	checksums := map[string]string{}
+	for rows.Next() {
+		var version, checksum string
+		if err := rows.Scan(&version, &checksum); err != nil {
+			return err
+		}
+		checksums[version] = checksum
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, migration := range migrationsData {
+		expected, ok := checksums[migration.Version]
+		if !ok {
+			continue
+		}
+		if expected == "" {
+			if err := i.storeChecksum(ctx, migration.Version, unverifiedChecksum); err != nil {
+				return err
+			}
+			continue
+		}
+		if expected == unverifiedChecksum {
+			continue
+		}
+
+		sql, err := i.renderedMigration(migration)
+		if err != nil {
+			return err
+		}
+		actual := migrationChecksum(sql)
+		if expected != actual {
+			return fmt.Errorf(
+				"%w: %s was applied with checksum %s, the embedded migration has %s",
+				ErrChecksumMismatch, migration.Version, expected, actual,
+			)
+		}
+	}
+
+	return nil
+}
+
+func (i *PostgresLockAdapter) storeChecksum(ctx context.Context, version, checksum string) error {
+	_, err := i.db.Exec(
+		ctx,
+		"UPDATE "+i.Cfg.migrationTable()+" SET checksum = $2 WHERE version = $1",
+		version, checksum,
+	)
+	return err
+}
+
+// RepairChecksums records the checksums of the embedded migrations for
+// every applied version, accepting intentional changes to migrations
+// reported by ApplyMigrations as ErrChecksumMismatch.
+//
+// The changed migrations are not re-applied.
+func (i *PostgresLockAdapter) RepairChecksums(ctx context.Context) error {
+	if err := i.begin(); err != nil {
+		return err
+	}
+	defer i.end()
+
+	unlock, err := i.lockMigrations(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := i.addChecksumColumn(ctx); err != nil {
+		return err
+	}
+	applied, err := i.appliedMigrations(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range migrationsData {
+		if !applied[migration.Version] {
+			continue
+		}
+		sql, err := i.renderedMigration(migration)
+		if err != nil {
+			return err
+		}
+		if err := i.storeChecksum(ctx, migration.Version, migrationChecksum(sql)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GenerateSQL writes every embedded migration, rendered with the configured
+// schema and table, as a script a DBA can review and apply manually.
+//
+// Transaction migrations are wrapped in BEGIN/COMMIT together with the
+// insert recording their version, so RunMigrations skips them afterwards.
+// Migrations building indexes concurrently are written as plain
+// statements, they cannot run inside a transaction block.
+//
+// Nothing is executed, the database is not queried.
+func (i *PostgresLockAdapter) GenerateSQL(w io.Writer) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "-- go-lockbox migrations for %s\n\n", i.Cfg.lockTable())
+	if i.Cfg.CreateSchemasIfNotExists {
+		fmt.Fprintf(&b, "CREATE SCHEMA IF NOT EXISTS %s;\n", i.Cfg.migrationSchema())
+		fmt.Fprintf(&b, "CREATE SCHEMA IF NOT EXISTS %s;\n\n", i.Cfg.lockSchema())
+	}
+	fmt.Fprintf(&b, "%s\n\n%s\n", i.createMigrationTableSQL(), i.migrationVersionIndexSQL())
+
+	for _, migration := range i.migrations() {
+		sql, err := i.renderedMigration(migration)
+		if err != nil {
+			return err
+		}
+		insert := fmt.Sprintf(
+			"INSERT INTO %s (version, checksum) VALUES ('%s', '%s') ON CONFLICT DO NOTHING;",
+			i.Cfg.migrationTable(), migration.Version, migrationChecksum(sql),
+		)
+
+		fmt.Fprintf(&b, "\n-- %s\n", migration.Version)
+		if migration.Transaction {
+			fmt.Fprintf(&b, "BEGIN;\n%s\n%s\nCOMMIT;\n", strings.TrimSpace(sql), insert)
+		} else {
+			fmt.Fprintf(&b, "%s\n%s\n", strings.TrimSpace(sql), insert)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
 	return err
 }
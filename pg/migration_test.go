@@ -0,0 +1,146 @@
+package pg_test
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/oliveiracleidson/go-lockbox/pg"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresLockAdapter_GenerateSQL(t *testing.T) {
+	// GenerateSQL never queries the database
+	pool, err := pgxpool.New(context.Background(), "postgres://lockbox@127.0.0.1:1/lockbox?connect_timeout=1")
+	require.NoError(t, err)
+	defer pool.Close()
+
+	cfg := pg.NewPostgresLockerConfig().
+		SetLockSchema("ops").
+		SetLockTableName("job_locks").
+		SetMigrationSchema("ops_migrations").
+		SetMigrationTableName("job_locks_migrations")
+	adapter, err := pg.NewPostgresLockAdapter(pool, cfg)
+	require.NoError(t, err)
+
+	t.Run("given a custom schema and table, when generate SQL, then the script references them", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, adapter.GenerateSQL(&buf))
+		script := buf.String()
+
+		require.NotContains(t, script, "{{")
+		require.Contains(t, script, `CREATE SCHEMA IF NOT EXISTS "ops";`)
+		require.Contains(t, script, `CREATE TABLE IF NOT EXISTS "ops_migrations"."job_locks_migrations"`)
+		require.Contains(t, script, `"ops"."job_locks"`)
+		require.NotContains(t, script, "locker_locks")
+	})
+
+	t.Run("given the embedded migrations, when generate SQL, then every version is recorded once", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, adapter.GenerateSQL(&buf))
+		script := buf.String()
+
+		for _, version := range []string{"v0.0.1", "v0.0.1-indexes", "v0.0.1-names", "v0.0.2", "v0.0.6", "v0.0.6-indexes", "v0.0.7", "v0.0.8"} {
+			insert := `INSERT INTO "ops_migrations"."job_locks_migrations" (version, checksum) VALUES ('` + version + `', '`
+			require.Equal(t, 1, strings.Count(script, insert), version)
+		}
+		require.Equal(t, strings.Count(script, "BEGIN;"), strings.Count(script, "COMMIT;"))
+	})
+
+	t.Run("given schemas are not created, when generate SQL, then the script has no CREATE SCHEMA", func(t *testing.T) {
+		noSchemas, err := pg.NewPostgresLockAdapter(pool, pg.NewPostgresLockerConfig().SetCreateSchemasIfNotExists(false))
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		require.NoError(t, noSchemas.GenerateSQL(&buf))
+		require.NotContains(t, buf.String(), "CREATE SCHEMA")
+	})
+	t.Run("given mixed-case identifiers, when generate SQL, then they are quoted and keep their case", func(t *testing.T) {
+		mixed, err := pg.NewPostgresLockAdapter(pool, pg.NewPostgresLockerConfig().
+			SetLockSchema("Ops").
+			SetLockTableName("JobLocks"),
+		)
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		require.NoError(t, mixed.GenerateSQL(&buf))
+		require.Contains(t, buf.String(), `CREATE TABLE "Ops"."JobLocks" (`)
+		require.NotContains(t, buf.String(), "joblocks")
+	})
+	t.Run("given the metadata index is enabled, when generate SQL, then the script creates it", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, adapter.GenerateSQL(&buf))
+		require.NotContains(t, buf.String(), "v0.0.6-metadata-index")
+
+		indexed, err := pg.NewPostgresLockAdapter(pool, pg.NewPostgresLockerConfig().SetMetadataIndex(true))
+		require.NoError(t, err)
+
+		buf.Reset()
+		require.NoError(t, indexed.GenerateSQL(&buf))
+		require.Contains(t, buf.String(), `CREATE INDEX CONCURRENTLY IF NOT EXISTS "locker_locks_metadata_idx"`)
+		require.Contains(t, buf.String(), "USING GIN (metadata jsonb_path_ops)")
+	})
+	t.Run("given the audit log is enabled, when generate SQL, then the script creates its table", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, adapter.GenerateSQL(&buf))
+		require.NotContains(t, buf.String(), "v0.0.8-audit")
+
+		audited, err := pg.NewPostgresLockAdapter(pool, pg.NewPostgresLockerConfig().SetAuditTableName("lock_audit"))
+		require.NoError(t, err)
+
+		buf.Reset()
+		require.NoError(t, audited.GenerateSQL(&buf))
+		require.Contains(t, buf.String(), `CREATE TABLE IF NOT EXISTS "public"."lock_audit" (`)
+		require.Contains(t, buf.String(), `CREATE INDEX IF NOT EXISTS "lock_audit_key_at_idx"`)
+		require.Contains(t, buf.String(), `VALUES ('v0.0.8-audit', '`)
+	})
+
+	t.Run("given unlogged tables, when generate SQL, then only the persistence differs", func(t *testing.T) {
+		var logged, unlogged bytes.Buffer
+		l, err := pg.NewPostgresLockAdapter(pool, pg.NewPostgresLockerConfig())
+		require.NoError(t, err)
+		require.NoError(t, l.GenerateSQL(&logged))
+		require.NotContains(t, logged.String(), "UNLOGGED")
+
+		u, err := pg.NewPostgresLockAdapter(pool, pg.NewPostgresLockerConfig().SetUnlogged(true))
+		require.NoError(t, err)
+		require.NoError(t, u.GenerateSQL(&unlogged))
+		require.Contains(t, unlogged.String(), `CREATE UNLOGGED TABLE "public"."locker_locks"`)
+		require.Contains(t, unlogged.String(), `CREATE UNLOGGED TABLE IF NOT EXISTS "public"."locker_locks_waiters"`)
+
+		// The checksums ignore the persistence, so the scripts match once
+		// the keyword is removed
+		require.Equal(t, logged.String(), strings.ReplaceAll(unlogged.String(), "UNLOGGED ", ""))
+	})
+	t.Run("given a lock table name of the identifier limit, when generate SQL, then the names of its objects fit and are unique", func(t *testing.T) {
+		names := map[string]string{}
+		for _, table := range []string{strings.Repeat("a", 62) + "b", strings.Repeat("a", 62) + "c"} {
+			long, err := pg.NewPostgresLockAdapter(pool, pg.NewPostgresLockerConfig().
+				SetLockTableName(table).
+				SetMetadataIndex(true).
+				SetAuditTableName(strings.Repeat("d", 52)))
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			require.NoError(t, long.GenerateSQL(&buf))
+			for _, identifier := range quotedIdentifier.FindAllStringSubmatch(buf.String(), -1) {
+				name := identifier[1]
+				require.LessOrEqual(t, len(name), 63, name)
+				// The schemas and the audit table are the same for both
+				if !strings.HasPrefix(name, "aaaa") {
+					continue
+				}
+				if other, ok := names[name]; ok {
+					require.Equal(t, table, other, name)
+				}
+				names[name] = table
+			}
+		}
+	})
+}
+
+// quotedIdentifier matches the identifiers quoted by pgx.Identifier
+var quotedIdentifier = regexp.MustCompile(`"([a-zA-Z0-9_]+)"`)
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS {{ LockSchema }}.idx_locks_expiration;
+DROP INDEX IF EXISTS {{ LockSchema }}.idx_locks_lease;
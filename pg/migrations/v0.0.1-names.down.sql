@@ -0,0 +1,13 @@
+-- Restores the names of v0.0.1
+ALTER INDEX IF EXISTS {{ LockSchema }}.{{ LockLeaseIndex }} RENAME TO idx_locks_lease;
+ALTER INDEX IF EXISTS {{ LockSchema }}.{{ LockExpirationIndex }} RENAME TO idx_locks_expiration;
+ALTER VIEW IF EXISTS {{ LockHealthView }} RENAME TO lock_health;
+
+DO $$
+BEGIN
+    IF to_regprocedure('{{ TryAcquireLock }}(text, text, bigint, text, jsonb)') IS NOT NULL THEN
+        ALTER FUNCTION {{ TryAcquireLock }}(TEXT, TEXT, BIGINT, TEXT, JSONB)
+            RENAME TO try_acquire_lock;
+    END IF;
+END
+$$;
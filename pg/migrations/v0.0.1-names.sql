@@ -0,0 +1,15 @@
+-- v0.0.1 named the acquisition function, the health view and the indexes
+-- after no table, so a schema held a single lock table. They are renamed
+-- after the lock table, the names used by the later migrations.
+DO $$
+BEGIN
+    IF to_regprocedure('{{ LockSchema }}.try_acquire_lock(text, text, bigint, text, jsonb)') IS NOT NULL THEN
+        ALTER FUNCTION {{ LockSchema }}.try_acquire_lock(TEXT, TEXT, BIGINT, TEXT, JSONB)
+            RENAME TO {{ TryAcquireLockName }};
+    END IF;
+END
+$$;
+
+ALTER VIEW IF EXISTS {{ LockSchema }}.lock_health RENAME TO {{ LockHealthViewName }};
+ALTER INDEX IF EXISTS {{ LockSchema }}.idx_locks_expiration RENAME TO {{ LockExpirationIndex }};
+ALTER INDEX IF EXISTS {{ LockSchema }}.idx_locks_lease RENAME TO {{ LockLeaseIndex }};
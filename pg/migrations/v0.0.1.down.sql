@@ -0,0 +1,3 @@
+DROP VIEW IF EXISTS {{ LockSchema }}.lock_health;
+DROP FUNCTION IF EXISTS {{ LockSchema }}.try_acquire_lock(TEXT, TEXT, BIGINT, TEXT, JSONB);
+DROP TABLE IF EXISTS {{ LockTable }};
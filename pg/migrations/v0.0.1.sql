@@ -1,6 +1,6 @@
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 -- Principal table for storing distributed locks
-CREATE TABLE "{{ LockSchema }}"."{{ LockTable }}" (
+CREATE {{ Unlogged }}TABLE {{ LockTable }} (
     key TEXT PRIMARY KEY
         CHECK (
             key ~ '^[a-zA-Z0-9_-]+$' AND 
@@ -16,7 +16,7 @@ CREATE TABLE "{{ LockSchema }}"."{{ LockTable }}" (
 
 
 -- Auxiliary function for atomic lock acquisition
-CREATE OR REPLACE FUNCTION "{{ LockSchema }}".try_acquire_lock(
+CREATE OR REPLACE FUNCTION {{ LockSchema }}.try_acquire_lock(
     _key TEXT,
     _lease_id TEXT,
     _ttl_ms BIGINT,
@@ -34,7 +34,7 @@ BEGIN
 
     -- Is added 10 milliseconds to the expiration time
     -- because the network latency can cause the lock to expire before the client receives the response
-    INSERT INTO "{{ LockSchema }}"."{{ LockTable }}" 
+    INSERT INTO {{ LockTable }} 
     VALUES (
         _key,
         _lease_id,
@@ -50,7 +50,7 @@ BEGIN
         server_nonce = EXCLUDED.server_nonce,
         metadata = EXCLUDED.metadata,
         updated_at = NOW()
-    WHERE "{{ LockSchema }}"."{{ LockTable }}".valid_until <= NOW()
+    WHERE {{ LockTable }}.valid_until <= NOW()
     RETURNING TRUE, valid_until INTO result_acquired, result_valid_until;  -- Store the result in the output variables
     
     -- Return the result of the operation if the lock was acquired
@@ -62,10 +62,10 @@ END;
 $$ LANGUAGE plpgsql VOLATILE;
 
 -- View for health monitoring
-CREATE VIEW "{{ LockSchema }}".lock_health AS
+CREATE VIEW {{ LockSchema }}.lock_health AS
 SELECT
     COUNT(*) FILTER (WHERE valid_until > NOW()) AS active_locks,
     COUNT(*) FILTER (WHERE valid_until <= NOW()) AS expired_locks,
     MIN(valid_until - NOW()) FILTER (WHERE valid_until > NOW()) AS oldest_lock_ttl,
     AVG(EXTRACT(EPOCH FROM (valid_until - created_at))) AS avg_ttl_seconds
-FROM "{{ LockSchema }}"."{{ LockTable }}";
\ No newline at end of file
+FROM {{ LockTable }};
\ No newline at end of file
@@ -0,0 +1,3 @@
+-- Lookup of the locks held by an owner
+CREATE INDEX CONCURRENTLY IF NOT EXISTS {{ LockOwnerIndex }}
+    ON {{ LockTable }} (owner_id);
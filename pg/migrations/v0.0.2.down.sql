@@ -0,0 +1,49 @@
+DROP FUNCTION IF EXISTS {{ TryAcquireLock }}(TEXT, TEXT, BIGINT, TEXT, JSONB, TEXT);
+ALTER TABLE {{ LockTable }} DROP COLUMN IF EXISTS owner_id;
+
+-- Restores the function of v0.0.1
+-- Auxiliary function for atomic lock acquisition
+CREATE OR REPLACE FUNCTION {{ TryAcquireLock }}(
+    _key TEXT,
+    _lease_id TEXT,
+    _ttl_ms BIGINT,
+    _nonce TEXT,
+    _metadata JSONB
+) RETURNS TABLE(
+    result_acquired BOOLEAN,
+    result_valid_until TIMESTAMPTZ
+) AS $$
+BEGIN
+    -- Security checks
+    IF LENGTH(_key) > 256 OR _key !~ '^[a-zA-Z0-9_-]+$' THEN
+        RAISE EXCEPTION 'Invalid key format' USING ERRCODE = '22023';
+    END IF;
+
+    -- Is added 10 milliseconds to the expiration time
+    -- because the network latency can cause the lock to expire before the client receives the response
+    INSERT INTO {{ LockTable }} 
+    VALUES (
+        _key,
+        _lease_id,
+        NOW() + (_ttl_ms * INTERVAL '1 millisecond') + (10 * INTERVAL '1 millisecond'),
+        _nonce,
+        _metadata,
+        NOW(),
+        NOW()
+    )
+    ON CONFLICT (key) DO UPDATE SET
+        lease_id = EXCLUDED.lease_id,
+        valid_until = EXCLUDED.valid_until,
+        server_nonce = EXCLUDED.server_nonce,
+        metadata = EXCLUDED.metadata,
+        updated_at = NOW()
+    WHERE {{ LockTable }}.valid_until <= NOW()
+    RETURNING TRUE, valid_until INTO result_acquired, result_valid_until;  -- Store the result in the output variables
+    
+    -- Return the result of the operation if the lock was acquired
+    RETURN QUERY SELECT COALESCE(result_acquired, FALSE), result_valid_until;
+EXCEPTION
+    WHEN unique_violation THEN
+        RETURN QUERY SELECT FALSE, NULL;
+END;
+$$ LANGUAGE plpgsql VOLATILE;
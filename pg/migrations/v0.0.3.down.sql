@@ -0,0 +1,7 @@
+-- try_acquire_lock keeps accepting namespaced keys, the table rejects them
+ALTER TABLE {{ LockTable }} DROP CONSTRAINT IF EXISTS {{ LockKeyCheck }};
+ALTER TABLE {{ LockTable }} ADD CONSTRAINT {{ LockKeyCheck }}
+    CHECK (
+        key ~ '^[a-zA-Z0-9_-]+$' AND
+        LENGTH(key) BETWEEN 1 AND 256
+    );
@@ -0,0 +1,66 @@
+-- Keys may be prefixed by namespace segments separated by ':'
+ALTER TABLE {{ LockTable }} DROP CONSTRAINT IF EXISTS {{ LockKeyCheck }};
+ALTER TABLE {{ LockTable }} ADD CONSTRAINT {{ LockKeyCheck }}
+    CHECK (
+        key ~ '^([a-zA-Z0-9_-]+:)*[a-zA-Z0-9_-]+$' AND
+        LENGTH(key) BETWEEN 1 AND 256
+    );
+
+-- Auxiliary function for atomic lock acquisition, (re)created for the
+-- namespaced keys and for databases migrated by older releases that miss it
+CREATE OR REPLACE FUNCTION {{ TryAcquireLock }}(
+    _key TEXT,
+    _lease_id TEXT,
+    _ttl_ms BIGINT,
+    _nonce TEXT,
+    _metadata JSONB,
+    _owner_id TEXT
+) RETURNS TABLE(
+    result_acquired BOOLEAN,
+    result_valid_until TIMESTAMPTZ
+) AS $$
+BEGIN
+    -- Security checks
+    IF LENGTH(_key) > 256 OR _key !~ '^([a-zA-Z0-9_-]+:)*[a-zA-Z0-9_-]+$' THEN
+        RAISE EXCEPTION 'Invalid key format' USING ERRCODE = '22023';
+    END IF;
+
+    -- Is added 10 milliseconds to the expiration time
+    -- because the network latency can cause the lock to expire before the client receives the response
+    INSERT INTO {{ LockTable }} AS l (
+        key,
+        lease_id,
+        valid_until,
+        server_nonce,
+        metadata,
+        owner_id,
+        created_at,
+        updated_at
+    )
+    VALUES (
+        _key,
+        _lease_id,
+        NOW() + (_ttl_ms * INTERVAL '1 millisecond') + (10 * INTERVAL '1 millisecond'),
+        _nonce,
+        _metadata,
+        _owner_id,
+        NOW(),
+        NOW()
+    )
+    ON CONFLICT (key) DO UPDATE SET
+        lease_id = EXCLUDED.lease_id,
+        valid_until = EXCLUDED.valid_until,
+        server_nonce = EXCLUDED.server_nonce,
+        metadata = EXCLUDED.metadata,
+        owner_id = EXCLUDED.owner_id,
+        updated_at = NOW()
+    WHERE l.valid_until <= NOW()
+    RETURNING TRUE, l.valid_until INTO result_acquired, result_valid_until;  -- Store the result in the output variables
+
+    -- Return the result of the operation if the lock was acquired
+    RETURN QUERY SELECT COALESCE(result_acquired, FALSE), result_valid_until;
+EXCEPTION
+    WHEN unique_violation THEN
+        RETURN QUERY SELECT FALSE, NULL::TIMESTAMPTZ;
+END;
+$$ LANGUAGE plpgsql VOLATILE;
@@ -0,0 +1,2 @@
+DROP FUNCTION IF EXISTS {{ TryAcquireLockFIFO }}(TEXT, TEXT, BIGINT, TEXT, JSONB, TEXT, BIGINT);
+DROP TABLE IF EXISTS {{ LockWaitersTable }};
@@ -0,0 +1,61 @@
+-- Queue of the acquirers waiting for a key, used by the FIFO mode
+CREATE {{ Unlogged }}TABLE IF NOT EXISTS {{ LockWaitersTable }} (
+    key TEXT NOT NULL,
+    lease_id TEXT NOT NULL,
+    enqueued_at TIMESTAMPTZ NOT NULL DEFAULT clock_timestamp(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (key, lease_id)
+);
+
+-- Atomic lock acquisition serving the waiters of a key in arrival order.
+--
+-- Every call enqueues the caller (or extends its place in the queue by _wait_ms)
+-- and only tries to acquire the lock when no older waiter is still alive.
+CREATE OR REPLACE FUNCTION {{ TryAcquireLockFIFO }}(
+    _key TEXT,
+    _lease_id TEXT,
+    _ttl_ms BIGINT,
+    _nonce TEXT,
+    _metadata JSONB,
+    _owner_id TEXT,
+    _wait_ms BIGINT
+) RETURNS TABLE(
+    result_acquired BOOLEAN,
+    result_valid_until TIMESTAMPTZ
+) AS $$
+DECLARE
+    _enqueued_at TIMESTAMPTZ;
+    _acquired BOOLEAN;
+    _valid_until TIMESTAMPTZ;
+BEGIN
+    INSERT INTO {{ LockWaitersTable }} AS w (key, lease_id, expires_at)
+    VALUES (_key, _lease_id, NOW() + (_wait_ms * INTERVAL '1 millisecond'))
+    ON CONFLICT (key, lease_id) DO UPDATE SET
+        expires_at = EXCLUDED.expires_at
+    RETURNING w.enqueued_at INTO _enqueued_at;
+
+    -- An older waiter is still alive, wait for our turn
+    IF EXISTS (
+        SELECT 1
+        FROM {{ LockWaitersTable }} w
+        WHERE w.key = _key
+          AND w.expires_at > NOW()
+          AND (w.enqueued_at, w.lease_id) < (_enqueued_at, _lease_id)
+    ) THEN
+        RETURN QUERY SELECT FALSE, NULL::TIMESTAMPTZ;
+        RETURN;
+    END IF;
+
+    SELECT t.result_acquired, t.result_valid_until
+    INTO _acquired, _valid_until
+    FROM {{ TryAcquireLock }}(_key, _lease_id, _ttl_ms, _nonce, _metadata, _owner_id) t;
+
+    IF _acquired THEN
+        DELETE FROM {{ LockWaitersTable }} w
+        WHERE w.key = _key
+          AND (w.lease_id = _lease_id OR w.expires_at <= NOW());
+    END IF;
+
+    RETURN QUERY SELECT _acquired, _valid_until;
+END;
+$$ LANGUAGE plpgsql VOLATILE;
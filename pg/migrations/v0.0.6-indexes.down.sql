@@ -0,0 +1,5 @@
+CREATE INDEX IF NOT EXISTS {{ LockExpirationIndex }}
+    ON {{ LockTable }} (valid_until);
+
+DROP INDEX IF EXISTS {{ LockSchema }}.{{ LockKeyExpiryIndex }};
+DROP INDEX IF EXISTS {{ LockSchema }}.{{ LockExpiryKeyIndex }};
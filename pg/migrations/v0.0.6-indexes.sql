@@ -0,0 +1,13 @@
+-- Range scans on the expiry: the sweeper (valid_until < NOW()) and the
+-- live lock listings (valid_until > NOW() ORDER BY valid_until), reading
+-- the key from the index
+CREATE INDEX CONCURRENTLY IF NOT EXISTS {{ LockExpiryKeyIndex }}
+    ON {{ LockTable }} (valid_until, key);
+
+-- Liveness of a key (key = $1 AND valid_until > NOW()) without
+-- visiting the table
+CREATE INDEX CONCURRENTLY IF NOT EXISTS {{ LockKeyExpiryIndex }}
+    ON {{ LockTable }} (key, valid_until);
+
+-- Superseded by the expiry and key index
+DROP INDEX CONCURRENTLY IF EXISTS {{ LockSchema }}.{{ LockExpirationIndex }};
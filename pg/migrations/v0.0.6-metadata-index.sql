@@ -0,0 +1,5 @@
+-- Containment lookups of the metadata, see FindLocksByMetadata.
+-- Opt-in through MetadataIndex: every acquisition with metadata
+-- updates the index.
+CREATE INDEX CONCURRENTLY IF NOT EXISTS {{ LockMetadataIndex }}
+    ON {{ LockTable }} USING GIN (metadata jsonb_path_ops);
@@ -0,0 +1,113 @@
+-- Restores the functions of v0.0.3 and v0.0.4
+DROP FUNCTION IF EXISTS {{ TryAcquireLockFIFO }}(TEXT, TEXT, BIGINT, TEXT, JSONB, TEXT, BIGINT);
+DROP FUNCTION IF EXISTS {{ TryAcquireLock }}(TEXT, TEXT, BIGINT, TEXT, JSONB, TEXT);
+
+CREATE OR REPLACE FUNCTION {{ TryAcquireLock }}(
+    _key TEXT,
+    _lease_id TEXT,
+    _ttl_ms BIGINT,
+    _nonce TEXT,
+    _metadata JSONB,
+    _owner_id TEXT
+) RETURNS TABLE(
+    result_acquired BOOLEAN,
+    result_valid_until TIMESTAMPTZ
+) AS $$
+BEGIN
+    -- Security checks
+    IF LENGTH(_key) > 256 OR _key !~ '^([a-zA-Z0-9_-]+:)*[a-zA-Z0-9_-]+$' THEN
+        RAISE EXCEPTION 'Invalid key format' USING ERRCODE = '22023';
+    END IF;
+
+    -- Is added 10 milliseconds to the expiration time
+    -- because the network latency can cause the lock to expire before the client receives the response
+    INSERT INTO {{ LockTable }} AS l (
+        key,
+        lease_id,
+        valid_until,
+        server_nonce,
+        metadata,
+        owner_id,
+        created_at,
+        updated_at
+    )
+    VALUES (
+        _key,
+        _lease_id,
+        NOW() + (_ttl_ms * INTERVAL '1 millisecond') + (10 * INTERVAL '1 millisecond'),
+        _nonce,
+        _metadata,
+        _owner_id,
+        NOW(),
+        NOW()
+    )
+    ON CONFLICT (key) DO UPDATE SET
+        lease_id = EXCLUDED.lease_id,
+        valid_until = EXCLUDED.valid_until,
+        server_nonce = EXCLUDED.server_nonce,
+        metadata = EXCLUDED.metadata,
+        owner_id = EXCLUDED.owner_id,
+        updated_at = NOW()
+    WHERE l.valid_until <= NOW()
+    RETURNING TRUE, l.valid_until INTO result_acquired, result_valid_until;  -- Store the result in the output variables
+
+    -- Return the result of the operation if the lock was acquired
+    RETURN QUERY SELECT COALESCE(result_acquired, FALSE), result_valid_until;
+EXCEPTION
+    WHEN unique_violation THEN
+        RETURN QUERY SELECT FALSE, NULL::TIMESTAMPTZ;
+END;
+$$ LANGUAGE plpgsql VOLATILE;
+
+-- Atomic lock acquisition serving the waiters of a key in arrival order.
+--
+-- Every call enqueues the caller (or extends its place in the queue by _wait_ms)
+-- and only tries to acquire the lock when no older waiter is still alive.
+CREATE OR REPLACE FUNCTION {{ TryAcquireLockFIFO }}(
+    _key TEXT,
+    _lease_id TEXT,
+    _ttl_ms BIGINT,
+    _nonce TEXT,
+    _metadata JSONB,
+    _owner_id TEXT,
+    _wait_ms BIGINT
+) RETURNS TABLE(
+    result_acquired BOOLEAN,
+    result_valid_until TIMESTAMPTZ
+) AS $$
+DECLARE
+    _enqueued_at TIMESTAMPTZ;
+    _acquired BOOLEAN;
+    _valid_until TIMESTAMPTZ;
+BEGIN
+    INSERT INTO {{ LockWaitersTable }} AS w (key, lease_id, expires_at)
+    VALUES (_key, _lease_id, NOW() + (_wait_ms * INTERVAL '1 millisecond'))
+    ON CONFLICT (key, lease_id) DO UPDATE SET
+        expires_at = EXCLUDED.expires_at
+    RETURNING w.enqueued_at INTO _enqueued_at;
+
+    -- An older waiter is still alive, wait for our turn
+    IF EXISTS (
+        SELECT 1
+        FROM {{ LockWaitersTable }} w
+        WHERE w.key = _key
+          AND w.expires_at > NOW()
+          AND (w.enqueued_at, w.lease_id) < (_enqueued_at, _lease_id)
+    ) THEN
+        RETURN QUERY SELECT FALSE, NULL::TIMESTAMPTZ;
+        RETURN;
+    END IF;
+
+    SELECT t.result_acquired, t.result_valid_until
+    INTO _acquired, _valid_until
+    FROM {{ TryAcquireLock }}(_key, _lease_id, _ttl_ms, _nonce, _metadata, _owner_id) t;
+
+    IF _acquired THEN
+        DELETE FROM {{ LockWaitersTable }} w
+        WHERE w.key = _key
+          AND (w.lease_id = _lease_id OR w.expires_at <= NOW());
+    END IF;
+
+    RETURN QUERY SELECT _acquired, _valid_until;
+END;
+$$ LANGUAGE plpgsql VOLATILE;
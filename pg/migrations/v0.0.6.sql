@@ -0,0 +1,122 @@
+-- Acquisition functions returning the lease that holds the key.
+--
+-- A new caller takes over an expired row in the same statement that would
+-- insert it, without waiting for a cleanup run, and gets back the lease and
+-- nonce now stored for the key.
+
+DROP FUNCTION IF EXISTS {{ TryAcquireLockFIFO }}(TEXT, TEXT, BIGINT, TEXT, JSONB, TEXT, BIGINT);
+DROP FUNCTION IF EXISTS {{ TryAcquireLock }}(TEXT, TEXT, BIGINT, TEXT, JSONB, TEXT);
+
+CREATE FUNCTION {{ TryAcquireLock }}(
+    _key TEXT,
+    _lease_id TEXT,
+    _ttl_ms BIGINT,
+    _nonce TEXT,
+    _metadata JSONB,
+    _owner_id TEXT
+) RETURNS TABLE(
+    result_acquired BOOLEAN,
+    result_valid_until TIMESTAMPTZ,
+    result_lease_id TEXT,
+    result_nonce TEXT
+) AS $$
+BEGIN
+    -- Security checks
+    IF LENGTH(_key) > 256 OR _key !~ '^([a-zA-Z0-9_-]+:)*[a-zA-Z0-9_-]+$' THEN
+        RAISE EXCEPTION 'Invalid key format' USING ERRCODE = '22023';
+    END IF;
+
+    -- Insert, or take over the existing row only if it is expired.
+    -- ON CONFLICT locks the conflicting row, so concurrent callers
+    -- cannot both take over the same expired lock.
+    --
+    -- Is added 10 milliseconds to the expiration time
+    -- because the network latency can cause the lock to expire before the client receives the response
+    INSERT INTO {{ LockTable }} AS l (
+        key,
+        lease_id,
+        valid_until,
+        server_nonce,
+        metadata,
+        owner_id,
+        created_at,
+        updated_at
+    )
+    VALUES (
+        _key,
+        _lease_id,
+        NOW() + (_ttl_ms * INTERVAL '1 millisecond') + (10 * INTERVAL '1 millisecond'),
+        _nonce,
+        _metadata,
+        _owner_id,
+        NOW(),
+        NOW()
+    )
+    ON CONFLICT (key) DO UPDATE SET
+        lease_id = EXCLUDED.lease_id,
+        valid_until = EXCLUDED.valid_until,
+        server_nonce = EXCLUDED.server_nonce,
+        metadata = EXCLUDED.metadata,
+        owner_id = EXCLUDED.owner_id,
+        created_at = NOW(),
+        updated_at = NOW()
+    WHERE l.valid_until <= NOW()
+    RETURNING TRUE, l.valid_until, l.lease_id, l.server_nonce
+    INTO result_acquired, result_valid_until, result_lease_id, result_nonce;
+
+    RETURN QUERY SELECT COALESCE(result_acquired, FALSE), result_valid_until, result_lease_id, result_nonce;
+END;
+$$ LANGUAGE plpgsql VOLATILE;
+
+CREATE FUNCTION {{ TryAcquireLockFIFO }}(
+    _key TEXT,
+    _lease_id TEXT,
+    _ttl_ms BIGINT,
+    _nonce TEXT,
+    _metadata JSONB,
+    _owner_id TEXT,
+    _wait_ms BIGINT
+) RETURNS TABLE(
+    result_acquired BOOLEAN,
+    result_valid_until TIMESTAMPTZ,
+    result_lease_id TEXT,
+    result_nonce TEXT
+) AS $$
+DECLARE
+    _enqueued_at TIMESTAMPTZ;
+    _acquired BOOLEAN;
+    _valid_until TIMESTAMPTZ;
+    _acquired_lease_id TEXT;
+    _acquired_nonce TEXT;
+BEGIN
+    INSERT INTO {{ LockWaitersTable }} AS w (key, lease_id, expires_at)
+    VALUES (_key, _lease_id, NOW() + (_wait_ms * INTERVAL '1 millisecond'))
+    ON CONFLICT (key, lease_id) DO UPDATE SET
+        expires_at = EXCLUDED.expires_at
+    RETURNING w.enqueued_at INTO _enqueued_at;
+
+    -- An older waiter is still alive, wait for our turn
+    IF EXISTS (
+        SELECT 1
+        FROM {{ LockWaitersTable }} w
+        WHERE w.key = _key
+          AND w.expires_at > NOW()
+          AND (w.enqueued_at, w.lease_id) < (_enqueued_at, _lease_id)
+    ) THEN
+        RETURN QUERY SELECT FALSE, NULL::TIMESTAMPTZ, NULL::TEXT, NULL::TEXT;
+        RETURN;
+    END IF;
+
+    SELECT t.result_acquired, t.result_valid_until, t.result_lease_id, t.result_nonce
+    INTO _acquired, _valid_until, _acquired_lease_id, _acquired_nonce
+    FROM {{ TryAcquireLock }}(_key, _lease_id, _ttl_ms, _nonce, _metadata, _owner_id) t;
+
+    IF _acquired THEN
+        DELETE FROM {{ LockWaitersTable }} w
+        WHERE w.key = _key
+          AND (w.lease_id = _lease_id OR w.expires_at <= NOW());
+    END IF;
+
+    RETURN QUERY SELECT _acquired, _valid_until, _acquired_lease_id, _acquired_nonce;
+END;
+$$ LANGUAGE plpgsql VOLATILE;
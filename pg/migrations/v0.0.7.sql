@@ -0,0 +1,148 @@
+-- Acquisition functions reporting the takeover of an expired lock.
+--
+-- result_took_over is true when the caller acquired the key by overwriting
+-- the row of a previous holder whose TTL lapsed, a sign it probably crashed
+-- mid-work, and result_previous_lease_id is the lease of that holder.
+
+DROP FUNCTION IF EXISTS {{ TryAcquireLockFIFO }}(TEXT, TEXT, BIGINT, TEXT, JSONB, TEXT, BIGINT);
+DROP FUNCTION IF EXISTS {{ TryAcquireLock }}(TEXT, TEXT, BIGINT, TEXT, JSONB, TEXT);
+
+CREATE FUNCTION {{ TryAcquireLock }}(
+    _key TEXT,
+    _lease_id TEXT,
+    _ttl_ms BIGINT,
+    _nonce TEXT,
+    _metadata JSONB,
+    _owner_id TEXT
+) RETURNS TABLE(
+    result_acquired BOOLEAN,
+    result_valid_until TIMESTAMPTZ,
+    result_lease_id TEXT,
+    result_nonce TEXT,
+    result_took_over BOOLEAN,
+    result_previous_lease_id TEXT
+) AS $$
+BEGIN
+    -- Security checks
+    IF LENGTH(_key) > 256 OR _key !~ '^([a-zA-Z0-9_-]+:)*[a-zA-Z0-9_-]+$' THEN
+        RAISE EXCEPTION 'Invalid key format' USING ERRCODE = '22023';
+    END IF;
+
+    -- The expired row about to be taken over, locked so its lease is
+    -- still the one replaced below
+    SELECT l.lease_id INTO result_previous_lease_id
+    FROM {{ LockTable }} l
+    WHERE l.key = _key
+      AND l.valid_until <= NOW()
+    FOR UPDATE;
+
+    -- Insert, or take over the existing row only if it is expired.
+    -- ON CONFLICT locks the conflicting row, so concurrent callers
+    -- cannot both take over the same expired lock.
+    --
+    -- Is added 10 milliseconds to the expiration time
+    -- because the network latency can cause the lock to expire before the client receives the response
+    INSERT INTO {{ LockTable }} AS l (
+        key,
+        lease_id,
+        valid_until,
+        server_nonce,
+        metadata,
+        owner_id,
+        created_at,
+        updated_at
+    )
+    VALUES (
+        _key,
+        _lease_id,
+        NOW() + (_ttl_ms * INTERVAL '1 millisecond') + (10 * INTERVAL '1 millisecond'),
+        _nonce,
+        _metadata,
+        _owner_id,
+        NOW(),
+        NOW()
+    )
+    ON CONFLICT (key) DO UPDATE SET
+        lease_id = EXCLUDED.lease_id,
+        valid_until = EXCLUDED.valid_until,
+        server_nonce = EXCLUDED.server_nonce,
+        metadata = EXCLUDED.metadata,
+        owner_id = EXCLUDED.owner_id,
+        created_at = NOW(),
+        updated_at = NOW()
+    WHERE l.valid_until <= NOW()
+    -- xmax is only set on the row updated by ON CONFLICT, not on an insert
+    RETURNING TRUE, l.valid_until, l.lease_id, l.server_nonce, l.xmax::TEXT <> '0'
+    INTO result_acquired, result_valid_until, result_lease_id, result_nonce, result_took_over;
+
+    IF NOT COALESCE(result_took_over, FALSE) THEN
+        result_previous_lease_id := NULL;
+    END IF;
+
+    RETURN QUERY SELECT
+        COALESCE(result_acquired, FALSE),
+        result_valid_until,
+        result_lease_id,
+        result_nonce,
+        COALESCE(result_took_over, FALSE),
+        result_previous_lease_id;
+END;
+$$ LANGUAGE plpgsql VOLATILE;
+
+CREATE FUNCTION {{ TryAcquireLockFIFO }}(
+    _key TEXT,
+    _lease_id TEXT,
+    _ttl_ms BIGINT,
+    _nonce TEXT,
+    _metadata JSONB,
+    _owner_id TEXT,
+    _wait_ms BIGINT
+) RETURNS TABLE(
+    result_acquired BOOLEAN,
+    result_valid_until TIMESTAMPTZ,
+    result_lease_id TEXT,
+    result_nonce TEXT,
+    result_took_over BOOLEAN,
+    result_previous_lease_id TEXT
+) AS $$
+DECLARE
+    _enqueued_at TIMESTAMPTZ;
+    _acquired BOOLEAN;
+    _valid_until TIMESTAMPTZ;
+    _acquired_lease_id TEXT;
+    _acquired_nonce TEXT;
+    _took_over BOOLEAN;
+    _previous_lease_id TEXT;
+BEGIN
+    INSERT INTO {{ LockWaitersTable }} AS w (key, lease_id, expires_at)
+    VALUES (_key, _lease_id, NOW() + (_wait_ms * INTERVAL '1 millisecond'))
+    ON CONFLICT (key, lease_id) DO UPDATE SET
+        expires_at = EXCLUDED.expires_at
+    RETURNING w.enqueued_at INTO _enqueued_at;
+
+    -- An older waiter is still alive, wait for our turn
+    IF EXISTS (
+        SELECT 1
+        FROM {{ LockWaitersTable }} w
+        WHERE w.key = _key
+          AND w.expires_at > NOW()
+          AND (w.enqueued_at, w.lease_id) < (_enqueued_at, _lease_id)
+    ) THEN
+        RETURN QUERY SELECT FALSE, NULL::TIMESTAMPTZ, NULL::TEXT, NULL::TEXT, FALSE, NULL::TEXT;
+        RETURN;
+    END IF;
+
+    SELECT t.result_acquired, t.result_valid_until, t.result_lease_id, t.result_nonce,
+        t.result_took_over, t.result_previous_lease_id
+    INTO _acquired, _valid_until, _acquired_lease_id, _acquired_nonce, _took_over, _previous_lease_id
+    FROM {{ TryAcquireLock }}(_key, _lease_id, _ttl_ms, _nonce, _metadata, _owner_id) t;
+
+    IF _acquired THEN
+        DELETE FROM {{ LockWaitersTable }} w
+        WHERE w.key = _key
+          AND (w.lease_id = _lease_id OR w.expires_at <= NOW());
+    END IF;
+
+    RETURN QUERY SELECT _acquired, _valid_until, _acquired_lease_id, _acquired_nonce, _took_over, _previous_lease_id;
+END;
+$$ LANGUAGE plpgsql VOLATILE;
@@ -0,0 +1,19 @@
+-- Append-only log of the lock lifecycle, see AuditTableName.
+-- Opt-in: every lock operation inserts a row.
+CREATE TABLE IF NOT EXISTS {{ AuditTable }} (
+    id BIGSERIAL PRIMARY KEY,
+    key TEXT NOT NULL,
+    lease_id TEXT NOT NULL,
+    owner_id TEXT,
+    event TEXT NOT NULL,
+    metadata JSONB,
+    at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- History of a key over a period, see QueryAudit
+CREATE INDEX IF NOT EXISTS {{ AuditKeyAtIndex }}
+    ON {{ AuditTable }} (key, at);
+
+-- Retention, see CleanupAudit
+CREATE INDEX IF NOT EXISTS {{ AuditAtIndex }}
+    ON {{ AuditTable }} (at);
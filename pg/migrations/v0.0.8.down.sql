@@ -0,0 +1,87 @@
+-- Restores the acquisition function of v0.0.7
+CREATE OR REPLACE FUNCTION {{ TryAcquireLock }}(
+    _key TEXT,
+    _lease_id TEXT,
+    _ttl_ms BIGINT,
+    _nonce TEXT,
+    _metadata JSONB,
+    _owner_id TEXT
+) RETURNS TABLE(
+    result_acquired BOOLEAN,
+    result_valid_until TIMESTAMPTZ,
+    result_lease_id TEXT,
+    result_nonce TEXT,
+    result_took_over BOOLEAN,
+    result_previous_lease_id TEXT
+) AS $$
+BEGIN
+    -- Security checks
+    IF LENGTH(_key) > 256 OR _key !~ '^([a-zA-Z0-9_-]+:)*[a-zA-Z0-9_-]+$' THEN
+        RAISE EXCEPTION 'Invalid key format' USING ERRCODE = '22023';
+    END IF;
+
+    -- The expired row about to be taken over, locked so its lease is
+    -- still the one replaced below
+    SELECT l.lease_id INTO result_previous_lease_id
+    FROM {{ LockTable }} l
+    WHERE l.key = _key
+      AND l.valid_until <= NOW()
+    FOR UPDATE;
+
+    -- Insert, or take over the existing row only if it is expired.
+    -- ON CONFLICT locks the conflicting row, so concurrent callers
+    -- cannot both take over the same expired lock.
+    --
+    -- Is added 10 milliseconds to the expiration time
+    -- because the network latency can cause the lock to expire before the client receives the response
+    INSERT INTO {{ LockTable }} AS l (
+        key,
+        lease_id,
+        valid_until,
+        server_nonce,
+        metadata,
+        owner_id,
+        created_at,
+        updated_at
+    )
+    VALUES (
+        _key,
+        _lease_id,
+        NOW() + (_ttl_ms * INTERVAL '1 millisecond') + (10 * INTERVAL '1 millisecond'),
+        _nonce,
+        _metadata,
+        _owner_id,
+        NOW(),
+        NOW()
+    )
+    ON CONFLICT (key) DO UPDATE SET
+        lease_id = EXCLUDED.lease_id,
+        valid_until = EXCLUDED.valid_until,
+        server_nonce = EXCLUDED.server_nonce,
+        metadata = EXCLUDED.metadata,
+        owner_id = EXCLUDED.owner_id,
+        created_at = NOW(),
+        updated_at = NOW()
+    WHERE l.valid_until <= NOW()
+    -- xmax is only set on the row updated by ON CONFLICT, not on an insert
+    RETURNING TRUE, l.valid_until, l.lease_id, l.server_nonce, l.xmax::TEXT <> '0'
+    INTO result_acquired, result_valid_until, result_lease_id, result_nonce, result_took_over;
+
+    IF NOT COALESCE(result_took_over, FALSE) THEN
+        result_previous_lease_id := NULL;
+    END IF;
+
+    RETURN QUERY SELECT
+        COALESCE(result_acquired, FALSE),
+        result_valid_until,
+        result_lease_id,
+        result_nonce,
+        COALESCE(result_took_over, FALSE),
+        result_previous_lease_id;
+END;
+$$ LANGUAGE plpgsql VOLATILE;
+
+ALTER TABLE {{ LockTable }}
+    DROP COLUMN IF EXISTS acquired_at,
+    DROP COLUMN IF EXISTS refresh_count,
+    DROP COLUMN IF EXISTS ttl_ms;
@@ -0,0 +1,234 @@
+package pg
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+// releaseChannel is the NOTIFY channel of a storage key.
+//
+// Keys are hashed because channel names are identifiers, limited to
+// 63 bytes. The lock table takes part in the hash so adapters with
+// different tables do not wake each other.
+func (i *PostgresLockAdapter) releaseChannel(storageKey string) string {
+	sum := sha256.Sum256([]byte(i.Cfg.lockTable() + "/" + storageKey))
+	return "lockbox_" + hex.EncodeToString(sum[:16])
+}
+
+// notifyRelease wakes the acquirers waiting for the storage key.
+//
+// It is best effort: the lock is already released, and waiters that
+// miss the notification retry on their own backoff.
+func (i *PostgresLockAdapter) notifyRelease(ctx context.Context, storageKey string) {
+	_, _ = i.db.Exec(ctx, `SELECT pg_notify($1, '')`, i.releaseChannel(storageKey))
+}
+
+// releaseHub multiplexes the release notifications awaited by every
+// contended Acquire over a single dedicated connection, started on the
+// first subscription and stopped by Close
+type releaseHub struct {
+	db backend
+
+	mu        sync.Mutex
+	waiters   map[string]map[chan struct{}]struct{} // By channel
+	interrupt context.CancelFunc                    // Wakes the loop to LISTEN or UNLISTEN
+	started   bool
+	closed    bool
+	stop      context.CancelFunc
+	done      chan struct{}
+}
+
+func newReleaseHub(db backend) *releaseHub {
+	return &releaseHub{
+		db:      db,
+		waiters: map[string]map[chan struct{}]struct{}{},
+		done:    make(chan struct{}),
+	}
+}
+
+// subscribe returns a channel receiving the releases notified on the
+// NOTIFY channel, and the function ending the subscription.
+//
+// Notifications are best effort, a nil wake channel is returned once
+// the hub is closed.
+func (h *releaseHub) subscribe(channel string) (<-chan struct{}, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, func() {}
+	}
+
+	wake := make(chan struct{}, 1)
+	if h.waiters[channel] == nil {
+		h.waiters[channel] = map[chan struct{}]struct{}{}
+	}
+	h.waiters[channel][wake] = struct{}{}
+	h.resync()
+
+	return wake, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.waiters[channel], wake)
+		if len(h.waiters[channel]) == 0 {
+			delete(h.waiters, channel)
+			h.resync()
+		}
+	}
+}
+
+// resync makes the loop update its LISTENs, starting it if needed.
+// The caller holds mu.
+func (h *releaseHub) resync() {
+	if !h.started {
+		h.started = true
+		ctx, stop := context.WithCancel(context.Background())
+		h.stop = stop
+		go h.run(ctx)
+		return
+	}
+	if h.interrupt != nil {
+		h.interrupt()
+	}
+}
+
+// close stops the loop and releases its connection
+func (h *releaseHub) close() {
+	h.mu.Lock()
+	h.closed = true
+	started := h.started
+	if started {
+		h.stop()
+	}
+	h.mu.Unlock()
+
+	if started {
+		<-h.done
+	}
+}
+
+// run keeps the connection LISTENing on the channels with waiters and
+// delivers their notifications, until ctx is done.
+//
+// While the connection is down, waiters are not notified and fall back
+// to their backoff.
+func (h *releaseHub) run(ctx context.Context) {
+	defer close(h.done)
+
+	var conn *pgx.Conn
+	var release func(broken bool)
+	listening := map[string]bool{}
+	defer func() {
+		if conn != nil {
+			releaseListenerConn(conn, release)
+		}
+	}()
+
+	for ctx.Err() == nil {
+		if conn == nil {
+			var err error
+			if conn, release, err = h.db.acquire(ctx); err != nil {
+				core.SleepContext(ctx, time.Second)
+				continue
+			}
+			listening = map[string]bool{}
+		}
+
+		h.mu.Lock()
+		wanted := make([]string, 0, len(h.waiters))
+		for channel := range h.waiters {
+			wanted = append(wanted, channel)
+		}
+		waitCtx, interrupt := context.WithCancel(ctx)
+		h.interrupt = interrupt
+		h.mu.Unlock()
+
+		// Cancelling a query closes the connection, so only the wait
+		// itself is interruptible
+		err := syncListens(ctx, conn, listening, wanted)
+		if err == nil {
+			var notification *pgconn.Notification
+			notification, err = conn.WaitForNotification(waitCtx)
+			if err == nil {
+				h.deliver(notification.Channel)
+			}
+		}
+		interrupted := waitCtx.Err() != nil
+		interrupt()
+
+		// Anything but an interruption means the connection is broken
+		if err != nil && !interrupted && ctx.Err() == nil {
+			release(true)
+			conn = nil
+		}
+	}
+}
+
+// syncListens LISTENs on the wanted channels and UNLISTENs the others
+func syncListens(ctx context.Context, conn *pgx.Conn, listening map[string]bool, wanted []string) error {
+	keep := map[string]bool{}
+	for _, channel := range wanted {
+		keep[channel] = true
+		if listening[channel] {
+			continue
+		}
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return err
+		}
+		listening[channel] = true
+	}
+	for channel := range listening {
+		if keep[channel] {
+			continue
+		}
+		if _, err := conn.Exec(ctx, "UNLISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return err
+		}
+		delete(listening, channel)
+	}
+	return nil
+}
+
+// deliver wakes the waiters of the channel without blocking,
+// a waiter already woken stays so
+func (h *releaseHub) deliver(channel string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for wake := range h.waiters[channel] {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// releaseListenerConn unsubscribes the connection and releases it.
+//
+// A connection that cannot UNLISTEN is closed instead, so it never
+// delivers stale notifications to the next user.
+func releaseListenerConn(conn *pgx.Conn, release func(broken bool)) {
+	ctx, cancel := context.WithTimeout(context.Background(), core.DefaultRequestTimeout)
+	defer cancel()
+
+	_, err := conn.Exec(ctx, "UNLISTEN *")
+	release(err != nil)
+}
+
+// waitRelease blocks until a release is notified on wake, the timeout
+// expires or ctx is done. A nil wake waits for the timeout only.
+func waitRelease(ctx context.Context, wake <-chan struct{}, timeout time.Duration) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-wake:
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,70 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+// DSN query parameters consumed by the adapter
+// and not forwarded to Postgres
+const (
+	dsnMigrateParam = "migrate"
+)
+
+func init() {
+	core.Register("postgres", open)
+	core.Register("postgresql", open)
+}
+
+// open creates a PostgresLockAdapter with its own pool from a DSN
+// for core.Open.
+//
+// When the migrate=true query parameter is present the schemas are
+// prepared and the migrations are run before returning.
+func open(ctx context.Context, dsn *url.URL, openCfg core.OpenConfig) (core.LockAdapter, error) {
+	query := dsn.Query()
+	migrate := false
+	if v := query.Get(dsnMigrateParam); v != "" {
+		var err error
+		migrate, err = strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s parameter: %w", dsnMigrateParam, err)
+		}
+	}
+	query.Del(dsnMigrateParam)
+
+	connURL := *dsn
+	connURL.RawQuery = query.Encode()
+
+	pool, err := pgxpool.New(ctx, connURL.String())
+	if err != nil {
+		return nil, err
+	}
+
+	adapter, err := NewPostgresLockAdapter(
+		pool,
+		NewPostgresLockerConfig().SetHooks(openCfg.Hooks),
+	)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	if migrate {
+		if err := adapter.PrepareDbForMigrations(ctx); err != nil {
+			pool.Close()
+			return nil, err
+		}
+		if err := adapter.RunMigrations(ctx); err != nil {
+			pool.Close()
+			return nil, err
+		}
+	}
+
+	return adapter, nil
+}
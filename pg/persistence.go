@@ -0,0 +1,53 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+)
+
+// ConvertToUnlogged converts the lock and waiters tables of an existing
+// installation to UNLOGGED, see Unlogged.
+//
+// Postgres rewrites the tables under an exclusive lock, blocking every
+// lock operation meanwhile: run it while the tables are small.
+func (i *PostgresLockAdapter) ConvertToUnlogged(ctx context.Context) error {
+	return i.setPersistence(ctx, "UNLOGGED")
+}
+
+// ConvertToLogged converts the lock and waiters tables back to regular
+// logged tables, written to the write-ahead log and replicated.
+//
+// Postgres rewrites the tables under an exclusive lock, blocking every
+// lock operation meanwhile.
+func (i *PostgresLockAdapter) ConvertToLogged(ctx context.Context) error {
+	return i.setPersistence(ctx, "LOGGED")
+}
+
+func (i *PostgresLockAdapter) setPersistence(ctx context.Context, persistence string) error {
+	if err := i.begin(); err != nil {
+		return err
+	}
+	defer i.end()
+
+	unlock, err := i.lockMigrations(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	tx, err := i.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, "ALTER TABLE "+i.Cfg.lockTable()+" SET "+persistence); err != nil {
+		return fmt.Errorf("failed to set the lock table %s: %w", persistence, err)
+	}
+	// The waiters table only exists from v0.0.4 on
+	if _, err := tx.Exec(ctx, "ALTER TABLE IF EXISTS "+i.Cfg.lockWaitersTable()+" SET "+persistence); err != nil {
+		return fmt.Errorf("failed to set the waiters table %s: %w", persistence, err)
+	}
+
+	return tx.Commit(ctx)
+}
@@ -2,10 +2,23 @@ package pg_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/oliveiracleidson/go-lockbox/pg"
 	"github.com/stretchr/testify/require"
 )
 
@@ -26,6 +39,11 @@ func TestNewPostgresLockerMigration_Migration(t *testing.T) {
 		adapter.Cfg.LockSchema = "locker"
 		adapter.Cfg.LockTableName = "locks"
 
+		// The adapter renders its SQL from the configuration once
+		var err error
+		adapter, err = pg.NewPostgresLockAdapter(pgxPool, adapter.Cfg)
+		require.NoError(t, err)
+
 		res, err := adapter.GetSchemaStatus(context.Background())
 		require.NoError(t, err)
 		require.NotNil(t, res)
@@ -51,6 +69,10 @@ func TestNewPostgresLockerMigration_Migration(t *testing.T) {
 		require.NoError(t, err)
 		require.NotNil(t, res)
 		require.False(t, res.LockTableExists)
+		require.False(t, res.TryAcquireLockExists)
+		require.Empty(t, res.AppliedVersions)
+		require.Contains(t, res.PendingVersions, "v0.0.1")
+		require.False(t, res.Ready())
 
 		err = adapter.RunMigrations(context.Background())
 		require.NoError(t, err)
@@ -59,6 +81,12 @@ func TestNewPostgresLockerMigration_Migration(t *testing.T) {
 		require.NoError(t, err)
 		require.NotNil(t, res)
 		require.True(t, res.LockTableExists)
+		require.True(t, res.TryAcquireLockExists)
+		require.True(t, res.IndexesExist)
+		require.Equal(t, "v0.0.1", res.AppliedVersions[0])
+		require.NotContains(t, res.AppliedVersions, "v0.0.6-metadata-index")
+		require.Empty(t, res.PendingVersions)
+		require.True(t, res.Ready())
 	})
 
 	t.Run("given a key with metadata and lock is not acquired by others, then create lock", func(t *testing.T) {
@@ -152,6 +180,15 @@ func TestNewPostgresLockerMigration_Migration(t *testing.T) {
 		require.Error(t, err)
 		require.Nil(t, res)
 		require.ErrorAs(t, err, &core.ErrLockAcquisitionFailed)
+
+		lockErr, ok := core.AsLockError(err)
+		require.True(t, ok)
+		require.Equal(t, "key-lock", lockErr.Key)
+		require.Equal(t, 6, lockErr.Attempts)
+		require.ErrorIs(t, err, core.ErrLockContention)
+		// 100ms + 200ms + 400ms + 800ms + 1.6s of backoff, minus jitter
+		require.Greater(t, lockErr.Elapsed, 2*time.Second)
+		require.False(t, lockErr.LastHolderExpiry.IsZero())
 	})
 
 	t.Run("given a key released, when try to acquire the key, then acquire with success", func(t *testing.T) {
@@ -218,4 +255,2443 @@ func TestNewPostgresLockerMigration_Migration(t *testing.T) {
 		require.NotEqual(t, firstLock.LeaseID, res.LeaseID)
 		require.NotEqual(t, firstLock.ServerNonce, res.ServerNonce)
 	})
+
+	t.Run("given a held key, when get contention info, then returns held and remaining ttl", func(t *testing.T) {
+		lock, err := adapter.Acquire(
+			context.Background(),
+			"key-contention-info",
+			core.LockOptions{
+				TTL: 10 * time.Second,
+				RetryStrategy: core.RetryStrategy{
+					MaxRetries:    0,
+					BackoffFactor: 2,
+				},
+				RequestTimeout: 5 * time.Second,
+			},
+		)
+		require.NoError(t, err)
+		require.NotNil(t, lock)
+
+		info, err := adapter.ContentionInfo(context.Background(), "key-contention-info")
+		require.NoError(t, err)
+		require.True(t, info.Held)
+		require.Greater(t, info.Remaining, 9*time.Second)
+		require.Equal(t, 0, info.Waiters)
+
+		info, err = adapter.ContentionInfo(context.Background(), "key-contention-info-not-held")
+		require.NoError(t, err)
+		require.False(t, info.Held)
+		require.Zero(t, info.Remaining)
+	})
+
+	t.Run("given a lock acquired with owner ID, when get lock info, then returns the owner", func(t *testing.T) {
+		opts := core.LockOptions{
+			TTL: 10 * time.Second,
+			RetryStrategy: core.RetryStrategy{
+				MaxRetries:    0,
+				BackoffFactor: 2,
+			},
+			Metadata:       map[string]string{"job": "billing"},
+			RequestTimeout: 5 * time.Second,
+			OwnerID:        "orders-worker-3",
+		}
+		lock, err := adapter.Acquire(context.Background(), "key-owned", opts)
+		require.NoError(t, err)
+		require.Equal(t, "orders-worker-3", lock.OwnerID)
+
+		info, err := adapter.GetLockInfo(context.Background(), "key-owned")
+		require.NoError(t, err)
+		require.Equal(t, "orders-worker-3", info.OwnerID)
+		require.Equal(t, map[string]string{"job": "billing"}, info.Metadata)
+
+		locks, err := adapter.ListLocks(context.Background())
+		require.NoError(t, err)
+		require.Contains(t, locks, *info)
+
+		opts.OwnerID = "orders-worker-4"
+		_, err = adapter.Acquire(context.Background(), "key-owned", opts)
+		require.ErrorIs(t, err, core.ErrLockAcquisitionFailed)
+		require.ErrorIs(t, err, core.ErrLockContention)
+		require.Contains(t, err.Error(), "held by orders-worker-3")
+
+		var contentionErr *core.ContentionError
+		require.ErrorAs(t, err, &contentionErr)
+		require.Equal(t, info.ValidUntil, contentionErr.HeldUntil)
+		require.Equal(t, map[string]string{"job": "billing"}, contentionErr.HolderMetadata)
+
+		_, err = adapter.GetLockInfo(context.Background(), "key-not-locked")
+		require.ErrorIs(t, err, core.ErrLockNotFound)
+	})
+
+	t.Run("given adapters with different namespaces, when acquire the same key, then both acquire", func(t *testing.T) {
+		opts := core.LockOptions{
+			TTL: 10 * time.Second,
+			RetryStrategy: core.RetryStrategy{
+				MaxRetries:    0,
+				BackoffFactor: 2,
+			},
+			RequestTimeout: 5 * time.Second,
+		}
+
+		teamA, err := pg.NewPostgresLockAdapter(pgxPool, namespacedConfig("team-a"))
+		require.NoError(t, err)
+		teamB, err := pg.NewPostgresLockAdapter(pgxPool, namespacedConfig("team-b"))
+		require.NoError(t, err)
+
+		lockA, err := teamA.Acquire(context.Background(), "orders-123", opts)
+		require.NoError(t, err)
+		require.Equal(t, "orders-123", lockA.Key)
+
+		lockB, err := teamB.Acquire(context.Background(), "orders-123", opts)
+		require.NoError(t, err)
+		require.Equal(t, "orders-123", lockB.Key)
+
+		locks, err := teamA.ListLocks(context.Background())
+		require.NoError(t, err)
+		require.Len(t, locks, 1)
+		require.Equal(t, "orders-123", locks[0].Key)
+
+		info, err := teamA.GetLockInfo(context.Background(), "orders-123")
+		require.NoError(t, err)
+		require.Equal(t, "orders-123", info.Key)
+
+		// Only a namespaced adapter reaches the keys of its namespace
+		_, err = adapter.GetLockInfo(context.Background(), "team-a:orders-123")
+		require.ErrorIs(t, err, core.ErrInvalidKeyFormat)
+
+		require.NoError(t, teamA.Release(context.Background(), lockA))
+		require.NoError(t, teamB.Release(context.Background(), lockB))
+	})
+	t.Run("given locks of several owners, when release all by owner, then only the owner locks are released", func(t *testing.T) {
+		opts := core.LockOptions{
+			TTL: 10 * time.Second,
+			RetryStrategy: core.RetryStrategy{
+				MaxRetries:    0,
+				BackoffFactor: 2,
+			},
+			RequestTimeout: 5 * time.Second,
+		}
+
+		opts.OwnerID = "shutdown-worker"
+		_, err := adapter.Acquire(context.Background(), "key-shutdown-1", opts)
+		require.NoError(t, err)
+		_, err = adapter.Acquire(context.Background(), "key-shutdown-2", opts)
+		require.NoError(t, err)
+
+		opts.OwnerID = "shutdown-worker-2"
+		other, err := adapter.Acquire(context.Background(), "key-shutdown-3", opts)
+		require.NoError(t, err)
+
+		released, err := adapter.ReleaseAllByOwner(context.Background(), "shutdown-worker")
+		require.NoError(t, err)
+		require.Equal(t, 2, released)
+
+		info, err := adapter.GetLockInfo(context.Background(), "key-shutdown-3")
+		require.NoError(t, err)
+		require.Equal(t, "shutdown-worker-2", info.OwnerID)
+
+		released, err = adapter.ReleaseAllByOwner(context.Background(), "shutdown-worker")
+		require.NoError(t, err)
+		require.Equal(t, 0, released)
+
+		require.NoError(t, adapter.Release(context.Background(), other))
+	})
+	t.Run("given FIFO mode and a held key, when two waiters retry, then the first waiter wins", func(t *testing.T) {
+		cfg := *adapter.Cfg
+		fifo, err := pg.NewPostgresLockAdapter(pgxPool, cfg.SetFIFO(true))
+		require.NoError(t, err)
+
+		holder, err := fifo.Acquire(context.Background(), "key-fifo", core.LockOptions{
+			TTL:            time.Second,
+			RetryStrategy:  core.RetryStrategy{BackoffFactor: 1},
+			RequestTimeout: 5 * time.Second,
+		})
+		require.NoError(t, err)
+		require.NotNil(t, holder)
+
+		winners := make(chan string, 2)
+		waiter := func(name string, delay time.Duration) {
+			token, err := fifo.Acquire(context.Background(), "key-fifo", core.LockOptions{
+				TTL: time.Second,
+				RetryStrategy: core.RetryStrategy{
+					MaxRetries:    int(5 * time.Second / delay),
+					BaseDelay:     delay,
+					MaxDelay:      delay,
+					BackoffFactor: 1,
+				},
+				RequestTimeout: 5 * time.Second,
+			})
+			if err != nil {
+				winners <- "error: " + err.Error()
+				return
+			}
+			winners <- name
+			_ = fifo.Release(context.Background(), token)
+		}
+
+		// The first waiter polls slowly, without FIFO the second one
+		// would almost always win
+		go waiter("first", 300*time.Millisecond)
+		time.Sleep(100 * time.Millisecond)
+		go waiter("second", 10*time.Millisecond)
+
+		require.Equal(t, "first", <-winners)
+		require.Equal(t, "second", <-winners)
+	})
+	t.Run("given tokens of several states, when refresh batch, then each token reports its own result", func(t *testing.T) {
+		opts := core.LockOptions{
+			TTL: 10 * time.Second,
+			RetryStrategy: core.RetryStrategy{
+				MaxRetries:    0,
+				BackoffFactor: 2,
+			},
+			RequestTimeout: 5 * time.Second,
+		}
+
+		owned, err := adapter.Acquire(context.Background(), "key-batch-owned", opts)
+		require.NoError(t, err)
+		other, err := adapter.Acquire(context.Background(), "key-batch-other", opts)
+		require.NoError(t, err)
+
+		stolen := *other
+		stolen.ServerNonce = "wrong-nonce"
+		missing := &core.LockToken{Key: "key-batch-missing", LeaseID: "lease", ServerNonce: "nonce"}
+		previousValidUntil := owned.ValidUntil
+
+		refreshed, errs := adapter.RefreshBatch(
+			context.Background(),
+			[]*core.LockToken{owned, &stolen, missing},
+			time.Minute,
+		)
+		require.Len(t, refreshed, 3)
+		require.Len(t, errs, 3)
+
+		require.NoError(t, errs[0])
+		require.True(t, refreshed[0].ValidUntil.After(previousValidUntil))
+		require.NotEqual(t, owned.ServerNonce, refreshed[0].ServerNonce)
+
+		require.Nil(t, refreshed[1])
+		require.ErrorIs(t, errs[1], core.ErrLockOwnershipMismatch)
+
+		require.Nil(t, refreshed[2])
+		require.ErrorIs(t, errs[2], core.ErrLockNotFound)
+
+		require.NoError(t, adapter.Release(context.Background(), refreshed[0]))
+		require.NoError(t, adapter.Release(context.Background(), other))
+	})
+	t.Run("given a retry budget, when the key stays held, then acquire gives up when the budget is spent", func(t *testing.T) {
+		holder, err := adapter.Acquire(context.Background(), "key-retry-budget", core.LockOptions{
+			TTL:            time.Minute,
+			RetryStrategy:  core.RetryStrategy{BackoffFactor: 1},
+			RequestTimeout: 5 * time.Second,
+		})
+		require.NoError(t, err)
+
+		start := time.Now()
+		_, err = adapter.Acquire(context.Background(), "key-retry-budget", core.LockOptions{
+			TTL: time.Minute,
+			RetryStrategy: core.RetryStrategy{
+				MaxRetries:    100,
+				BaseDelay:     100 * time.Millisecond,
+				MaxDelay:      100 * time.Millisecond,
+				BackoffFactor: 1,
+				MaxElapsed:    2 * time.Second,
+			},
+			RequestTimeout: 5 * time.Second,
+		})
+		elapsed := time.Since(start)
+
+		require.ErrorIs(t, err, core.ErrLockAcquisitionFailed)
+		require.InDelta(t, 2*time.Second, elapsed, float64(300*time.Millisecond))
+
+		lockErr, ok := core.AsLockError(err)
+		require.True(t, ok)
+		require.Less(t, lockErr.Attempts, 101)
+		require.InDelta(t, elapsed, lockErr.Elapsed, float64(50*time.Millisecond))
+
+		require.NoError(t, adapter.Release(context.Background(), holder))
+	})
+	t.Run("given a partially applied migration set, when plan migrations, then returns only the pending ones", func(t *testing.T) {
+		pending, err := adapter.PlanMigrations(context.Background())
+		require.NoError(t, err)
+		require.Empty(t, pending)
+
+		migrationTable := adapter.Cfg.MigrationSchema + "." + adapter.Cfg.MigrationTableName
+		_, err = pgxPool.Exec(context.Background(),
+			"DELETE FROM "+migrationTable+" WHERE version IN ('v0.0.3', 'v0.0.4')",
+		)
+		require.NoError(t, err)
+
+		pending, err = adapter.PlanMigrations(context.Background())
+		require.NoError(t, err)
+		require.Len(t, pending, 2)
+		// Matches the lock table and the tables named after it
+		lockTable := `"` + adapter.Cfg.LockSchema + `"."` + adapter.Cfg.LockTableName
+		for i, version := range []string{"v0.0.3", "v0.0.4"} {
+			require.Equal(t, version, pending[i].Version)
+			require.Equal(t, "migrations/"+version+".sql", pending[i].FileName)
+			require.True(t, pending[i].Transaction)
+			require.Contains(t, pending[i].SQL, lockTable)
+			require.NotContains(t, pending[i].SQL, "{{")
+		}
+
+		_, err = pgxPool.Exec(context.Background(),
+			"INSERT INTO "+migrationTable+" (version) VALUES ('v0.0.3'), ('v0.0.4')",
+		)
+		require.NoError(t, err)
+	})
+	t.Run("given operations were made, when health check, then reports percentiles and backend details", func(t *testing.T) {
+		report := adapter.HealthCheck(context.Background())
+		require.Equal(t, core.StatusGreen, report.Status)
+		require.NoError(t, report.Error)
+		require.Equal(t, "postgres", report.Backend)
+		require.Equal(t, int32(50), report.Pool.MaxConns)
+		require.LessOrEqual(t, report.Pool.Usage, 1.0)
+		require.Positive(t, report.Pool.NewConns)
+		require.Positive(t, report.Pool.EmptyAcquires)
+		require.NotEmpty(t, report.Details["server_version"])
+		require.Equal(t, adapter.Cfg.LockTableName, report.Details["lock_table"])
+		require.Positive(t, report.Operations)
+		require.Positive(t, report.Uptime)
+		require.Positive(t, report.LatencyP50)
+		require.GreaterOrEqual(t, report.LatencyP99, report.LatencyP95)
+		require.GreaterOrEqual(t, report.LatencyP95, report.LatencyP50)
+		require.Positive(t, report.Latency)
+		require.Positive(t, report.Throughput)
+	})
+	t.Run("given a clean database, when run migrations, then a lock can be acquired immediately", func(t *testing.T) {
+		cfg := *adapter.Cfg
+		clean, err := pg.NewPostgresLockAdapter(pgxPool, cfg.
+			SetMigrationSchema("locker_clean").
+			SetLockSchema("locker_clean"),
+		)
+		require.NoError(t, err)
+
+		require.NoError(t, clean.PrepareDbForMigrations(context.Background()))
+		require.NoError(t, clean.RunMigrations(context.Background()))
+
+		lock, err := clean.Acquire(context.Background(), "key-clean", core.LockOptions{
+			TTL:            time.Second,
+			RetryStrategy:  core.RetryStrategy{BackoffFactor: 1},
+			RequestTimeout: 5 * time.Second,
+		})
+		require.NoError(t, err)
+		require.NotNil(t, lock)
+
+		_, err = pgxPool.Exec(context.Background(), `DROP SCHEMA "locker_clean" CASCADE`)
+		require.NoError(t, err)
+	})
+	t.Run("given applied migrations, when roll back in reverse order, then the lock table is dropped", func(t *testing.T) {
+		cfg := *adapter.Cfg
+		rollback, err := pg.NewPostgresLockAdapter(pgxPool, cfg.
+			SetMigrationSchema("locker_rollback").
+			SetLockSchema("locker_rollback"),
+		)
+		require.NoError(t, err)
+
+		require.NoError(t, rollback.PrepareDbForMigrations(context.Background()))
+		require.NoError(t, rollback.RunMigrations(context.Background()))
+
+		err = rollback.RollbackMigration(context.Background(), "v0.0.1")
+		require.ErrorIs(t, err, pg.ErrRollbackOutOfOrder)
+
+		versions := []string{"v0.0.8", "v0.0.7", "v0.0.6-indexes", "v0.0.6", "v0.0.4", "v0.0.3", "v0.0.2-indexes", "v0.0.2", "v0.0.1-names", "v0.0.1-indexes", "v0.0.1"}
+		for _, version := range versions {
+			require.NoError(t, rollback.RollbackMigration(context.Background(), version), version)
+		}
+
+		status, err := rollback.GetSchemaStatus(context.Background())
+		require.NoError(t, err)
+		require.False(t, status.LockTableExists)
+
+		pending, err := rollback.PlanMigrations(context.Background())
+		require.NoError(t, err)
+		require.Len(t, pending, len(versions))
+
+		err = rollback.RollbackMigration(context.Background(), "v0.0.1")
+		require.ErrorIs(t, err, pg.ErrMigrationNotFound)
+
+		_, err = pgxPool.Exec(context.Background(), `DROP SCHEMA "locker_rollback" CASCADE`)
+		require.NoError(t, err)
+	})
+	t.Run("given a latency threshold lower than the probe, when health check, then status is yellow", func(t *testing.T) {
+		cfg := *adapter.Cfg
+		slow, err := pg.NewPostgresLockAdapter(pgxPool, cfg.SetLatencyThreshold(time.Nanosecond))
+		require.NoError(t, err)
+
+		report := slow.HealthCheck(context.Background())
+		require.Equal(t, core.StatusYellow, report.Status)
+		require.ErrorContains(t, report.Error, "high latency")
+		require.NotEmpty(t, report.Details["probe_latency"])
+		require.Equal(t, int32(50), report.Pool.MaxConns)
+	})
+	t.Run("given a held lock, when refresh, then extends the lock", func(t *testing.T) {
+		lock, err := adapter.Acquire(context.Background(), "key-refresh", core.LockOptions{
+			TTL:            time.Second,
+			RetryStrategy:  core.RetryStrategy{BackoffFactor: 1},
+			RequestTimeout: 5 * time.Second,
+		})
+		require.NoError(t, err)
+
+		refreshed, err := adapter.Refresh(context.Background(), lock, time.Minute)
+		require.NoError(t, err)
+		require.Equal(t, lock.Key, refreshed.Key)
+		require.Equal(t, lock.LeaseID, refreshed.LeaseID)
+		require.True(t, refreshed.ValidUntil.After(lock.ValidUntil.Add(50*time.Second)))
+
+		_, err = adapter.Refresh(context.Background(), refreshed, core.MaxLockTTL+time.Second)
+		require.ErrorIs(t, err, core.ErrInvalidTTL)
+
+		require.NoError(t, adapter.Release(context.Background(), refreshed))
+	})
+
+	t.Run("given an expired lock, when refresh, then returns ErrRefreshTooLate", func(t *testing.T) {
+		lock, err := adapter.Acquire(context.Background(), "key-refresh-expired", core.LockOptions{
+			TTL:            100 * time.Millisecond,
+			RetryStrategy:  core.RetryStrategy{BackoffFactor: 1},
+			RequestTimeout: 5 * time.Second,
+		})
+		require.NoError(t, err)
+
+		time.Sleep(500 * time.Millisecond)
+
+		refreshed, err := adapter.Refresh(context.Background(), lock, time.Second)
+		require.ErrorIs(t, err, core.ErrRefreshTooLate)
+		require.Nil(t, refreshed)
+	})
+
+	t.Run("given a wrong nonce, when refresh, then returns ErrLockOwnershipMismatch", func(t *testing.T) {
+		lock, err := adapter.Acquire(context.Background(), "key-refresh-wrong-nonce", core.LockOptions{
+			TTL:            time.Minute,
+			RetryStrategy:  core.RetryStrategy{BackoffFactor: 1},
+			RequestTimeout: 5 * time.Second,
+		})
+		require.NoError(t, err)
+
+		wrong := *lock
+		wrong.ServerNonce = "wrong-nonce"
+		refreshed, err := adapter.Refresh(context.Background(), &wrong, time.Minute)
+		require.ErrorIs(t, err, core.ErrLockOwnershipMismatch)
+		require.Nil(t, refreshed)
+
+		require.NoError(t, adapter.Release(context.Background(), lock))
+	})
+	t.Run("given a refreshed lock, when release with the pre-refresh token, then returns ErrLockOwnershipMismatch", func(t *testing.T) {
+		lock, err := adapter.Acquire(context.Background(), "key-refresh-rotate", core.LockOptions{
+			TTL:            time.Minute,
+			RetryStrategy:  core.RetryStrategy{BackoffFactor: 1},
+			RequestTimeout: 5 * time.Second,
+		})
+		require.NoError(t, err)
+		previous := *lock
+
+		refreshed, err := adapter.Refresh(context.Background(), lock, time.Minute)
+		require.NoError(t, err)
+		require.NotEqual(t, previous.ServerNonce, refreshed.ServerNonce)
+		require.Equal(t, previous, *lock)
+
+		err = adapter.Release(context.Background(), &previous)
+		require.ErrorIs(t, err, core.ErrLockOwnershipMismatch)
+
+		require.NoError(t, adapter.Release(context.Background(), refreshed))
+	})
+	t.Run("given notify on release and a held key, when released, then the waiter wakes before its backoff", func(t *testing.T) {
+		cfg := *adapter.Cfg
+		notify, err := pg.NewPostgresLockAdapter(pgxPool, cfg.SetNotifyOnRelease(true))
+		require.NoError(t, err)
+
+		holder, err := notify.Acquire(context.Background(), "key-notify", core.LockOptions{
+			TTL:            time.Minute,
+			RetryStrategy:  core.RetryStrategy{BackoffFactor: 1},
+			RequestTimeout: 5 * time.Second,
+		})
+		require.NoError(t, err)
+
+		go func() {
+			time.Sleep(300 * time.Millisecond)
+			_ = notify.Release(context.Background(), holder)
+		}()
+
+		start := time.Now()
+		token, err := notify.Acquire(context.Background(), "key-notify", core.LockOptions{
+			TTL: time.Second,
+			RetryStrategy: core.RetryStrategy{
+				MaxRetries:    1,
+				BaseDelay:     10 * time.Second,
+				MaxDelay:      10 * time.Second,
+				BackoffFactor: 1,
+			},
+			RequestTimeout: 5 * time.Second,
+		})
+		require.NoError(t, err)
+		require.Less(t, time.Since(start), 5*time.Second)
+
+		require.NoError(t, notify.Release(context.Background(), token))
+	})
+	t.Run("given an expired lock taken over by another owner, when is held, then only the new owner holds it", func(t *testing.T) {
+		stale, err := adapter.Acquire(context.Background(), "key-is-held-takeover", core.LockOptions{
+			TTL:            100 * time.Millisecond,
+			RetryStrategy:  core.RetryStrategy{BackoffFactor: 1},
+			RequestTimeout: 5 * time.Second,
+		})
+		require.NoError(t, err)
+
+		time.Sleep(500 * time.Millisecond)
+
+		current, err := adapter.Acquire(context.Background(), "key-is-held-takeover", core.LockOptions{
+			TTL:            time.Minute,
+			RetryStrategy:  core.RetryStrategy{BackoffFactor: 1},
+			RequestTimeout: 5 * time.Second,
+		})
+		require.NoError(t, err)
+
+		held, remaining, err := adapter.IsHeld(context.Background(), stale)
+		require.NoError(t, err)
+		require.False(t, held)
+		require.Zero(t, remaining)
+
+		held, remaining, err = adapter.IsHeld(context.Background(), current)
+		require.NoError(t, err)
+		require.True(t, held)
+		require.Positive(t, remaining)
+
+		locked, _, err := adapter.IsKeyLocked(context.Background(), "key-is-held-takeover")
+		require.NoError(t, err)
+		require.True(t, locked)
+
+		require.NoError(t, adapter.Release(context.Background(), current))
+
+		locked, _, err = adapter.IsKeyLocked(context.Background(), "key-is-held-takeover")
+		require.NoError(t, err)
+		require.False(t, locked)
+	})
+	t.Run("given a lock lifecycle, when get stats, then counters reflect every operation", func(t *testing.T) {
+		cfg := *adapter.Cfg
+		counted, err := pg.NewPostgresLockAdapter(pgxPool, &cfg)
+		require.NoError(t, err)
+		require.Equal(t, core.Stats{}, counted.Stats())
+
+		opts := core.LockOptions{
+			TTL:            time.Minute,
+			RetryStrategy:  core.RetryStrategy{BackoffFactor: 1},
+			RequestTimeout: 5 * time.Second,
+		}
+		lock, err := counted.Acquire(context.Background(), "key-stats", opts)
+		require.NoError(t, err)
+
+		_, err = counted.Acquire(context.Background(), "key-stats", opts)
+		require.ErrorIs(t, err, core.ErrLockContention)
+
+		refreshed, err := counted.Refresh(context.Background(), lock, time.Minute)
+		require.NoError(t, err)
+
+		require.Equal(t, int64(1), counted.Stats().Held)
+		require.NoError(t, counted.Release(context.Background(), refreshed))
+
+		require.Equal(t, core.Stats{
+			Acquires:    2,
+			Successes:   1,
+			Contentions: 1,
+			Releases:    1,
+			Refreshes:   1,
+			Held:        0,
+		}, counted.Stats())
+	})
+	t.Run("given a lock with a 1500ms TTL, when is held, then the remaining TTL keeps sub-second precision", func(t *testing.T) {
+		lock, err := adapter.Acquire(context.Background(), "key-is-held-precision", core.LockOptions{
+			TTL:            1500 * time.Millisecond,
+			RetryStrategy:  core.RetryStrategy{BackoffFactor: 1},
+			RequestTimeout: 5 * time.Second,
+		})
+		require.NoError(t, err)
+
+		held, remaining, err := adapter.IsHeld(context.Background(), lock)
+		require.NoError(t, err)
+		require.True(t, held)
+		require.Greater(t, remaining, time.Second)
+		require.Less(t, remaining, 1500*time.Millisecond)
+
+		require.NoError(t, adapter.Release(context.Background(), lock))
+	})
+	t.Run("given an expired lock not yet cleaned up, when acquire, then takes it over with a new lease", func(t *testing.T) {
+		expired, err := adapter.Acquire(context.Background(), "key-takeover", core.LockOptions{
+			TTL:            100 * time.Millisecond,
+			RetryStrategy:  core.RetryStrategy{BackoffFactor: 1},
+			RequestTimeout: 5 * time.Second,
+		})
+		require.NoError(t, err)
+
+		time.Sleep(500 * time.Millisecond)
+
+		lock, err := adapter.Acquire(context.Background(), "key-takeover", core.LockOptions{
+			TTL:            time.Minute,
+			RetryStrategy:  core.RetryStrategy{BackoffFactor: 1},
+			RequestTimeout: 5 * time.Second,
+		})
+		require.NoError(t, err)
+		require.NotEqual(t, expired.LeaseID, lock.LeaseID)
+		require.NotEqual(t, expired.ServerNonce, lock.ServerNonce)
+
+		var leaseID, nonce string
+		err = pgxPool.QueryRow(context.Background(),
+			"SELECT lease_id, server_nonce FROM "+adapter.Cfg.LockSchema+"."+adapter.Cfg.LockTableName+" WHERE key = $1",
+			"key-takeover",
+		).Scan(&leaseID, &nonce)
+		require.NoError(t, err)
+		require.Equal(t, lock.LeaseID, leaseID)
+		require.Equal(t, lock.ServerNonce, nonce)
+
+		require.NoError(t, adapter.Release(context.Background(), lock))
+	})
+	t.Run("given missing and stolen locks, when release, then distinguishes not found from ownership mismatch", func(t *testing.T) {
+		err := adapter.Release(context.Background(), &core.LockToken{
+			Key:         "key-release-missing",
+			LeaseID:     "lease",
+			ServerNonce: "nonce",
+		})
+		require.ErrorIs(t, err, core.ErrLockNotFound)
+		require.NotErrorIs(t, err, core.ErrLockOwnershipMismatch)
+
+		lock, err := adapter.Acquire(context.Background(), "key-release-stolen", core.LockOptions{
+			TTL:            time.Minute,
+			RetryStrategy:  core.RetryStrategy{BackoffFactor: 1},
+			RequestTimeout: 5 * time.Second,
+		})
+		require.NoError(t, err)
+
+		stolen := *lock
+		stolen.LeaseID = "another-lease"
+		err = adapter.Release(context.Background(), &stolen)
+		require.ErrorIs(t, err, core.ErrLockOwnershipMismatch)
+
+		require.NoError(t, adapter.Release(context.Background(), lock))
+
+		err = adapter.Release(context.Background(), lock)
+		require.ErrorIs(t, err, core.ErrLockNotFound)
+	})
+	t.Run("given two lock tables in the same schema, when run migrations for both, then they coexist", func(t *testing.T) {
+		newAdapter := func(table string) *pg.PostgresLockAdapter {
+			cfg := pg.NewPostgresLockerConfig().
+				SetMigrationSchema("locker_shared").
+				SetMigrationTableName(table + "_migrations").
+				SetLockSchema("locker_shared").
+				SetLockTableName(table)
+			a, err := pg.NewPostgresLockAdapter(pgxPool, cfg)
+			require.NoError(t, err)
+			require.NoError(t, a.PrepareDbForMigrations(context.Background()))
+			require.NoError(t, a.RunMigrations(context.Background()))
+			return a
+		}
+		first := newAdapter("first_locks")
+		second := newAdapter("second_locks")
+
+		opts := core.LockOptions{
+			TTL:            time.Minute,
+			RetryStrategy:  core.RetryStrategy{BackoffFactor: 1},
+			RequestTimeout: 5 * time.Second,
+		}
+		lockA, err := first.Acquire(context.Background(), "key-shared-schema", opts)
+		require.NoError(t, err)
+		lockB, err := second.Acquire(context.Background(), "key-shared-schema", opts)
+		require.NoError(t, err)
+
+		require.NoError(t, first.Release(context.Background(), lockA))
+		require.NoError(t, second.Release(context.Background(), lockB))
+
+		_, err = pgxPool.Exec(context.Background(), `DROP SCHEMA "locker_shared" CASCADE`)
+		require.NoError(t, err)
+	})
+	t.Run("given a heavily retried acquire, when it retries, then attempt contexts do not accumulate", func(t *testing.T) {
+		holder, err := adapter.Acquire(context.Background(), "key-retry-leak", core.LockOptions{
+			TTL:            time.Minute,
+			RetryStrategy:  core.RetryStrategy{BackoffFactor: 1},
+			RequestTimeout: 5 * time.Second,
+		})
+		require.NoError(t, err)
+
+		// Children of a foreign context type are watched by a goroutine
+		// until cancelled, so leaked attempt contexts show up as goroutines
+		goroutines := []int{}
+		cfg := *adapter.Cfg
+		counted, err := pg.NewPostgresLockAdapter(pgxPool, cfg.SetHooks(core.Hooks{
+			OnContention: func(ctx context.Context, key string, attempt int) {
+				goroutines = append(goroutines, runtime.NumGoroutine())
+			},
+		}))
+		require.NoError(t, err)
+
+		_, err = counted.Acquire(foreignContext{make(chan struct{})}, "key-retry-leak", core.LockOptions{
+			TTL: time.Second,
+			RetryStrategy: core.RetryStrategy{
+				MaxRetries:    50,
+				BaseDelay:     time.Millisecond,
+				MaxDelay:      time.Millisecond,
+				BackoffFactor: 1,
+			},
+			RequestTimeout: 5 * time.Second,
+		})
+		require.ErrorIs(t, err, core.ErrLockContention)
+		require.Len(t, goroutines, 51)
+		require.Less(t, goroutines[50]-goroutines[0], 10)
+
+		require.NoError(t, adapter.Release(context.Background(), holder))
+	})
+	t.Run("given an acquired and refreshed lock, when inspect the token, then carries its TTL", func(t *testing.T) {
+		lock, err := adapter.Acquire(context.Background(), "key-token-ttl", core.LockOptions{
+			TTL:            time.Minute,
+			RetryStrategy:  core.RetryStrategy{BackoffFactor: 1},
+			RequestTimeout: 5 * time.Second,
+		})
+		require.NoError(t, err)
+		require.Equal(t, time.Minute, lock.TTL)
+		require.False(t, lock.IsExpired())
+		require.False(t, lock.NeedsRefresh(0))
+
+		refreshed, err := adapter.Refresh(context.Background(), lock, 2*time.Minute)
+		require.NoError(t, err)
+		require.Equal(t, 2*time.Minute, refreshed.TTL)
+		require.Greater(t, refreshed.Remaining(), time.Minute)
+
+		require.NoError(t, adapter.Release(context.Background(), refreshed))
+	})
+	t.Run("given nil or empty metadata, when acquire, then stores SQL NULL", func(t *testing.T) {
+		for key, metadata := range map[string]map[string]string{
+			"key-metadata-nil":   nil,
+			"key-metadata-empty": {},
+		} {
+			lock, err := adapter.Acquire(context.Background(), key, core.LockOptions{
+				TTL:            time.Minute,
+				RetryStrategy:  core.RetryStrategy{BackoffFactor: 1},
+				RequestTimeout: 5 * time.Second,
+				Metadata:       metadata,
+			})
+			require.NoError(t, err)
+
+			var isNull bool
+			err = pgxPool.QueryRow(context.Background(),
+				"SELECT metadata IS NULL FROM "+adapter.Cfg.LockSchema+"."+adapter.Cfg.LockTableName+" WHERE key = $1",
+				key,
+			).Scan(&isNull)
+			require.NoError(t, err)
+			require.True(t, isNull, key)
+
+			require.NoError(t, adapter.Release(context.Background(), lock))
+		}
+	})
+
+	t.Run("given metadata larger than 8KB, when acquire, then returns ErrMetadataTooLarge", func(t *testing.T) {
+		_, err := adapter.Acquire(context.Background(), "key-metadata-large", core.LockOptions{
+			TTL:            time.Minute,
+			RetryStrategy:  core.RetryStrategy{BackoffFactor: 1},
+			RequestTimeout: 5 * time.Second,
+			Metadata:       map[string]string{"payload": strings.Repeat("a", core.MaxMetadataSize)},
+		})
+		require.ErrorIs(t, err, core.ErrMetadataTooLarge)
+
+		locked, _, err := adapter.IsKeyLocked(context.Background(), "key-metadata-large")
+		require.NoError(t, err)
+		require.False(t, locked)
+	})
+	t.Run("given a key held by the same owner, when acquire with ConfirmIfOwned, then returns the refreshed lock", func(t *testing.T) {
+		opts := core.LockOptions{
+			TTL:            time.Minute,
+			RetryStrategy:  core.RetryStrategy{BackoffFactor: 1},
+			RequestTimeout: 5 * time.Second,
+			OwnerID:        "leader-1",
+			ConfirmIfOwned: true,
+		}
+		lock, err := adapter.Acquire(context.Background(), "key-confirm-owned", opts)
+		require.NoError(t, err)
+
+		confirmed, err := adapter.Acquire(context.Background(), "key-confirm-owned", opts)
+		require.NoError(t, err)
+		require.Equal(t, lock.LeaseID, confirmed.LeaseID)
+		require.NotEqual(t, lock.ServerNonce, confirmed.ServerNonce)
+		require.Equal(t, "leader-1", confirmed.OwnerID)
+
+		other := opts
+		other.OwnerID = "leader-2"
+		_, err = adapter.Acquire(context.Background(), "key-confirm-owned", other)
+		require.ErrorIs(t, err, core.ErrLockContention)
+
+		err = adapter.Release(context.Background(), lock)
+		require.ErrorIs(t, err, core.ErrLockOwnershipMismatch)
+		require.NoError(t, adapter.Release(context.Background(), confirmed))
+	})
+	t.Run("given an acquire in flight, when close, then it completes and later operations are rejected", func(t *testing.T) {
+		pool, err := pgxpool.New(context.Background(), os.Getenv("DB_URL"))
+		require.NoError(t, err)
+		cfg := *adapter.Cfg
+		closing, err := pg.NewPostgresLockAdapter(pool, &cfg)
+		require.NoError(t, err)
+
+		holder, err := closing.Acquire(context.Background(), "key-close", core.LockOptions{
+			TTL:            300 * time.Millisecond,
+			RetryStrategy:  core.RetryStrategy{BackoffFactor: 1},
+			RequestTimeout: 5 * time.Second,
+		})
+		require.NoError(t, err)
+
+		// Retries until the holder expires
+		acquired := make(chan error, 1)
+		go func() {
+			token, err := closing.Acquire(context.Background(), "key-close", core.LockOptions{
+				TTL: time.Second,
+				RetryStrategy: core.RetryStrategy{
+					MaxRetries:    20,
+					BaseDelay:     100 * time.Millisecond,
+					MaxDelay:      100 * time.Millisecond,
+					BackoffFactor: 1,
+				},
+				RequestTimeout: 5 * time.Second,
+			})
+			if err == nil && token == nil {
+				err = errors.New("nil token")
+			}
+			acquired <- err
+		}()
+		time.Sleep(50 * time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		require.NoError(t, closing.Close(ctx))
+		require.NoError(t, <-acquired)
+
+		_, err = closing.Acquire(context.Background(), "key-close-after", core.LockOptions{
+			TTL:            time.Second,
+			RetryStrategy:  core.RetryStrategy{BackoffFactor: 1},
+			RequestTimeout: 5 * time.Second,
+		})
+		require.ErrorIs(t, err, core.ErrAdapterClosed)
+		require.ErrorIs(t, closing.Release(context.Background(), holder), core.ErrAdapterClosed)
+		require.NoError(t, closing.Close(context.Background()))
+	})
+	t.Run("given applied migrations, when apply migrations again, then the second run is a no-op", func(t *testing.T) {
+		cfg := pg.NewPostgresLockerConfig().
+			SetMigrationSchema("locker_twice").
+			SetLockSchema("locker_twice")
+		twice, err := pg.NewPostgresLockAdapter(pgxPool, cfg)
+		require.NoError(t, err)
+		require.NoError(t, twice.PrepareDbForMigrations(context.Background()))
+
+		first, err := twice.ApplyMigrations(context.Background())
+		require.NoError(t, err)
+		require.NotEmpty(t, first.Applied)
+		require.Empty(t, first.Skipped)
+
+		second, err := twice.ApplyMigrations(context.Background())
+		require.NoError(t, err)
+		require.Empty(t, second.Applied)
+		require.Equal(t, first.Applied, second.Skipped)
+
+		var rows, versions int
+		err = pgxPool.QueryRow(context.Background(),
+			`SELECT COUNT(*), COUNT(DISTINCT version) FROM "locker_twice"."locker_migrations"`,
+		).Scan(&rows, &versions)
+		require.NoError(t, err)
+		require.Equal(t, len(first.Applied), rows)
+		require.Equal(t, rows, versions)
+
+		_, err = pgxPool.Exec(context.Background(), `DROP SCHEMA "locker_twice" CASCADE`)
+		require.NoError(t, err)
+	})
+	t.Run("given replicas starting together, when they run migrations concurrently, then each version is applied once", func(t *testing.T) {
+		const replicas = 6
+		errs := make(chan error, replicas)
+		for n := 0; n < replicas; n++ {
+			go func() {
+				cfg := pg.NewPostgresLockerConfig().
+					SetMigrationSchema("locker_replicas").
+					SetLockSchema("locker_replicas")
+				replica, err := pg.NewPostgresLockAdapter(pgxPool, cfg)
+				if err == nil {
+					err = replica.PrepareDbForMigrations(context.Background())
+				}
+				if err == nil {
+					err = replica.RunMigrations(context.Background())
+				}
+				errs <- err
+			}()
+		}
+		for n := 0; n < replicas; n++ {
+			require.NoError(t, <-errs)
+		}
+
+		var rows, versions int
+		err := pgxPool.QueryRow(context.Background(),
+			`SELECT COUNT(*), COUNT(DISTINCT version) FROM "locker_replicas"."locker_migrations"`,
+		).Scan(&rows, &versions)
+		require.NoError(t, err)
+		require.Equal(t, rows, versions)
+
+		_, err = pgxPool.Exec(context.Background(), `DROP SCHEMA "locker_replicas" CASCADE`)
+		require.NoError(t, err)
+	})
+	t.Run("given a tampered migration checksum, when apply migrations, then fails until repaired", func(t *testing.T) {
+		cfg := pg.NewPostgresLockerConfig().
+			SetMigrationSchema("locker_checksum").
+			SetLockSchema("locker_checksum")
+		checked, err := pg.NewPostgresLockAdapter(pgxPool, cfg)
+		require.NoError(t, err)
+		require.NoError(t, checked.PrepareDbForMigrations(context.Background()))
+		require.NoError(t, checked.RunMigrations(context.Background()))
+
+		var checksum string
+		err = pgxPool.QueryRow(context.Background(),
+			`SELECT checksum FROM "locker_checksum"."locker_migrations" WHERE version = 'v0.0.1'`,
+		).Scan(&checksum)
+		require.NoError(t, err)
+		require.Len(t, checksum, 64)
+
+		_, err = pgxPool.Exec(context.Background(),
+			`UPDATE "locker_checksum"."locker_migrations" SET checksum = 'tampered' WHERE version = 'v0.0.1'`,
+		)
+		require.NoError(t, err)
+
+		_, err = checked.ApplyMigrations(context.Background())
+		require.ErrorIs(t, err, pg.ErrChecksumMismatch)
+		require.ErrorContains(t, err, "v0.0.1")
+		require.ErrorContains(t, err, "tampered")
+		require.ErrorContains(t, err, checksum)
+
+		require.NoError(t, checked.RepairChecksums(context.Background()))
+		summary, err := checked.ApplyMigrations(context.Background())
+		require.NoError(t, err)
+		require.Empty(t, summary.Applied)
+
+		_, err = pgxPool.Exec(context.Background(), `DROP SCHEMA "locker_checksum" CASCADE`)
+		require.NoError(t, err)
+	})
+	t.Run("given a version applied before checksums, when apply migrations, then it is recorded as unverified", func(t *testing.T) {
+		cfg := pg.NewPostgresLockerConfig().
+			SetMigrationSchema("locker_unverified").
+			SetLockSchema("locker_unverified")
+		checked, err := pg.NewPostgresLockAdapter(pgxPool, cfg)
+		require.NoError(t, err)
+		require.NoError(t, checked.PrepareDbForMigrations(context.Background()))
+		require.NoError(t, checked.RunMigrations(context.Background()))
+
+		_, err = pgxPool.Exec(context.Background(),
+			`UPDATE "locker_unverified"."locker_migrations" SET checksum = NULL WHERE version = 'v0.0.1'`,
+		)
+		require.NoError(t, err)
+
+		// The SQL it was applied with is unknown, it is not stamped with today's
+		_, err = checked.ApplyMigrations(context.Background())
+		require.NoError(t, err)
+		checksum := func() string {
+			var checksum string
+			err := pgxPool.QueryRow(context.Background(),
+				`SELECT checksum FROM "locker_unverified"."locker_migrations" WHERE version = 'v0.0.1'`,
+			).Scan(&checksum)
+			require.NoError(t, err)
+			return checksum
+		}
+		require.Equal(t, "unverified", checksum())
+
+		_, err = checked.ApplyMigrations(context.Background())
+		require.NoError(t, err)
+
+		require.NoError(t, checked.RepairChecksums(context.Background()))
+		require.Len(t, checksum(), 64)
+
+		_, err = pgxPool.Exec(context.Background(), `DROP SCHEMA "locker_unverified" CASCADE`)
+		require.NoError(t, err)
+	})
+	t.Run("given a long backoff, when the context is cancelled, then acquire returns promptly", func(t *testing.T) {
+		holder, err := adapter.Acquire(context.Background(), "key-cancel-backoff", core.LockOptions{
+			TTL:            time.Minute,
+			RetryStrategy:  core.RetryStrategy{BackoffFactor: 1},
+			RequestTimeout: 5 * time.Second,
+		})
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(200*time.Millisecond, cancel)
+
+		start := time.Now()
+		_, err = adapter.Acquire(ctx, "key-cancel-backoff", core.LockOptions{
+			TTL: time.Second,
+			RetryStrategy: core.RetryStrategy{
+				MaxRetries:    3,
+				BaseDelay:     30 * time.Second,
+				MaxDelay:      30 * time.Second,
+				BackoffFactor: 1,
+			},
+			RequestTimeout: 5 * time.Second,
+		})
+		require.ErrorIs(t, err, core.ErrOperationTimeout)
+		require.ErrorIs(t, err, context.Canceled)
+		require.Less(t, time.Since(start), 2*time.Second)
+
+		require.NoError(t, adapter.Release(context.Background(), holder))
+	})
+	t.Run("given tokens of several states, when release many, then each token reports its own result", func(t *testing.T) {
+		opts := core.LockOptions{
+			TTL:            10 * time.Second,
+			RetryStrategy:  core.NoRetry(),
+			RequestTimeout: 5 * time.Second,
+		}
+
+		first, err := adapter.Acquire(context.Background(), "key-release-many-first", opts)
+		require.NoError(t, err)
+		second, err := adapter.Acquire(context.Background(), "key-release-many-second", opts)
+		require.NoError(t, err)
+		other, err := adapter.Acquire(context.Background(), "key-release-many-other", opts)
+		require.NoError(t, err)
+
+		stolen := *other
+		stolen.ServerNonce = "wrong-nonce"
+		missing := &core.LockToken{Key: "key-release-many-missing", LeaseID: "lease", ServerNonce: "nonce"}
+
+		errs := adapter.ReleaseMany(
+			context.Background(),
+			[]*core.LockToken{first, &stolen, second, missing},
+		)
+		require.Len(t, errs, 4)
+		require.NoError(t, errs[0])
+		require.ErrorIs(t, errs[1], core.ErrLockOwnershipMismatch)
+		require.NoError(t, errs[2])
+		require.ErrorIs(t, errs[3], core.ErrLockNotFound)
+
+		held, _, err := adapter.IsKeyLocked(context.Background(), "key-release-many-first")
+		require.NoError(t, err)
+		require.False(t, held)
+		held, _, err = adapter.IsKeyLocked(context.Background(), "key-release-many-other")
+		require.NoError(t, err)
+		require.True(t, held)
+
+		require.NoError(t, adapter.Release(context.Background(), other))
+	})
+	t.Run("given applied migrations, when roll back all, then every version is reverted", func(t *testing.T) {
+		cfg := *adapter.Cfg
+		rollback, err := pg.NewPostgresLockAdapter(pgxPool, cfg.
+			SetMigrationSchema("locker_rollback_all").
+			SetLockSchema("locker_rollback_all"),
+		)
+		require.NoError(t, err)
+
+		require.NoError(t, rollback.PrepareDbForMigrations(context.Background()))
+		require.NoError(t, rollback.RunMigrations(context.Background()))
+
+		require.NoError(t, rollback.ForceRollbackMigration(context.Background(), "v0.0.6"))
+		require.NoError(t, rollback.RunMigrations(context.Background()))
+
+		require.NoError(t, rollback.RollbackAll(context.Background()))
+
+		status, err := rollback.GetSchemaStatus(context.Background())
+		require.NoError(t, err)
+		require.True(t, status.MigrationTableExists)
+		require.False(t, status.LockTableExists)
+
+		pending, err := rollback.PlanMigrations(context.Background())
+		require.NoError(t, err)
+		require.Len(t, pending, 11)
+
+		require.NoError(t, rollback.RollbackAll(context.Background()))
+
+		_, err = pgxPool.Exec(context.Background(), `DROP SCHEMA "locker_rollback_all" CASCADE`)
+		require.NoError(t, err)
+	})
+	t.Run("given later migrations applied, when force roll back, then only that version is reverted", func(t *testing.T) {
+		cfg := *adapter.Cfg
+		rollback, err := pg.NewPostgresLockAdapter(pgxPool, cfg.
+			SetMigrationSchema("locker_rollback_force").
+			SetLockSchema("locker_rollback_force"),
+		)
+		require.NoError(t, err)
+
+		require.NoError(t, rollback.PrepareDbForMigrations(context.Background()))
+		require.NoError(t, rollback.RunMigrations(context.Background()))
+
+		err = rollback.RollbackMigration(context.Background(), "v0.0.2-indexes")
+		require.ErrorIs(t, err, pg.ErrRollbackOutOfOrder)
+		require.NoError(t, rollback.ForceRollbackMigration(context.Background(), "v0.0.2-indexes"))
+
+		pending, err := rollback.PlanMigrations(context.Background())
+		require.NoError(t, err)
+		require.Len(t, pending, 1)
+		require.Equal(t, "v0.0.2-indexes", pending[0].Version)
+
+		_, err = pgxPool.Exec(context.Background(), `DROP SCHEMA "locker_rollback_force" CASCADE`)
+		require.NoError(t, err)
+	})
+	t.Run("given mixed-case identifiers, when migrate and acquire, then the quoted objects are used", func(t *testing.T) {
+		cfg := *adapter.Cfg
+		mixed, err := pg.NewPostgresLockAdapter(pgxPool, cfg.
+			SetMigrationSchema("Locker_Mixed").
+			SetLockSchema("Locker_Mixed").
+			SetLockTableName("Mixed_Locks"),
+		)
+		require.NoError(t, err)
+
+		require.NoError(t, mixed.PrepareDbForMigrations(context.Background()))
+		require.NoError(t, mixed.RunMigrations(context.Background()))
+
+		status, err := mixed.GetSchemaStatus(context.Background())
+		require.NoError(t, err)
+		require.True(t, status.LockTableExists)
+
+		lock, err := mixed.Acquire(context.Background(), "key-mixed", core.LockOptions{
+			TTL:            time.Second,
+			RetryStrategy:  core.NoRetry(),
+			RequestTimeout: 5 * time.Second,
+		})
+		require.NoError(t, err)
+		require.NoError(t, mixed.Release(context.Background(), lock))
+
+		_, err = pgxPool.Exec(context.Background(), `DROP SCHEMA "Locker_Mixed" CASCADE`)
+		require.NoError(t, err)
+	})
+	t.Run("given a lock acquired in a transaction, when rolled back, then the lock is gone", func(t *testing.T) {
+		opts := core.LockOptions{
+			TTL:            time.Minute,
+			RetryStrategy:  core.NoRetry(),
+			RequestTimeout: 5 * time.Second,
+		}
+
+		tx, err := pgxPool.Begin(context.Background())
+		require.NoError(t, err)
+		defer tx.Rollback(context.Background())
+
+		lock, err := adapter.AcquireTx(context.Background(), tx, "key-tx-rollback", opts)
+		require.NoError(t, err)
+		require.NotEmpty(t, lock.LeaseID)
+
+		require.NoError(t, tx.Rollback(context.Background()))
+
+		held, _, err := adapter.IsKeyLocked(context.Background(), "key-tx-rollback")
+		require.NoError(t, err)
+		require.False(t, held)
+	})
+	t.Run("given a lock acquired in a transaction, when committed, then it is a regular lock", func(t *testing.T) {
+		opts := core.LockOptions{
+			TTL:            time.Minute,
+			RetryStrategy:  core.NoRetry(),
+			RequestTimeout: 5 * time.Second,
+		}
+
+		holder, err := adapter.Acquire(context.Background(), "key-tx-held", opts)
+		require.NoError(t, err)
+
+		tx, err := pgxPool.Begin(context.Background())
+		require.NoError(t, err)
+		defer tx.Rollback(context.Background())
+
+		_, err = adapter.AcquireTx(context.Background(), tx, "key-tx-held", opts)
+		require.ErrorIs(t, err, core.ErrLockContention)
+
+		lock, err := adapter.AcquireTx(context.Background(), tx, "key-tx-commit", opts)
+		require.NoError(t, err)
+		require.NoError(t, tx.Commit(context.Background()))
+
+		held, _, err := adapter.IsHeld(context.Background(), lock)
+		require.NoError(t, err)
+		require.True(t, held)
+
+		require.NoError(t, adapter.Release(context.Background(), lock))
+		require.NoError(t, adapter.Release(context.Background(), holder))
+	})
+	t.Run("given a session lock, when its connection is closed, then the lock is freed", func(t *testing.T) {
+		// Closing the session adapter closes its pool, keep it apart
+		sessionPool, err := pgxpool.New(context.Background(), os.Getenv("DB_URL"))
+		require.NoError(t, err)
+		session, err := pg.NewPostgresSessionLockAdapter(sessionPool, pg.NewPostgresLockerConfig())
+		require.NoError(t, err)
+		defer session.Close(context.Background())
+
+		opts := core.LockOptions{
+			TTL:            time.Second,
+			RetryStrategy:  core.NoRetry(),
+			RequestTimeout: 5 * time.Second,
+		}
+
+		lock, err := session.Acquire(context.Background(), "key-session", opts)
+		require.NoError(t, err)
+
+		_, err = session.Acquire(context.Background(), "key-session", opts)
+		require.ErrorIs(t, err, core.ErrLockContention)
+
+		refreshed, err := session.Refresh(context.Background(), lock, time.Second)
+		require.NoError(t, err)
+		require.Equal(t, lock, refreshed)
+
+		held, _, err := session.IsHeld(context.Background(), lock)
+		require.NoError(t, err)
+		require.True(t, held)
+
+		// Simulates a crash of the holder
+		var terminated bool
+		err = pgxPool.QueryRow(context.Background(), `
+			SELECT pg_terminate_backend(pid)
+			FROM pg_locks
+			WHERE locktype = 'advisory' AND granted AND pid <> pg_backend_pid()
+			AND objid = (hashtextextended('"public"."locker_locks":key-session', 0) & x'FFFFFFFF'::BIGINT)::OID`,
+		).Scan(&terminated)
+		require.NoError(t, err)
+		require.True(t, terminated)
+
+		require.Eventually(t, func() bool {
+			other, err := session.Acquire(context.Background(), "key-session", opts)
+			if err != nil {
+				return false
+			}
+			require.NoError(t, session.Release(context.Background(), other))
+			return true
+		}, 5*time.Second, 100*time.Millisecond)
+
+		held, _, err = session.IsHeld(context.Background(), lock)
+		require.NoError(t, err)
+		require.False(t, held)
+	})
+	t.Run("given expired and valid locks, when cleanup expired, then only the old expired ones are removed", func(t *testing.T) {
+		lockTable := `"` + adapter.Cfg.LockSchema + `"."` + adapter.Cfg.LockTableName + `"`
+		_, err := pgxPool.Exec(context.Background(), `
+			INSERT INTO `+lockTable+` (key, lease_id, valid_until, server_nonce)
+			SELECT 'key-cleanup-' || n, 'lease', NOW() - INTERVAL '1 hour', 'nonce'
+			FROM generate_series(1, 5) n
+			UNION ALL
+			SELECT 'key-cleanup-recent', 'lease', NOW() - INTERVAL '1 second', 'nonce'`,
+		)
+		require.NoError(t, err)
+
+		valid, err := adapter.Acquire(context.Background(), "key-cleanup-valid", core.LockOptions{
+			TTL:            50 * time.Millisecond,
+			RetryStrategy:  core.NoRetry(),
+			RequestTimeout: 5 * time.Second,
+		})
+		require.NoError(t, err)
+
+		var reported int64
+		cfg := *adapter.Cfg
+		hooked, err := pg.NewPostgresLockAdapter(pgxPool, cfg.SetHooks(core.Hooks{
+			OnExpiredCleanup: func(ctx context.Context, removed int64) { reported += removed },
+		}))
+		require.NoError(t, err)
+
+		removed, err := hooked.CleanupExpired(context.Background(), time.Minute, 2)
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, removed, int64(5))
+		require.Equal(t, removed, reported)
+
+		var left int
+		err = pgxPool.QueryRow(context.Background(),
+			`SELECT COUNT(*) FROM `+lockTable+` WHERE key LIKE 'key-cleanup-%'`,
+		).Scan(&left)
+		require.NoError(t, err)
+		require.Equal(t, 2, left)
+
+		require.NoError(t, adapter.Release(context.Background(), valid))
+		_, err = pgxPool.Exec(context.Background(), `DELETE FROM `+lockTable+` WHERE key = 'key-cleanup-recent'`)
+		require.NoError(t, err)
+	})
+	t.Run("given a sweep interval, when a lock expired, then the sweeper removes it until close", func(t *testing.T) {
+		// Close closes the pool, keep it apart
+		sweeperPool, err := pgxpool.New(context.Background(), os.Getenv("DB_URL"))
+		require.NoError(t, err)
+		cfg := *adapter.Cfg
+		sweeping, err := pg.NewPostgresLockAdapter(sweeperPool, cfg.SetSweepInterval(50*time.Millisecond))
+		require.NoError(t, err)
+
+		lockTable := `"` + adapter.Cfg.LockSchema + `"."` + adapter.Cfg.LockTableName + `"`
+		_, err = pgxPool.Exec(context.Background(), `
+			INSERT INTO `+lockTable+` (key, lease_id, valid_until, server_nonce)
+			VALUES ('key-sweep', 'lease', NOW() - INTERVAL '1 second', 'nonce')`,
+		)
+		require.NoError(t, err)
+
+		require.Eventually(t, func() bool {
+			held, _, err := adapter.IsKeyLocked(context.Background(), "key-sweep")
+			if err != nil || held {
+				return false
+			}
+			var exists bool
+			err = pgxPool.QueryRow(context.Background(),
+				`SELECT EXISTS (SELECT 1 FROM `+lockTable+` WHERE key = 'key-sweep')`,
+			).Scan(&exists)
+			return err == nil && !exists
+		}, 5*time.Second, 50*time.Millisecond)
+
+		require.NoError(t, sweeping.Close(context.Background()))
+	})
+	t.Run("given a counting ID generator, when acquire and refresh, then tokens carry its unique IDs", func(t *testing.T) {
+		var count atomic.Int64
+		cfg := *adapter.Cfg
+		counting, err := pg.NewPostgresLockAdapter(pgxPool, cfg.SetIDGenerator(core.IDGeneratorFunc(func() string {
+			return fmt.Sprintf("id-%d", count.Add(1))
+		})))
+		require.NoError(t, err)
+
+		opts := core.LockOptions{
+			TTL:            time.Minute,
+			RetryStrategy:  core.NoRetry(),
+			RequestTimeout: 5 * time.Second,
+		}
+
+		seen := map[string]bool{}
+		tokens := []*core.LockToken{}
+		for n := range 3 {
+			token, err := counting.Acquire(context.Background(), fmt.Sprintf("key-id-generator-%d", n), opts)
+			require.NoError(t, err)
+			require.Regexp(t, `^id-\d+$`, token.LeaseID)
+			require.Regexp(t, `^id-\d+$`, token.ServerNonce)
+			require.False(t, seen[token.LeaseID])
+			seen[token.LeaseID] = true
+			tokens = append(tokens, token)
+		}
+		require.Equal(t, int64(6), count.Load())
+
+		refreshed, err := counting.Refresh(context.Background(), tokens[0], time.Minute)
+		require.NoError(t, err)
+		require.Equal(t, "id-7", refreshed.ServerNonce)
+		tokens[0] = refreshed
+
+		for _, token := range tokens {
+			require.NoError(t, counting.Release(context.Background(), token))
+		}
+	})
+	t.Run("given notify on release and waiters on several keys, when released, then each waiter wakes before its backoff", func(t *testing.T) {
+		cfg := *adapter.Cfg
+		notify, err := pg.NewPostgresLockAdapter(pgxPool, cfg.SetNotifyOnRelease(true))
+		require.NoError(t, err)
+
+		keys := []string{"key-notify-shared-1", "key-notify-shared-2", "key-notify-shared-3"}
+		holders := make([]*core.LockToken, 0, len(keys))
+		for _, key := range keys {
+			holder, err := notify.Acquire(context.Background(), key, core.LockOptions{
+				TTL:           time.Minute,
+				RetryStrategy: core.RetryStrategy{BackoffFactor: 1},
+			})
+			require.NoError(t, err)
+			holders = append(holders, holder)
+		}
+
+		go func() {
+			time.Sleep(300 * time.Millisecond)
+			for _, holder := range holders {
+				_ = notify.Release(context.Background(), holder)
+			}
+		}()
+
+		start := time.Now()
+		var wg sync.WaitGroup
+		errs := make([]error, len(keys))
+		for idx, key := range keys {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				token, err := notify.Acquire(context.Background(), key, core.LockOptions{
+					TTL: time.Second,
+					RetryStrategy: core.RetryStrategy{
+						MaxRetries:    1,
+						BaseDelay:     10 * time.Second,
+						MaxDelay:      10 * time.Second,
+						BackoffFactor: 1,
+					},
+				})
+				if err == nil {
+					err = notify.Release(context.Background(), token)
+				}
+				errs[idx] = err
+			}()
+		}
+		wg.Wait()
+
+		require.Less(t, time.Since(start), 5*time.Second)
+		for _, err := range errs {
+			require.NoError(t, err)
+		}
+	})
+	t.Run("given the metadata index, when find locks by metadata, then only the matching live locks are returned", func(t *testing.T) {
+		cfg := *adapter.Cfg
+		indexed, err := pg.NewPostgresLockAdapter(pgxPool, cfg.SetMetadataIndex(true))
+		require.NoError(t, err)
+
+		summary, err := indexed.ApplyMigrations(context.Background())
+		require.NoError(t, err)
+		require.Contains(t, summary.Applied, "v0.0.6-metadata-index")
+
+		opts := core.LockOptions{
+			TTL:            time.Minute,
+			RetryStrategy:  core.NoRetry(),
+			RequestTimeout: 5 * time.Second,
+		}
+		tokens := []*core.LockToken{}
+		for _, lock := range []struct {
+			key   string
+			owner string
+		}{
+			{"key-metadata-1", "batch-worker-7"},
+			{"key-metadata-2", "batch-worker-8"},
+			{"key-metadata-3", "batch-worker-7"},
+		} {
+			opts.Metadata = map[string]string{"owner": lock.owner, "job": "billing"}
+			token, err := indexed.Acquire(context.Background(), lock.key, opts)
+			require.NoError(t, err)
+			tokens = append(tokens, token)
+		}
+
+		locks, err := indexed.FindLocksByMetadata(context.Background(), "owner", "batch-worker-7")
+		require.NoError(t, err)
+		require.Len(t, locks, 2)
+		require.Equal(t, "key-metadata-1", locks[0].Key)
+		require.Equal(t, "key-metadata-3", locks[1].Key)
+
+		require.NoError(t, indexed.Release(context.Background(), tokens[0]))
+		locks, err = indexed.FindLocksByMetadata(context.Background(), "owner", "batch-worker-7")
+		require.NoError(t, err)
+		require.Len(t, locks, 1)
+
+		locks, err = indexed.FindLocksByMetadata(context.Background(), "owner", "batch-worker-9")
+		require.NoError(t, err)
+		require.Empty(t, locks)
+
+		for _, token := range tokens[1:] {
+			require.NoError(t, indexed.Release(context.Background(), token))
+		}
+		require.NoError(t, indexed.RollbackMigration(context.Background(), "v0.0.6-metadata-index"))
+	})
+	t.Run("given a server clock, when server time, then drift is measured and carried by tokens", func(t *testing.T) {
+		serverTime, err := adapter.ServerTime(context.Background())
+		require.NoError(t, err)
+		require.WithinDuration(t, time.Now(), serverTime, time.Minute)
+		drift := adapter.ClockDrift()
+		require.Less(t, drift.Abs(), time.Minute)
+
+		token, err := adapter.Acquire(context.Background(), "key-clock-drift", core.LockOptions{
+			TTL:           time.Minute,
+			RetryStrategy: core.NoRetry(),
+		})
+		require.NoError(t, err)
+		require.Equal(t, adapter.ClockDrift(), token.ClockOffset)
+		require.NoError(t, adapter.Release(context.Background(), token))
+	})
+	t.Run("given a drift above the threshold, when health check, then reports yellow", func(t *testing.T) {
+		cfg := *adapter.Cfg
+		strict, err := pg.NewPostgresLockAdapter(pgxPool, cfg.SetClockDriftThreshold(time.Nanosecond))
+		require.NoError(t, err)
+
+		report := strict.HealthCheck(context.Background())
+		require.Equal(t, core.StatusYellow, report.Status)
+		require.ErrorContains(t, report.Error, "clock drift")
+		require.Equal(t, strict.ClockDrift().String(), report.Details["clock_drift"])
+	})
+	t.Run("given a lock bound to a request, when the request is cancelled, then the lock is released", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		lock, err := core.AcquireBound(ctx, adapter, "key-bound", core.LockOptions{
+			TTL:           time.Minute,
+			RetryStrategy: core.NoRetry(),
+		})
+		require.NoError(t, err)
+
+		held, _, err := adapter.IsKeyLocked(context.Background(), "key-bound")
+		require.NoError(t, err)
+		require.True(t, held)
+
+		cancel()
+		<-lock.Done()
+		require.NoError(t, lock.Err())
+
+		held, _, err = adapter.IsKeyLocked(context.Background(), "key-bound")
+		require.NoError(t, err)
+		require.False(t, held)
+	})
+	t.Run("given a lock expired within the safety margin, when refresh, then only a margin covering the delay succeeds", func(t *testing.T) {
+		opts := core.LockOptions{
+			TTL:            100 * time.Millisecond,
+			RetryStrategy:  core.NoRetry(),
+			RequestTimeout: 5 * time.Second,
+		}
+
+		for _, tc := range []struct {
+			margin float64
+			err    error
+		}{
+			// 15% of the 10s new TTL covers the 200ms past the expiry
+			{core.MaxClockDriftMargin, nil},
+			{0, core.ErrRefreshTooLate},
+		} {
+			cfg := *adapter.Cfg
+			margined, err := pg.NewPostgresLockAdapter(pgxPool, cfg.SetRefreshSafetyMargin(tc.margin))
+			require.NoError(t, err)
+
+			lock, err := margined.Acquire(context.Background(), "key-refresh-margin", opts)
+			require.NoError(t, err)
+
+			time.Sleep(300 * time.Millisecond)
+
+			refreshed, err := margined.Refresh(context.Background(), lock, 10*time.Second)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				// Expired, so the next acquisition takes it over
+				continue
+			}
+			require.NoError(t, err)
+			require.NoError(t, margined.Release(context.Background(), refreshed))
+		}
+	})
+	t.Run("given a per lock safety margin, when refresh, then it overrides the margin of the adapter around the delay", func(t *testing.T) {
+		for _, tc := range []struct {
+			margin float64
+			err    error
+		}{
+			// The 10s new TTL gives 500ms, then 100ms, for the 200ms past the expiry
+			{0.05, nil},
+			{0.01, core.ErrRefreshTooLate},
+		} {
+			lock, err := adapter.Acquire(context.Background(), "key-refresh-margin-override", core.LockOptions{
+				TTL:                 100 * time.Millisecond,
+				RetryStrategy:       core.NoRetry(),
+				RequestTimeout:      5 * time.Second,
+				RefreshSafetyMargin: &tc.margin,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tc.margin, *lock.RefreshSafetyMargin)
+
+			time.Sleep(300 * time.Millisecond)
+
+			refreshed, err := adapter.Refresh(context.Background(), lock, 10*time.Second)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err, tc.margin)
+				continue
+			}
+			require.NoError(t, err, tc.margin)
+			require.Equal(t, tc.margin, *refreshed.RefreshSafetyMargin, "kept by the refreshed token")
+			require.NoError(t, adapter.Release(context.Background(), refreshed))
+		}
+	})
+	t.Run("given a pool, when warm up, then the connections are open and idle", func(t *testing.T) {
+		require.NoError(t, adapter.Warmup(context.Background(), 5))
+
+		stat := pgxPool.Stat()
+		require.GreaterOrEqual(t, stat.TotalConns(), int32(5))
+		require.GreaterOrEqual(t, stat.IdleConns(), int32(5))
+	})
+	t.Run("given applied migrations, when index status, then the expiry indexes exist and serve the sweeper", func(t *testing.T) {
+		statuses, err := adapter.IndexStatus(context.Background())
+		require.NoError(t, err)
+
+		exists := map[string]bool{}
+		for _, status := range statuses {
+			exists[status.Name] = status.Exists && status.Valid
+		}
+		table := adapter.Cfg.LockTableName
+		require.True(t, exists[table+"_expiry_key_idx"])
+		require.True(t, exists[table+"_key_expiry_idx"])
+		require.True(t, exists[table+"_lease_idx"])
+		require.False(t, exists[table+"_expiration_idx"])
+
+		// The table is too small for the planner to prefer an index
+		tx, err := pgxPool.Begin(context.Background())
+		require.NoError(t, err)
+		defer func() { _ = tx.Rollback(context.Background()) }()
+		_, err = tx.Exec(context.Background(), "SET LOCAL enable_seqscan = off")
+		require.NoError(t, err)
+
+		lockTable := `"` + adapter.Cfg.LockSchema + `"."` + table + `"`
+		rows, err := tx.Query(context.Background(), `EXPLAIN SELECT key FROM `+lockTable+` WHERE valid_until < NOW()`)
+		require.NoError(t, err)
+		plan, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		require.NoError(t, err)
+		require.Contains(t, strings.Join(plan, "\n"), table+"_expiry_key_idx")
+	})
+
+	t.Run("given the unlogged option, when migrate fresh in both modes, then the schema status reports the persistence", func(t *testing.T) {
+		for _, unlogged := range []bool{false, true} {
+			cfg := pg.NewPostgresLockerConfig().
+				SetMigrationSchema("locker_persistence").
+				SetLockSchema("locker_persistence").
+				SetUnlogged(unlogged)
+			fresh, err := pg.NewPostgresLockAdapter(pgxPool, cfg)
+			require.NoError(t, err)
+			require.NoError(t, fresh.PrepareDbForMigrations(context.Background()))
+			require.NoError(t, fresh.RunMigrations(context.Background()))
+
+			status, err := fresh.GetSchemaStatus(context.Background())
+			require.NoError(t, err)
+			require.True(t, status.LockTableExists)
+			require.Equal(t, unlogged, status.LockTableUnlogged)
+
+			_, err = fresh.Acquire(context.Background(), "persistence", core.LockOptions{TTL: time.Minute})
+			require.NoError(t, err)
+
+			// Converting keeps the locks and flips the persistence
+			if unlogged {
+				require.NoError(t, fresh.ConvertToLogged(context.Background()))
+			} else {
+				require.NoError(t, fresh.ConvertToUnlogged(context.Background()))
+			}
+			status, err = fresh.GetSchemaStatus(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, !unlogged, status.LockTableUnlogged)
+
+			held, _, err := fresh.IsKeyLocked(context.Background(), "persistence")
+			require.NoError(t, err)
+			require.True(t, held)
+
+			// A converted installation still passes the checksum verification
+			require.NoError(t, fresh.RunMigrations(context.Background()))
+
+			_, err = pgxPool.Exec(context.Background(), `DROP SCHEMA "locker_persistence" CASCADE`)
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("given a held key, when queried without its token, then only is key locked reports it", func(t *testing.T) {
+		lock, err := adapter.Acquire(context.Background(), "key-monitored", core.LockOptions{TTL: time.Minute})
+		require.NoError(t, err)
+
+		// A monitoring tool knows the key but not the lease
+		unknown := &core.LockToken{Key: "key-monitored"}
+		held, _, err := adapter.IsHeld(context.Background(), unknown)
+		require.NoError(t, err)
+		require.False(t, held)
+
+		locked, remaining, err := adapter.IsKeyLocked(context.Background(), "key-monitored")
+		require.NoError(t, err)
+		require.True(t, locked)
+		require.Greater(t, remaining, 50*time.Second)
+
+		require.NoError(t, adapter.Release(context.Background(), lock))
+		locked, remaining, err = adapter.IsKeyLocked(context.Background(), "key-monitored")
+		require.NoError(t, err)
+		require.False(t, locked)
+		require.Zero(t, remaining)
+	})
+
+	t.Run("given events enabled, when a key goes through its lifecycle, then the events arrive in order", func(t *testing.T) {
+		evented, err := pg.NewPostgresLockAdapter(pgxPool, pg.NewPostgresLockerConfig().SetEventBufferSize(16))
+		require.NoError(t, err)
+
+		opts := core.LockOptions{TTL: time.Minute, RetryStrategy: core.NoRetry()}
+		lock, err := evented.Acquire(context.Background(), "key-events", opts)
+		require.NoError(t, err)
+		_, err = evented.Acquire(context.Background(), "key-events", opts)
+		require.Error(t, err)
+		lock, err = evented.Refresh(context.Background(), lock, time.Minute)
+		require.NoError(t, err)
+		require.NoError(t, evented.Release(context.Background(), lock))
+
+		want := []core.LockEventType{core.EventAcquired, core.EventContentionHit, core.EventRefreshed, core.EventReleased}
+		var last time.Time
+		for _, typ := range want {
+			event := <-evented.Events()
+			require.Equal(t, typ, event.Type, event.Type.String())
+			require.Equal(t, "key-events", event.Key)
+			require.False(t, event.At.Before(last))
+			last = event.At
+			if typ != core.EventContentionHit {
+				require.Equal(t, lock.LeaseID, event.LeaseID)
+			}
+		}
+		require.Empty(t, evented.Events())
+		require.Zero(t, evented.Stats().EventsDropped)
+	})
+	t.Run("given an expired lock, when acquire, then the token reports the takeover", func(t *testing.T) {
+		opts := core.LockOptions{TTL: 10 * time.Millisecond, RetryStrategy: core.NoRetry()}
+		crashed, err := adapter.Acquire(context.Background(), "key-takeover", opts)
+		require.NoError(t, err)
+		require.False(t, crashed.TookOver)
+		require.Empty(t, crashed.PreviousLeaseID)
+
+		time.Sleep(50 * time.Millisecond)
+
+		opts.TTL = time.Minute
+		token, err := adapter.Acquire(context.Background(), "key-takeover", opts)
+		require.NoError(t, err)
+		require.True(t, token.TookOver)
+		require.Equal(t, crashed.LeaseID, token.PreviousLeaseID)
+		require.NoError(t, adapter.Release(context.Background(), token))
+
+		token, err = adapter.Acquire(context.Background(), "key-takeover", opts)
+		require.NoError(t, err)
+		require.False(t, token.TookOver)
+		require.NoError(t, adapter.Release(context.Background(), token))
+	})
+	t.Run("given a refreshed lock, when get lock info, then it reports the lifetime of the hold", func(t *testing.T) {
+		opts := core.LockOptions{TTL: 10 * time.Millisecond, RetryStrategy: core.NoRetry()}
+		expired, err := adapter.Acquire(context.Background(), "key-lifetime", opts)
+		require.NoError(t, err)
+		_, err = adapter.Refresh(context.Background(), expired, 10*time.Millisecond)
+		require.NoError(t, err)
+		time.Sleep(50 * time.Millisecond)
+
+		opts.TTL = time.Minute
+		token, err := adapter.Acquire(context.Background(), "key-lifetime", opts)
+		require.NoError(t, err)
+		require.True(t, token.TookOver)
+
+		info, err := adapter.GetLockInfo(context.Background(), "key-lifetime")
+		require.NoError(t, err)
+		require.Equal(t, time.Minute, info.TTL)
+		require.Zero(t, info.RefreshCount, "a takeover starts a new hold")
+		acquiredAt := info.AcquiredAt
+
+		for range 2 {
+			token, err = adapter.Refresh(context.Background(), token, time.Minute)
+			require.NoError(t, err)
+		}
+
+		locks, err := adapter.ListLocks(context.Background())
+		require.NoError(t, err)
+		idx := slices.IndexFunc(locks, func(l core.LockInfo) bool { return l.Key == "key-lifetime" })
+		require.NotEqual(t, -1, idx)
+		require.Equal(t, 2, locks[idx].RefreshCount)
+		require.Equal(t, time.Minute, locks[idx].TTL)
+		require.True(t, acquiredAt.Equal(locks[idx].AcquiredAt))
+		require.True(t, locks[idx].ValidUntil.After(locks[idx].AcquiredAt))
+
+		require.NoError(t, adapter.Release(context.Background(), token))
+	})
+	t.Run("given some held keys, when acquire batch, then the free keys are acquired and the others reported", func(t *testing.T) {
+		opts := core.LockOptions{TTL: time.Minute, RetryStrategy: core.NoRetry(), OwnerID: "batch-owner"}
+		held, err := adapter.Acquire(context.Background(), "key-batch-2", core.LockOptions{TTL: time.Minute, OwnerID: "other"})
+		require.NoError(t, err)
+		expired, err := adapter.Acquire(context.Background(), "key-batch-3", core.LockOptions{TTL: 10 * time.Millisecond})
+		require.NoError(t, err)
+		time.Sleep(50 * time.Millisecond)
+
+		keys := []string{"key-batch-1", "key-batch-2", "key-batch-3", "invalid key", "key-batch-1"}
+		acquired, failed := adapter.AcquireBatch(context.Background(), keys, opts)
+		require.Len(t, acquired, 2)
+		require.Equal(t, "key-batch-1", acquired[0].Key)
+		require.False(t, acquired[0].TookOver)
+		require.Equal(t, "key-batch-3", acquired[1].Key)
+		require.True(t, acquired[1].TookOver)
+		require.Equal(t, expired.LeaseID, acquired[1].PreviousLeaseID)
+
+		require.Len(t, failed, 2)
+		require.ErrorIs(t, failed["key-batch-2"], core.ErrLockContention)
+		lockErr, ok := core.AsLockError(failed["key-batch-2"])
+		require.True(t, ok)
+		require.Equal(t, "other", lockErr.LastHolderID)
+		require.ErrorIs(t, failed["invalid key"], core.ErrInvalidKeyFormat)
+
+		for _, token := range acquired {
+			ok, _, err := adapter.IsHeld(context.Background(), token)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, "batch-owner", token.OwnerID)
+		}
+
+		errs := adapter.ReleaseMany(context.Background(), append(acquired, held))
+		for _, err := range errs {
+			require.NoError(t, err)
+		}
+	})
+	t.Run("given a released lock, when release if held again, then reports it without error", func(t *testing.T) {
+		token, err := adapter.Acquire(context.Background(), "key-release-if-held", core.LockOptions{TTL: time.Minute})
+		require.NoError(t, err)
+
+		released, err := adapter.ReleaseIfHeld(context.Background(), token)
+		require.NoError(t, err)
+		require.True(t, released)
+
+		released, err = adapter.ReleaseIfHeld(context.Background(), token)
+		require.NoError(t, err)
+		require.False(t, released)
+
+		require.ErrorIs(t, adapter.Release(context.Background(), token), core.ErrLockNotFound)
+	})
+	t.Run("given typed JSON metadata, when acquire, then it is stored verbatim", func(t *testing.T) {
+		token, err := adapter.Acquire(context.Background(), "key-json-metadata", core.LockOptions{
+			TTL:          time.Minute,
+			Metadata:     map[string]string{"ignored": "yes"},
+			MetadataJSON: json.RawMessage(`{"attempt": 3, "job": "42", "started": {"at": "2024-01-01T00:00:00Z"}}`),
+		})
+		require.NoError(t, err)
+
+		raw, err := adapter.ReadMetadata(context.Background(), "key-json-metadata")
+		require.NoError(t, err)
+		require.JSONEq(t, `{"attempt": 3, "job": "42", "started": {"at": "2024-01-01T00:00:00Z"}}`, string(raw))
+
+		info, err := adapter.GetLockInfo(context.Background(), "key-json-metadata")
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"job": "42"}, info.Metadata)
+		require.JSONEq(t, string(raw), string(info.MetadataJSON))
+
+		require.NoError(t, adapter.Release(context.Background(), token))
+
+		_, err = adapter.ReadMetadata(context.Background(), "key-json-metadata")
+		require.ErrorIs(t, err, core.ErrLockNotFound)
+	})
+	t.Run("given a schema missing a column, when verify schema, then the error names it", func(t *testing.T) {
+		require.NoError(t, adapter.VerifySchema(context.Background()))
+
+		cfg := *adapter.Cfg
+		broken, err := pg.NewPostgresLockAdapter(pgxPool, cfg.
+			SetMigrationSchema("locker_verify").
+			SetLockSchema("locker_verify"),
+		)
+		require.NoError(t, err)
+
+		err = broken.VerifySchema(context.Background())
+		require.ErrorIs(t, err, pg.ErrSchemaMismatch)
+		require.ErrorContains(t, err, `table "locker_verify"."locks" is missing`)
+		require.ErrorContains(t, err, "_try_acquire_lock(text, text, bigint, text, jsonb, text) is missing")
+
+		require.NoError(t, broken.PrepareDbForMigrations(context.Background()))
+		require.NoError(t, broken.RunMigrations(context.Background()))
+		require.NoError(t, broken.VerifySchema(context.Background()))
+
+		_, err = pgxPool.Exec(context.Background(), `ALTER TABLE "locker_verify"."locks" DROP COLUMN refresh_count`)
+		require.NoError(t, err)
+
+		err = broken.VerifySchema(context.Background())
+		require.ErrorIs(t, err, pg.ErrSchemaMismatch)
+		require.ErrorContains(t, err, "column refresh_count is missing")
+
+		verified := *broken.Cfg
+		_, err = pg.NewPostgresLockAdapter(pgxPool, verified.SetValidateOnStart(true))
+		require.ErrorIs(t, err, pg.ErrSchemaMismatch)
+
+		_, err = pgxPool.Exec(context.Background(), `DROP SCHEMA "locker_verify" CASCADE`)
+		require.NoError(t, err)
+	})
+	t.Run("given a lock without metadata, when read metadata, then it is nil", func(t *testing.T) {
+		token, err := adapter.Acquire(context.Background(), "key-no-metadata", core.LockOptions{TTL: time.Minute})
+		require.NoError(t, err)
+
+		raw, err := adapter.ReadMetadata(context.Background(), "key-no-metadata")
+		require.NoError(t, err)
+		require.Nil(t, raw)
+
+		info, err := adapter.GetLockInfo(context.Background(), "key-no-metadata")
+		require.NoError(t, err)
+		require.Nil(t, info.Metadata)
+		require.Nil(t, info.MetadataJSON)
+
+		var stored *string
+		err = pgxPool.QueryRow(context.Background(),
+			"SELECT metadata::TEXT FROM "+adapter.Cfg.LockSchema+"."+adapter.Cfg.LockTableName+" WHERE key = $1",
+			"key-no-metadata",
+		).Scan(&stored)
+		require.NoError(t, err)
+		require.Nil(t, stored, "stored as SQL NULL, not a JSON null")
+
+		require.NoError(t, adapter.Release(context.Background(), token))
+	})
+	t.Run("given databases migrated by other versions, when check compatibility, then the versions are reported", func(t *testing.T) {
+		current, err := adapter.CurrentSchemaVersion(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, pg.SchemaVersion, current)
+		require.NoError(t, adapter.CheckCompatibility(context.Background()))
+
+		cfg := *adapter.Cfg
+		other, err := pg.NewPostgresLockAdapter(pgxPool, cfg.
+			SetMigrationSchema("locker_version").
+			SetLockSchema("locker_version"),
+		)
+		require.NoError(t, err)
+
+		err = other.CheckCompatibility(context.Background())
+		require.ErrorIs(t, err, pg.ErrSchemaIncompatible)
+		require.ErrorContains(t, err, "no migration applied")
+
+		require.NoError(t, other.PrepareDbForMigrations(context.Background()))
+		require.NoError(t, other.RunMigrations(context.Background()))
+		require.NoError(t, other.CheckCompatibility(context.Background()))
+
+		expects := "library expects " + pg.MinSchemaVersion + ".." + pg.SchemaVersion
+
+		// Migrated by a newer library
+		_, err = pgxPool.Exec(context.Background(),
+			`INSERT INTO "locker_version"."migrations" (version, checksum) VALUES ('v0.0.99', 'newer')`)
+		require.NoError(t, err)
+		err = other.CheckCompatibility(context.Background())
+		require.ErrorIs(t, err, pg.ErrSchemaIncompatible)
+		require.ErrorContains(t, err, "database at v0.0.99, "+expects)
+
+		verified := *other.Cfg
+		_, err = pg.NewPostgresLockAdapter(pgxPool, verified.SetCheckSchemaVersionOnStart(true))
+		require.ErrorIs(t, err, pg.ErrSchemaIncompatible)
+
+		// Migrated by an older library
+		_, err = pgxPool.Exec(context.Background(),
+			`DELETE FROM "locker_version"."migrations" WHERE version IN ('v0.0.99', 'v0.0.8', 'v0.0.7')`)
+		require.NoError(t, err)
+		err = other.CheckCompatibility(context.Background())
+		require.ErrorIs(t, err, pg.ErrSchemaIncompatible)
+		require.ErrorContains(t, err, "database at v0.0.6, "+expects)
+
+		_, err = pgxPool.Exec(context.Background(), `DROP SCHEMA "locker_version" CASCADE`)
+		require.NoError(t, err)
+	})
+	t.Run("given hashed long keys, when acquire, then the original key round trips", func(t *testing.T) {
+		cfg := *adapter.Cfg
+		hashing, err := pg.NewPostgresLockAdapter(pgxPool, cfg.SetHashLongKeys(true))
+		require.NoError(t, err)
+
+		key := "https://example.com/orders/" + strings.Repeat("x", 300)
+		_, err = adapter.Acquire(context.Background(), key, core.LockOptions{TTL: time.Minute})
+		require.ErrorIs(t, err, core.ErrInvalidKeyFormat, "rejected without HashLongKeys")
+
+		token, err := hashing.Acquire(context.Background(), key, core.LockOptions{
+			TTL:      time.Minute,
+			Metadata: map[string]string{"job": "42"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, key, token.Key)
+
+		held, _, err := hashing.IsKeyLocked(context.Background(), key)
+		require.NoError(t, err)
+		require.True(t, held)
+		held, _, err = adapter.IsKeyLocked(context.Background(), core.HashKey(key))
+		require.NoError(t, err)
+		require.True(t, held, "stored under its hash")
+
+		info, err := hashing.GetLockInfo(context.Background(), key)
+		require.NoError(t, err)
+		require.Equal(t, key, info.Key)
+		require.Equal(t, "42", info.Metadata["job"])
+		require.Equal(t, key, info.Metadata[pg.OriginalKeyMetadata])
+
+		locks, err := hashing.ListLocks(context.Background())
+		require.NoError(t, err)
+		require.True(t, slices.ContainsFunc(locks, func(l core.LockInfo) bool { return l.Key == key }))
+
+		refreshed, err := hashing.Refresh(context.Background(), token, time.Minute)
+		require.NoError(t, err)
+		require.NoError(t, hashing.Release(context.Background(), refreshed))
+
+		acquired, failed := hashing.AcquireBatch(context.Background(), []string{key, "key-not-hashed"}, core.LockOptions{TTL: time.Minute})
+		require.Empty(t, failed)
+		info, err = hashing.GetLockInfo(context.Background(), key)
+		require.NoError(t, err)
+		require.Equal(t, key, info.Key)
+		info, err = hashing.GetLockInfo(context.Background(), "key-not-hashed")
+		require.NoError(t, err)
+		require.Nil(t, info.Metadata)
+		for _, err := range hashing.ReleaseMany(context.Background(), acquired) {
+			require.NoError(t, err)
+		}
+	})
+	t.Run("given a lock with plenty of time left, when refresh if expiring, then nothing is updated until the threshold", func(t *testing.T) {
+		token, err := adapter.Acquire(context.Background(), "key-refresh-if-expiring", core.LockOptions{TTL: time.Minute})
+		require.NoError(t, err)
+
+		same, refreshed, err := adapter.RefreshIfExpiring(context.Background(), token, time.Minute, 10*time.Second)
+		require.NoError(t, err)
+		require.False(t, refreshed)
+		require.Equal(t, token, same)
+
+		info, err := adapter.GetLockInfo(context.Background(), "key-refresh-if-expiring")
+		require.NoError(t, err)
+		require.Zero(t, info.RefreshCount)
+
+		extended, refreshed, err := adapter.RefreshIfExpiring(context.Background(), token, 2*time.Minute, 2*time.Minute)
+		require.NoError(t, err)
+		require.True(t, refreshed)
+		require.True(t, extended.ValidUntil.After(token.ValidUntil))
+		require.NotEqual(t, token.ServerNonce, extended.ServerNonce)
+
+		_, _, err = adapter.RefreshIfExpiring(context.Background(), token, time.Minute, time.Second)
+		require.ErrorIs(t, err, core.ErrLockOwnershipMismatch, "the previous nonce is stale")
+
+		require.NoError(t, adapter.Release(context.Background(), extended))
+		_, _, err = adapter.RefreshIfExpiring(context.Background(), extended, time.Minute, time.Second)
+		require.ErrorIs(t, err, core.ErrLockNotFound)
+	})
+	t.Run("given a statement stuck behind a table lock, when release and is held, then they time out", func(t *testing.T) {
+		cfg := *adapter.Cfg
+		bounded, err := pg.NewPostgresLockAdapter(pgxPool, cfg.SetDefaultRequestTimeout(200*time.Millisecond))
+		require.NoError(t, err)
+
+		token, err := bounded.Acquire(context.Background(), "key-request-timeout", core.LockOptions{TTL: time.Minute})
+		require.NoError(t, err)
+
+		// Every statement on the lock table waits for the sleeping transaction
+		slept := make(chan error, 1)
+		go func() {
+			_, err := pgxPool.Exec(context.Background(),
+				"BEGIN; LOCK TABLE public.locker_locks IN ACCESS EXCLUSIVE MODE; SELECT pg_sleep(1); COMMIT;",
+				pgx.QueryExecModeSimpleProtocol,
+			)
+			slept <- err
+		}()
+		require.Eventually(t, func() bool {
+			var locked bool
+			err := pgxPool.QueryRow(context.Background(), `
+				SELECT EXISTS (
+					SELECT 1 FROM pg_locks
+					WHERE relation = 'public.locker_locks'::REGCLASS AND mode = 'AccessExclusiveLock' AND granted
+				)`).Scan(&locked)
+			return err == nil && locked
+		}, time.Second, 10*time.Millisecond)
+
+		start := time.Now()
+		err = bounded.Release(context.Background(), token)
+		require.ErrorIs(t, err, core.ErrOperationTimeout)
+		require.Less(t, time.Since(start), time.Second)
+
+		_, _, err = bounded.IsHeld(context.Background(), token)
+		require.ErrorIs(t, err, core.ErrOperationTimeout)
+
+		require.NoError(t, <-slept)
+
+		held, _, err := bounded.IsHeld(context.Background(), token)
+		require.NoError(t, err)
+		require.True(t, held, "the timed out release removed nothing")
+		require.NoError(t, bounded.Release(context.Background(), token))
+	})
+	t.Run("given an instance holding the migration lock, when run migrations with a lock timeout, then gives up", func(t *testing.T) {
+		cfg := pg.NewPostgresLockerConfig().
+			SetMigrationSchema("locker_miglock").
+			SetLockSchema("locker_miglock").
+			SetMigrationLockTimeout(300 * time.Millisecond)
+		waiting, err := pg.NewPostgresLockAdapter(pgxPool, cfg)
+		require.NoError(t, err)
+
+		// The lock of a replica stuck mid-migration
+		conn, err := pgxPool.Acquire(context.Background())
+		require.NoError(t, err)
+		defer conn.Release()
+		key := `lockbox:migrations:"locker_miglock"."locker_migrations"`
+		_, err = conn.Exec(context.Background(), "SELECT pg_advisory_lock(hashtext($1))", key)
+		require.NoError(t, err)
+
+		start := time.Now()
+		err = waiting.PrepareDbForMigrations(context.Background())
+		require.ErrorIs(t, err, pg.ErrMigrationLockTimeout)
+		require.InDelta(t, 300*time.Millisecond, time.Since(start), float64(200*time.Millisecond))
+
+		_, err = conn.Exec(context.Background(), "SELECT pg_advisory_unlock(hashtext($1))", key)
+		require.NoError(t, err)
+
+		require.NoError(t, waiting.PrepareDbForMigrations(context.Background()))
+		require.NoError(t, waiting.RunMigrations(context.Background()))
+
+		_, err = pgxPool.Exec(context.Background(), `DROP SCHEMA "locker_miglock" CASCADE`)
+		require.NoError(t, err)
+	})
+	t.Run("given an audit table, when acquire, refresh and release, then the audit log records the lifecycle", func(t *testing.T) {
+		var auditFailures atomic.Int64
+		cfg := pg.NewPostgresLockerConfig().
+			SetMigrationSchema("locker_audit").
+			SetLockSchema("locker_audit").
+			SetAuditTableName("lock_audit").
+			SetHooks(core.Hooks{
+				OnAuditFailed: func(ctx context.Context, err error) { auditFailures.Add(1) },
+			})
+		audited, err := pg.NewPostgresLockAdapter(pgxPool, cfg)
+		require.NoError(t, err)
+		require.NoError(t, audited.PrepareDbForMigrations(context.Background()))
+		require.NoError(t, audited.RunMigrations(context.Background()))
+
+		from := time.Now().Add(-time.Minute)
+		opts := core.LockOptions{
+			TTL:            10 * time.Second,
+			OwnerID:        "deployer-1",
+			Metadata:       map[string]string{"release": "v42"},
+			RequestTimeout: 5 * time.Second,
+		}
+		token, err := audited.Acquire(context.Background(), "deploy", opts)
+		require.NoError(t, err)
+		token, err = audited.Refresh(context.Background(), token, time.Minute)
+		require.NoError(t, err)
+		require.NoError(t, audited.Release(context.Background(), token))
+
+		records, err := audited.QueryAudit(context.Background(), "deploy", from, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+		for idx, event := range []pg.AuditEvent{pg.AuditAcquired, pg.AuditRefreshed, pg.AuditReleased} {
+			require.Equal(t, event, records[idx].Event)
+			require.Equal(t, "deploy", records[idx].Key)
+			require.Equal(t, token.LeaseID, records[idx].LeaseID)
+			require.Equal(t, "deployer-1", records[idx].OwnerID)
+			require.JSONEq(t, `{"release": "v42"}`, string(records[idx].Metadata))
+		}
+
+		removed, err := audited.CleanupAudit(context.Background(), 0, 2)
+		require.NoError(t, err)
+		require.EqualValues(t, 3, removed)
+		records, err = audited.QueryAudit(context.Background(), "deploy", from, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		require.Empty(t, records)
+
+		// A broken audit log never fails the operation
+		_, err = pgxPool.Exec(context.Background(), `DROP TABLE "locker_audit"."lock_audit"`)
+		require.NoError(t, err)
+		token, err = audited.Acquire(context.Background(), "deploy", opts)
+		require.NoError(t, err)
+		require.NoError(t, audited.Release(context.Background(), token))
+		require.EqualValues(t, 2, auditFailures.Load())
+
+		_, err = pgxPool.Exec(context.Background(), `DROP SCHEMA "locker_audit" CASCADE`)
+		require.NoError(t, err)
+	})
+	t.Run("given a held lock, when transfer ownership, then only the new token refreshes and releases it", func(t *testing.T) {
+		token, err := adapter.Acquire(context.Background(), "transfer-key", core.LockOptions{
+			TTL:            10 * time.Second,
+			OwnerID:        "coordinator",
+			RequestTimeout: 5 * time.Second,
+		})
+		require.NoError(t, err)
+
+		_, err = adapter.TransferOwnership(context.Background(), token, "worker 2")
+		require.ErrorIs(t, err, core.ErrInvalidOwnerID)
+
+		transferred, err := adapter.TransferOwnership(context.Background(), token, "worker-2")
+		require.NoError(t, err)
+		require.Equal(t, token.LeaseID, transferred.LeaseID)
+		require.Equal(t, "worker-2", transferred.OwnerID)
+		require.NotEqual(t, token.ServerNonce, transferred.ServerNonce)
+		require.WithinDuration(t, token.ValidUntil, transferred.ValidUntil, time.Millisecond)
+
+		info, err := adapter.GetLockInfo(context.Background(), "transfer-key")
+		require.NoError(t, err)
+		require.Equal(t, "worker-2", info.OwnerID)
+
+		_, err = adapter.Refresh(context.Background(), token, 10*time.Second)
+		require.ErrorIs(t, err, core.ErrLockOwnershipMismatch)
+		err = adapter.Release(context.Background(), token)
+		require.ErrorIs(t, err, core.ErrLockOwnershipMismatch)
+		_, err = adapter.TransferOwnership(context.Background(), token, "worker-3")
+		require.ErrorIs(t, err, core.ErrLockOwnershipMismatch)
+
+		transferred, err = adapter.Refresh(context.Background(), transferred, 10*time.Second)
+		require.NoError(t, err)
+		require.NoError(t, adapter.Release(context.Background(), transferred))
+
+		_, err = adapter.TransferOwnership(context.Background(), transferred, "worker-3")
+		require.ErrorIs(t, err, core.ErrLockNotFound)
+	})
+	t.Run("given a lock, when assert held, then tells apart held, taken, expiring and gone", func(t *testing.T) {
+		opts := core.LockOptions{TTL: time.Second, RetryStrategy: core.NoRetry(), RequestTimeout: 5 * time.Second}
+		token, err := adapter.Acquire(context.Background(), "assert-held-key", opts)
+		require.NoError(t, err)
+		require.NoError(t, adapter.AssertHeld(context.Background(), token))
+
+		refreshed, err := adapter.Refresh(context.Background(), token, time.Second)
+		require.NoError(t, err)
+		err = adapter.AssertHeld(context.Background(), token)
+		require.ErrorIs(t, err, core.ErrLockOwnershipMismatch)
+
+		// Within the last 15% of the TTL
+		time.Sleep(900 * time.Millisecond)
+		err = adapter.AssertHeld(context.Background(), refreshed)
+		require.ErrorIs(t, err, core.ErrLockExpiring)
+
+		time.Sleep(200 * time.Millisecond)
+		err = adapter.AssertHeld(context.Background(), refreshed)
+		require.ErrorIs(t, err, core.ErrLockNotFound)
+		lockErr, ok := core.AsLockError(err)
+		require.True(t, ok)
+		require.Equal(t, core.OpAssertHeld, lockErr.Op)
+		require.Equal(t, "assert-held-key", lockErr.Key)
+	})
+	t.Run("given a lock with a wider safety margin, when assert held within it, then it is expiring", func(t *testing.T) {
+		margin := core.MaxRefreshMargin
+		opts := core.LockOptions{
+			TTL:                 time.Second,
+			RetryStrategy:       core.NoRetry(),
+			RequestTimeout:      5 * time.Second,
+			RefreshSafetyMargin: &margin,
+		}
+		token, err := adapter.Acquire(context.Background(), "assert-held-margin-key", opts)
+		require.NoError(t, err)
+		defer adapter.Release(context.Background(), token)
+
+		// Past half of the TTL, still outside the default 15%
+		time.Sleep(600 * time.Millisecond)
+		err = adapter.AssertHeld(context.Background(), token)
+		require.ErrorIs(t, err, core.ErrLockExpiring)
+	})
+	t.Run("given a statement timeout, when a statement outlasts the ctx deadline, then the server cancels it", func(t *testing.T) {
+		cfg := namespacedConfig("statement-timeout").SetStatementTimeout(true)
+		bounded, err := pg.NewPostgresLockAdapter(pgxPool, cfg)
+		require.NoError(t, err)
+
+		// Never cancelled on the client, so only the server can stop it
+		ctx := deadlineOnlyContext{
+			foreignContext: foreignContext{make(chan struct{})},
+			deadline:       time.Now().Add(200 * time.Millisecond),
+		}
+		start := time.Now()
+		err = bounded.Exec(ctx, "SELECT pg_sleep(5)")
+		require.ErrorIs(t, err, core.ErrOperationTimeout)
+		var pgErr *pgconn.PgError
+		require.ErrorAs(t, err, &pgErr)
+		require.Equal(t, "57014", pgErr.Code) // query_canceled
+		require.Less(t, time.Since(start), 2*time.Second)
+
+		// The timeout is local to the transaction of the statement
+		var timeout string
+		require.NoError(t, pgxPool.QueryRow(context.Background(), "SHOW statement_timeout").Scan(&timeout))
+		require.Equal(t, "0", timeout)
+
+		token, err := bounded.Acquire(context.Background(), "statement-timeout-key", core.LockOptions{TTL: time.Second})
+		require.NoError(t, err)
+		require.NoError(t, bounded.Release(context.Background(), token))
+	})
+	t.Run("given a waiter retrying a held key, when get contention info, then counts the waiter", func(t *testing.T) {
+		opts := core.LockOptions{
+			TTL:            10 * time.Second,
+			RetryStrategy:  core.NoRetry(),
+			RequestTimeout: 5 * time.Second,
+		}
+		holder, err := adapter.Acquire(context.Background(), "key-contention-waiters", opts)
+		require.NoError(t, err)
+
+		retried := opts
+		retried.RetryStrategy = core.RetryStrategy{
+			MaxRetries:    100,
+			BaseDelay:     50 * time.Millisecond,
+			MaxDelay:      50 * time.Millisecond,
+			BackoffFactor: 1,
+		}
+		done := make(chan error, 1)
+		go func() {
+			token, err := adapter.Acquire(context.Background(), "key-contention-waiters", retried)
+			if err == nil {
+				err = adapter.Release(context.Background(), token)
+			}
+			done <- err
+		}()
+
+		require.Eventually(t, func() bool {
+			info, err := adapter.ContentionInfo(context.Background(), "key-contention-waiters")
+			return err == nil && info.Held && info.Waiters >= 1
+		}, 2*time.Second, 20*time.Millisecond)
+		require.GreaterOrEqual(t, adapter.Stats().Waiters, int64(1))
+
+		require.NoError(t, adapter.Release(context.Background(), holder))
+		require.NoError(t, <-done)
+	})
+	t.Run("given FIFO mode and a waiter of another adapter, when get contention info, then counts the waiter", func(t *testing.T) {
+		cfg := *adapter.Cfg
+		waiting, err := pg.NewPostgresLockAdapter(pgxPool, cfg.SetFIFO(true))
+		require.NoError(t, err)
+		cfg = *adapter.Cfg
+		observing, err := pg.NewPostgresLockAdapter(pgxPool, cfg.SetFIFO(true))
+		require.NoError(t, err)
+
+		opts := core.LockOptions{
+			TTL:            10 * time.Second,
+			RetryStrategy:  core.NoRetry(),
+			RequestTimeout: 5 * time.Second,
+		}
+		holder, err := observing.Acquire(context.Background(), "key-contention-fifo", opts)
+		require.NoError(t, err)
+
+		retried := opts
+		retried.RetryStrategy = core.RetryStrategy{
+			MaxRetries:    100,
+			BaseDelay:     50 * time.Millisecond,
+			MaxDelay:      50 * time.Millisecond,
+			BackoffFactor: 1,
+		}
+		done := make(chan error, 1)
+		go func() {
+			token, err := waiting.Acquire(context.Background(), "key-contention-fifo", retried)
+			if err == nil {
+				err = waiting.Release(context.Background(), token)
+			}
+			done <- err
+		}()
+
+		// The observing adapter has no local waiter, only the queue has one
+		require.Eventually(t, func() bool {
+			info, err := observing.ContentionInfo(context.Background(), "key-contention-fifo")
+			return err == nil && info.Held && info.Waiters >= 1
+		}, 2*time.Second, 20*time.Millisecond)
+		require.Zero(t, observing.Stats().Waiters)
+
+		require.NoError(t, observing.Release(context.Background(), holder))
+		require.NoError(t, <-done)
+	})
+	t.Run("given a schema migrated by the v0.0.1 release, when run migrations, then its objects are renamed after the lock table", func(t *testing.T) {
+		ctx := context.Background()
+		cfg := *adapter.Cfg
+		upgraded, err := pg.NewPostgresLockAdapter(pgxPool, cfg.
+			SetMigrationSchema("locker_upgrade").
+			SetLockSchema("locker_upgrade"),
+		)
+		require.NoError(t, err)
+		require.NoError(t, upgraded.PrepareDbForMigrations(ctx))
+
+		// The release recorded its versions without checksum
+		for _, statement := range baselineSQL("locker_upgrade", upgraded.Cfg.LockTableName) {
+			_, err = pgxPool.Exec(ctx, statement)
+			require.NoError(t, err)
+		}
+		_, err = pgxPool.Exec(ctx,
+			`INSERT INTO "locker_upgrade"."`+upgraded.Cfg.MigrationTableName+`" (version) VALUES ('v0.0.1'), ('v0.0.1-indexes')`,
+		)
+		require.NoError(t, err)
+
+		require.NoError(t, upgraded.RunMigrations(ctx))
+
+		status, err := upgraded.GetSchemaStatus(ctx)
+		require.NoError(t, err)
+		require.True(t, status.Ready())
+
+		var legacy int
+		err = pgxPool.QueryRow(ctx, `
+			SELECT COUNT(*)
+			FROM pg_class c
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE n.nspname = 'locker_upgrade'
+			  AND c.relname IN ('lock_health', 'idx_locks_expiration', 'idx_locks_lease')`,
+		).Scan(&legacy)
+		require.NoError(t, err)
+		require.Zero(t, legacy)
+
+		var legacyFunctions int
+		err = pgxPool.QueryRow(ctx, `
+			SELECT COUNT(*)
+			FROM pg_proc p
+			JOIN pg_namespace n ON n.oid = p.pronamespace
+			WHERE n.nspname = 'locker_upgrade' AND p.proname = 'try_acquire_lock'`,
+		).Scan(&legacyFunctions)
+		require.NoError(t, err)
+		require.Zero(t, legacyFunctions)
+
+		lock, err := upgraded.Acquire(ctx, "key-upgraded", core.LockOptions{
+			TTL:            time.Second,
+			RetryStrategy:  core.RetryStrategy{BackoffFactor: 1},
+			RequestTimeout: 5 * time.Second,
+		})
+		require.NoError(t, err)
+		require.NoError(t, upgraded.Release(ctx, lock))
+
+		_, err = pgxPool.Exec(ctx, `DROP SCHEMA "locker_upgrade" CASCADE`)
+		require.NoError(t, err)
+	})
+	t.Run("given a key taken over by another owner, when release if held with the stale token, then fails with ErrLockOwnershipMismatch", func(t *testing.T) {
+		opts := core.LockOptions{TTL: 100 * time.Millisecond, RetryStrategy: core.NoRetry(), RequestTimeout: 5 * time.Second}
+		stale, err := adapter.Acquire(context.Background(), "key-release-if-held-taken", opts)
+		require.NoError(t, err)
+
+		time.Sleep(300 * time.Millisecond)
+
+		opts.TTL = time.Minute
+		current, err := adapter.Acquire(context.Background(), "key-release-if-held-taken", opts)
+		require.NoError(t, err)
+
+		released, err := adapter.ReleaseIfHeld(context.Background(), stale)
+		require.ErrorIs(t, err, core.ErrLockOwnershipMismatch)
+		require.False(t, released)
+
+		held, _, err := adapter.IsHeld(context.Background(), current)
+		require.NoError(t, err)
+		require.True(t, held)
+		require.NoError(t, adapter.Release(context.Background(), current))
+	})
+	t.Run("given an expired lock nobody took over, when transfer ownership, then fails with ErrLockNotFound", func(t *testing.T) {
+		token, err := adapter.Acquire(context.Background(), "transfer-expired-key", core.LockOptions{
+			TTL:            100 * time.Millisecond,
+			OwnerID:        "coordinator",
+			RetryStrategy:  core.NoRetry(),
+			RequestTimeout: 5 * time.Second,
+		})
+		require.NoError(t, err)
+
+		time.Sleep(300 * time.Millisecond)
+
+		transferred, err := adapter.TransferOwnership(context.Background(), token, "worker-2")
+		require.ErrorIs(t, err, core.ErrLockNotFound)
+		require.NotErrorIs(t, err, core.ErrLockOwnershipMismatch)
+		require.Nil(t, transferred)
+	})
+}
+
+// namespacedConfig returns a copy of the shared adapter config
+// with the given namespace
+func namespacedConfig(namespace string) *pg.PostgresLockerConfig {
+	cfg := *adapter.Cfg
+	return cfg.SetNamespace(namespace)
+}
+
+// foreignContext is a never cancelled context unknown to the context package
+type foreignContext struct {
+	done chan struct{}
+}
+
+func (c foreignContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (c foreignContext) Done() <-chan struct{}       { return c.done }
+func (c foreignContext) Err() error                  { return nil }
+func (c foreignContext) Value(key any) any           { return nil }
+
+// deadlineOnlyContext reports a deadline but is never done, like a ctx
+// whose cancellation never reaches the server
+type deadlineOnlyContext struct {
+	foreignContext
+	deadline time.Time
+}
+
+func (c deadlineOnlyContext) Deadline() (time.Time, bool) { return c.deadline, true }
+
+// baselineSQL returns the statements of the v0.0.1 and v0.0.1-indexes
+// migrations as shipped by the v0.0.1 release, with their fixed names
+func baselineSQL(schema, table string) []string {
+	lockTable := `"` + schema + `"."` + table + `"`
+	return []string{
+		`CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+		CREATE TABLE ` + lockTable + ` (
+			key TEXT PRIMARY KEY
+				CHECK (
+					key ~ '^[a-zA-Z0-9_-]+$' AND
+					LENGTH(key) BETWEEN 1 AND 256
+				),
+			lease_id TEXT NOT NULL,
+			valid_until TIMESTAMPTZ NOT NULL,
+			server_nonce TEXT NOT NULL,
+			metadata JSONB,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);
+
+		CREATE OR REPLACE FUNCTION "` + schema + `".try_acquire_lock(
+			_key TEXT,
+			_lease_id TEXT,
+			_ttl_ms BIGINT,
+			_nonce TEXT,
+			_metadata JSONB
+		) RETURNS TABLE(
+			result_acquired BOOLEAN,
+			result_valid_until TIMESTAMPTZ
+		) AS $$
+		BEGIN
+			IF LENGTH(_key) > 256 OR _key !~ '^[a-zA-Z0-9_-]+$' THEN
+				RAISE EXCEPTION 'Invalid key format' USING ERRCODE = '22023';
+			END IF;
+
+			INSERT INTO ` + lockTable + `
+			VALUES (
+				_key,
+				_lease_id,
+				NOW() + (_ttl_ms * INTERVAL '1 millisecond') + (10 * INTERVAL '1 millisecond'),
+				_nonce,
+				_metadata,
+				NOW(),
+				NOW()
+			)
+			ON CONFLICT (key) DO UPDATE SET
+				lease_id = EXCLUDED.lease_id,
+				valid_until = EXCLUDED.valid_until,
+				server_nonce = EXCLUDED.server_nonce,
+				metadata = EXCLUDED.metadata,
+				updated_at = NOW()
+			WHERE ` + lockTable + `.valid_until <= NOW()
+			RETURNING TRUE, valid_until INTO result_acquired, result_valid_until;
+
+			RETURN QUERY SELECT COALESCE(result_acquired, FALSE), result_valid_until;
+		EXCEPTION
+			WHEN unique_violation THEN
+				RETURN QUERY SELECT FALSE, NULL;
+		END;
+		$$ LANGUAGE plpgsql VOLATILE;
+
+		CREATE VIEW "` + schema + `".lock_health AS
+		SELECT
+			COUNT(*) FILTER (WHERE valid_until > NOW()) AS active_locks,
+			COUNT(*) FILTER (WHERE valid_until <= NOW()) AS expired_locks,
+			MIN(valid_until - NOW()) FILTER (WHERE valid_until > NOW()) AS oldest_lock_ttl,
+			AVG(EXTRACT(EPOCH FROM (valid_until - created_at))) AS avg_ttl_seconds
+		FROM ` + lockTable + `;`,
+		// Concurrent index builds run outside of a transaction block
+		`CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_locks_expiration ON ` + lockTable + ` (valid_until);`,
+		`CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_locks_lease ON ` + lockTable + ` (lease_id, server_nonce);`,
+	}
 }
@@ -0,0 +1,51 @@
+package pg
+
+import (
+	"context"
+	"time"
+)
+
+// Gauges recorded by the pool metrics sampler, see PoolMetricsInterval
+const (
+	MetricPoolAcquiredConns = "lockbox_pool_acquired_conns"
+	MetricPoolIdleConns     = "lockbox_pool_idle_conns"
+
+	// Cumulative since the pool was created
+	MetricPoolNewConns      = "lockbox_pool_new_conns_count"
+	MetricPoolEmptyAcquires = "lockbox_pool_empty_acquire_count"
+)
+
+// startPoolSampler records the state of the pool into Metrics every
+// PoolMetricsInterval until Close, which waits for the last sample
+func (i *PostgresLockAdapter) startPoolSampler() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	i.stopPoolSampler = func() {
+		cancel()
+		<-done
+	}
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(i.Cfg.PoolMetricsInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				i.samplePool()
+			}
+		}
+	}()
+}
+
+// samplePool records the current state of the pool into Metrics
+func (i *PostgresLockAdapter) samplePool() {
+	s := i.db.stat()
+	i.Cfg.Metrics.Gauge(MetricPoolAcquiredConns, float64(s.AcquiredConns))
+	i.Cfg.Metrics.Gauge(MetricPoolIdleConns, float64(s.IdleConns))
+	i.Cfg.Metrics.Gauge(MetricPoolNewConns, float64(s.NewConns))
+	i.Cfg.Metrics.Gauge(MetricPoolEmptyAcquires, float64(s.EmptyAcquires))
+}
@@ -0,0 +1,84 @@
+package pg_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/oliveiracleidson/go-lockbox/pg"
+	"github.com/stretchr/testify/require"
+)
+
+// gaugeRecorder records the gauges of a LockMetrics sink
+type gaugeRecorder struct {
+	mu     sync.Mutex
+	gauges map[string][]float64
+}
+
+func (r *gaugeRecorder) Gauge(name string, value float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.gauges[name] = append(r.gauges[name], value)
+}
+
+func (r *gaugeRecorder) samples(name string) []float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]float64(nil), r.gauges[name]...)
+}
+
+func TestPostgresLockAdapter_PoolMetrics(t *testing.T) {
+	// The pool connects lazily, its statistics are available without a server
+	pool, err := pgxpool.New(context.Background(), "postgres://lockbox@127.0.0.1:1/lockbox?pool_max_conns=4")
+	require.NoError(t, err)
+
+	recorder := &gaugeRecorder{gauges: map[string][]float64{}}
+	adapter, err := pg.NewPostgresLockAdapter(pool, pg.NewPostgresLockerConfig().
+		SetPoolMetricsInterval(10*time.Millisecond).
+		SetMetrics(recorder),
+	)
+	require.NoError(t, err)
+
+	t.Run("given a sample interval, when it elapses, then the pool gauges are recorded", func(t *testing.T) {
+		require.Eventually(t, func() bool {
+			return len(recorder.samples(pg.MetricPoolEmptyAcquires)) >= 2
+		}, time.Second, 5*time.Millisecond)
+
+		for _, name := range []string{
+			pg.MetricPoolAcquiredConns,
+			pg.MetricPoolIdleConns,
+			pg.MetricPoolNewConns,
+			pg.MetricPoolEmptyAcquires,
+		} {
+			require.NotEmpty(t, recorder.samples(name), name)
+			require.Zero(t, recorder.samples(name)[0], name)
+		}
+	})
+
+	t.Run("given a closed adapter, when the interval elapses, then nothing is recorded", func(t *testing.T) {
+		require.NoError(t, adapter.Close(context.Background()))
+		sampled := len(recorder.samples(pg.MetricPoolEmptyAcquires))
+
+		time.Sleep(50 * time.Millisecond)
+		require.Len(t, recorder.samples(pg.MetricPoolEmptyAcquires), sampled)
+	})
+}
+
+func TestPostgresLockerConfig_Validate_PoolMetrics(t *testing.T) {
+	err := pg.NewPostgresLockerConfig().SetPoolMetricsInterval(time.Second).Validate()
+	var configErr *pg.ConfigError
+	require.ErrorAs(t, err, &configErr)
+	require.NotNil(t, configErr.Field("Metrics"))
+
+	err = pg.NewPostgresLockerConfig().SetPoolMetricsInterval(-time.Second).Validate()
+	require.ErrorIs(t, err, &pg.FieldError{Field: "PoolMetricsInterval"})
+
+	err = pg.NewPostgresLockerConfig().
+		SetPoolMetricsInterval(time.Second).
+		SetMetrics(core.LockMetricsFunc(func(string, float64) {})).
+		Validate()
+	require.NoError(t, err)
+}
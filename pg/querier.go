@@ -0,0 +1,324 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+// querier is the subset of the pgx API the adapter runs its statements
+// on, implemented by *pgxpool.Pool, *pgx.Conn and pgx.Tx
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// backend is where the adapter gets its connections from: a pool, a
+// single connection or a database/sql handle
+type backend interface {
+	querier
+
+	// acquire returns a connection for the exclusive use of the caller
+	// until release, which closes it instead of reusing it when broken
+	acquire(ctx context.Context) (conn *pgx.Conn, release func(broken bool), err error)
+
+	// stat returns the usage of the connections, without Usage
+	stat() core.PoolStats
+
+	// close closes the connections owned by the adapter
+	close()
+}
+
+// poolBackend runs on a pgxpool.Pool, owned by the adapter
+type poolBackend struct {
+	*pgxpool.Pool
+}
+
+func (b poolBackend) acquire(ctx context.Context) (*pgx.Conn, func(bool), error) {
+	conn, err := b.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return conn.Conn(), func(broken bool) {
+		if broken {
+			_ = conn.Conn().Close(context.Background())
+		}
+		conn.Release()
+	}, nil
+}
+
+func (b poolBackend) stat() core.PoolStats {
+	s := b.Pool.Stat()
+	return core.PoolStats{
+		MaxConns:      s.MaxConns(),
+		TotalConns:    s.TotalConns(),
+		AcquiredConns: s.AcquiredConns(),
+		IdleConns:     s.IdleConns(),
+		NewConns:      s.NewConnsCount(),
+		EmptyAcquires: s.EmptyAcquireCount(),
+	}
+}
+
+func (b poolBackend) close() {
+	b.Pool.Close()
+}
+
+// connBackend runs on a single pgx.Conn managed by the caller.
+//
+// A pgx.Conn is not safe for concurrent use, so every statement, result
+// set and transaction holds the connection until it is done.
+type connBackend struct {
+	conn *pgx.Conn
+	sem  chan struct{}
+}
+
+func newConnBackend(conn *pgx.Conn) *connBackend {
+	return &connBackend{conn: conn, sem: make(chan struct{}, 1)}
+}
+
+// lock waits for the connection, the returned function releases it
+func (b *connBackend) lock(ctx context.Context) (func(), error) {
+	select {
+	case b.sem <- struct{}{}:
+		return sync.OnceFunc(func() { <-b.sem }), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (b *connBackend) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	unlock, err := b.lock(ctx)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	defer unlock()
+	return b.conn.Exec(ctx, sql, args...)
+}
+
+func (b *connBackend) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	unlock, err := b.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := b.conn.Query(ctx, sql, args...)
+	return holdRows(rows, err, unlock)
+}
+
+func (b *connBackend) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	rows, err := b.Query(ctx, sql, args...)
+	return heldRow{rows: rows, err: err}
+}
+
+func (b *connBackend) Begin(ctx context.Context) (pgx.Tx, error) {
+	unlock, err := b.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := b.conn.Begin(ctx)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	return &heldTx{Tx: tx, release: unlock}, nil
+}
+
+func (b *connBackend) acquire(ctx context.Context) (*pgx.Conn, func(bool), error) {
+	unlock, err := b.lock(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The connection belongs to the caller, it is never closed here
+	return b.conn, func(bool) { unlock() }, nil
+}
+
+func (b *connBackend) stat() core.PoolStats {
+	acquired := int32(len(b.sem))
+	return core.PoolStats{
+		MaxConns:      1,
+		TotalConns:    1,
+		AcquiredConns: acquired,
+		IdleConns:     1 - acquired,
+	}
+}
+
+func (b *connBackend) close() {}
+
+// dbBackend runs on a database/sql handle opened with the pgx stdlib
+// driver and managed by the caller.
+//
+// Every statement takes a connection of the handle and runs on the
+// pgx.Conn beneath it, held until its result set or transaction is done.
+type dbBackend struct {
+	db *sql.DB
+}
+
+func newDBBackend(db *sql.DB) (*dbBackend, error) {
+	if _, ok := db.Driver().(*stdlib.Driver); !ok {
+		return nil, errors.New("the database/sql handle must use the pgx stdlib driver")
+	}
+	return &dbBackend{db: db}, nil
+}
+
+// conn takes a connection of the handle, the returned function gives it
+// back.
+//
+// The pgx.Conn is used after Raw returns, which database/sql allows as
+// long as the sql.Conn is held: nothing else can use it meanwhile.
+func (b *dbBackend) conn(ctx context.Context) (*pgx.Conn, func(), error) {
+	c, err := b.db.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	var conn *pgx.Conn
+	err = c.Raw(func(driverConn any) error {
+		conn = driverConn.(*stdlib.Conn).Conn()
+		return nil
+	})
+	if err != nil {
+		_ = c.Close()
+		return nil, nil, err
+	}
+	return conn, sync.OnceFunc(func() { _ = c.Close() }), nil
+}
+
+func (b *dbBackend) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	conn, release, err := b.conn(ctx)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	defer release()
+	return conn.Exec(ctx, sql, args...)
+}
+
+func (b *dbBackend) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	conn, release, err := b.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := conn.Query(ctx, sql, args...)
+	return holdRows(rows, err, release)
+}
+
+func (b *dbBackend) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	rows, err := b.Query(ctx, sql, args...)
+	return heldRow{rows: rows, err: err}
+}
+
+func (b *dbBackend) Begin(ctx context.Context) (pgx.Tx, error) {
+	conn, release, err := b.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return &heldTx{Tx: tx, release: release}, nil
+}
+
+func (b *dbBackend) acquire(ctx context.Context) (*pgx.Conn, func(bool), error) {
+	conn, release, err := b.conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	// database/sql discards a closed pgx.Conn when it is given back
+	return conn, func(broken bool) {
+		if broken {
+			_ = conn.Close(context.Background())
+		}
+		release()
+	}, nil
+}
+
+func (b *dbBackend) stat() core.PoolStats {
+	s := b.db.Stats()
+	return core.PoolStats{
+		MaxConns:      int32(s.MaxOpenConnections), // 0 when unlimited
+		TotalConns:    int32(s.OpenConnections),
+		AcquiredConns: int32(s.InUse),
+		IdleConns:     int32(s.Idle),
+		EmptyAcquires: s.WaitCount,
+	}
+}
+
+func (b *dbBackend) close() {}
+
+// holdRows wraps rows read from a held connection, so the connection is
+// released once they are closed or fully read
+func holdRows(rows pgx.Rows, err error, release func()) (pgx.Rows, error) {
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return &releasingRows{Rows: rows, release: release}, nil
+}
+
+type releasingRows struct {
+	pgx.Rows
+	release func()
+}
+
+func (r *releasingRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.release()
+	return false
+}
+
+func (r *releasingRows) Close() {
+	r.Rows.Close()
+	r.release()
+}
+
+// heldRow is the pgx.Row of a held connection, released by Scan
+type heldRow struct {
+	rows pgx.Rows
+	err  error
+}
+
+func (r heldRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	defer r.rows.Close()
+
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return pgx.ErrNoRows
+	}
+	if err := r.rows.Scan(dest...); err != nil {
+		return err
+	}
+	r.rows.Close()
+	return r.rows.Err()
+}
+
+// heldTx is a transaction of a held connection, released once it is
+// committed or rolled back
+type heldTx struct {
+	pgx.Tx
+	release func()
+}
+
+func (t *heldTx) Commit(ctx context.Context) error {
+	defer t.release()
+	return t.Tx.Commit(ctx)
+}
+
+func (t *heldTx) Rollback(ctx context.Context) error {
+	defer t.release()
+	return t.Tx.Rollback(ctx)
+}
@@ -0,0 +1,72 @@
+package pg_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/oliveiracleidson/go-lockbox/pg"
+	"github.com/stretchr/testify/require"
+)
+
+// otherDriver stands for a database/sql driver other than pgx
+type otherDriver struct{}
+
+func (otherDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("not implemented")
+}
+
+func init() {
+	sql.Register("lockbox-other", otherDriver{})
+}
+
+func TestNewPostgresLockAdapterFromDB(t *testing.T) {
+	t.Run("given a handle of another driver, when create the adapter, then fails", func(t *testing.T) {
+		db, err := sql.Open("lockbox-other", "")
+		require.NoError(t, err)
+		defer db.Close()
+
+		_, err = pg.NewPostgresLockAdapterFromDB(db, pg.NewPostgresLockerConfig())
+		require.Error(t, err)
+	})
+
+	t.Run("given an unreachable pgx handle, when used, then operations fail and close leaves it open", func(t *testing.T) {
+		connCfg, err := pgx.ParseConfig("postgres://lockbox@127.0.0.1:1/lockbox?connect_timeout=1")
+		require.NoError(t, err)
+		db := stdlib.OpenDB(*connCfg)
+		defer db.Close()
+
+		fromDB, err := pg.NewPostgresLockAdapterFromDB(db, pg.NewPostgresLockerConfig())
+		require.NoError(t, err)
+
+		// GenerateSQL never queries the database
+		var buf bytes.Buffer
+		require.NoError(t, fromDB.GenerateSQL(&buf))
+
+		_, err = fromDB.Acquire(context.Background(), "key", core.LockOptions{TTL: time.Second, RetryStrategy: core.NoRetry()})
+		require.Error(t, err)
+
+		report := fromDB.HealthCheck(context.Background())
+		require.Equal(t, core.StatusRed, report.Status)
+		require.NotNil(t, report.Pool)
+
+		require.NoError(t, fromDB.Close(context.Background()))
+		_, err = db.Conn(context.Background())
+		require.Error(t, err)
+		require.NotContains(t, err.Error(), "database is closed")
+	})
+}
+
+func TestNewPostgresLockAdapterFromConn(t *testing.T) {
+	t.Run("given notify on release, when create the adapter, then fails", func(t *testing.T) {
+		_, err := pg.NewPostgresLockAdapterFromConn(nil, pg.NewPostgresLockerConfig().SetNotifyOnRelease(true))
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,79 @@
+package pg
+
+import "fmt"
+
+// queries holds the SQL of the adapter operations with the configured
+// identifiers already injected.
+//
+// They are rendered once by NewPostgresLockAdapter, the configuration
+// being fixed after construction. Besides saving a fmt.Sprintf per call,
+// sending the exact same text lets the statement cache of pgx, enabled
+// by its default QueryExecModeCacheStatement, prepare each statement
+// once per connection.
+type queries struct {
+	tryAcquireLock     string
+	tryAcquireLockFIFO string
+	acquireBatch       string
+	confirmOwned       string
+	holder             string
+	dequeue            string
+	release            string
+	releaseMany        string
+	releaseAllByOwner  string
+	refresh            string
+	refreshBatch       string
+	refreshIfExpiring  string
+	transferOwnership  string
+	isHeld             string
+	isKeyLocked        string
+	assertHeld         string
+	contentionInfo     string
+	getLockInfo        string
+	listLocks          string
+	readMetadata       string
+	findByMetadata     string
+	cleanupExpired     string
+	audit              string
+	queryAudit         string
+	cleanupAudit       string
+}
+
+func newQueries(cfg *PostgresLockerConfig) queries {
+	lockTable := cfg.lockTable()
+	return queries{
+		tryAcquireLock:     fmt.Sprintf(tryAcquireLockSQL, cfg.tryAcquireLock()),
+		tryAcquireLockFIFO: fmt.Sprintf(tryAcquireLockFIFOSQL, cfg.tryAcquireLockFIFO()),
+		acquireBatch:       fmt.Sprintf(acquireBatchSQL, lockTable),
+		confirmOwned:       fmt.Sprintf(confirmOwnedSQL, lockTable),
+		holder:             fmt.Sprintf(holderSQL, lockTable),
+		dequeue:            fmt.Sprintf(dequeueSQL, cfg.lockWaitersTable()),
+		release:            fmt.Sprintf(releaseLockSQL, lockTable),
+		releaseMany:        fmt.Sprintf(releaseManySQL, lockTable),
+		releaseAllByOwner:  fmt.Sprintf(releaseAllByOwnerSQL, lockTable),
+		refresh:            fmt.Sprintf(refreshLockSQL, lockTable),
+		refreshBatch:       fmt.Sprintf(refreshBatchSQL, lockTable),
+		refreshIfExpiring:  fmt.Sprintf(refreshIfExpiringSQL, lockTable),
+		transferOwnership:  fmt.Sprintf(transferOwnershipSQL, lockTable),
+		isHeld:             fmt.Sprintf(isHeldLockSQL, lockTable),
+		isKeyLocked:        fmt.Sprintf(isKeyLockedSQL, lockTable),
+		assertHeld:         fmt.Sprintf(assertHeldSQL, lockTable),
+		contentionInfo:     contentionInfo(cfg),
+		getLockInfo:        fmt.Sprintf(getLockInfoSQL, lockTable),
+		listLocks:          fmt.Sprintf(listLocksSQL, lockTable),
+		readMetadata:       fmt.Sprintf(readMetadataSQL, lockTable),
+		findByMetadata:     fmt.Sprintf(findLocksByMetadataSQL, lockTable),
+		cleanupExpired:     fmt.Sprintf(cleanupExpiredSQL, lockTable),
+		audit:              fmt.Sprintf(auditSQL, cfg.auditTable(), lockTable),
+		queryAudit:         fmt.Sprintf(queryAuditSQL, cfg.auditTable()),
+		cleanupAudit:       fmt.Sprintf(cleanupAuditSQL, cfg.auditTable()),
+	}
+}
+
+// contentionInfo returns the query of ContentionInfo, counting the
+// waiters of the FIFO queue in FIFO mode
+func contentionInfo(cfg *PostgresLockerConfig) string {
+	if cfg.FIFO {
+		return fmt.Sprintf(contentionInfoFIFOSQL, cfg.lockTable(), cfg.lockWaitersTable())
+	}
+	return fmt.Sprintf(contentionInfoSQL, cfg.lockTable())
+}
@@ -2,47 +2,207 @@ package pg
 
 import (
 	"context"
-	"errors"
-	"fmt"
 	"time"
 
-	"github.com/jackc/pgx/v5"
 	"github.com/oliveiracleidson/go-lockbox/core"
 )
 
-// i.pool = pgxpool.Pool
+// i.db = pgxpool.Pool, pgx.Conn or database/sql, see backend
 
 var (
+	// The lock can be refreshed until a safety margin (RefreshSafetyMargin of the
+	// new TTL, $6) after its expiration, as long as nobody took it over.
+	// The current row tells why nothing was updated.
 	refreshLockSQL = `
-	UPDATE "%s"."%s"
-	SET
-			valid_until = NOW() + ($ttl * INTERVAL '1 millisecond'),
-			server_nonce = $new_nonce,
+	WITH holder AS (
+		SELECT lease_id, server_nonce
+		FROM %[1]s
+		WHERE key = $1
+	),
+	updated AS (
+		UPDATE %[1]s
+		SET
+			valid_until = NOW() + ($4::BIGINT * INTERVAL '1 millisecond'),
+			server_nonce = $5,
+			refresh_count = refresh_count + 1,
 			updated_at = NOW()
-	WHERE
+		WHERE
 			key = $1 AND
 			lease_id = $2 AND
 			server_nonce = $3 AND
-			valid_until > NOW() - ($ttl * 0.15 * INTERVAL '1 millisecond');
-	RETURNING valid_until;`
+			valid_until > NOW() - ($4::BIGINT * $6::FLOAT8 * INTERVAL '1 millisecond')
+		RETURNING valid_until, server_nonce
+	)
+	SELECT
+		u.valid_until,
+		u.server_nonce,
+		c.lease_id IS NOT NULL AS found,
+		COALESCE(c.lease_id = $2 AND c.server_nonce = $3, FALSE) AS owned,
+		FALSE AS fresh
+	FROM (SELECT 1) AS one
+	LEFT JOIN updated u ON TRUE
+	LEFT JOIN holder c ON TRUE;`
+
+	// Same as refreshLockSQL, skipping the update while more than the
+	// threshold ($7) remains
+	refreshIfExpiringSQL = `
+	WITH holder AS (
+		SELECT lease_id, server_nonce, valid_until
+		FROM %[1]s
+		WHERE key = $1
+	),
+	updated AS (
+		UPDATE %[1]s
+		SET
+			valid_until = NOW() + ($4::BIGINT * INTERVAL '1 millisecond'),
+			server_nonce = $5,
+			refresh_count = refresh_count + 1,
+			updated_at = NOW()
+		WHERE
+			key = $1 AND
+			lease_id = $2 AND
+			server_nonce = $3 AND
+			valid_until > NOW() - ($4::BIGINT * $6::FLOAT8 * INTERVAL '1 millisecond') AND
+			valid_until <= NOW() + ($7::BIGINT * INTERVAL '1 millisecond')
+		RETURNING valid_until, server_nonce
+	)
+	SELECT
+		u.valid_until,
+		u.server_nonce,
+		c.lease_id IS NOT NULL AS found,
+		COALESCE(c.lease_id = $2 AND c.server_nonce = $3, FALSE) AS owned,
+		COALESCE(c.valid_until > NOW() + ($7::BIGINT * INTERVAL '1 millisecond'), FALSE) AS fresh
+	FROM (SELECT 1) AS one
+	LEFT JOIN updated u ON TRUE
+	LEFT JOIN holder c ON TRUE;`
 )
 
+// Refresh extends the lock and returns a new token.
+//
+// Errors wrap:
+//
+// - core.ErrInvalidTTL: newTTL is out of range
+//
+// - core.ErrRefreshTooLate: the lock is still ours but expired beyond the safety margin
+//
+// - core.ErrLockOwnershipMismatch: the key is held with another lease or nonce
+//
+// - core.ErrLockNotFound: there is no lock for the key
+//
+// - core.ErrOperationTimeout: the statement outlived DefaultRequestTimeout
 func (i *PostgresLockAdapter) Refresh(ctx context.Context, token *core.LockToken, newTTL time.Duration) (*core.LockToken, error) {
+	if err := i.begin(); err != nil {
+		return nil, err
+	}
+	defer i.end()
 
-	row := i.pool.QueryRow(ctx,
-		fmt.Sprintf(refreshLockSQL, i.Cfg.LockSchema, i.Cfg.LockTableName),
-		token.Key, token.LeaseID, token.ServerNonce,
-	)
+	refreshed, _, err := i.refresh(ctx, token, newTTL, i.sql.refresh)
+	return refreshed, err
+}
+
+// RefreshIfExpiring extends the lock like Refresh, but only once at most
+// threshold remains: until then the statement updates nothing and the
+// token is returned unchanged, with refreshed false. It spares the
+// writes of the renewers heartbeating more often than needed.
+//
+// The remaining time is read in the same statement, on the database
+// clock. A threshold ≤ 0 only refreshes a lock already expired, within
+// the safety margin.
+//
+// Errors are the ones of Refresh.
+func (i *PostgresLockAdapter) RefreshIfExpiring(
+	ctx context.Context,
+	token *core.LockToken,
+	newTTL, threshold time.Duration,
+) (_ *core.LockToken, refreshed bool, _ error) {
+	if err := i.begin(); err != nil {
+		return nil, false, err
+	}
+	defer i.end()
+
+	return i.refresh(ctx, token, newTTL, i.sql.refreshIfExpiring, max(threshold, 0).Milliseconds())
+}
+
+// refresh runs the refresh query, given extra arguments after the ones
+// of refreshLockSQL. A lock left as is because it has more time left
+// than asked returns the token and false.
+func (i *PostgresLockAdapter) refresh(
+	ctx context.Context,
+	token *core.LockToken,
+	newTTL time.Duration,
+	query string,
+	extra ...any,
+) (*core.LockToken, bool, error) {
+	fail := func(err error) (*core.LockToken, bool, error) {
+		i.Cfg.Hooks.RefreshFailed(ctx, token, err)
+		return nil, false, &core.LockError{Op: core.OpRefresh, Key: token.Key, Attempts: 1, Err: err}
+	}
+
+	if err := core.ValidateTTL(newTTL, i.Cfg.maxTTL()); err != nil {
+		return fail(err)
+	}
+
+	storageKey, err := i.Cfg.storageKey(token.Key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	newNonce := i.Cfg.newID()
+
+	queryCtx, cancel := i.withRequestTimeout(ctx)
+	defer cancel()
+
+	start := time.Now()
+	args := append([]any{
+		storageKey, token.LeaseID, token.ServerNonce,
+		newTTL.Milliseconds(), newNonce, i.refreshSafetyMargin(token),
+	}, extra...)
+	row := i.db.QueryRow(queryCtx, query, args...)
+
+	var validUntil *time.Time
+	var serverNonce *string
+	var found, owned, fresh bool
+	err = row.Scan(&validUntil, &serverNonce, &found, &owned, &fresh)
+	i.observe(start, err)
 
-	var valid_until time.Time
-	err := row.Scan(&valid_until)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, core.ErrRefreshTooLate
+		return fail(timedOut(ctx, queryCtx, err))
+	}
+	if validUntil == nil {
+		switch {
+		case owned && fresh:
+			return token, false, nil
+		case owned:
+			i.stats.refreshTooLate.Add(1)
+			return fail(core.ErrRefreshTooLate)
+		case found:
+			return fail(core.ErrLockOwnershipMismatch)
+		default:
+			return fail(core.ErrLockNotFound)
 		}
-		return nil, err
 	}
-	token.ValidUntil = valid_until
 
-	return token, nil
+	refreshed := *token
+	refreshed.ValidUntil = *validUntil
+	refreshed.ServerNonce = *serverNonce
+	refreshed.TTL = newTTL
+	refreshed.ClockOffset = i.ClockDrift()
+	i.stats.refreshes.Add(1)
+	i.emit(ctx, core.EventRefreshed, refreshed.Key, refreshed.LeaseID)
+	i.audit(ctx, auditEntry{storageKey, refreshed.LeaseID, refreshed.OwnerID, AuditRefreshed, nil})
+
+	return &refreshed, true, nil
+}
+
+// refreshSafetyMargin returns the margin of the token, or the one of the
+// config when the acquisition didn't override it, falling back to
+// core.MaxClockDriftMargin for configurations built without WithDefaults
+func (i *PostgresLockAdapter) refreshSafetyMargin(token *core.LockToken) float64 {
+	if token.RefreshSafetyMargin != nil {
+		return *token.RefreshSafetyMargin
+	}
+	if i.Cfg.RefreshSafetyMargin != nil {
+		return *i.Cfg.RefreshSafetyMargin
+	}
+	return core.MaxClockDriftMargin
 }
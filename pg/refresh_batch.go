@@ -0,0 +1,155 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+var (
+	refreshBatchSQL = `
+	WITH input AS (
+		SELECT *
+		FROM unnest($1::TEXT[], $2::TEXT[], $3::TEXT[], $5::TEXT[])
+			WITH ORDINALITY AS t(key, lease_id, server_nonce, new_nonce, idx)
+	),
+	updated AS (
+		UPDATE %[1]s AS l
+		SET
+			valid_until = NOW() + ($4::BIGINT * INTERVAL '1 millisecond'),
+			server_nonce = i.new_nonce,
+			refresh_count = l.refresh_count + 1,
+			updated_at = NOW()
+		FROM input i
+		WHERE
+			l.key = i.key AND
+			l.lease_id = i.lease_id AND
+			l.server_nonce = i.server_nonce AND
+			l.valid_until > NOW()
+		RETURNING i.idx, l.valid_until, l.server_nonce
+	)
+	SELECT
+		i.idx,
+		u.valid_until,
+		u.server_nonce,
+		l.key IS NOT NULL AS found,
+		COALESCE(l.lease_id = i.lease_id AND l.server_nonce = i.server_nonce, FALSE) AS owned
+	FROM input i
+	LEFT JOIN updated u ON u.idx = i.idx
+	LEFT JOIN %[1]s l ON l.key = i.key
+	ORDER BY i.idx;`
+)
+
+// RefreshBatch extends many locks in a single round trip.
+//
+// Like Refresh, every refreshed token gets a new ServerNonce and the
+// previous tokens stop working.
+//
+// The returned slices have the same length and order as tokens: for each
+// index either the refreshed token or the error is set, so one lost lock
+// doesn't mask the others. Per token errors are *core.LockError wrapping:
+//
+// - core.ErrRefreshTooLate: the lock is still ours but already expired
+//
+// - core.ErrLockOwnershipMismatch: the key is held with another lease or nonce
+//
+// - core.ErrLockNotFound: there is no lock for the key
+func (i *PostgresLockAdapter) RefreshBatch(ctx context.Context, tokens []*core.LockToken, newTTL time.Duration) ([]*core.LockToken, []error) {
+	refreshed := make([]*core.LockToken, len(tokens))
+	errs := make([]error, len(tokens))
+	if len(tokens) == 0 {
+		return refreshed, errs
+	}
+
+	failAll := func(err error) ([]*core.LockToken, []error) {
+		for idx, token := range tokens {
+			refreshed[idx] = nil
+			errs[idx] = &core.LockError{Op: core.OpRefresh, Key: token.Key, Attempts: 1, Err: err}
+			i.Cfg.Hooks.RefreshFailed(ctx, token, err)
+		}
+		return refreshed, errs
+	}
+
+	if err := i.begin(); err != nil {
+		return failAll(err)
+	}
+	defer i.end()
+
+	if err := core.ValidateTTL(newTTL, i.Cfg.maxTTL()); err != nil {
+		return failAll(err)
+	}
+
+	keys := make([]string, len(tokens))
+	leaseIDs := make([]string, len(tokens))
+	nonces := make([]string, len(tokens))
+	newNonces := make([]string, len(tokens))
+	for idx, token := range tokens {
+		storageKey, err := i.Cfg.storageKey(token.Key)
+		if err != nil {
+			return failAll(err)
+		}
+		keys[idx] = storageKey
+		leaseIDs[idx] = token.LeaseID
+		nonces[idx] = token.ServerNonce
+		newNonces[idx] = i.Cfg.newID()
+	}
+
+	start := time.Now()
+	rows, err := i.db.Query(ctx,
+		i.sql.refreshBatch,
+		keys, leaseIDs, nonces, newTTL.Milliseconds(), newNonces,
+	)
+	if err != nil {
+		return failAll(err)
+	}
+	defer rows.Close()
+	defer func() { i.observe(start, rows.Err()) }()
+
+	for rows.Next() {
+		var idx int
+		var validUntil *time.Time
+		var serverNonce *string
+		var found, owned bool
+		if err := rows.Scan(&idx, &validUntil, &serverNonce, &found, &owned); err != nil {
+			return failAll(err)
+		}
+
+		// WITH ORDINALITY starts at 1
+		token := tokens[idx-1]
+		switch {
+		case validUntil != nil:
+			refreshedToken := *token
+			refreshedToken.ValidUntil = *validUntil
+			refreshedToken.ServerNonce = *serverNonce
+			refreshedToken.TTL = newTTL
+			refreshedToken.ClockOffset = i.ClockDrift()
+			refreshed[idx-1] = &refreshedToken
+			i.stats.refreshes.Add(1)
+			continue
+		case owned:
+			i.stats.refreshTooLate.Add(1)
+			err = core.ErrRefreshTooLate
+		case found:
+			err = core.ErrLockOwnershipMismatch
+		default:
+			err = core.ErrLockNotFound
+		}
+		errs[idx-1] = &core.LockError{Op: core.OpRefresh, Key: token.Key, Attempts: 1, Err: err}
+		i.Cfg.Hooks.RefreshFailed(ctx, token, err)
+	}
+	if err := rows.Err(); err != nil {
+		return failAll(err)
+	}
+
+	var entries []auditEntry
+	for idx, token := range refreshed {
+		if token != nil {
+			i.emit(ctx, core.EventRefreshed, token.Key, token.LeaseID)
+			entries = append(entries, auditEntry{keys[idx], token.LeaseID, token.OwnerID, AuditRefreshed, nil})
+		}
+	}
+	i.audit(ctx, entries...)
+
+	return refreshed, errs
+}
@@ -3,41 +3,101 @@ package pg
 import (
 	"context"
 	"errors"
-	"fmt"
+	"time"
 
-	"github.com/jackc/pgx/v5"
 	"github.com/oliveiracleidson/go-lockbox/core"
 )
 
-// i.pool = pgxpool.Pool
+// i.db = pgxpool.Pool, pgx.Conn or database/sql, see backend
 
 var (
+	// The SELECT sees the table as it was before the DELETE,
+	// so found tells whether any lock existed for the key.
+	// The metadata of the released lock goes to the audit log.
 	releaseLockSQL = `
-	DELETE FROM "%s"."%s"
-	WHERE
-  	key = $1
-		AND lease_id = $2 
-		AND server_nonce = $3;`
+	WITH deleted AS (
+		DELETE FROM %[1]s
+		WHERE
+			key = $1
+			AND lease_id = $2
+			AND server_nonce = $3
+		RETURNING key, metadata
+	)
+	SELECT
+		EXISTS (SELECT 1 FROM deleted) AS released,
+		EXISTS (SELECT 1 FROM %[1]s WHERE key = $1) AS found,
+		(SELECT metadata FROM deleted) AS metadata;`
 )
 
+// Release releases the lock of the token.
+//
+// Errors wrap:
+//
+// - core.ErrLockNotFound: there is no lock for the key, e.g. it expired and was cleaned up
+//
+// - core.ErrLockOwnershipMismatch: the key is held with another lease or nonce
+//
+// - core.ErrOperationTimeout: the statement outlasted DefaultRequestTimeout
 func (i *PostgresLockAdapter) Release(ctx context.Context, token *core.LockToken) error {
+	if err := i.begin(); err != nil {
+		return err
+	}
+	defer i.end()
 
-	r, err := i.pool.Exec(ctx,
-		fmt.Sprintf(releaseLockSQL, i.Cfg.LockSchema, i.Cfg.LockTableName),
-		token.Key, token.LeaseID, token.ServerNonce,
-	)
+	storageKey, err := i.Cfg.storageKey(token.Key)
+	if err != nil {
+		return err
+	}
+
+	queryCtx, cancel := i.withRequestTimeout(ctx)
+	defer cancel()
+
+	start := time.Now()
+	var released, found bool
+	var metadata []byte
+	err = i.db.QueryRow(queryCtx,
+		i.sql.release,
+		storageKey, token.LeaseID, token.ServerNonce,
+	).Scan(&released, &found, &metadata)
+	i.observe(start, err)
 
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return core.ErrLockOwnershipMismatch
-		} else {
-			return err
-		}
+		err = timedOut(ctx, queryCtx, err)
+		return &core.LockError{Op: core.OpRelease, Key: token.Key, Attempts: 1, Err: err}
 	}
 
-	if r.RowsAffected() == 0 {
-		return core.ErrLockOwnershipMismatch
+	if !released {
+		err = core.ErrLockNotFound
+		if found {
+			err = core.ErrLockOwnershipMismatch
+		}
+		return &core.LockError{Op: core.OpRelease, Key: token.Key, Attempts: 1, Err: err}
 	}
 
+	i.stats.released(1)
+	if i.Cfg.NotifyOnRelease {
+		i.notifyRelease(ctx, storageKey)
+	}
+	i.Cfg.Hooks.Released(ctx, token)
+	i.emit(ctx, core.EventReleased, token.Key, token.LeaseID)
+	i.audit(ctx, auditEntry{storageKey, token.LeaseID, token.OwnerID, AuditReleased, metadata})
 	return nil
 }
+
+// ReleaseIfHeld releases the lock of the token if it still holds the
+// key, for release paths that may run twice, e.g. a manual Release
+// followed by a deferred one:
+//
+//	defer adapter.ReleaseIfHeld(context.Background(), token)
+//
+// released is false, without error, when the key has no lock anymore
+// (it was already released or cleaned up after expiring). A key taken
+// over by another owner still fails with core.ErrLockOwnershipMismatch,
+// as do the other failures, e.g. of the database, as Release does.
+func (i *PostgresLockAdapter) ReleaseIfHeld(ctx context.Context, token *core.LockToken) (released bool, err error) {
+	err = i.Release(ctx, token)
+	if errors.Is(err, core.ErrLockNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,80 @@
+package pg
+
+import (
+	"context"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+var (
+	releaseAllByOwnerSQL = `
+	DELETE FROM %s
+	WHERE
+		owner_id = $1
+		AND LEFT(key, LENGTH($2)) = $2
+	RETURNING key, lease_id, server_nonce, valid_until, metadata;`
+)
+
+// ReleaseAllByOwner releases every lock of the namespace held by the owner
+// and returns how many were released.
+//
+// It is meant for graceful shutdown, when tracking every token across
+// goroutines is error-prone. Releasing for an owner holding nothing
+// returns 0 and no error.
+func (i *PostgresLockAdapter) ReleaseAllByOwner(ctx context.Context, ownerID string) (int, error) {
+	if err := i.begin(); err != nil {
+		return 0, err
+	}
+	defer i.end()
+
+	if err := core.ValidateOwnerID(ownerID); err != nil {
+		return 0, err
+	}
+
+	prefix := ""
+	if i.Cfg.Namespace != "" {
+		prefix = i.Cfg.Namespace + core.KeySeparator
+	}
+
+	rows, err := i.db.Query(ctx,
+		i.sql.releaseAllByOwner,
+		ownerID, prefix,
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	released := []*core.LockToken{}
+	storageKeys := []string{}
+	var entries []auditEntry
+	for rows.Next() {
+		token := &core.LockToken{OwnerID: ownerID}
+		var storageKey string
+		var metadata []byte
+		if err := rows.Scan(&storageKey, &token.LeaseID, &token.ServerNonce, &token.ValidUntil, &metadata); err != nil {
+			return 0, err
+		}
+		token.Key = i.Cfg.userKey(storageKey)
+		released = append(released, token)
+		storageKeys = append(storageKeys, storageKey)
+		entries = append(entries, auditEntry{storageKey, token.LeaseID, ownerID, AuditForceReleased, metadata})
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	i.stats.released(len(released))
+	if i.Cfg.NotifyOnRelease {
+		for _, storageKey := range storageKeys {
+			i.notifyRelease(ctx, storageKey)
+		}
+	}
+	for _, token := range released {
+		i.Cfg.Hooks.Released(ctx, token)
+		i.emit(ctx, core.EventForceReleased, token.Key, token.LeaseID)
+	}
+	i.audit(ctx, entries...)
+
+	return len(released), nil
+}
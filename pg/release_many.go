@@ -0,0 +1,129 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+var (
+	// The outer SELECT sees the table as it was before the DELETE,
+	// so found tells whether any lock existed for the key
+	releaseManySQL = `
+	WITH input AS (
+		SELECT *
+		FROM unnest($1::TEXT[], $2::TEXT[], $3::TEXT[])
+			WITH ORDINALITY AS t(key, lease_id, server_nonce, idx)
+	),
+	deleted AS (
+		DELETE FROM %[1]s AS l
+		USING input i
+		WHERE
+			l.key = i.key AND
+			l.lease_id = i.lease_id AND
+			l.server_nonce = i.server_nonce
+		RETURNING i.idx, l.metadata
+	)
+	SELECT
+		i.idx,
+		d.idx IS NOT NULL AS released,
+		l.key IS NOT NULL AS found,
+		d.metadata
+	FROM input i
+	LEFT JOIN deleted d ON d.idx = i.idx
+	LEFT JOIN %[1]s l ON l.key = i.key
+	ORDER BY i.idx;`
+)
+
+// ReleaseMany releases many locks in a single statement, e.g. on
+// graceful shutdown.
+//
+// The returned slice has the same length and order as tokens, with a nil
+// error for every released lock. Per token errors are *core.LockError
+// wrapping:
+//
+// - core.ErrLockOwnershipMismatch: the key is held with another lease or nonce
+//
+// - core.ErrLockNotFound: there is no lock for the key
+func (i *PostgresLockAdapter) ReleaseMany(ctx context.Context, tokens []*core.LockToken) []error {
+	errs := make([]error, len(tokens))
+	if len(tokens) == 0 {
+		return errs
+	}
+
+	failAll := func(err error) []error {
+		for idx, token := range tokens {
+			errs[idx] = &core.LockError{Op: core.OpRelease, Key: token.Key, Attempts: 1, Err: err}
+		}
+		return errs
+	}
+
+	if err := i.begin(); err != nil {
+		return failAll(err)
+	}
+	defer i.end()
+
+	keys := make([]string, len(tokens))
+	leaseIDs := make([]string, len(tokens))
+	nonces := make([]string, len(tokens))
+	for idx, token := range tokens {
+		storageKey, err := i.Cfg.storageKey(token.Key)
+		if err != nil {
+			return failAll(err)
+		}
+		keys[idx] = storageKey
+		leaseIDs[idx] = token.LeaseID
+		nonces[idx] = token.ServerNonce
+	}
+
+	start := time.Now()
+	rows, err := i.db.Query(ctx,
+		i.sql.releaseMany,
+		keys, leaseIDs, nonces,
+	)
+	if err != nil {
+		return failAll(err)
+	}
+	defer rows.Close()
+	defer func() { i.observe(start, rows.Err()) }()
+
+	released := []int{}
+	var entries []auditEntry
+	for rows.Next() {
+		var idx int
+		var ok, found bool
+		var metadata []byte
+		if err := rows.Scan(&idx, &ok, &found, &metadata); err != nil {
+			return failAll(err)
+		}
+
+		// WITH ORDINALITY starts at 1
+		if ok {
+			released = append(released, idx-1)
+			token := tokens[idx-1]
+			entries = append(entries, auditEntry{keys[idx-1], token.LeaseID, token.OwnerID, AuditReleased, metadata})
+			continue
+		}
+		err := core.ErrLockNotFound
+		if found {
+			err = core.ErrLockOwnershipMismatch
+		}
+		errs[idx-1] = &core.LockError{Op: core.OpRelease, Key: tokens[idx-1].Key, Attempts: 1, Err: err}
+	}
+	if err := rows.Err(); err != nil {
+		return failAll(err)
+	}
+
+	i.stats.released(len(released))
+	for _, idx := range released {
+		if i.Cfg.NotifyOnRelease {
+			i.notifyRelease(ctx, keys[idx])
+		}
+		i.Cfg.Hooks.Released(ctx, tokens[idx])
+		i.emit(ctx, core.EventReleased, tokens[idx].Key, tokens[idx].LeaseID)
+	}
+	i.audit(ctx, entries...)
+
+	return errs
+}
@@ -0,0 +1,125 @@
+package pg_test
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/oliveiracleidson/go-lockbox/pg"
+	"github.com/stretchr/testify/require"
+)
+
+// stalledServer accepts connections and never answers, like a database
+// stuck under load
+func stalledServer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var conns []net.Conn
+	t.Cleanup(func() {
+		_ = listener.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+	})
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			conns = append(conns, conn)
+			mu.Unlock()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestPostgresLockAdapter_DefaultRequestTimeout(t *testing.T) {
+	pool, err := pgxpool.New(context.Background(), "postgres://lockbox@"+stalledServer(t)+"/lockbox")
+	require.NoError(t, err)
+	defer pool.Close()
+
+	adapter, err := pg.NewPostgresLockAdapter(pool, pg.NewPostgresLockerConfig().
+		SetDefaultRequestTimeout(100*time.Millisecond),
+	)
+	require.NoError(t, err)
+
+	token := &core.LockToken{Key: "key", LeaseID: "lease", ServerNonce: "nonce"}
+
+	t.Run("given a context never expiring, when the database stalls, then release and is held time out", func(t *testing.T) {
+		start := time.Now()
+		err := adapter.Release(context.Background(), token)
+		require.ErrorIs(t, err, core.ErrOperationTimeout)
+		require.Less(t, time.Since(start), time.Second)
+
+		_, _, err = adapter.IsHeld(context.Background(), token)
+		require.ErrorIs(t, err, core.ErrOperationTimeout)
+
+		_, _, err = adapter.IsKeyLocked(context.Background(), "key")
+		require.ErrorIs(t, err, core.ErrOperationTimeout)
+
+		err = adapter.AssertHeld(context.Background(), token)
+		require.ErrorIs(t, err, core.ErrOperationTimeout)
+
+		_, err = adapter.ContentionInfo(context.Background(), "key")
+		require.ErrorIs(t, err, core.ErrOperationTimeout)
+
+		_, err = adapter.Refresh(context.Background(), token, time.Second)
+		require.ErrorIs(t, err, core.ErrOperationTimeout)
+
+		_, _, err = adapter.RefreshIfExpiring(context.Background(), token, time.Second, time.Second)
+		require.ErrorIs(t, err, core.ErrOperationTimeout)
+	})
+
+	t.Run("given a cancelled context, when release, then the cancellation is not masked", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := adapter.Release(ctx, token)
+		require.ErrorIs(t, err, context.Canceled)
+		require.NotErrorIs(t, err, core.ErrOperationTimeout)
+	})
+
+	t.Run("given a context expiring first, when is held, then its deadline is not masked", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		_, _, err := adapter.IsHeld(ctx, token)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.NotErrorIs(t, err, core.ErrOperationTimeout)
+	})
+}
+
+func TestPostgresLockAdapter_StatementTimeout_Stalled(t *testing.T) {
+	pool, err := pgxpool.New(context.Background(), "postgres://lockbox@"+stalledServer(t)+"/lockbox")
+	require.NoError(t, err)
+	defer pool.Close()
+
+	adapter, err := pg.NewPostgresLockAdapter(pool, pg.NewPostgresLockerConfig().
+		SetDefaultRequestTimeout(100*time.Millisecond).
+		SetStatementTimeout(true),
+	)
+	require.NoError(t, err)
+
+	token := &core.LockToken{Key: "key", LeaseID: "lease", ServerNonce: "nonce"}
+
+	t.Run("given a statement timeout, when the database stalls, then the ctx still times out the operation", func(t *testing.T) {
+		start := time.Now()
+		err := adapter.Release(context.Background(), token)
+		require.ErrorIs(t, err, core.ErrOperationTimeout)
+		require.Less(t, time.Since(start), time.Second)
+
+		_, _, err = adapter.IsHeld(context.Background(), token)
+		require.ErrorIs(t, err, core.ErrOperationTimeout)
+	})
+}
@@ -0,0 +1,93 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Schema versions the library works with: the database must have
+// migrations applied up to a version within [MinSchemaVersion,
+// SchemaVersion], see CheckCompatibility
+const (
+	SchemaVersion    = "v0.0.8" // Latest embedded migration
+	MinSchemaVersion = "v0.0.8" // Oldest schema the SQL of the adapter runs on
+)
+
+// CurrentSchemaVersion returns the most recent version applied to the
+// database, without the suffix of the index migrations (v0.0.6-indexes
+// counts as v0.0.6), or "" when no migration is applied
+func (i *PostgresLockAdapter) CurrentSchemaVersion(ctx context.Context) (string, error) {
+	if err := i.begin(); err != nil {
+		return "", err
+	}
+	defer i.end()
+
+	return i.currentSchemaVersion(ctx)
+}
+
+func (i *PostgresLockAdapter) currentSchemaVersion(ctx context.Context) (string, error) {
+	applied, err := i.appliedMigrations(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	current := ""
+	for version := range applied {
+		base, _, _ := strings.Cut(version, "-")
+		if current == "" || compareSchemaVersions(base, current) > 0 {
+			current = base
+		}
+	}
+	return current, nil
+}
+
+// CheckCompatibility fails with ErrSchemaIncompatible when the database
+// is migrated to a version outside [MinSchemaVersion, SchemaVersion],
+// e.g. an old binary running against a database migrated by a newer one.
+// See also CheckSchemaVersionOnStart.
+func (i *PostgresLockAdapter) CheckCompatibility(ctx context.Context) error {
+	if err := i.begin(); err != nil {
+		return err
+	}
+	defer i.end()
+
+	current, err := i.currentSchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	if current == "" {
+		return fmt.Errorf("%w: database has no migration applied, library expects %s..%s",
+			ErrSchemaIncompatible, MinSchemaVersion, SchemaVersion)
+	}
+	if compareSchemaVersions(current, MinSchemaVersion) < 0 || compareSchemaVersions(current, SchemaVersion) > 0 {
+		return fmt.Errorf("%w: database at %s, library expects %s..%s",
+			ErrSchemaIncompatible, current, MinSchemaVersion, SchemaVersion)
+	}
+	return nil
+}
+
+// compareSchemaVersions compares two vMAJOR.MINOR.PATCH versions
+// numerically, returning -1, 0 or 1
+func compareSchemaVersions(a, b string) int {
+	partsA := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	partsB := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for idx := 0; idx < max(len(partsA), len(partsB)); idx++ {
+		var na, nb int
+		if idx < len(partsA) {
+			na, _ = strconv.Atoi(partsA[idx])
+		}
+		if idx < len(partsB) {
+			nb, _ = strconv.Atoi(partsB[idx])
+		}
+		switch {
+		case na < nb:
+			return -1
+		case na > nb:
+			return 1
+		}
+	}
+	return 0
+}
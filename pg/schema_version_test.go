@@ -0,0 +1,24 @@
+package pg_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/oliveiracleidson/go-lockbox/pg"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaVersion(t *testing.T) {
+	t.Run("given the embedded migrations, when compared, then SchemaVersion is the latest", func(t *testing.T) {
+		latest, _, _ := strings.Cut(pg.LatestMigration(), "-")
+		require.Equal(t, latest, pg.SchemaVersion)
+		require.LessOrEqual(t, pg.CompareSchemaVersions(pg.MinSchemaVersion, pg.SchemaVersion), 0)
+	})
+
+	t.Run("given versions, when compared, then the numbers are compared", func(t *testing.T) {
+		require.Equal(t, 0, pg.CompareSchemaVersions("v0.0.8", "v0.0.8"))
+		require.Equal(t, -1, pg.CompareSchemaVersions("v0.0.8", "v0.0.10"))
+		require.Equal(t, 1, pg.CompareSchemaVersions("v0.1.0", "v0.0.99"))
+		require.Equal(t, 1, pg.CompareSchemaVersions("v1.0.0", "v0.9.9"))
+	})
+}
@@ -0,0 +1,420 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+var (
+	// The lock table namespaces the advisory locks, so adapters with
+	// different tables never collide
+	sessionTryLockSQL = `SELECT pg_try_advisory_lock(hashtextextended($1, 0));`
+	sessionUnlockSQL  = `SELECT pg_advisory_unlock(hashtextextended($1, 0));`
+)
+
+// PostgresSessionLockAdapter holds each lock as a session advisory lock
+// on a dedicated pool connection, for "hold until I disconnect" semantics
+// such as leader election.
+//
+// The lock lives as long as its connection: if the process crashes, the
+// TCP close frees it right away instead of waiting for a TTL. In return
+// every held lock pins a pool connection, so size the pool for the
+// expected number of locks held at once.
+//
+// TTLs are validated and reported on tokens, but do not expire the lock:
+// Refresh is a no-op and the lock is held until Release, Close or the loss
+// of its connection. Nothing is stored in the lock table, locks are not
+// visible to the table based PostgresLockAdapter and vice versa.
+//
+// NewAdvisoryLockAdapter creates the same adapter with TTLs enforced.
+type PostgresSessionLockAdapter struct {
+	pool *pgxpool.Pool
+	Cfg  *PostgresLockerConfig
+
+	// Set by NewAdvisoryLockAdapter, unlocks when the token TTL elapses
+	expireByTTL bool
+
+	mu     sync.Mutex
+	closed bool
+	held   map[string]*sessionLock // By lease ID
+}
+
+// sessionLock is a lock and the connection holding it
+type sessionLock struct {
+	token      core.LockToken
+	storageKey string
+	timer      *time.Timer // TTL watchdog, nil unless expireByTTL
+
+	// A pgx connection must not be used concurrently
+	connMu sync.Mutex
+	conn   *pgxpool.Conn
+}
+
+// NewPostgresSessionLockAdapter creates a session lock adapter.
+//
+// Only the identifiers, Namespace, MaxAllowedTTL and Hooks of the
+// configuration apply.
+func NewPostgresSessionLockAdapter(
+	pool *pgxpool.Pool,
+	cfg *PostgresLockerConfig,
+) (*PostgresSessionLockAdapter, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &PostgresSessionLockAdapter{
+		pool: pool,
+		Cfg:  cfg,
+		held: map[string]*sessionLock{},
+	}, nil
+}
+
+// Acquire takes the advisory lock of the key on a dedicated connection,
+// retrying according to opts.RetryStrategy while another session holds it
+func (s *PostgresSessionLockAdapter) Acquire(ctx context.Context, key string, opts core.LockOptions) (*core.LockToken, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+
+	storageKey, err := s.Cfg.storageKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.ValidateWithMaxTTL(s.Cfg.maxTTL()); err != nil {
+		return nil, err
+	}
+
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return nil, &core.LockError{Op: core.OpAcquire, Key: key, Attempts: 1, Err: err}
+	}
+
+	lockKey := s.lockKey(storageKey)
+	started := time.Now()
+	deadline, hasDeadline := opts.RetryStrategy.Deadline(ctx, started)
+	attempts := 0
+
+	for attempt := 0; attempt <= opts.RetryStrategy.MaxRetries; attempt++ {
+		attempts++
+		attemptCtx, cancel := context.WithTimeout(ctx, opts.RequestTimeout)
+		var acquired bool
+		err := conn.QueryRow(attemptCtx, sessionTryLockSQL, lockKey).Scan(&acquired)
+		cancel()
+		if err != nil {
+			conn.Release()
+			return nil, &core.LockError{
+				Op:       core.OpAcquire,
+				Key:      key,
+				Attempts: attempt + 1,
+				Elapsed:  time.Since(started),
+				Err:      fmt.Errorf("failed to acquire lock: %w", err),
+			}
+		}
+
+		if acquired {
+			lock := &sessionLock{
+				token: core.LockToken{
+					Key:         key,
+					LeaseID:     s.Cfg.newID(),
+					ValidUntil:  time.Now().Add(opts.TTL),
+					ServerNonce: s.Cfg.newID(),
+					OwnerID:     opts.OwnerID,
+					TTL:         opts.TTL,
+				},
+				storageKey: storageKey,
+				conn:       conn,
+			}
+			if err := s.track(lock); err != nil {
+				s.unlock(ctx, lock)
+				return nil, err
+			}
+
+			token := lock.token
+			s.Cfg.Hooks.Acquired(ctx, &token)
+			return &token, nil
+		}
+
+		s.Cfg.Hooks.Contention(ctx, key, attempt)
+
+		delay := opts.Backoff(attempt)
+		if attempt == opts.RetryStrategy.MaxRetries {
+			break
+		}
+		if hasDeadline && time.Now().Add(delay).After(deadline) {
+			break
+		}
+		core.SleepContext(ctx, delay)
+		if err := ctx.Err(); err != nil {
+			conn.Release()
+			return nil, &core.LockError{
+				Op:       core.OpAcquire,
+				Key:      key,
+				Attempts: attempt + 1,
+				Elapsed:  time.Since(started),
+				Err:      fmt.Errorf("%w: %w", core.ErrOperationTimeout, err),
+			}
+		}
+	}
+
+	conn.Release()
+	return nil, &core.LockError{
+		Op:       core.OpAcquire,
+		Key:      key,
+		Attempts: attempts,
+		Elapsed:  time.Since(started),
+		Err:      &core.ContentionError{},
+	}
+}
+
+// Release unlocks the advisory lock of the token and returns its
+// connection to the pool.
+//
+// Errors wrap core.ErrLockNotFound when the token holds nothing.
+func (s *PostgresSessionLockAdapter) Release(ctx context.Context, token *core.LockToken) error {
+	if err := s.checkOpen(); err != nil {
+		return err
+	}
+
+	lock, err := s.untrack(token)
+	if err != nil {
+		return &core.LockError{Op: core.OpRelease, Key: token.Key, Attempts: 1, Err: err}
+	}
+
+	s.unlock(ctx, lock)
+	s.Cfg.Hooks.Released(ctx, token)
+	return nil
+}
+
+// Refresh is a no-op, session locks do not expire: it returns the token
+// as is while the lock is held, and an error wrapping
+// core.ErrLockNotFound otherwise.
+func (s *PostgresSessionLockAdapter) Refresh(ctx context.Context, token *core.LockToken, newTTL time.Duration) (*core.LockToken, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+	if err := core.ValidateTTL(newTTL, s.Cfg.maxTTL()); err != nil {
+		return nil, err
+	}
+
+	var refreshed *core.LockToken
+	var err error
+	if s.expireByTTL {
+		refreshed, err = s.extend(token, newTTL)
+	} else if _, _, ok := s.lookup(token); ok {
+		refreshed = token
+	} else {
+		err = core.ErrLockNotFound
+	}
+	if err != nil {
+		err = &core.LockError{Op: core.OpRefresh, Key: token.Key, Attempts: 1, Err: err}
+		s.Cfg.Hooks.RefreshFailed(ctx, token, err)
+		return nil, err
+	}
+	return refreshed, nil
+}
+
+// IsHeld reports whether the token holds its lock and its connection is
+// alive, and the remaining duration of the token TTL.
+func (s *PostgresSessionLockAdapter) IsHeld(ctx context.Context, token *core.LockToken) (bool, time.Duration, error) {
+	if err := s.checkOpen(); err != nil {
+		return false, 0, err
+	}
+
+	lock, held, ok := s.lookup(token)
+	if !ok {
+		return false, 0, nil
+	}
+	remaining := time.Until(held.ValidUntil)
+	if s.expireByTTL && remaining <= 0 {
+		// The watchdog is about to unlock it
+		return false, 0, nil
+	}
+
+	lock.connMu.Lock()
+	err := lock.conn.Ping(ctx)
+	lock.connMu.Unlock()
+	if err != nil {
+		// The connection is gone and the lock with it
+		return false, 0, nil
+	}
+
+	return true, max(remaining, 0), nil
+}
+
+// Close releases every held lock and closes the pgxPool.
+// Closing twice is a no-op.
+func (s *PostgresSessionLockAdapter) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	held := s.held
+	s.held = map[string]*sessionLock{}
+	s.mu.Unlock()
+
+	for _, lock := range held {
+		if lock.timer != nil {
+			lock.timer.Stop()
+		}
+		s.unlock(ctx, lock)
+	}
+	s.pool.Close()
+	return nil
+}
+
+// HealthCheck reports StatusRed when the database cannot be reached
+// or the adapter is closed
+func (s *PostgresSessionLockAdapter) HealthCheck(ctx context.Context) core.HealthReport {
+	report := core.HealthReport{Status: core.StatusGreen, Backend: "postgres-session"}
+	if s.expireByTTL {
+		report.Backend = "postgres-advisory"
+	}
+	if err := s.checkOpen(); err != nil {
+		report.Status = core.StatusRed
+		report.Error = err
+		return report
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	if err := s.pool.Ping(ctx); err != nil {
+		report.Status = core.StatusRed
+		report.Error = fmt.Errorf("health probe failed: %w", err)
+	}
+
+	s.mu.Lock()
+	heldLocks := len(s.held)
+	s.mu.Unlock()
+
+	report.Details = map[string]string{
+		"probe_latency": time.Since(start).String(),
+		"held_locks":    fmt.Sprint(heldLocks),
+	}
+	return report
+}
+
+// lockKey returns the text hashed into the advisory lock key
+func (s *PostgresSessionLockAdapter) lockKey(storageKey string) string {
+	return s.Cfg.lockTable() + core.KeySeparator + storageKey
+}
+
+func (s *PostgresSessionLockAdapter) checkOpen() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return core.ErrAdapterClosed
+	}
+	return nil
+}
+
+// track registers an acquired lock, arming its watchdog, failing if the
+// adapter was closed during the acquisition
+func (s *PostgresSessionLockAdapter) track(lock *sessionLock) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return core.ErrAdapterClosed
+	}
+	s.held[lock.token.LeaseID] = lock
+	if s.expireByTTL {
+		lock.timer = s.watchdog(lock.token)
+	}
+	return nil
+}
+
+// watchdog unlocks the lock of the token once its TTL elapses,
+// unless the token was refreshed or released in the meantime
+func (s *PostgresSessionLockAdapter) watchdog(token core.LockToken) *time.Timer {
+	return time.AfterFunc(time.Until(token.ValidUntil), func() {
+		s.mu.Lock()
+		lock, ok := s.held[token.LeaseID]
+		if !ok || lock.token.ServerNonce != token.ServerNonce {
+			s.mu.Unlock()
+			return
+		}
+		delete(s.held, token.LeaseID)
+		s.mu.Unlock()
+
+		s.unlock(context.Background(), lock)
+	})
+}
+
+// extend rotates the nonce of the token and restarts its watchdog
+// with the new TTL
+func (s *PostgresSessionLockAdapter) extend(token *core.LockToken, newTTL time.Duration) (*core.LockToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lock, ok := s.held[token.LeaseID]
+	if !ok || lock.token.Key != token.Key {
+		return nil, core.ErrLockNotFound
+	}
+	if lock.token.ServerNonce != token.ServerNonce {
+		return nil, core.ErrLockOwnershipMismatch
+	}
+	if !time.Now().Before(lock.token.ValidUntil) {
+		return nil, core.ErrRefreshTooLate
+	}
+
+	lock.timer.Stop()
+	lock.token.ServerNonce = s.Cfg.newID()
+	lock.token.ValidUntil = time.Now().Add(newTTL)
+	lock.token.TTL = newTTL
+	lock.timer = s.watchdog(lock.token)
+
+	refreshed := lock.token
+	return &refreshed, nil
+}
+
+// untrack removes the lock of the token from the held locks
+func (s *PostgresSessionLockAdapter) untrack(token *core.LockToken) (*sessionLock, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lock, ok := s.held[token.LeaseID]
+	if !ok {
+		return nil, core.ErrLockNotFound
+	}
+	if lock.token.ServerNonce != token.ServerNonce || lock.token.Key != token.Key {
+		return nil, core.ErrLockOwnershipMismatch
+	}
+	delete(s.held, token.LeaseID)
+	if lock.timer != nil {
+		lock.timer.Stop()
+	}
+	return lock, nil
+}
+
+// lookup returns the lock of the token and a copy of its current token
+func (s *PostgresSessionLockAdapter) lookup(token *core.LockToken) (*sessionLock, core.LockToken, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lock, ok := s.held[token.LeaseID]
+	if !ok || lock.token.ServerNonce != token.ServerNonce || lock.token.Key != token.Key {
+		return nil, core.LockToken{}, false
+	}
+	return lock, lock.token, true
+}
+
+// unlock frees the advisory lock and returns the connection to the pool.
+//
+// It runs even if ctx is done; a connection that cannot unlock is closed,
+// which frees the lock as well.
+func (s *PostgresSessionLockAdapter) unlock(ctx context.Context, lock *sessionLock) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), core.DefaultRequestTimeout)
+	defer cancel()
+
+	lock.connMu.Lock()
+	defer lock.connMu.Unlock()
+	if _, err := lock.conn.Exec(ctx, sessionUnlockSQL, s.lockKey(lock.storageKey)); err != nil {
+		_ = lock.conn.Conn().Close(ctx)
+	}
+	lock.conn.Release()
+}
@@ -0,0 +1,106 @@
+package pg
+
+import (
+	"strings"
+)
+
+// splitStatements splits a SQL script on the semicolons ending its
+// statements, ignoring the ones inside quoted strings and identifiers,
+// dollar-quoted bodies and comments.
+//
+// Statements holding only comments are dropped. Escape string constants
+// (E'...') and nested block comments are not supported.
+func splitStatements(sql string) []string {
+	statements := []string{}
+	start := 0
+	hasCode := false
+
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql) - i
+			}
+			i += end
+		case strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				end = len(sql) - i - 3
+			}
+			i += 2 + end + 1
+		case c == '\'' || c == '"':
+			hasCode = true
+			i = skipQuoted(sql, i)
+		case c == '$':
+			hasCode = true
+			if tag, ok := dollarTag(sql, i); ok {
+				end := strings.Index(sql[i+len(tag):], tag)
+				if end < 0 {
+					end = len(sql) - i - 2*len(tag)
+				}
+				i += 2*len(tag) + end - 1
+			}
+		case c == ';':
+			if hasCode {
+				statements = append(statements, strings.TrimSpace(sql[start:i]))
+			}
+			start = i + 1
+			hasCode = false
+		case c != ' ' && c != '\t' && c != '\n' && c != '\r':
+			hasCode = true
+		}
+	}
+
+	if hasCode {
+		statements = append(statements, strings.TrimSpace(sql[start:]))
+	}
+
+	return statements
+}
+
+// skipQuoted returns the index of the quote closing the string or
+// identifier opened at i, a doubled quote being an escaped one
+func skipQuoted(sql string, i int) int {
+	quote := sql[i]
+	for j := i + 1; j < len(sql); j++ {
+		if sql[j] != quote {
+			continue
+		}
+		if j+1 < len(sql) && sql[j+1] == quote {
+			j++
+			continue
+		}
+		return j
+	}
+	return len(sql)
+}
+
+// dollarTag returns the $tag$ opening a dollar-quoted string at i.
+// Positional parameters ($1) and identifiers holding $ are not tags.
+func dollarTag(sql string, i int) (string, bool) {
+	if i > 0 && isIdentifierChar(sql[i-1]) {
+		return "", false
+	}
+	for j := i + 1; j < len(sql); j++ {
+		switch c := sql[j]; {
+		case c == '$':
+			return sql[i : j+1], true
+		case c >= '0' && c <= '9':
+			if j == i+1 {
+				return "", false
+			}
+		case !isIdentifierChar(c):
+			return "", false
+		}
+	}
+	return "", false
+}
+
+func isIdentifierChar(c byte) bool {
+	return c == '_' || c == '$' ||
+		(c >= 'a' && c <= 'z') ||
+		(c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9')
+}
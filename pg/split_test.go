@@ -0,0 +1,50 @@
+package pg_test
+
+import (
+	"testing"
+
+	"github.com/oliveiracleidson/go-lockbox/pg"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitStatements(t *testing.T) {
+	t.Run("given a function with internal semicolons, when split, then the body is kept whole", func(t *testing.T) {
+		sql := `-- Acquire; or not
+CREATE OR REPLACE FUNCTION try_acquire(p_key TEXT) RETURNS BOOLEAN AS $fn$
+DECLARE
+    acquired BOOLEAN;
+BEGIN
+    INSERT INTO locks (key) VALUES (p_key) ON CONFLICT DO NOTHING;
+    GET DIAGNOSTICS acquired = ROW_COUNT;
+    RETURN acquired;
+END;
+$fn$ LANGUAGE plpgsql;
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS locks_key_idx ON locks (key);`
+
+		statements := pg.SplitStatements(sql)
+		require.Len(t, statements, 2)
+		require.Contains(t, statements[0], "RETURN acquired;\nEND;\n$fn$ LANGUAGE plpgsql")
+		require.Equal(t, "CREATE INDEX CONCURRENTLY IF NOT EXISTS locks_key_idx ON locks (key)", statements[1])
+	})
+
+	t.Run("given a DO block with an anonymous dollar quote, when split, then the block is one statement", func(t *testing.T) {
+		sql := `DO $$ BEGIN PERFORM 1; PERFORM 2; END $$; SELECT 1`
+
+		require.Equal(t, []string{"DO $$ BEGIN PERFORM 1; PERFORM 2; END $$", "SELECT 1"}, pg.SplitStatements(sql))
+	})
+
+	t.Run("given semicolons in strings, identifiers and comments, when split, then they are ignored", func(t *testing.T) {
+		sql := `SELECT 'a;b', 'it''s;', "odd;name" FROM t /* c; d */ WHERE x = $1; -- trailing; comment
+SELECT 2;`
+
+		require.Equal(t, []string{
+			`SELECT 'a;b', 'it''s;', "odd;name" FROM t /* c; d */ WHERE x = $1`,
+			"-- trailing; comment\nSELECT 2",
+		}, pg.SplitStatements(sql))
+	})
+
+	t.Run("given only comments and empty statements, when split, then nothing is returned", func(t *testing.T) {
+		require.Empty(t, pg.SplitStatements("-- nothing;\n;;\n/* here; */\n"))
+	})
+}
@@ -0,0 +1,144 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+// SQLSTATE of a statement cancelled by the server, e.g. by statement_timeout
+const queryCanceled = "57014"
+
+// Sets statement_timeout (in milliseconds) until the end of the
+// transaction, like SET LOCAL but with a parameter, so the statement is
+// prepared once whatever the timeout
+const setStatementTimeoutSQL = `SELECT set_config('statement_timeout', $1, true);`
+
+// timeoutBackend runs each statement in a transaction bounding it by the
+// deadline of its ctx on the server, see StatementTimeout. Statements
+// without a deadline run as is.
+//
+// It wraps the compatBackend, if any, so set_config also runs with the
+// simple protocol.
+type timeoutBackend struct {
+	backend
+}
+
+func (b timeoutBackend) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		return b.backend.Exec(ctx, sql, args...)
+	}
+	tx, err := b.Begin(ctx)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		_ = tx.Rollback(ctx)
+		return tag, serverTimedOut(ctx, err)
+	}
+	return tag, tx.Commit(ctx)
+}
+
+func (b timeoutBackend) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		return b.backend.Query(ctx, sql, args...)
+	}
+	tx, err := b.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		_ = tx.Rollback(ctx)
+		return nil, serverTimedOut(ctx, err)
+	}
+	return &txRows{Rows: rows, ctx: ctx, tx: tx}, nil
+}
+
+func (b timeoutBackend) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	rows, err := b.Query(ctx, sql, args...)
+	return heldRow{rows: rows, err: err}
+}
+
+// Begin starts a transaction whose statements are bounded by the
+// deadline of ctx
+func (b timeoutBackend) Begin(ctx context.Context) (pgx.Tx, error) {
+	tx, err := b.backend.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return tx, nil
+	}
+
+	// Rounded up, at least 1ms: 0 would disable the timeout
+	timeout := max(time.Until(deadline)+time.Millisecond-1, time.Millisecond).Milliseconds()
+	if _, err := tx.Exec(ctx, setStatementTimeoutSQL, strconv.FormatInt(timeout, 10)); err != nil {
+		_ = tx.Rollback(ctx)
+		return nil, err
+	}
+	return tx, nil
+}
+
+// txRows are the rows of a statement run in its own transaction,
+// committed once they are closed or fully read. A failed commit is
+// reported by Err, the statement having had no effect.
+type txRows struct {
+	pgx.Rows
+	ctx context.Context
+	tx  pgx.Tx
+
+	once sync.Once
+	err  error
+}
+
+func (r *txRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.end()
+	return false
+}
+
+func (r *txRows) Close() {
+	r.Rows.Close()
+	r.end()
+}
+
+func (r *txRows) Err() error {
+	if err := r.Rows.Err(); err != nil {
+		return serverTimedOut(r.ctx, err)
+	}
+	return r.err
+}
+
+// end commits the transaction, or rolls it back when the statement failed
+func (r *txRows) end() {
+	r.once.Do(func() {
+		if r.Rows.Err() != nil {
+			_ = r.tx.Rollback(r.ctx)
+			return
+		}
+		r.err = r.tx.Commit(r.ctx)
+	})
+}
+
+// serverTimedOut wraps with core.ErrOperationTimeout the error of a
+// statement cancelled by the server while ctx was still alive, that is by
+// its statement_timeout
+func serverTimedOut(ctx context.Context, err error) error {
+	var pgErr *pgconn.PgError
+	if ctx.Err() != nil || !errors.As(err, &pgErr) || pgErr.Code != queryCanceled {
+		return err
+	}
+	return fmt.Errorf("%w: %w", core.ErrOperationTimeout, err)
+}
@@ -0,0 +1,45 @@
+package pg
+
+import (
+	"sync/atomic"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+// stats holds the counters reported by Stats
+type stats struct {
+	acquires        atomic.Uint64
+	successes       atomic.Uint64
+	contentions     atomic.Uint64
+	releases        atomic.Uint64
+	refreshes       atomic.Uint64
+	refreshTooLate  atomic.Uint64
+	transientErrors atomic.Uint64
+	held            atomic.Int64
+	waiters         atomic.Int64
+}
+
+// Stats returns a snapshot of the operation counters of the adapter.
+//
+// Counters are read one by one, so a snapshot taken under load may be
+// slightly inconsistent, e.g. Successes momentarily above Held plus Releases.
+func (i *PostgresLockAdapter) Stats() core.Stats {
+	return core.Stats{
+		Acquires:        i.stats.acquires.Load(),
+		Successes:       i.stats.successes.Load(),
+		Contentions:     i.stats.contentions.Load(),
+		Releases:        i.stats.releases.Load(),
+		Refreshes:       i.stats.refreshes.Load(),
+		RefreshTooLate:  i.stats.refreshTooLate.Load(),
+		TransientErrors: i.stats.transientErrors.Load(),
+		EventsDropped:   i.events.Dropped(),
+		Held:            i.stats.held.Load(),
+		Waiters:         i.stats.waiters.Load(),
+	}
+}
+
+// released counts n released locks
+func (s *stats) released(n int) {
+	s.releases.Add(uint64(n))
+	s.held.Add(-int64(n))
+}
@@ -0,0 +1,114 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+// i.db = pgxpool.Pool, pgx.Conn or database/sql, see backend
+
+var (
+	// Rotating the nonce invalidates the token of the previous owner in the
+	// same statement that records the new one. An expired lock is not
+	// transferred, even if nobody took it over yet. The current row tells
+	// why nothing was updated.
+	transferOwnershipSQL = `
+	WITH holder AS (
+		SELECT lease_id, server_nonce
+		FROM %[1]s
+		WHERE key = $1
+	),
+	updated AS (
+		UPDATE %[1]s
+		SET
+			server_nonce = $4,
+			owner_id = $5,
+			updated_at = NOW()
+		WHERE
+			key = $1 AND
+			lease_id = $2 AND
+			server_nonce = $3 AND
+			valid_until > NOW()
+		RETURNING valid_until
+	)
+	SELECT
+		u.valid_until,
+		c.lease_id IS NOT NULL AS found,
+		COALESCE(c.lease_id = $2 AND c.server_nonce = $3, FALSE) AS owned
+	FROM (SELECT 1) AS one
+	LEFT JOIN updated u ON TRUE
+	LEFT JOIN holder c ON TRUE;`
+)
+
+// TransferOwnership hands the lock of the token over to newOwnerID
+// without releasing it, so no third party can grab the key in between,
+// e.g. a coordinator passing a job lock to the worker it dispatched.
+//
+// The ServerNonce is rotated and the owner recorded atomically: the
+// returned token, with the same lease and expiration, is the one the new
+// owner must use. The old token can no longer refresh or release the
+// lock.
+//
+// Errors wrap:
+//
+// - core.ErrInvalidOwnerID: newOwnerID is invalid
+//
+// - core.ErrLockOwnershipMismatch: the key is held with another lease or nonce
+//
+// - core.ErrLockNotFound: there is no lock for the key, or the lock of the
+// token expired
+//
+// - core.ErrOperationTimeout: the statement outlasted DefaultRequestTimeout
+func (i *PostgresLockAdapter) TransferOwnership(ctx context.Context, token *core.LockToken, newOwnerID string) (*core.LockToken, error) {
+	if err := i.begin(); err != nil {
+		return nil, err
+	}
+	defer i.end()
+
+	fail := func(err error) (*core.LockToken, error) {
+		return nil, &core.LockError{Op: core.OpTransfer, Key: token.Key, Attempts: 1, Err: err}
+	}
+
+	if err := core.ValidateOwnerID(newOwnerID); err != nil {
+		return fail(err)
+	}
+	storageKey, err := i.Cfg.storageKey(token.Key)
+	if err != nil {
+		return fail(err)
+	}
+
+	queryCtx, cancel := i.withRequestTimeout(ctx)
+	defer cancel()
+
+	newNonce := i.Cfg.newID()
+
+	start := time.Now()
+	var validUntil *time.Time
+	var found, owned bool
+	err = i.db.QueryRow(queryCtx,
+		i.sql.transferOwnership,
+		storageKey, token.LeaseID, token.ServerNonce, newNonce, newOwnerID,
+	).Scan(&validUntil, &found, &owned)
+	i.observe(start, err)
+
+	if err != nil {
+		return fail(timedOut(ctx, queryCtx, err))
+	}
+	if validUntil == nil {
+		if found && !owned {
+			return fail(core.ErrLockOwnershipMismatch)
+		}
+		return fail(core.ErrLockNotFound)
+	}
+
+	transferred := *token
+	transferred.ValidUntil = *validUntil
+	transferred.ServerNonce = newNonce
+	transferred.OwnerID = newOwnerID
+	transferred.ClockOffset = i.ClockDrift()
+	i.audit(ctx, auditEntry{storageKey, transferred.LeaseID, newOwnerID, AuditTransferred, nil})
+
+	return &transferred, nil
+}
@@ -0,0 +1,53 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// transientSQLStates are the SQLSTATEs of the failures a new attempt of
+// the same statement may not hit, besides the class 08 (connection
+// exception)
+var transientSQLStates = map[string]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"55P03": true, // lock_not_available
+	"53300": true, // too_many_connections
+	"57P01": true, // admin_shutdown
+	"57P02": true, // crash_shutdown
+	"57P03": true, // cannot_connect_now
+}
+
+// IsTransient reports whether err is a failure a retry may absorb: a
+// serialization failure, a deadlock, a server restarting or a connection
+// refused, reset or closed midway.
+//
+// The errors of the statements (syntax, permission, constraint...) and
+// the context errors are not transient: retrying would fail the same way
+// or outlive the caller.
+//
+// It only inspects err, so decorators and other backends talking to
+// Postgres through pgx can reuse it.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return transientSQLStates[pgErr.Code] || strings.HasPrefix(pgErr.Code, "08")
+	}
+
+	var netErr net.Error
+	var connectErr *pgconn.ConnectError
+	return errors.As(err, &netErr) ||
+		errors.As(err, &connectErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		pgconn.SafeToRetry(err)
+}
@@ -0,0 +1,116 @@
+package pg_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/oliveiracleidson/go-lockbox/pg"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsTransient(t *testing.T) {
+	t.Run("given a Postgres error, when is transient, then its SQLSTATE decides", func(t *testing.T) {
+		for _, tc := range []struct {
+			code      string
+			transient bool
+		}{
+			{"40001", true},  // serialization_failure
+			{"40P01", true},  // deadlock_detected
+			{"55P03", true},  // lock_not_available
+			{"53300", true},  // too_many_connections
+			{"57P01", true},  // admin_shutdown
+			{"57P02", true},  // crash_shutdown
+			{"57P03", true},  // cannot_connect_now
+			{"08000", true},  // connection_exception
+			{"08006", true},  // connection_failure
+			{"08003", true},  // connection_does_not_exist
+			{"42601", false}, // syntax_error
+			{"42501", false}, // insufficient_privilege
+			{"42883", false}, // undefined_function
+			{"42P01", false}, // undefined_table
+			{"23505", false}, // unique_violation
+			{"23502", false}, // not_null_violation
+			{"22P02", false}, // invalid_text_representation
+			{"28P01", false}, // invalid_password
+			{"53100", false}, // disk_full
+			{"57014", false}, // query_canceled
+		} {
+			err := fmt.Errorf("failed: %w", &pgconn.PgError{Code: tc.code})
+			require.Equal(t, tc.transient, pg.IsTransient(err), tc.code)
+		}
+	})
+
+	t.Run("given a connection failure, when is transient, then it is", func(t *testing.T) {
+		for _, err := range []error{
+			&net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET},
+			&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED},
+			io.EOF,
+			fmt.Errorf("unexpected EOF: %w", io.ErrUnexpectedEOF),
+		} {
+			require.True(t, pg.IsTransient(err), err.Error())
+		}
+	})
+
+	t.Run("given no error, a context error or another error, when is transient, then it is not", func(t *testing.T) {
+		for _, err := range []error{
+			nil,
+			context.Canceled,
+			fmt.Errorf("timeout: %w", context.DeadlineExceeded),
+			errors.New("cannot scan NULL into *string"),
+			core.ErrInvalidKeyFormat,
+		} {
+			require.False(t, pg.IsTransient(err), fmt.Sprint(err))
+		}
+	})
+}
+
+func TestPostgresLockAdapter_Acquire_Transient(t *testing.T) {
+	// Every connection attempt is refused, a transient failure
+	pool, err := pgxpool.New(context.Background(), "postgres://lockbox@127.0.0.1:1/lockbox?connect_timeout=1")
+	require.NoError(t, err)
+	defer pool.Close()
+
+	adapter, err := pg.NewPostgresLockAdapter(pool, pg.NewPostgresLockerConfig())
+	require.NoError(t, err)
+
+	t.Run("given transient failures, when acquire, then retries within the budget without counting contention", func(t *testing.T) {
+		_, err := adapter.Acquire(context.Background(), "key", core.LockOptions{
+			TTL: time.Second,
+			RetryStrategy: core.RetryStrategy{
+				MaxRetries:    2,
+				BaseDelay:     time.Millisecond,
+				MaxDelay:      time.Millisecond,
+				BackoffFactor: 1,
+			},
+		})
+		require.Error(t, err)
+		require.True(t, pg.IsTransient(err))
+
+		lockErr, ok := core.AsLockError(err)
+		require.True(t, ok)
+		require.Equal(t, 3, lockErr.Attempts)
+
+		stats := adapter.Stats()
+		require.Equal(t, uint64(2), stats.TransientErrors)
+		require.Zero(t, stats.Contentions)
+	})
+
+	t.Run("given a canceled context, when acquire, then does not retry", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		before := adapter.Stats().TransientErrors
+		_, err := adapter.Acquire(ctx, "key", core.LockOptions{TTL: time.Second})
+		require.ErrorIs(t, err, context.Canceled)
+		require.Equal(t, before, adapter.Stats().TransientErrors)
+	})
+}
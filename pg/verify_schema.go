@@ -0,0 +1,145 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+var (
+	lockColumnsSQL = `
+	SELECT attname, format_type(atttypid, atttypmod)
+	FROM pg_attribute
+	WHERE attrelid = to_regclass($1) AND attnum > 0 AND NOT attisdropped;`
+
+	// The result is NULL when no function has the signature
+	functionResultSQL = `
+	SELECT pg_get_function_result(to_regprocedure($1));`
+)
+
+// lockColumns are the columns of the lock table with the types the
+// adapter reads and writes
+var lockColumns = []struct{ name, typ string }{
+	{"key", "text"},
+	{"lease_id", "text"},
+	{"valid_until", "timestamp with time zone"},
+	{"server_nonce", "text"},
+	{"metadata", "jsonb"},
+	{"owner_id", "text"},
+	{"created_at", "timestamp with time zone"},
+	{"updated_at", "timestamp with time zone"},
+	{"acquired_at", "timestamp with time zone"},
+	{"refresh_count", "integer"},
+	{"ttl_ms", "bigint"},
+}
+
+// The result of the acquisition functions, as formatted by Postgres
+const tryAcquireLockResult = "TABLE(result_acquired boolean, result_valid_until timestamp with time zone, " +
+	"result_lease_id text, result_nonce text, result_took_over boolean, result_previous_lease_id text)"
+
+// VerifySchema checks that the lock table, its columns and their types,
+// the indexes and the acquisition functions are the ones the adapter
+// expects, without changing anything, e.g. when the schema is created by
+// a DBA pipeline rather than RunMigrations.
+//
+// The returned error wraps ErrSchemaMismatch and lists everything missing
+// or mismatched. See also ValidateOnStart.
+func (i *PostgresLockAdapter) VerifySchema(ctx context.Context) error {
+	if err := i.begin(); err != nil {
+		return err
+	}
+	defer i.end()
+
+	problems := []string{}
+
+	var tableExists bool
+	err := i.db.QueryRow(ctx,
+		tableExistsQuery,
+		i.Cfg.LockSchema, i.Cfg.LockTableName,
+	).Scan(&tableExists)
+	if err != nil {
+		return err
+	}
+
+	if !tableExists {
+		problems = append(problems, fmt.Sprintf("table %s is missing", i.Cfg.lockTable()))
+	} else {
+		columnProblems, err := i.verifyColumns(ctx)
+		if err != nil {
+			return err
+		}
+		problems = append(problems, columnProblems...)
+	}
+
+	indexes, err := i.indexStatus(ctx)
+	if err != nil {
+		return err
+	}
+	for idx, index := range indexes {
+		switch {
+		case !i.Cfg.requiredIndex(lockIndexes[idx]):
+		case !index.Exists:
+			problems = append(problems, fmt.Sprintf("index %s is missing", index.Name))
+		case !index.Valid:
+			problems = append(problems, fmt.Sprintf("index %s is invalid", index.Name))
+		}
+	}
+
+	functions := []string{i.Cfg.tryAcquireLock() + "(text, text, bigint, text, jsonb, text)"}
+	if i.Cfg.FIFO {
+		functions = append(functions, i.Cfg.tryAcquireLockFIFO()+"(text, text, bigint, text, jsonb, text, bigint)")
+	}
+	for _, function := range functions {
+		var result *string
+		if err := i.db.QueryRow(ctx, functionResultSQL, function).Scan(&result); err != nil {
+			return err
+		}
+		switch {
+		case result == nil:
+			problems = append(problems, fmt.Sprintf("function %s is missing", function))
+		case *result != tryAcquireLockResult:
+			problems = append(problems, fmt.Sprintf("function %s returns %s, expected %s", function, *result, tryAcquireLockResult))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrSchemaMismatch, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// verifyColumns compares the columns of the lock table to lockColumns
+func (i *PostgresLockAdapter) verifyColumns(ctx context.Context) ([]string, error) {
+	rows, err := i.db.Query(ctx,
+		lockColumnsSQL,
+		i.Cfg.lockTable(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	types := map[string]string{}
+	for rows.Next() {
+		var name, typ string
+		if err := rows.Scan(&name, &typ); err != nil {
+			return nil, err
+		}
+		types[name] = typ
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	problems := []string{}
+	for _, column := range lockColumns {
+		typ, ok := types[column.name]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("column %s is missing", column.name))
+		case typ != column.typ:
+			problems = append(problems, fmt.Sprintf("column %s is %s, expected %s", column.name, typ, column.typ))
+		}
+	}
+	return problems, nil
+}
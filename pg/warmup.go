@@ -0,0 +1,69 @@
+package pg
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Warmup opens n pool connections up front, so the first acquisitions
+// after startup, e.g. a leader election at boot, don't pay for the
+// connection establishment.
+//
+// The n connections are held at the same time, forcing the pool to open
+// the missing ones, pinged and then returned to the pool idle. It fails
+// if n exceeds the pool MaxConns or any connection can't be established
+// before ctx is done. Idle connections above MinConns are still closed
+// by the pool after its MaxConnIdleTime.
+func (i *PostgresLockAdapter) Warmup(ctx context.Context, n int) error {
+	if err := i.begin(); err != nil {
+		return err
+	}
+	defer i.end()
+
+	if n <= 0 {
+		return errors.New("n must be > 0")
+	}
+	// database/sql reports 0 when unlimited
+	if maxConns := i.db.stat().MaxConns; maxConns > 0 && int32(n) > maxConns {
+		return fmt.Errorf("cannot warm up %d connections, the pool is limited to %d", n, maxConns)
+	}
+
+	releases := make([]func(bool), n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for idx := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, release, err := i.db.acquire(ctx)
+			if err == nil {
+				err = conn.Ping(ctx)
+				releases[idx] = release
+			}
+			errs[idx] = err
+		}()
+	}
+	wg.Wait()
+
+	for _, release := range releases {
+		if release != nil {
+			release(false)
+		}
+	}
+
+	failed := 0
+	var firstErr error
+	for _, err := range errs {
+		if err != nil {
+			failed++
+			firstErr = cmp.Or(firstErr, err)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to warm up %d of %d connections: %w", failed, n, firstErr)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package pg_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/oliveiracleidson/go-lockbox/pg"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresLockAdapter_Warmup_Unreachable(t *testing.T) {
+	// Nothing listens on port 1, every connection fails
+	pool, err := pgxpool.New(context.Background(), "postgres://lockbox@127.0.0.1:1/lockbox?connect_timeout=1&pool_max_conns=4")
+	require.NoError(t, err)
+	defer pool.Close()
+
+	unreachable, err := pg.NewPostgresLockAdapter(pool, pg.NewPostgresLockerConfig())
+	require.NoError(t, err)
+
+	t.Run("given an unreachable database, when warm up, then reports the failed connections", func(t *testing.T) {
+		err := unreachable.Warmup(context.Background(), 2)
+		require.ErrorContains(t, err, "failed to warm up 2 of 2 connections")
+	})
+
+	t.Run("given more connections than the pool allows, when warm up, then return error", func(t *testing.T) {
+		err := unreachable.Warmup(context.Background(), 5)
+		require.ErrorContains(t, err, "the pool is limited to 4")
+	})
+
+	t.Run("given no connections, when warm up, then return error", func(t *testing.T) {
+		require.Error(t, unreachable.Warmup(context.Background(), 0))
+	})
+}
@@ -0,0 +1,156 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Acquire obtains the lock of key with SET NX PX, retrying a contended
+// key with the backoff of opts.RetryStrategy, like the pg adapter.
+//
+// ConfirmIfOwned takes over the lease of a lock held by opts.OwnerID
+// with a new nonce, keeping its metadata.
+func (a *RedisLockAdapter) Acquire(ctx context.Context, key string, opts core.LockOptions) (*core.LockToken, error) {
+	if err := a.begin(); err != nil {
+		return nil, err
+	}
+	defer a.end()
+
+	storageKey, err := a.Cfg.storageKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.ValidateWithMaxTTL(a.Cfg.maxTTL()); err != nil {
+		return nil, err
+	}
+	metadata, err := core.EncodeMetadata(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	leaseID := a.Cfg.newID()
+
+	// tryAcquire runs a single attempt, returning a nil token on contention
+	tryAcquire := func(ctx context.Context, _ int) (*core.LockToken, func() *core.ContentionError, error) {
+		reqCtx, cancel := context.WithTimeout(ctx, opts.RequestTimeout)
+		defer cancel()
+
+		nonce := a.Cfg.newID()
+		start := time.Now()
+		acquired, err := a.client.SetNX(reqCtx,
+			storageKey, lockValue(nonce, leaseID, opts.OwnerID, metadata), opts.TTL,
+		).Result()
+		a.observe(start, err)
+		if err != nil {
+			return nil, nil, err
+		}
+		if acquired {
+			// The key expires TTL after it was set, no sooner than start+TTL
+			return &core.LockToken{
+				Key:                 key,
+				LeaseID:             leaseID,
+				ValidUntil:          start.Add(opts.TTL),
+				ServerNonce:         nonce,
+				OwnerID:             opts.OwnerID,
+				TTL:                 opts.TTL,
+				RefreshSafetyMargin: opts.RefreshSafetyMargin,
+			}, nil, nil
+		}
+
+		if opts.ConfirmIfOwned {
+			token, err := a.confirmOwned(ctx, key, storageKey, opts)
+			if err != nil || token != nil {
+				return token, nil, err
+			}
+		}
+		return nil, func() *core.ContentionError { return a.holder(ctx, storageKey) }, nil
+	}
+
+	return core.AcquireRetry{Key: key, Options: opts, Hooks: a.Cfg.Hooks}.Run(ctx, tryAcquire)
+}
+
+// confirmOwned renews the lock of the key if it is held by opts.OwnerID,
+// returning a nil token otherwise
+func (a *RedisLockAdapter) confirmOwned(ctx context.Context, key, storageKey string, opts core.LockOptions) (*core.LockToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, opts.RequestTimeout)
+	defer cancel()
+
+	start := time.Now()
+	value, err := a.client.Get(ctx, storageKey).Result()
+	a.observe(start, err)
+	if errors.Is(err, goredis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	current := parseLockValue(value)
+	if current.ownerID != opts.OwnerID {
+		return nil, nil
+	}
+
+	nonce := a.Cfg.newID()
+	start = time.Now()
+	result, err := refreshScript.Run(ctx, a.client,
+		[]string{storageKey},
+		tokenPrefix(current.nonce, current.leaseID), tokenPrefix(nonce, current.leaseID), opts.TTL.Milliseconds(),
+	).Int()
+	a.observe(start, err)
+	if err != nil || result != scriptDone {
+		// Released or taken over meanwhile
+		return nil, err
+	}
+
+	return &core.LockToken{
+		Key:                 key,
+		LeaseID:             current.leaseID,
+		ValidUntil:          start.Add(opts.TTL),
+		ServerNonce:         nonce,
+		OwnerID:             opts.OwnerID,
+		TTL:                 opts.TTL,
+		RefreshSafetyMargin: opts.RefreshSafetyMargin,
+	}, nil
+}
+
+// holder describes the current holder of the storage key,
+// leaving the fields it cannot read empty
+func (a *RedisLockAdapter) holder(ctx context.Context, storageKey string) *core.ContentionError {
+	value, remaining, err := a.read(ctx, storageKey)
+	if err != nil || value == "" {
+		return &core.ContentionError{}
+	}
+	current := parseLockValue(value)
+	holder := &core.ContentionError{
+		HolderID:       current.ownerID,
+		HolderMetadata: core.DecodeMetadata(current.metadata),
+	}
+	if remaining > 0 {
+		holder.HeldUntil = time.Now().Add(remaining)
+	}
+	return holder
+}
+
+// read returns the value and remaining TTL of the storage key, read at
+// once with GET and PTTL; an empty value when there is no lock
+func (a *RedisLockAdapter) read(ctx context.Context, storageKey string) (string, time.Duration, error) {
+	var get *goredis.StringCmd
+	var pttl *goredis.DurationCmd
+	start := time.Now()
+	_, err := a.client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		get = pipe.Get(ctx, storageKey)
+		pttl = pipe.PTTL(ctx, storageKey)
+		return nil
+	})
+	a.observe(start, err)
+	if errors.Is(err, goredis.Nil) {
+		return "", 0, nil
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	return get.Val(), pttl.Val(), nil
+}
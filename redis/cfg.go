@@ -0,0 +1,258 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+// HealthCheck defaults
+const (
+	DefaultPoolHighWaterMark = 0.8
+	DefaultLatencyThreshold  = 100 * time.Millisecond
+
+	// Fraction of failed recent operations
+	DefaultErrorRateThreshold = 0.05
+)
+
+// Prefix of the lock keys by default
+const DefaultKeyPrefix = "lockbox:"
+
+// ErrInvalidConfig is returned by Validate for an invalid configuration
+var ErrInvalidConfig = errors.New("invalid redis locker config")
+
+type RedisLockerConfig struct {
+	// KeyPrefix is prepended to the key of every lock, so the locks
+	// don't collide with the other keys of the database.
+	// Defaults to DefaultKeyPrefix.
+	KeyPrefix string
+
+	// Namespace transparently prefixed to every key, after KeyPrefix, so
+	// adapters sharing the same database with different namespaces never
+	// collide. Segments are separated by core.KeySeparator.
+	Namespace string
+
+	Hooks core.Hooks
+
+	// MaxAllowedTTL raises (or lowers) the TTL ceiling of the adapter.
+	// Defaults to core.MaxLockTTL.
+	MaxAllowedTTL time.Duration
+
+	// DefaultRequestTimeout bounds the commands of Release, Refresh and
+	// IsHeld, which have no LockOptions carrying a RequestTimeout. They
+	// fail with core.ErrOperationTimeout once it fires.
+	// Defaults to core.DefaultRequestTimeout.
+	DefaultRequestTimeout time.Duration
+
+	// HealthCheck reports StatusYellow when the fraction of the
+	// connections in use reaches PoolHighWaterMark (0.0-1.0) or the PING
+	// takes longer than LatencyThreshold
+	PoolHighWaterMark float64
+	LatencyThreshold  time.Duration
+
+	// HealthCheck reports StatusYellow when the fraction (0.0-1.0) of the
+	// recent operations whose command failed exceeds ErrorRateThreshold
+	ErrorRateThreshold float64
+
+	// IDGenerator produces the LeaseID and ServerNonce of the tokens,
+	// which must not contain newlines. Defaults to core.UUIDGenerator.
+	IDGenerator core.IDGenerator
+}
+
+// NewRedisLockerConfig creates a new instance of RedisLockerConfig
+// with default values
+func NewRedisLockerConfig() *RedisLockerConfig {
+	r := &RedisLockerConfig{}
+	return r.WithDefaults()
+}
+
+func (r *RedisLockerConfig) Validate() error {
+	msgs := []string{}
+	if r.Namespace != "" {
+		if err := core.ValidateNamespace(r.Namespace); err != nil {
+			msgs = append(msgs, "Namespace must be [a-zA-Z0-9_-] segments separated by ':'")
+		}
+	}
+	if r.MaxAllowedTTL != 0 && r.MaxAllowedTTL < core.MinLockTTL {
+		msgs = append(msgs, fmt.Sprintf("MaxAllowedTTL must be ≥ %v", core.MinLockTTL))
+	}
+	if r.DefaultRequestTimeout < 0 {
+		msgs = append(msgs, "DefaultRequestTimeout must be ≥ 0")
+	}
+	if r.PoolHighWaterMark < 0 || r.PoolHighWaterMark > 1 {
+		msgs = append(msgs, "PoolHighWaterMark must be [0.0, 1.0]")
+	}
+	if r.LatencyThreshold < 0 {
+		msgs = append(msgs, "LatencyThreshold must be ≥ 0")
+	}
+	if r.ErrorRateThreshold < 0 || r.ErrorRateThreshold > 1 {
+		msgs = append(msgs, "ErrorRateThreshold must be [0.0, 1.0]")
+	}
+
+	if len(msgs) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidConfig, strings.Join(msgs, ", "))
+	}
+	return nil
+}
+
+// WithDefaults sets default values for the zero-valued fields.
+//
+// Returns the same instance
+// Defaults:
+//
+// - KeyPrefix: DefaultKeyPrefix
+//
+// - MaxAllowedTTL: core.MaxLockTTL
+//
+// - DefaultRequestTimeout: core.DefaultRequestTimeout
+//
+// - PoolHighWaterMark: 0.8
+//
+// - LatencyThreshold: 100ms
+//
+// - ErrorRateThreshold: 0.05
+//
+// - IDGenerator: core.UUIDGenerator
+func (r *RedisLockerConfig) WithDefaults() *RedisLockerConfig {
+	if r.KeyPrefix == "" {
+		r.KeyPrefix = DefaultKeyPrefix
+	}
+	if r.MaxAllowedTTL == 0 {
+		r.MaxAllowedTTL = core.MaxLockTTL
+	}
+	if r.DefaultRequestTimeout == 0 {
+		r.DefaultRequestTimeout = core.DefaultRequestTimeout
+	}
+	if r.PoolHighWaterMark == 0 {
+		r.PoolHighWaterMark = DefaultPoolHighWaterMark
+	}
+	if r.LatencyThreshold == 0 {
+		r.LatencyThreshold = DefaultLatencyThreshold
+	}
+	if r.ErrorRateThreshold == 0 {
+		r.ErrorRateThreshold = DefaultErrorRateThreshold
+	}
+	if r.IDGenerator == nil {
+		r.IDGenerator = core.UUIDGenerator{}
+	}
+	return r
+}
+
+// maxTTL returns the TTL ceiling of the adapter
+func (r *RedisLockerConfig) maxTTL() time.Duration {
+	if r.MaxAllowedTTL == 0 {
+		return core.MaxLockTTL
+	}
+	return r.MaxAllowedTTL
+}
+
+// requestTimeout returns the bound of the commands without LockOptions
+func (r *RedisLockerConfig) requestTimeout() time.Duration {
+	if r.DefaultRequestTimeout == 0 {
+		return core.DefaultRequestTimeout
+	}
+	return r.DefaultRequestTimeout
+}
+
+// storageKey returns the Redis key of the lock of key, prefixed by
+// KeyPrefix and the namespace
+func (r *RedisLockerConfig) storageKey(key string) (string, error) {
+	namespaced, err := core.NamespaceKey(r.Namespace, key)
+	if err != nil {
+		return "", err
+	}
+	return r.KeyPrefix + namespaced, nil
+}
+
+// newID returns a LeaseID or ServerNonce from the IDGenerator,
+// falling back to a UUID for configurations built without WithDefaults
+func (r *RedisLockerConfig) newID() string {
+	if r.IDGenerator == nil {
+		return core.UUIDGenerator{}.NewID()
+	}
+	return r.IDGenerator.NewID()
+}
+
+// SetKeyPrefix sets the KeyPrefix field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (r *RedisLockerConfig) SetKeyPrefix(v string) *RedisLockerConfig {
+	r.KeyPrefix = v
+	return r
+}
+
+// SetNamespace sets the Namespace field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (r *RedisLockerConfig) SetNamespace(v string) *RedisLockerConfig {
+	r.Namespace = v
+	return r
+}
+
+// SetHooks sets the Hooks field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (r *RedisLockerConfig) SetHooks(v core.Hooks) *RedisLockerConfig {
+	r.Hooks = v
+	return r
+}
+
+// SetMaxAllowedTTL sets the MaxAllowedTTL field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (r *RedisLockerConfig) SetMaxAllowedTTL(v time.Duration) *RedisLockerConfig {
+	r.MaxAllowedTTL = v
+	return r
+}
+
+// SetDefaultRequestTimeout sets the DefaultRequestTimeout field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (r *RedisLockerConfig) SetDefaultRequestTimeout(v time.Duration) *RedisLockerConfig {
+	r.DefaultRequestTimeout = v
+	return r
+}
+
+// SetPoolHighWaterMark sets the PoolHighWaterMark field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (r *RedisLockerConfig) SetPoolHighWaterMark(v float64) *RedisLockerConfig {
+	r.PoolHighWaterMark = v
+	return r
+}
+
+// SetLatencyThreshold sets the LatencyThreshold field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (r *RedisLockerConfig) SetLatencyThreshold(v time.Duration) *RedisLockerConfig {
+	r.LatencyThreshold = v
+	return r
+}
+
+// SetErrorRateThreshold sets the ErrorRateThreshold field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (r *RedisLockerConfig) SetErrorRateThreshold(v float64) *RedisLockerConfig {
+	r.ErrorRateThreshold = v
+	return r
+}
+
+// SetIDGenerator sets the IDGenerator field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (r *RedisLockerConfig) SetIDGenerator(v core.IDGenerator) *RedisLockerConfig {
+	r.IDGenerator = v
+	return r
+}
@@ -0,0 +1,55 @@
+package redis_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/oliveiracleidson/go-lockbox/redis"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRedisLockerConfig_WithDefaults(t *testing.T) {
+	config := redis.NewRedisLockerConfig()
+
+	assert.Equal(t, redis.DefaultKeyPrefix, config.KeyPrefix)
+	assert.Equal(t, core.MaxLockTTL, config.MaxAllowedTTL)
+	assert.Equal(t, core.DefaultRequestTimeout, config.DefaultRequestTimeout)
+	assert.Equal(t, redis.DefaultPoolHighWaterMark, config.PoolHighWaterMark)
+	assert.Equal(t, redis.DefaultLatencyThreshold, config.LatencyThreshold)
+	assert.Equal(t, redis.DefaultErrorRateThreshold, config.ErrorRateThreshold)
+	assert.Equal(t, core.UUIDGenerator{}, config.IDGenerator)
+	assert.NoError(t, config.Validate())
+}
+
+func TestRedisLockerConfig_Validate(t *testing.T) {
+	config := redis.NewRedisLockerConfig().
+		SetNamespace("team a").
+		SetMaxAllowedTTL(time.Microsecond).
+		SetDefaultRequestTimeout(-time.Second).
+		SetPoolHighWaterMark(1.5).
+		SetLatencyThreshold(-time.Second).
+		SetErrorRateThreshold(-0.1)
+
+	err := config.Validate()
+	require.ErrorIs(t, err, redis.ErrInvalidConfig)
+	assert.Contains(t, err.Error(), "Namespace must be")
+	assert.Contains(t, err.Error(), "MaxAllowedTTL must be")
+	assert.Contains(t, err.Error(), "DefaultRequestTimeout must be ≥ 0")
+	assert.Contains(t, err.Error(), "PoolHighWaterMark must be [0.0, 1.0]")
+	assert.Contains(t, err.Error(), "LatencyThreshold must be ≥ 0")
+	assert.Contains(t, err.Error(), "ErrorRateThreshold must be [0.0, 1.0]")
+}
+
+func TestNewRedisLockAdapter_InvalidConfig(t *testing.T) {
+	client := goredis.NewClient(&goredis.Options{Addr: "127.0.0.1:1"})
+	defer client.Close()
+
+	_, err := redis.NewRedisLockAdapter(client, redis.NewRedisLockerConfig().SetPoolHighWaterMark(2))
+	require.ErrorIs(t, err, redis.ErrInvalidConfig)
+
+	_, err = redis.NewRedisLockAdapter(nil, redis.NewRedisLockerConfig())
+	require.Error(t, err)
+}
@@ -0,0 +1,122 @@
+package redis_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/oliveiracleidson/go-lockbox/core/locktest"
+	"github.com/oliveiracleidson/go-lockbox/redis"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+// newAdapter returns an adapter on the Redis of REDIS_URL, skipping the
+// test without one
+func newAdapter(t *testing.T, cfg *redis.RedisLockerConfig) *redis.RedisLockAdapter {
+	redisURL := os.Getenv("REDIS_URL")
+	if redisURL == "" {
+		t.Skip("REDIS_URL is required for the integration tests")
+	}
+	opts, err := goredis.ParseURL(redisURL)
+	require.NoError(t, err)
+
+	adapter, err := redis.NewRedisLockAdapter(goredis.NewClient(opts), cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { adapter.Close(context.Background()) })
+	return adapter
+}
+
+func TestRedisLockAdapter_Contract(t *testing.T) {
+	adapter := newAdapter(t, redis.NewRedisLockerConfig().SetNamespace("contract"))
+	locktest.Run(t, adapter, "contract-redis")
+}
+
+func TestRedisLockAdapter_Integration(t *testing.T) {
+	adapter := newAdapter(t, redis.NewRedisLockerConfig().SetNamespace("integration"))
+	ctx := context.Background()
+	opts := core.LockOptions{
+		TTL:            10 * time.Second,
+		RetryStrategy:  core.NoRetry(),
+		RequestTimeout: 5 * time.Second,
+		OwnerID:        "worker-1",
+		Metadata:       map[string]string{"job": "reindex"},
+	}
+
+	t.Run("given a held key, when acquire with retries, then succeeds once released", func(t *testing.T) {
+		token, err := adapter.Acquire(ctx, "retried", opts)
+		require.NoError(t, err)
+		go func() {
+			time.Sleep(150 * time.Millisecond)
+			adapter.Release(ctx, token)
+		}()
+
+		retried := opts
+		retried.RetryStrategy = core.RetryStrategy{
+			MaxRetries:    10,
+			BaseDelay:     50 * time.Millisecond,
+			MaxDelay:      100 * time.Millisecond,
+			BackoffFactor: 2,
+		}
+		token, err = adapter.Acquire(ctx, "retried", retried)
+		require.NoError(t, err)
+		require.NoError(t, adapter.Release(ctx, token))
+	})
+
+	t.Run("given a held key, when acquire, then the contention error describes the holder", func(t *testing.T) {
+		token, err := adapter.Acquire(ctx, "described", opts)
+		require.NoError(t, err)
+		defer adapter.Release(ctx, token)
+
+		other := opts
+		other.OwnerID = "worker-2"
+		_, err = adapter.Acquire(ctx, "described", other)
+		var contentionErr *core.ContentionError
+		require.ErrorAs(t, err, &contentionErr)
+		require.Equal(t, "worker-1", contentionErr.HolderID)
+		require.Equal(t, map[string]string{"job": "reindex"}, contentionErr.HolderMetadata)
+		require.WithinDuration(t, token.ValidUntil, contentionErr.HeldUntil, time.Second)
+	})
+
+	t.Run("given a key held by the same owner, when acquire with ConfirmIfOwned, then takes over its lease", func(t *testing.T) {
+		token, err := adapter.Acquire(ctx, "confirmed", opts)
+		require.NoError(t, err)
+
+		confirm := opts
+		confirm.ConfirmIfOwned = true
+		confirmed, err := adapter.Acquire(ctx, "confirmed", confirm)
+		require.NoError(t, err)
+		require.Equal(t, token.LeaseID, confirmed.LeaseID)
+		require.NotEqual(t, token.ServerNonce, confirmed.ServerNonce)
+
+		require.ErrorIs(t, adapter.Release(ctx, token), core.ErrLockOwnershipMismatch)
+		require.NoError(t, adapter.Release(ctx, confirmed))
+	})
+
+	t.Run("given an expired lock, when refresh, then fails with lock not found", func(t *testing.T) {
+		short := opts
+		short.TTL = 50 * time.Millisecond
+		token, err := adapter.Acquire(ctx, "expired", short)
+		require.NoError(t, err)
+		time.Sleep(150 * time.Millisecond)
+
+		_, err = adapter.Refresh(ctx, token, time.Second)
+		require.ErrorIs(t, err, core.ErrLockNotFound)
+	})
+
+	t.Run("given a reachable server, when health check, then status is Green", func(t *testing.T) {
+		report := adapter.HealthCheck(ctx)
+		require.Equal(t, core.StatusGreen, report.Status, report.Error)
+		require.NotNil(t, report.Pool)
+		require.Positive(t, report.Pool.MaxConns)
+	})
+
+	t.Run("given a redis DSN, when open, then returns a redis adapter", func(t *testing.T) {
+		opened, err := core.Open(ctx, os.Getenv("REDIS_URL"))
+		require.NoError(t, err)
+		defer opened.Close(ctx)
+		require.Equal(t, "redis", opened.HealthCheck(ctx).Backend)
+	})
+}
@@ -0,0 +1,88 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// HealthCheck monitors service health.
+// Latency is the average latency and Throughput the operations per second
+// of the recent lock operations; the latency of the PING probe itself is
+// reported in Details["probe_latency"] and the state of the connection
+// pool in Pool.
+//
+// The status is Red when the PING fails or the adapter is closed, and
+// Yellow when the pool usage reaches PoolHighWaterMark, the PING takes
+// longer than LatencyThreshold or the fraction of the recent operations
+// that failed exceeds ErrorRateThreshold.
+func (a *RedisLockAdapter) HealthCheck(ctx context.Context) core.HealthReport {
+	if err := a.begin(); err != nil {
+		return core.HealthReport{Status: core.StatusRed, Error: err, Backend: "redis"}
+	}
+	defer a.end()
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	err := a.client.Ping(ctx).Err()
+	latency := time.Since(start)
+
+	pool := a.poolStats()
+	errorRate := a.latencies.ErrorRate()
+
+	status, reportErr := core.StatusGreen, error(nil)
+	switch {
+	case err != nil:
+		status, reportErr = core.StatusRed, fmt.Errorf("health probe failed: %w", err)
+	case pool.MaxConns > 0 && pool.Usage >= a.Cfg.PoolHighWaterMark:
+		status, reportErr = core.StatusYellow, fmt.Errorf("pool saturated: %d/%d connections in use", pool.AcquiredConns, pool.MaxConns)
+	case latency > a.Cfg.LatencyThreshold:
+		status, reportErr = core.StatusYellow, fmt.Errorf("high latency: %v > %v", latency, a.Cfg.LatencyThreshold)
+	case errorRate > a.Cfg.ErrorRateThreshold:
+		status, reportErr = core.StatusYellow, fmt.Errorf("high error rate: %.2f > %.2f", errorRate, a.Cfg.ErrorRateThreshold)
+	}
+
+	percentiles := a.latencies.Percentiles(50, 95, 99)
+
+	return core.HealthReport{
+		Status:     status,
+		Latency:    a.latencies.Average(),
+		Throughput: a.latencies.Throughput(time.Now(), core.DefaultThroughputWindow),
+		ErrorRate:  errorRate,
+		Error:      reportErr,
+		LatencyP50: percentiles[0],
+		LatencyP95: percentiles[1],
+		LatencyP99: percentiles[2],
+		Operations: a.latencies.Total(),
+		Uptime:     time.Since(a.startedAt),
+		Backend:    "redis",
+		Pool:       pool,
+		Details: map[string]string{
+			"probe_latency": latency.String(),
+			"key_prefix":    a.Cfg.KeyPrefix,
+		},
+	}
+}
+
+// poolStats returns the usage of the connections of the client; MaxConns
+// is only known for single node and Sentinel clients
+func (a *RedisLockAdapter) poolStats() *core.PoolStats {
+	s := a.client.PoolStats()
+	stats := &core.PoolStats{
+		TotalConns:    int32(s.TotalConns),
+		AcquiredConns: int32(s.TotalConns - s.IdleConns),
+		IdleConns:     int32(s.IdleConns),
+	}
+	if c, ok := a.client.(interface{ Options() *goredis.Options }); ok {
+		stats.MaxConns = int32(c.Options().PoolSize)
+	}
+	if stats.MaxConns > 0 {
+		stats.Usage = float64(stats.AcquiredConns) / float64(stats.MaxConns)
+	}
+	return stats
+}
@@ -0,0 +1,36 @@
+package redis
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+// IsHeld reports whether the token still owns its lock and the remaining
+// TTL, read with GET and PTTL. The commands fail with
+// core.ErrOperationTimeout after DefaultRequestTimeout.
+func (a *RedisLockAdapter) IsHeld(ctx context.Context, token *core.LockToken) (bool, time.Duration, error) {
+	if err := a.begin(); err != nil {
+		return false, 0, err
+	}
+	defer a.end()
+
+	storageKey, err := a.Cfg.storageKey(token.Key)
+	if err != nil {
+		return false, 0, err
+	}
+
+	reqCtx, cancel := a.withRequestTimeout(ctx)
+	defer cancel()
+
+	value, remaining, err := a.read(reqCtx, storageKey)
+	if err != nil {
+		return false, 0, timedOut(ctx, reqCtx, err)
+	}
+	if !strings.HasPrefix(value, tokenPrefix(token.ServerNonce, token.LeaseID)) || remaining <= 0 {
+		return false, 0, nil
+	}
+	return true, remaining, nil
+}
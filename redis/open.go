@@ -0,0 +1,31 @@
+package redis
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func init() {
+	core.Register("redis", open)
+	core.Register("rediss", open)
+}
+
+// open creates a RedisLockAdapter with its own client from a DSN for
+// core.Open. The query parameters are the ones of goredis.ParseURL.
+func open(ctx context.Context, dsn *url.URL, openCfg core.OpenConfig) (core.LockAdapter, error) {
+	opts, err := goredis.ParseURL(dsn.String())
+	if err != nil {
+		return nil, err
+	}
+	client := goredis.NewClient(opts)
+
+	adapter, err := NewRedisLockAdapter(client, NewRedisLockerConfig().SetHooks(openCfg.Hooks))
+	if err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	return adapter, nil
+}
@@ -0,0 +1,171 @@
+// Package redis implements core.LockAdapter on Redis.
+//
+// It is a module of its own, so the Redis client is only pulled by the
+// services importing it:
+//
+//	client := goredis.NewClient(&goredis.Options{Addr: "localhost:6379"})
+//	adapter, err := redis.NewRedisLockAdapter(client, redis.NewRedisLockerConfig())
+//
+// A lock is a string key, set with SET NX PX and expiring with its TTL,
+// holding the ServerNonce and LeaseID of the token followed by the
+// OwnerID and metadata of the holder. Release and Refresh compare them
+// atomically in Lua scripts.
+//
+// Redis deletes a key as soon as its TTL lapses: unlike the pg adapter,
+// an expired lock can't be refreshed, so RefreshSafetyMargin does not
+// apply, and TookOver is never set. Network failures are retried by the
+// client itself, see the MaxRetries of its options.
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+type RedisLockAdapter struct {
+	client goredis.UniversalClient
+
+	// Read only once the adapter is created
+	Cfg *RedisLockerConfig
+
+	// Reported by HealthCheck
+	startedAt time.Time
+	latencies *core.LatencyWindow
+
+	// Operations in flight, awaited by Close
+	closeMu  sync.RWMutex
+	closed   atomic.Bool
+	inFlight sync.WaitGroup
+}
+
+// NewRedisLockAdapter creates an adapter storing its locks through
+// client, a single node, Sentinel or Cluster client
+func NewRedisLockAdapter(client goredis.UniversalClient, cfg *RedisLockerConfig) (*RedisLockAdapter, error) {
+	if client == nil {
+		return nil, errors.New("redis client is required")
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &RedisLockAdapter{
+		client:    client,
+		Cfg:       cfg,
+		startedAt: time.Now(),
+		latencies: core.NewLatencyWindow(core.DefaultLatencyWindowSize),
+	}, nil
+}
+
+// Close stops accepting operations, waits for the ones in flight and
+// closes the client.
+//
+// Operations started after Close return core.ErrAdapterClosed. If ctx
+// expires first, the client is closed anyway and the ctx error is
+// returned. Closing twice is a no-op.
+func (a *RedisLockAdapter) Close(ctx context.Context) error {
+	a.closeMu.Lock()
+	alreadyClosed := a.closed.Swap(true)
+	a.closeMu.Unlock()
+	if alreadyClosed {
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		a.inFlight.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("operations still in flight: %w", ctx.Err())
+	}
+
+	if closeErr := a.client.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// begin registers an operation in flight, failing once the adapter is
+// closed. Every successful begin must be paired with an end.
+func (a *RedisLockAdapter) begin() error {
+	a.closeMu.RLock()
+	defer a.closeMu.RUnlock()
+	if a.closed.Load() {
+		return core.ErrAdapterClosed
+	}
+	a.inFlight.Add(1)
+	return nil
+}
+
+// end marks an operation started by begin as finished
+func (a *RedisLockAdapter) end() {
+	a.inFlight.Done()
+}
+
+// withRequestTimeout bounds a command without LockOptions by the
+// DefaultRequestTimeout of the config
+func (a *RedisLockAdapter) withRequestTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, a.Cfg.requestTimeout())
+}
+
+// timedOut wraps with core.ErrOperationTimeout the error of a command
+// cut by the timeout of reqCtx. The error of a ctx done beforehand is
+// returned as is.
+func timedOut(ctx, reqCtx context.Context, err error) error {
+	if err == nil || ctx.Err() != nil || !errors.Is(reqCtx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", core.ErrOperationTimeout, err)
+}
+
+// observe records the latency of an operation started at start, and
+// whether its command failed. Finding no key and a ctx cancelled by the
+// caller are not failures.
+func (a *RedisLockAdapter) observe(start time.Time, err error) {
+	if err == nil || errors.Is(err, goredis.Nil) || errors.Is(err, context.Canceled) {
+		a.latencies.Record(time.Since(start))
+		return
+	}
+	a.latencies.RecordFailure(time.Since(start))
+}
+
+// lockValue is the value of the key of a lock: the prefix compared by
+// the scripts, then the OwnerID and metadata of the holder, one per line
+func lockValue(nonce, leaseID, ownerID string, metadata []byte) string {
+	return tokenPrefix(nonce, leaseID) + ownerID + "\n" + string(metadata)
+}
+
+// tokenPrefix starts the value of the lock held by the token with the
+// given nonce and lease
+func tokenPrefix(nonce, leaseID string) string {
+	return nonce + "\n" + leaseID + "\n"
+}
+
+// lockHolder is the parsed value of the key of a lock
+type lockHolder struct {
+	nonce, leaseID, ownerID string
+	metadata                []byte
+}
+
+func parseLockValue(value string) lockHolder {
+	fields := strings.SplitN(value, "\n", 4)
+	for len(fields) < 4 {
+		fields = append(fields, "")
+	}
+	holder := lockHolder{nonce: fields[0], leaseID: fields[1], ownerID: fields[2]}
+	if fields[3] != "" {
+		holder.metadata = []byte(fields[3])
+	}
+	return holder
+}
@@ -0,0 +1,66 @@
+package redis_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/oliveiracleidson/go-lockbox/redis"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisLockAdapter_Unreachable(t *testing.T) {
+	// Nothing listens on port 1
+	client := goredis.NewClient(&goredis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: time.Second})
+	adapter, err := redis.NewRedisLockAdapter(client, redis.NewRedisLockerConfig())
+	require.NoError(t, err)
+	defer adapter.Close(context.Background())
+
+	t.Run("given an unreachable server, when health check, then status is Red", func(t *testing.T) {
+		report := adapter.HealthCheck(context.Background())
+		require.Equal(t, core.StatusRed, report.Status)
+		require.Equal(t, "redis", report.Backend)
+		require.ErrorContains(t, report.Error, "health probe failed")
+		require.NotNil(t, report.Pool)
+	})
+
+	t.Run("given an unreachable server, when acquire, then fails without retrying", func(t *testing.T) {
+		_, err := adapter.Acquire(context.Background(), "key", core.LockOptions{TTL: time.Second})
+		lockErr, ok := core.AsLockError(err)
+		require.True(t, ok)
+		require.Equal(t, 1, lockErr.Attempts)
+		require.NotErrorIs(t, err, core.ErrLockContention)
+	})
+}
+
+func TestRedisLockAdapter_Closed(t *testing.T) {
+	client := goredis.NewClient(&goredis.Options{Addr: "127.0.0.1:1"})
+	closed, err := redis.NewRedisLockAdapter(client, redis.NewRedisLockerConfig())
+	require.NoError(t, err)
+	require.NoError(t, closed.Close(context.Background()))
+	require.NoError(t, closed.Close(context.Background()), "closing twice is a no-op")
+
+	ctx := context.Background()
+	token := &core.LockToken{Key: "key", LeaseID: "lease", ServerNonce: "nonce"}
+
+	t.Run("given a closed adapter, when operate, then returns ErrAdapterClosed", func(t *testing.T) {
+		_, err := closed.Acquire(ctx, "key", core.LockOptions{TTL: time.Second})
+		require.ErrorIs(t, err, core.ErrAdapterClosed)
+
+		_, err = closed.Refresh(ctx, token, time.Second)
+		require.ErrorIs(t, err, core.ErrAdapterClosed)
+
+		require.ErrorIs(t, closed.Release(ctx, token), core.ErrAdapterClosed)
+
+		_, _, err = closed.IsHeld(ctx, token)
+		require.ErrorIs(t, err, core.ErrAdapterClosed)
+	})
+
+	t.Run("given a closed adapter, when health check, then status is Red", func(t *testing.T) {
+		report := closed.HealthCheck(ctx)
+		require.Equal(t, core.StatusRed, report.Status)
+		require.ErrorIs(t, report.Error, core.ErrAdapterClosed)
+	})
+}
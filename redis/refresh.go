@@ -0,0 +1,83 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Replaces the prefix of the token (ARGV[1]) with the one of the new
+// nonce (ARGV[2]) and resets the TTL to ARGV[3] milliseconds, if the key
+// still holds the lease and nonce of the token
+var refreshScript = goredis.NewScript(`
+local value = redis.call('GET', KEYS[1])
+if not value then
+	return 0
+end
+if string.sub(value, 1, #ARGV[1]) ~= ARGV[1] then
+	return -1
+end
+redis.call('SET', KEYS[1], ARGV[2] .. string.sub(value, #ARGV[1] + 1), 'PX', ARGV[3])
+return 1
+`)
+
+// Refresh extends the lock and returns a new token.
+//
+// Errors wrap:
+//
+// - core.ErrInvalidTTL: newTTL is out of range
+//
+// - core.ErrLockOwnershipMismatch: the key is held with another lease or nonce
+//
+// - core.ErrLockNotFound: there is no lock for the key, e.g. it expired
+//
+// - core.ErrOperationTimeout: the script outlasted DefaultRequestTimeout
+func (a *RedisLockAdapter) Refresh(ctx context.Context, token *core.LockToken, newTTL time.Duration) (*core.LockToken, error) {
+	if err := a.begin(); err != nil {
+		return nil, err
+	}
+	defer a.end()
+
+	fail := func(err error) (*core.LockToken, error) {
+		a.Cfg.Hooks.RefreshFailed(ctx, token, err)
+		return nil, &core.LockError{Op: core.OpRefresh, Key: token.Key, Attempts: 1, Err: err}
+	}
+
+	if err := core.ValidateTTL(newTTL, a.Cfg.maxTTL()); err != nil {
+		return fail(err)
+	}
+	storageKey, err := a.Cfg.storageKey(token.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	reqCtx, cancel := a.withRequestTimeout(ctx)
+	defer cancel()
+
+	newNonce := a.Cfg.newID()
+
+	start := time.Now()
+	result, err := refreshScript.Run(reqCtx, a.client,
+		[]string{storageKey},
+		tokenPrefix(token.ServerNonce, token.LeaseID), tokenPrefix(newNonce, token.LeaseID), newTTL.Milliseconds(),
+	).Int()
+	a.observe(start, err)
+
+	if err != nil {
+		return fail(timedOut(ctx, reqCtx, err))
+	}
+	switch result {
+	case scriptNotFound:
+		return fail(core.ErrLockNotFound)
+	case scriptMismatch:
+		return fail(core.ErrLockOwnershipMismatch)
+	}
+
+	refreshed := *token
+	refreshed.ValidUntil = start.Add(newTTL)
+	refreshed.ServerNonce = newNonce
+	refreshed.TTL = newTTL
+	return &refreshed, nil
+}
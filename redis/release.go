@@ -0,0 +1,77 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Results of the scripts
+const (
+	scriptNotFound = 0  // There is no lock for the key
+	scriptDone     = 1  // The lock was released or refreshed
+	scriptMismatch = -1 // The key is held with another lease or nonce
+)
+
+// Deletes the key if it still holds the lease and nonce of the token,
+// whose prefix is ARGV[1]
+var releaseScript = goredis.NewScript(`
+local value = redis.call('GET', KEYS[1])
+if not value then
+	return 0
+end
+if string.sub(value, 1, #ARGV[1]) ~= ARGV[1] then
+	return -1
+end
+redis.call('DEL', KEYS[1])
+return 1
+`)
+
+// Release releases the lock of the token.
+//
+// Errors wrap:
+//
+// - core.ErrLockNotFound: there is no lock for the key, e.g. it expired
+//
+// - core.ErrLockOwnershipMismatch: the key is held with another lease or nonce
+//
+// - core.ErrOperationTimeout: the script outlasted DefaultRequestTimeout
+func (a *RedisLockAdapter) Release(ctx context.Context, token *core.LockToken) error {
+	if err := a.begin(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	storageKey, err := a.Cfg.storageKey(token.Key)
+	if err != nil {
+		return err
+	}
+
+	reqCtx, cancel := a.withRequestTimeout(ctx)
+	defer cancel()
+
+	start := time.Now()
+	result, err := releaseScript.Run(reqCtx, a.client,
+		[]string{storageKey},
+		tokenPrefix(token.ServerNonce, token.LeaseID),
+	).Int()
+	a.observe(start, err)
+
+	if err == nil {
+		switch result {
+		case scriptNotFound:
+			err = core.ErrLockNotFound
+		case scriptMismatch:
+			err = core.ErrLockOwnershipMismatch
+		}
+	}
+	if err != nil {
+		err = timedOut(ctx, reqCtx, err)
+		return &core.LockError{Op: core.OpRelease, Key: token.Key, Attempts: 1, Err: err}
+	}
+
+	a.Cfg.Hooks.Released(ctx, token)
+	return nil
+}
@@ -0,0 +1,143 @@
+package sqlitelock
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+// Acquire obtains the lock of key with an UPSERT, retrying a contended
+// key, or a database found busy, with the backoff of opts.RetryStrategy,
+// like the pg adapter.
+//
+// An expired lock is taken over, setting TookOver and PreviousLeaseID.
+// ConfirmIfOwned takes over the lease of a lock held by opts.OwnerID
+// with a new nonce, keeping its metadata.
+func (a *SQLiteLockAdapter) Acquire(ctx context.Context, key string, opts core.LockOptions) (*core.LockToken, error) {
+	if err := a.begin(); err != nil {
+		return nil, err
+	}
+	defer a.end()
+
+	storageKey, err := a.Cfg.storageKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.ValidateWithMaxTTL(a.Cfg.maxTTL()); err != nil {
+		return nil, err
+	}
+	encoded, err := core.EncodeMetadata(opts)
+	if err != nil {
+		return nil, err
+	}
+	// Stored as TEXT, or NULL without metadata
+	var metadata any
+	if encoded != nil {
+		metadata = string(encoded)
+	}
+
+	leaseID := a.Cfg.newID()
+
+	// tryAcquire runs a single attempt, returning a nil token on contention
+	tryAcquire := func(ctx context.Context, _ int) (*core.LockToken, func() *core.ContentionError, error) {
+		reqCtx, cancel := context.WithTimeout(ctx, opts.RequestTimeout)
+		defer cancel()
+
+		nonce := a.Cfg.newID()
+		var validUntil int64
+		var previousLeaseID sql.NullString
+
+		start := time.Now()
+		err := a.db.QueryRowContext(reqCtx,
+			a.sql.tryAcquire,
+			storageKey, leaseID, nonce, opts.OwnerID, metadata, opts.TTL.Milliseconds(),
+		).Scan(&validUntil, &previousLeaseID)
+		a.observe(start, err)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, timedOut(ctx, reqCtx, err)
+		}
+		if err == nil {
+			return &core.LockToken{
+				Key:                 key,
+				LeaseID:             leaseID,
+				ValidUntil:          fromMillis(validUntil),
+				ServerNonce:         nonce,
+				OwnerID:             opts.OwnerID,
+				TTL:                 opts.TTL,
+				TookOver:            previousLeaseID.Valid,
+				PreviousLeaseID:     previousLeaseID.String,
+				RefreshSafetyMargin: opts.RefreshSafetyMargin,
+			}, nil, nil
+		}
+
+		if opts.ConfirmIfOwned {
+			token, err := a.confirmOwned(ctx, key, storageKey, opts)
+			if err != nil || token != nil {
+				return token, nil, err
+			}
+		}
+		return nil, func() *core.ContentionError { return a.holder(ctx, storageKey) }, nil
+	}
+
+	// A busy database is retried like a contention, without counting as one
+	retry := core.AcquireRetry{Key: key, Options: opts, Hooks: a.Cfg.Hooks, Transient: IsTransient}
+	return retry.Run(ctx, tryAcquire)
+}
+
+// confirmOwned renews the lock of the key if it is held by opts.OwnerID,
+// returning a nil token otherwise
+func (a *SQLiteLockAdapter) confirmOwned(ctx context.Context, key, storageKey string, opts core.LockOptions) (*core.LockToken, error) {
+	reqCtx, cancel := context.WithTimeout(ctx, opts.RequestTimeout)
+	defer cancel()
+
+	token := &core.LockToken{
+		Key:                 key,
+		ServerNonce:         a.Cfg.newID(),
+		OwnerID:             opts.OwnerID,
+		TTL:                 opts.TTL,
+		RefreshSafetyMargin: opts.RefreshSafetyMargin,
+	}
+	var validUntil int64
+
+	start := time.Now()
+	err := a.db.QueryRowContext(reqCtx,
+		a.sql.confirmOwned,
+		storageKey, opts.OwnerID, token.ServerNonce, opts.TTL.Milliseconds(),
+	).Scan(&token.LeaseID, &validUntil)
+	a.observe(start, err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, timedOut(ctx, reqCtx, err)
+	}
+
+	token.ValidUntil = fromMillis(validUntil)
+	return token, nil
+}
+
+// holder describes the current holder of the storage key,
+// leaving the fields it cannot read empty
+func (a *SQLiteLockAdapter) holder(ctx context.Context, storageKey string) *core.ContentionError {
+	reqCtx, cancel := a.withRequestTimeout(ctx)
+	defer cancel()
+
+	var ownerID string
+	var metadata sql.NullString
+	var validUntil int64
+
+	start := time.Now()
+	err := a.db.QueryRowContext(reqCtx, a.sql.holder, storageKey).Scan(&ownerID, &metadata, &validUntil)
+	a.observe(start, err)
+	if err != nil {
+		return &core.ContentionError{}
+	}
+	return &core.ContentionError{
+		HolderID:       ownerID,
+		HeldUntil:      fromMillis(validUntil),
+		HolderMetadata: core.DecodeMetadata([]byte(metadata.String)),
+	}
+}
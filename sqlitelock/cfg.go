@@ -0,0 +1,262 @@
+package sqlitelock
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+// HealthCheck defaults
+const (
+	DefaultLatencyThreshold = 100 * time.Millisecond
+
+	// Fraction of failed recent operations
+	DefaultErrorRateThreshold = 0.05
+)
+
+// Name of the table of the locks by default
+const DefaultTableName = "lockbox_locks"
+
+// ErrInvalidConfig is returned by Validate for an invalid configuration
+var ErrInvalidConfig = errors.New("invalid sqlite locker config")
+
+var tableNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]{0,62}$`)
+
+type SQLiteLockerConfig struct {
+	// TableName of the locks, created by NewSQLiteLockAdapter when missing.
+	// Defaults to DefaultTableName.
+	TableName string
+
+	// Namespace transparently prefixed to every key, so adapters sharing
+	// the same table with different namespaces never collide. Segments
+	// are separated by core.KeySeparator.
+	Namespace string
+
+	Hooks core.Hooks
+
+	// MaxAllowedTTL raises (or lowers) the TTL ceiling of the adapter.
+	// Defaults to core.MaxLockTTL.
+	MaxAllowedTTL time.Duration
+
+	// DefaultRequestTimeout bounds the statements of Release, Refresh and
+	// IsHeld, which have no LockOptions carrying a RequestTimeout. They
+	// fail with core.ErrOperationTimeout once it fires.
+	// Defaults to core.DefaultRequestTimeout.
+	DefaultRequestTimeout time.Duration
+
+	// RefreshSafetyMargin is the fraction of the new TTL during which an
+	// expired lock can still be refreshed, as long as nobody took it
+	// over. Must be [0, core.MaxRefreshMargin]; overridden per lock by
+	// core.LockOptions.RefreshSafetyMargin.
+	// Defaults to core.MaxClockDriftMargin when nil.
+	RefreshSafetyMargin *float64
+
+	// HealthCheck reports StatusYellow when the probe takes longer than
+	// LatencyThreshold or the fraction (0.0-1.0) of the recent operations
+	// whose statement failed exceeds ErrorRateThreshold
+	LatencyThreshold   time.Duration
+	ErrorRateThreshold float64
+
+	// IDGenerator produces the LeaseID and ServerNonce of the tokens.
+	// Defaults to core.UUIDGenerator.
+	IDGenerator core.IDGenerator
+}
+
+// NewSQLiteLockerConfig creates a new instance of SQLiteLockerConfig
+// with default values
+func NewSQLiteLockerConfig() *SQLiteLockerConfig {
+	s := &SQLiteLockerConfig{}
+	return s.WithDefaults()
+}
+
+func (s *SQLiteLockerConfig) Validate() error {
+	msgs := []string{}
+	if !tableNameRegex.MatchString(s.TableName) {
+		msgs = append(msgs, "TableName must be a valid identifier of up to 63 characters")
+	}
+	if s.Namespace != "" {
+		if err := core.ValidateNamespace(s.Namespace); err != nil {
+			msgs = append(msgs, "Namespace must be [a-zA-Z0-9_-] segments separated by ':'")
+		}
+	}
+	if s.MaxAllowedTTL != 0 && s.MaxAllowedTTL < core.MinLockTTL {
+		msgs = append(msgs, fmt.Sprintf("MaxAllowedTTL must be ≥ %v", core.MinLockTTL))
+	}
+	if s.DefaultRequestTimeout < 0 {
+		msgs = append(msgs, "DefaultRequestTimeout must be ≥ 0")
+	}
+	if m := s.RefreshSafetyMargin; m != nil && (*m < 0 || *m > core.MaxRefreshMargin) {
+		msgs = append(msgs, fmt.Sprintf("RefreshSafetyMargin must be [0, %v]", core.MaxRefreshMargin))
+	}
+	if s.LatencyThreshold < 0 {
+		msgs = append(msgs, "LatencyThreshold must be ≥ 0")
+	}
+	if s.ErrorRateThreshold < 0 || s.ErrorRateThreshold > 1 {
+		msgs = append(msgs, "ErrorRateThreshold must be [0.0, 1.0]")
+	}
+
+	if len(msgs) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidConfig, strings.Join(msgs, ", "))
+	}
+	return nil
+}
+
+// WithDefaults sets default values for the zero-valued fields.
+//
+// Returns the same instance
+// Defaults:
+//
+// - TableName: DefaultTableName
+//
+// - MaxAllowedTTL: core.MaxLockTTL
+//
+// - DefaultRequestTimeout: core.DefaultRequestTimeout
+//
+// - RefreshSafetyMargin: core.MaxClockDriftMargin
+//
+// - LatencyThreshold: 100ms
+//
+// - ErrorRateThreshold: 0.05
+//
+// - IDGenerator: core.UUIDGenerator
+func (s *SQLiteLockerConfig) WithDefaults() *SQLiteLockerConfig {
+	if s.TableName == "" {
+		s.TableName = DefaultTableName
+	}
+	if s.MaxAllowedTTL == 0 {
+		s.MaxAllowedTTL = core.MaxLockTTL
+	}
+	if s.DefaultRequestTimeout == 0 {
+		s.DefaultRequestTimeout = core.DefaultRequestTimeout
+	}
+	if s.RefreshSafetyMargin == nil {
+		margin := core.MaxClockDriftMargin
+		s.RefreshSafetyMargin = &margin
+	}
+	if s.LatencyThreshold == 0 {
+		s.LatencyThreshold = DefaultLatencyThreshold
+	}
+	if s.ErrorRateThreshold == 0 {
+		s.ErrorRateThreshold = DefaultErrorRateThreshold
+	}
+	if s.IDGenerator == nil {
+		s.IDGenerator = core.UUIDGenerator{}
+	}
+	return s
+}
+
+// maxTTL returns the TTL ceiling of the adapter
+func (s *SQLiteLockerConfig) maxTTL() time.Duration {
+	if s.MaxAllowedTTL == 0 {
+		return core.MaxLockTTL
+	}
+	return s.MaxAllowedTTL
+}
+
+// requestTimeout returns the bound of the statements without LockOptions
+func (s *SQLiteLockerConfig) requestTimeout() time.Duration {
+	if s.DefaultRequestTimeout == 0 {
+		return core.DefaultRequestTimeout
+	}
+	return s.DefaultRequestTimeout
+}
+
+// storageKey returns the key of the lock of key in the table, prefixed
+// by the namespace
+func (s *SQLiteLockerConfig) storageKey(key string) (string, error) {
+	return core.NamespaceKey(s.Namespace, key)
+}
+
+// newID returns a LeaseID or ServerNonce from the IDGenerator,
+// falling back to a UUID for configurations built without WithDefaults
+func (s *SQLiteLockerConfig) newID() string {
+	if s.IDGenerator == nil {
+		return core.UUIDGenerator{}.NewID()
+	}
+	return s.IDGenerator.NewID()
+}
+
+// SetTableName sets the TableName field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (s *SQLiteLockerConfig) SetTableName(v string) *SQLiteLockerConfig {
+	s.TableName = v
+	return s
+}
+
+// SetNamespace sets the Namespace field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (s *SQLiteLockerConfig) SetNamespace(v string) *SQLiteLockerConfig {
+	s.Namespace = v
+	return s
+}
+
+// SetHooks sets the Hooks field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (s *SQLiteLockerConfig) SetHooks(v core.Hooks) *SQLiteLockerConfig {
+	s.Hooks = v
+	return s
+}
+
+// SetMaxAllowedTTL sets the MaxAllowedTTL field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (s *SQLiteLockerConfig) SetMaxAllowedTTL(v time.Duration) *SQLiteLockerConfig {
+	s.MaxAllowedTTL = v
+	return s
+}
+
+// SetDefaultRequestTimeout sets the DefaultRequestTimeout field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (s *SQLiteLockerConfig) SetDefaultRequestTimeout(v time.Duration) *SQLiteLockerConfig {
+	s.DefaultRequestTimeout = v
+	return s
+}
+
+// SetRefreshSafetyMargin sets the RefreshSafetyMargin field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (s *SQLiteLockerConfig) SetRefreshSafetyMargin(v float64) *SQLiteLockerConfig {
+	s.RefreshSafetyMargin = &v
+	return s
+}
+
+// SetLatencyThreshold sets the LatencyThreshold field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (s *SQLiteLockerConfig) SetLatencyThreshold(v time.Duration) *SQLiteLockerConfig {
+	s.LatencyThreshold = v
+	return s
+}
+
+// SetErrorRateThreshold sets the ErrorRateThreshold field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (s *SQLiteLockerConfig) SetErrorRateThreshold(v float64) *SQLiteLockerConfig {
+	s.ErrorRateThreshold = v
+	return s
+}
+
+// SetIDGenerator sets the IDGenerator field.
+//
+// This method exists to allow functional options to set the field
+// in fluent style.
+func (s *SQLiteLockerConfig) SetIDGenerator(v core.IDGenerator) *SQLiteLockerConfig {
+	s.IDGenerator = v
+	return s
+}
@@ -0,0 +1,66 @@
+package sqlitelock_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/oliveiracleidson/go-lockbox/sqlitelock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSQLiteLockerConfig_WithDefaults(t *testing.T) {
+	config := sqlitelock.NewSQLiteLockerConfig()
+
+	assert.Equal(t, sqlitelock.DefaultTableName, config.TableName)
+	assert.Equal(t, core.MaxLockTTL, config.MaxAllowedTTL)
+	assert.Equal(t, core.DefaultRequestTimeout, config.DefaultRequestTimeout)
+	assert.Equal(t, core.MaxClockDriftMargin, *config.RefreshSafetyMargin)
+	assert.Equal(t, sqlitelock.DefaultLatencyThreshold, config.LatencyThreshold)
+	assert.Equal(t, sqlitelock.DefaultErrorRateThreshold, config.ErrorRateThreshold)
+	assert.Equal(t, core.UUIDGenerator{}, config.IDGenerator)
+	assert.NoError(t, config.Validate())
+}
+
+func TestSQLiteLockerConfig_WithDefaults_RefreshSafetyMargin(t *testing.T) {
+	assert.Equal(t, core.MaxClockDriftMargin, *(&sqlitelock.SQLiteLockerConfig{}).WithDefaults().RefreshSafetyMargin)
+
+	// An explicit 0 refuses any late refresh, it is not defaulted
+	zero := 0.0
+	assert.Zero(t, *(&sqlitelock.SQLiteLockerConfig{RefreshSafetyMargin: &zero}).WithDefaults().RefreshSafetyMargin)
+}
+
+func TestSQLiteLockerConfig_Validate(t *testing.T) {
+	config := sqlitelock.NewSQLiteLockerConfig().
+		SetTableName(`locks"; DROP TABLE x; --`).
+		SetNamespace("team a").
+		SetMaxAllowedTTL(time.Microsecond).
+		SetDefaultRequestTimeout(-time.Second).
+		SetRefreshSafetyMargin(0.9).
+		SetLatencyThreshold(-time.Second).
+		SetErrorRateThreshold(-0.1)
+
+	err := config.Validate()
+	require.ErrorIs(t, err, sqlitelock.ErrInvalidConfig)
+	assert.Contains(t, err.Error(), "TableName must be")
+	assert.Contains(t, err.Error(), "Namespace must be")
+	assert.Contains(t, err.Error(), "MaxAllowedTTL must be")
+	assert.Contains(t, err.Error(), "DefaultRequestTimeout must be ≥ 0")
+	assert.Contains(t, err.Error(), "RefreshSafetyMargin must be [0, 0.5]")
+	assert.Contains(t, err.Error(), "LatencyThreshold must be ≥ 0")
+	assert.Contains(t, err.Error(), "ErrorRateThreshold must be [0.0, 1.0]")
+}
+
+func TestNewSQLiteLockAdapter_InvalidConfig(t *testing.T) {
+	db, err := sqlitelock.OpenDB(t.TempDir() + "/locks.db")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = sqlitelock.NewSQLiteLockAdapter(context.Background(), db, sqlitelock.NewSQLiteLockerConfig().SetTableName("1locks"))
+	require.ErrorIs(t, err, sqlitelock.ErrInvalidConfig)
+
+	_, err = sqlitelock.NewSQLiteLockAdapter(context.Background(), nil, sqlitelock.NewSQLiteLockerConfig())
+	require.Error(t, err)
+}
@@ -0,0 +1,85 @@
+package sqlitelock
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+// HealthCheck monitors service health.
+// Latency is the average latency and Throughput the operations per second
+// of the recent lock operations; the probe counts the locks held, reported
+// in Details["locks"] with its latency in Details["probe_latency"], and
+// the state of the connections of the database is reported in Pool.
+//
+// The status is Red when the probe fails or the adapter is closed, and
+// Yellow when the probe takes longer than LatencyThreshold or the
+// fraction of the recent operations that failed exceeds
+// ErrorRateThreshold.
+func (a *SQLiteLockAdapter) HealthCheck(ctx context.Context) core.HealthReport {
+	if err := a.begin(); err != nil {
+		return core.HealthReport{Status: core.StatusRed, Error: err, Backend: "sqlite"}
+	}
+	defer a.end()
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var locks int64
+	start := time.Now()
+	err := a.db.QueryRowContext(ctx, a.sql.countHeld).Scan(&locks)
+	latency := time.Since(start)
+
+	errorRate := a.latencies.ErrorRate()
+
+	status, reportErr := core.StatusGreen, error(nil)
+	switch {
+	case err != nil:
+		status, reportErr = core.StatusRed, fmt.Errorf("health probe failed: %w", err)
+	case latency > a.Cfg.LatencyThreshold:
+		status, reportErr = core.StatusYellow, fmt.Errorf("high latency: %v > %v", latency, a.Cfg.LatencyThreshold)
+	case errorRate > a.Cfg.ErrorRateThreshold:
+		status, reportErr = core.StatusYellow, fmt.Errorf("high error rate: %.2f > %.2f", errorRate, a.Cfg.ErrorRateThreshold)
+	}
+
+	percentiles := a.latencies.Percentiles(50, 95, 99)
+
+	return core.HealthReport{
+		Status:     status,
+		Latency:    a.latencies.Average(),
+		Throughput: a.latencies.Throughput(time.Now(), core.DefaultThroughputWindow),
+		ErrorRate:  errorRate,
+		Error:      reportErr,
+		LatencyP50: percentiles[0],
+		LatencyP95: percentiles[1],
+		LatencyP99: percentiles[2],
+		Operations: a.latencies.Total(),
+		Uptime:     time.Since(a.startedAt),
+		Backend:    "sqlite",
+		Pool:       a.poolStats(),
+		Details: map[string]string{
+			"probe_latency": latency.String(),
+			"table":         a.Cfg.TableName,
+			"locks":         strconv.FormatInt(locks, 10),
+		},
+	}
+}
+
+// poolStats returns the usage of the connections of the database;
+// MaxConns is 0 when database/sql does not limit them
+func (a *SQLiteLockAdapter) poolStats() *core.PoolStats {
+	s := a.db.Stats()
+	stats := &core.PoolStats{
+		TotalConns:    int32(s.OpenConnections),
+		AcquiredConns: int32(s.InUse),
+		IdleConns:     int32(s.Idle),
+		MaxConns:      int32(s.MaxOpenConnections),
+	}
+	if stats.MaxConns > 0 {
+		stats.Usage = float64(stats.AcquiredConns) / float64(stats.MaxConns)
+	}
+	return stats
+}
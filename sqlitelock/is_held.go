@@ -0,0 +1,48 @@
+package sqlitelock
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+// IsHeld reports whether the token still owns its lock and the remaining
+// TTL. A lock that expired is not held, even before being taken over.
+// The statement fails with core.ErrOperationTimeout after
+// DefaultRequestTimeout.
+func (a *SQLiteLockAdapter) IsHeld(ctx context.Context, token *core.LockToken) (bool, time.Duration, error) {
+	if err := a.begin(); err != nil {
+		return false, 0, err
+	}
+	defer a.end()
+
+	storageKey, err := a.Cfg.storageKey(token.Key)
+	if err != nil {
+		return false, 0, err
+	}
+
+	reqCtx, cancel := a.withRequestTimeout(ctx)
+	defer cancel()
+
+	var remainingMillis int64
+	start := time.Now()
+	err = a.db.QueryRowContext(reqCtx,
+		a.sql.isHeld,
+		storageKey, token.LeaseID, token.ServerNonce,
+	).Scan(&remainingMillis)
+	a.observe(start, err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, 0, nil
+	}
+	if err != nil {
+		return false, 0, timedOut(ctx, reqCtx, err)
+	}
+
+	if remainingMillis <= 0 {
+		return false, 0, nil
+	}
+	return true, time.Duration(remainingMillis) * time.Millisecond, nil
+}
@@ -0,0 +1,54 @@
+package sqlitelock
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+func init() {
+	core.Register("sqlite", open)
+}
+
+// DefaultBusyTimeout is the busy_timeout, in milliseconds, of the
+// connections of OpenDB: how long a statement waits for the writer of
+// another connection or process before failing with SQLITE_BUSY
+const DefaultBusyTimeout = 1000
+
+// OpenDB opens the SQLite database file at path, created when missing,
+// for an adapter shared by the goroutines and processes of a host: in
+// WAL mode, so readers don't block the writer, with DefaultBusyTimeout
+func OpenDB(path string) (*sql.DB, error) {
+	query := url.Values{}
+	query.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", DefaultBusyTimeout))
+	query.Add("_pragma", "journal_mode(WAL)")
+	query.Add("_pragma", "synchronous(NORMAL)")
+	return sql.Open("sqlite", "file:"+path+"?"+query.Encode())
+}
+
+// open creates a SQLiteLockAdapter with its own database from a DSN for
+// core.Open: sqlite:///absolute/path.db or sqlite:relative/path.db
+func open(ctx context.Context, dsn *url.URL, openCfg core.OpenConfig) (core.LockAdapter, error) {
+	path := dsn.Path
+	if dsn.Opaque != "" {
+		path = dsn.Opaque
+	}
+	if path == "" {
+		return nil, errors.New("sqlite DSN requires a database path")
+	}
+
+	db, err := OpenDB(path)
+	if err != nil {
+		return nil, err
+	}
+	adapter, err := NewSQLiteLockAdapter(ctx, db, NewSQLiteLockerConfig().SetHooks(openCfg.Hooks))
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return adapter, nil
+}
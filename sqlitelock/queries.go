@@ -0,0 +1,113 @@
+package sqlitelock
+
+import "fmt"
+
+// nowMillis is the current time of SQLite in Unix milliseconds. 'now' is
+// the same for every use within a statement.
+const nowMillis = `CAST(ROUND((julianday('now') - 2440587.5) * 86400000.0) AS INTEGER)`
+
+var (
+	// valid_until is in Unix milliseconds; previous_lease_id is the lease
+	// of the expired lock the last acquisition of the row took over
+	createTableSQL = `
+	CREATE TABLE IF NOT EXISTS "%[1]s" (
+		key TEXT PRIMARY KEY,
+		lease_id TEXT NOT NULL,
+		server_nonce TEXT NOT NULL,
+		owner_id TEXT NOT NULL,
+		metadata TEXT,
+		valid_until INTEGER NOT NULL,
+		previous_lease_id TEXT
+	) WITHOUT ROWID;`
+
+	// Inserts the lock, or takes over the row of an expired one. Nothing
+	// is returned while the key is held.
+	tryAcquireSQL = `
+	INSERT INTO "%[1]s" (key, lease_id, server_nonce, owner_id, metadata, valid_until)
+	VALUES (?1, ?2, ?3, ?4, ?5, %[2]s + ?6)
+	ON CONFLICT (key) DO UPDATE SET
+		lease_id = excluded.lease_id,
+		server_nonce = excluded.server_nonce,
+		owner_id = excluded.owner_id,
+		metadata = excluded.metadata,
+		valid_until = excluded.valid_until,
+		previous_lease_id = "%[1]s".lease_id
+	WHERE "%[1]s".valid_until <= %[2]s
+	RETURNING valid_until, previous_lease_id;`
+
+	// Renews the lock of the key if held by the owner ?2
+	confirmOwnedSQL = `
+	UPDATE "%[1]s"
+	SET server_nonce = ?3, valid_until = %[2]s + ?4
+	WHERE key = ?1 AND owner_id = ?2 AND valid_until > %[2]s
+	RETURNING lease_id, valid_until;`
+
+	holderSQL = `
+	SELECT owner_id, metadata, valid_until
+	FROM "%[1]s"
+	WHERE key = ?1 AND valid_until > %[2]s;`
+
+	releaseSQL = `
+	DELETE FROM "%[1]s"
+	WHERE key = ?1 AND lease_id = ?2 AND server_nonce = ?3;`
+
+	// The lock can be refreshed until a safety margin (?6 of the new TTL)
+	// after its expiration, as long as nobody took it over
+	refreshSQL = `
+	UPDATE "%[1]s"
+	SET server_nonce = ?4, valid_until = %[2]s + ?5
+	WHERE
+		key = ?1 AND
+		lease_id = ?2 AND
+		server_nonce = ?3 AND
+		valid_until > %[2]s - CAST(?5 * ?6 AS INTEGER)
+	RETURNING valid_until;`
+
+	// Tells why a release or refresh changed nothing: no row when there
+	// is no lock, else whether the token still owns it
+	ownedSQL = `
+	SELECT lease_id = ?2 AND server_nonce = ?3
+	FROM "%[1]s"
+	WHERE key = ?1;`
+
+	isHeldSQL = `
+	SELECT valid_until - %[2]s
+	FROM "%[1]s"
+	WHERE key = ?1 AND lease_id = ?2 AND server_nonce = ?3;`
+
+	countHeldSQL = `
+	SELECT COUNT(*)
+	FROM "%[1]s"
+	WHERE valid_until > %[2]s;`
+)
+
+// queries holds the SQL of the adapter operations with the table name
+// already injected, rendered once by NewSQLiteLockAdapter
+type queries struct {
+	createTable  string
+	tryAcquire   string
+	confirmOwned string
+	holder       string
+	release      string
+	refresh      string
+	owned        string
+	isHeld       string
+	countHeld    string
+}
+
+func newQueries(table string) queries {
+	render := func(query string) string {
+		return fmt.Sprintf(query, table, nowMillis)
+	}
+	return queries{
+		createTable:  fmt.Sprintf(createTableSQL, table),
+		tryAcquire:   render(tryAcquireSQL),
+		confirmOwned: render(confirmOwnedSQL),
+		holder:       render(holderSQL),
+		release:      render(releaseSQL),
+		refresh:      render(refreshSQL),
+		owned:        render(ownedSQL),
+		isHeld:       render(isHeldSQL),
+		countHeld:    render(countHeldSQL),
+	}
+}
@@ -0,0 +1,92 @@
+package sqlitelock
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+// Refresh extends the lock and returns a new token. An expired lock can
+// still be refreshed during the safety margin (RefreshSafetyMargin of
+// newTTL), as long as nobody took it over.
+//
+// Errors wrap:
+//
+// - core.ErrInvalidTTL: newTTL is out of range
+//
+// - core.ErrRefreshTooLate: the lock expired beyond the safety margin
+//
+// - core.ErrLockOwnershipMismatch: the key is held with another lease or nonce
+//
+// - core.ErrLockNotFound: there is no lock for the key
+//
+// - core.ErrOperationTimeout: the statement outlasted DefaultRequestTimeout
+func (a *SQLiteLockAdapter) Refresh(ctx context.Context, token *core.LockToken, newTTL time.Duration) (*core.LockToken, error) {
+	if err := a.begin(); err != nil {
+		return nil, err
+	}
+	defer a.end()
+
+	fail := func(err error) (*core.LockToken, error) {
+		a.Cfg.Hooks.RefreshFailed(ctx, token, err)
+		return nil, &core.LockError{Op: core.OpRefresh, Key: token.Key, Attempts: 1, Err: err}
+	}
+
+	if err := core.ValidateTTL(newTTL, a.Cfg.maxTTL()); err != nil {
+		return fail(err)
+	}
+	storageKey, err := a.Cfg.storageKey(token.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	reqCtx, cancel := a.withRequestTimeout(ctx)
+	defer cancel()
+
+	newNonce := a.Cfg.newID()
+	var validUntil int64
+
+	start := time.Now()
+	err = a.db.QueryRowContext(reqCtx,
+		a.sql.refresh,
+		storageKey, token.LeaseID, token.ServerNonce, newNonce, newTTL.Milliseconds(), a.refreshSafetyMargin(token),
+	).Scan(&validUntil)
+	a.observe(start, err)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		owned, err := a.owned(reqCtx, storageKey, token)
+		switch {
+		case err != nil:
+			return fail(timedOut(ctx, reqCtx, err))
+		case owned:
+			return fail(core.ErrRefreshTooLate)
+		default:
+			return fail(core.ErrLockOwnershipMismatch)
+		}
+	}
+	if err != nil {
+		return fail(timedOut(ctx, reqCtx, err))
+	}
+
+	refreshed := *token
+	refreshed.ValidUntil = fromMillis(validUntil)
+	refreshed.ServerNonce = newNonce
+	refreshed.TTL = newTTL
+	return &refreshed, nil
+}
+
+// refreshSafetyMargin returns the margin of the token, or the one of the
+// config when the acquisition didn't override it, falling back to
+// core.MaxClockDriftMargin for configurations built without WithDefaults
+func (a *SQLiteLockAdapter) refreshSafetyMargin(token *core.LockToken) float64 {
+	if token.RefreshSafetyMargin != nil {
+		return *token.RefreshSafetyMargin
+	}
+	if a.Cfg.RefreshSafetyMargin != nil {
+		return *a.Cfg.RefreshSafetyMargin
+	}
+	return core.MaxClockDriftMargin
+}
@@ -0,0 +1,74 @@
+package sqlitelock
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+)
+
+// Release releases the lock of the token, even once expired as long as
+// nobody took it over.
+//
+// Errors wrap:
+//
+// - core.ErrLockNotFound: there is no lock for the key
+//
+// - core.ErrLockOwnershipMismatch: the key is held with another lease or nonce
+//
+// - core.ErrOperationTimeout: the statement outlasted DefaultRequestTimeout
+func (a *SQLiteLockAdapter) Release(ctx context.Context, token *core.LockToken) error {
+	if err := a.begin(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	storageKey, err := a.Cfg.storageKey(token.Key)
+	if err != nil {
+		return err
+	}
+
+	reqCtx, cancel := a.withRequestTimeout(ctx)
+	defer cancel()
+
+	fail := func(err error) error {
+		return &core.LockError{Op: core.OpRelease, Key: token.Key, Attempts: 1, Err: err}
+	}
+
+	start := time.Now()
+	result, err := a.db.ExecContext(reqCtx, a.sql.release, storageKey, token.LeaseID, token.ServerNonce)
+	var deleted int64
+	if err == nil {
+		deleted, err = result.RowsAffected()
+	}
+	a.observe(start, err)
+	if err != nil {
+		return fail(timedOut(ctx, reqCtx, err))
+	}
+
+	if deleted == 0 {
+		_, err := a.owned(reqCtx, storageKey, token)
+		if err == nil {
+			err = core.ErrLockOwnershipMismatch
+		}
+		return fail(timedOut(ctx, reqCtx, err))
+	}
+
+	a.Cfg.Hooks.Released(ctx, token)
+	return nil
+}
+
+// owned reports whether the lock of the storage key still belongs to the
+// token, failing with core.ErrLockNotFound when there is none
+func (a *SQLiteLockAdapter) owned(ctx context.Context, storageKey string, token *core.LockToken) (bool, error) {
+	var owned bool
+	start := time.Now()
+	err := a.db.QueryRowContext(ctx, a.sql.owned, storageKey, token.LeaseID, token.ServerNonce).Scan(&owned)
+	a.observe(start, err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, core.ErrLockNotFound
+	}
+	return owned, err
+}
@@ -0,0 +1,160 @@
+// Package sqlitelock implements core.LockAdapter on a SQLite database,
+// for single binary and edge deployments sharing locks between the
+// goroutines and processes of a host through a file.
+//
+// It is a module of its own, so the SQLite driver (modernc.org/sqlite,
+// pure Go) is only pulled by the services importing it:
+//
+//	db, err := sqlitelock.OpenDB("/var/lib/agent/locks.db")
+//	adapter, err := sqlitelock.NewSQLiteLockAdapter(ctx, db, sqlitelock.NewSQLiteLockerConfig())
+//
+// A lock is a row of the table, acquired with an UPSERT taking over the
+// row only once expired. The expirations are stored as Unix milliseconds
+// computed from julianday('now') by SQLite itself, so every process
+// compares them against the same clock without losing the millisecond
+// precision of the TTL.
+//
+// SQLite serializes the writers of a file: a statement finding the
+// database busy waits for the busy_timeout of its connection, then fails
+// with SQLITE_BUSY, which Acquire retries within its RetryStrategy, see
+// IsTransient.
+package sqlitelock
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	_ "modernc.org/sqlite" // registers the "sqlite" driver
+)
+
+type SQLiteLockAdapter struct {
+	db *sql.DB
+
+	// Read only once the adapter is created
+	Cfg *SQLiteLockerConfig
+	sql queries
+
+	// Reported by HealthCheck
+	startedAt time.Time
+	latencies *core.LatencyWindow
+
+	// Operations in flight, awaited by Close
+	closeMu  sync.RWMutex
+	closed   atomic.Bool
+	inFlight sync.WaitGroup
+}
+
+// NewSQLiteLockAdapter creates an adapter storing its locks in db,
+// creating the table of the locks when missing
+func NewSQLiteLockAdapter(ctx context.Context, db *sql.DB, cfg *SQLiteLockerConfig) (*SQLiteLockAdapter, error) {
+	if db == nil {
+		return nil, errors.New("sqlite database is required")
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	adapter := &SQLiteLockAdapter{
+		db:        db,
+		Cfg:       cfg,
+		sql:       newQueries(cfg.TableName),
+		startedAt: time.Now(),
+		latencies: core.NewLatencyWindow(core.DefaultLatencyWindowSize),
+	}
+	if _, err := db.ExecContext(ctx, adapter.sql.createTable); err != nil {
+		return nil, fmt.Errorf("failed to create table %q: %w", cfg.TableName, err)
+	}
+	return adapter, nil
+}
+
+// Close stops accepting operations, waits for the ones in flight,
+// checkpoints the WAL into the database file and closes the database.
+//
+// Operations started after Close return core.ErrAdapterClosed. If ctx
+// expires first, the database is closed anyway and the ctx error is
+// returned. Closing twice is a no-op.
+func (a *SQLiteLockAdapter) Close(ctx context.Context) error {
+	a.closeMu.Lock()
+	alreadyClosed := a.closed.Swap(true)
+	a.closeMu.Unlock()
+	if alreadyClosed {
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		a.inFlight.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+		// A no-op unless the database is in WAL mode
+		if _, checkpointErr := a.db.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)"); checkpointErr != nil {
+			err = fmt.Errorf("failed to checkpoint the WAL: %w", checkpointErr)
+		}
+	case <-ctx.Done():
+		err = fmt.Errorf("operations still in flight: %w", ctx.Err())
+	}
+
+	if closeErr := a.db.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// begin registers an operation in flight, failing once the adapter is
+// closed. Every successful begin must be paired with an end.
+func (a *SQLiteLockAdapter) begin() error {
+	a.closeMu.RLock()
+	defer a.closeMu.RUnlock()
+	if a.closed.Load() {
+		return core.ErrAdapterClosed
+	}
+	a.inFlight.Add(1)
+	return nil
+}
+
+// end marks an operation started by begin as finished
+func (a *SQLiteLockAdapter) end() {
+	a.inFlight.Done()
+}
+
+// withRequestTimeout bounds a statement without LockOptions by the
+// DefaultRequestTimeout of the config
+func (a *SQLiteLockAdapter) withRequestTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, a.Cfg.requestTimeout())
+}
+
+// timedOut wraps with core.ErrOperationTimeout the error of a statement
+// cut by the timeout of reqCtx. The error of a ctx done beforehand is
+// returned as is.
+func timedOut(ctx, reqCtx context.Context, err error) error {
+	if err == nil || ctx.Err() != nil || !errors.Is(reqCtx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", core.ErrOperationTimeout, err)
+}
+
+// observe records the latency of an operation started at start, and
+// whether its statement failed. Finding no row and a ctx cancelled by
+// the caller are not failures.
+func (a *SQLiteLockAdapter) observe(start time.Time, err error) {
+	if err == nil || errors.Is(err, sql.ErrNoRows) || errors.Is(err, context.Canceled) {
+		a.latencies.Record(time.Since(start))
+		return
+	}
+	a.latencies.RecordFailure(time.Since(start))
+}
+
+// fromMillis converts the Unix milliseconds of the table into a time
+func fromMillis(ms int64) time.Time {
+	return time.UnixMilli(ms)
+}
@@ -0,0 +1,246 @@
+package sqlitelock_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/oliveiracleidson/go-lockbox/core"
+	"github.com/oliveiracleidson/go-lockbox/core/locktest"
+	"github.com/oliveiracleidson/go-lockbox/sqlitelock"
+	"github.com/stretchr/testify/require"
+)
+
+// newAdapter returns an adapter on a database file of its own
+func newAdapter(t *testing.T, cfg *sqlitelock.SQLiteLockerConfig) (*sqlitelock.SQLiteLockAdapter, string) {
+	path := filepath.Join(t.TempDir(), "locks.db")
+	return openAdapter(t, path, cfg), path
+}
+
+// openAdapter returns an adapter on the database file at path, like
+// another process sharing it would
+func openAdapter(t *testing.T, path string, cfg *sqlitelock.SQLiteLockerConfig) *sqlitelock.SQLiteLockAdapter {
+	db, err := sqlitelock.OpenDB(path)
+	require.NoError(t, err)
+
+	adapter, err := sqlitelock.NewSQLiteLockAdapter(context.Background(), db, cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { adapter.Close(context.Background()) })
+	return adapter
+}
+
+func TestSQLiteLockAdapter_Contract(t *testing.T) {
+	adapter, _ := newAdapter(t, sqlitelock.NewSQLiteLockerConfig())
+	locktest.Run(t, adapter, "contract-sqlite")
+}
+
+func TestSQLiteLockAdapter_Integration(t *testing.T) {
+	ctx := context.Background()
+	opts := core.LockOptions{
+		TTL:            10 * time.Second,
+		RetryStrategy:  core.NoRetry(),
+		RequestTimeout: 5 * time.Second,
+		OwnerID:        "worker-1",
+		Metadata:       map[string]string{"job": "reindex"},
+	}
+
+	t.Run("given a TTL in milliseconds, when acquire, then the lock expires with millisecond precision", func(t *testing.T) {
+		adapter, _ := newAdapter(t, sqlitelock.NewSQLiteLockerConfig())
+
+		short := opts
+		short.TTL = 150 * time.Millisecond
+		start := time.Now()
+		token, err := adapter.Acquire(ctx, "precise", short)
+		require.NoError(t, err)
+		require.WithinDuration(t, start.Add(short.TTL), token.ValidUntil, 20*time.Millisecond)
+
+		held, remaining, err := adapter.IsHeld(ctx, token)
+		require.NoError(t, err)
+		require.True(t, held)
+		require.LessOrEqual(t, remaining, short.TTL)
+		require.Greater(t, remaining, 100*time.Millisecond)
+
+		time.Sleep(short.TTL)
+		held, _, err = adapter.IsHeld(ctx, token)
+		require.NoError(t, err)
+		require.False(t, held)
+	})
+
+	t.Run("given an expired lock, when acquire, then takes it over", func(t *testing.T) {
+		adapter, _ := newAdapter(t, sqlitelock.NewSQLiteLockerConfig())
+
+		short := opts
+		short.TTL = 50 * time.Millisecond
+		previous, err := adapter.Acquire(ctx, "taken-over", short)
+		require.NoError(t, err)
+		require.False(t, previous.TookOver)
+		time.Sleep(short.TTL)
+
+		token, err := adapter.Acquire(ctx, "taken-over", opts)
+		require.NoError(t, err)
+		require.True(t, token.TookOver)
+		require.Equal(t, previous.LeaseID, token.PreviousLeaseID)
+
+		_, err = adapter.Refresh(ctx, previous, time.Second)
+		require.ErrorIs(t, err, core.ErrLockOwnershipMismatch)
+		require.ErrorIs(t, adapter.Release(ctx, previous), core.ErrLockOwnershipMismatch)
+		require.NoError(t, adapter.Release(ctx, token))
+	})
+
+	t.Run("given an expired lock, when refresh within the safety margin, then extends it", func(t *testing.T) {
+		adapter, _ := newAdapter(t, sqlitelock.NewSQLiteLockerConfig())
+
+		short := opts
+		short.TTL = 50 * time.Millisecond
+		token, err := adapter.Acquire(ctx, "late", short)
+		require.NoError(t, err)
+		time.Sleep(short.TTL + 10*time.Millisecond)
+
+		// 15% of 10s is still ahead
+		refreshed, err := adapter.Refresh(ctx, token, 10*time.Second)
+		require.NoError(t, err)
+		require.NotEqual(t, token.ServerNonce, refreshed.ServerNonce)
+	})
+
+	t.Run("given a lock expired beyond the safety margin, when refresh, then fails with ErrRefreshTooLate", func(t *testing.T) {
+		adapter, _ := newAdapter(t, sqlitelock.NewSQLiteLockerConfig())
+
+		short := opts
+		short.TTL = 50 * time.Millisecond
+		token, err := adapter.Acquire(ctx, "too-late", short)
+		require.NoError(t, err)
+		time.Sleep(200 * time.Millisecond)
+
+		_, err = adapter.Refresh(ctx, token, short.TTL)
+		require.ErrorIs(t, err, core.ErrRefreshTooLate)
+	})
+
+	t.Run("given a held key, when acquire, then the contention error describes the holder", func(t *testing.T) {
+		adapter, _ := newAdapter(t, sqlitelock.NewSQLiteLockerConfig())
+		token, err := adapter.Acquire(ctx, "described", opts)
+		require.NoError(t, err)
+
+		other := opts
+		other.OwnerID = "worker-2"
+		_, err = adapter.Acquire(ctx, "described", other)
+		var contentionErr *core.ContentionError
+		require.ErrorAs(t, err, &contentionErr)
+		require.Equal(t, "worker-1", contentionErr.HolderID)
+		require.Equal(t, map[string]string{"job": "reindex"}, contentionErr.HolderMetadata)
+		require.Equal(t, token.ValidUntil, contentionErr.HeldUntil)
+	})
+
+	t.Run("given a key held by the same owner, when acquire with ConfirmIfOwned, then takes over its lease", func(t *testing.T) {
+		adapter, _ := newAdapter(t, sqlitelock.NewSQLiteLockerConfig())
+		token, err := adapter.Acquire(ctx, "confirmed", opts)
+		require.NoError(t, err)
+
+		confirm := opts
+		confirm.ConfirmIfOwned = true
+		confirmed, err := adapter.Acquire(ctx, "confirmed", confirm)
+		require.NoError(t, err)
+		require.Equal(t, token.LeaseID, confirmed.LeaseID)
+		require.NotEqual(t, token.ServerNonce, confirmed.ServerNonce)
+		require.ErrorIs(t, adapter.Release(ctx, token), core.ErrLockOwnershipMismatch)
+		require.NoError(t, adapter.Release(ctx, confirmed))
+	})
+
+	t.Run("given two adapters on the same file, when both acquire, then only one holds the key", func(t *testing.T) {
+		first, path := newAdapter(t, sqlitelock.NewSQLiteLockerConfig())
+		second := openAdapter(t, path, sqlitelock.NewSQLiteLockerConfig())
+
+		token, err := first.Acquire(ctx, "shared", opts)
+		require.NoError(t, err)
+		_, err = second.Acquire(ctx, "shared", opts)
+		require.ErrorIs(t, err, core.ErrLockContention)
+
+		require.NoError(t, first.Release(ctx, token))
+		token, err = second.Acquire(ctx, "shared", opts)
+		require.NoError(t, err)
+		require.NoError(t, second.Release(ctx, token))
+	})
+
+	t.Run("given a database busy with another writer, when acquire, then the busy error is retried", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "locks.db")
+		db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(10)&_pragma=journal_mode(WAL)")
+		require.NoError(t, err)
+		adapter, err := sqlitelock.NewSQLiteLockAdapter(ctx, db, sqlitelock.NewSQLiteLockerConfig())
+		require.NoError(t, err)
+		defer adapter.Close(ctx)
+
+		// Another process holding the write lock of the file
+		writer, err := sqlitelock.OpenDB(path)
+		require.NoError(t, err)
+		defer writer.Close()
+		conn, err := writer.Conn(ctx)
+		require.NoError(t, err)
+		defer conn.Close()
+		_, err = conn.ExecContext(ctx, "BEGIN IMMEDIATE")
+		require.NoError(t, err)
+
+		_, err = adapter.Acquire(ctx, "busy", opts)
+		require.True(t, sqlitelock.IsTransient(err), "got %v", err)
+
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			conn.ExecContext(ctx, "COMMIT")
+		}()
+		retried := opts
+		retried.RetryStrategy = core.RetryStrategy{MaxRetries: 50, BaseDelay: 20 * time.Millisecond, MaxDelay: 20 * time.Millisecond, BackoffFactor: 1}
+		token, err := adapter.Acquire(ctx, "busy", retried)
+		require.NoError(t, err)
+		require.NoError(t, adapter.Release(ctx, token))
+	})
+
+	t.Run("given an adapter, when health check, then reports the locks held", func(t *testing.T) {
+		adapter, _ := newAdapter(t, sqlitelock.NewSQLiteLockerConfig())
+		_, err := adapter.Acquire(ctx, "counted", opts)
+		require.NoError(t, err)
+
+		report := adapter.HealthCheck(ctx)
+		require.Equal(t, core.StatusGreen, report.Status, report.Error)
+		require.Equal(t, "sqlite", report.Backend)
+		require.Equal(t, "1", report.Details["locks"])
+	})
+}
+
+func TestSQLiteLockAdapter_Closed(t *testing.T) {
+	closed, _ := newAdapter(t, sqlitelock.NewSQLiteLockerConfig())
+	require.NoError(t, closed.Close(context.Background()))
+	require.NoError(t, closed.Close(context.Background()), "closing twice is a no-op")
+
+	ctx := context.Background()
+	token := &core.LockToken{Key: "key", LeaseID: "lease", ServerNonce: "nonce"}
+
+	t.Run("given a closed adapter, when operate, then returns ErrAdapterClosed", func(t *testing.T) {
+		_, err := closed.Acquire(ctx, "key", core.LockOptions{TTL: time.Second})
+		require.ErrorIs(t, err, core.ErrAdapterClosed)
+
+		_, err = closed.Refresh(ctx, token, time.Second)
+		require.ErrorIs(t, err, core.ErrAdapterClosed)
+
+		require.ErrorIs(t, closed.Release(ctx, token), core.ErrAdapterClosed)
+
+		_, _, err = closed.IsHeld(ctx, token)
+		require.ErrorIs(t, err, core.ErrAdapterClosed)
+	})
+
+	t.Run("given a closed adapter, when health check, then status is Red", func(t *testing.T) {
+		report := closed.HealthCheck(ctx)
+		require.Equal(t, core.StatusRed, report.Status)
+		require.ErrorIs(t, report.Error, core.ErrAdapterClosed)
+	})
+}
+
+func TestOpen_SQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locks.db")
+	adapter, err := core.Open(context.Background(), "sqlite://"+path)
+	require.NoError(t, err)
+	defer adapter.Close(context.Background())
+
+	token, err := adapter.Acquire(context.Background(), "opened", core.LockOptions{TTL: time.Second})
+	require.NoError(t, err)
+	require.NoError(t, adapter.Release(context.Background(), token))
+}
@@ -0,0 +1,33 @@
+package sqlitelock
+
+import (
+	"context"
+	"errors"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// IsTransient reports whether err is a failure a retry may absorb: the
+// database file busy (SQLITE_BUSY), e.g. written by another process
+// beyond the busy_timeout, or a table locked (SQLITE_LOCKED) by another
+// connection of a shared cache.
+//
+// The context errors are not transient: retrying would outlive the
+// caller.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	// The extended codes keep the primary code in their low byte
+	switch sqliteErr.Code() & 0xff {
+	case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+		return true
+	}
+	return false
+}